@@ -0,0 +1,141 @@
+package gogis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Dialect converts geometries into column values for a database.
+//
+// The geometry types implement gorm.Valuer and write their values through the
+// dialect registered for the GORM dialector in use. PostGIS, MySQL,
+// SpatiaLite and SQL Server are registered by default; other databases fall
+// back to the EWKT of Value.
+type Dialect interface {
+	// GeometryExpr returns the SQL expression storing g in a geometry
+	// column.
+	GeometryExpr(g Geometry) (clause.Expr, error)
+}
+
+var (
+	dialectsMu sync.RWMutex
+	dialects   = map[string]Dialect{
+		"postgres":  PostGISDialect{},
+		"mysql":     MySQLDialect{},
+		"sqlite":    SpatiaLiteDialect{},
+		"sqlserver": SQLServerDialect{},
+	}
+)
+
+// RegisterDialect registers the dialect used with the GORM dialector of the
+// given name, replacing any previous registration. For example, as
+// gorm.io/driver/mysql serves both MySQL and MariaDB under the name "mysql",
+// MariaDB databases need
+//
+//	gogis.RegisterDialect("mysql", gogis.MySQLDialect{MariaDB: true})
+func RegisterDialect(dialector string, d Dialect) {
+	dialectsMu.Lock()
+	defer dialectsMu.Unlock()
+	dialects[dialector] = d
+}
+
+// dialectOf returns the dialect registered for the dialector of db.
+func dialectOf(db *gorm.DB) Dialect {
+	name := "postgres"
+	if db != nil && db.Dialector != nil {
+		name = db.Dialector.Name()
+	}
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+	if d, ok := dialects[name]; ok {
+		return d
+	}
+	return PostGISDialect{}
+}
+
+// gormValue returns the column value expression of g in the dialect of db.
+func gormValue(db *gorm.DB, g Geometry) clause.Expr {
+	expr, err := dialectOf(db).GeometryExpr(g)
+	if err != nil {
+		if db != nil {
+			_ = db.AddError(err)
+		}
+		return clause.Expr{SQL: "NULL"}
+	}
+	return expr
+}
+
+// PostGISDialect writes geometries as EWKT parameters, which PostGIS casts
+// to geometry.
+type PostGISDialect struct{}
+
+// GeometryExpr returns a parameter holding the EWKT of g.
+func (PostGISDialect) GeometryExpr(g Geometry) (clause.Expr, error) {
+	return clause.Expr{SQL: "?", Vars: []interface{}{g.String()}}, nil
+}
+
+// GormValue implements the gorm.Valuer interface, writing the point in the
+// dialect registered for the GORM dialector; see Dialect.
+func (p Point) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	return gormValue(db, &p)
+}
+
+// GormValue implements the gorm.Valuer interface, writing the line string in
+// the dialect registered for the GORM dialector; see Dialect.
+func (ls LineString) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	return gormValue(db, &ls)
+}
+
+// GormValue implements the gorm.Valuer interface, writing the polygon in the
+// dialect registered for the GORM dialector; see Dialect.
+func (p Polygon) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	return gormValue(db, &p)
+}
+
+// GormValue implements the gorm.Valuer interface, writing the collection in
+// the dialect registered for the GORM dialector; see Dialect.
+func (gc GeometryCollection) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	return gormValue(db, &gc)
+}
+
+// scanBinary decodes a raw binary column value, as returned by MySQL, SQLite
+// and SQL Server, for the Scan methods of all geometry types: raw WKB,
+// MySQL's internal geometry format, SpatiaLite BLOBs, GeoPackage binary and
+// the SQL Server CLR format are accepted. It reports false for nil and for
+// hex encoded values, which PostGIS returns and Scan decodes as WKB. The
+// geography flag selects the latitude-longitude order of SQL Server
+// geography values.
+func scanBinary(val any, geography bool) (Geometry, bool, error) {
+	b, ok := val.([]byte)
+	if !ok || len(b) == 0 || isHex(b) {
+		return nil, false, nil
+	}
+	g, err := decodeBinary(b, geography)
+	return g, true, err
+}
+
+// decodeBinary decodes GeoPackage binary, SpatiaLite BLOBs, WKB, EWKB, the
+// MySQL internal geometry format or the SQL Server CLR format. Formats with
+// distinctive markers are tried first.
+func decodeBinary(b []byte, geography bool) (Geometry, error) {
+	if g, _, err := DecodeGeoPackage(b); err == nil {
+		return g, nil
+	}
+	if g, _, err := DecodeSpatiaLite(b); err == nil {
+		return g, nil
+	}
+	if g, _, err := DecodeEWKB(b); err == nil {
+		return g, nil
+	}
+	if g, _, err := DecodeMySQL(b); err == nil {
+		return g, nil
+	}
+	if g, _, err := DecodeSQLServer(b, geography); err == nil {
+		return g, nil
+	}
+	return nil, fmt.Errorf("gogis: unrecognized binary geometry format")
+}
@@ -30,10 +30,44 @@
 //		Path     gogis.LineString `gorm:"type:geometry(LineString,4326)"`
 //	}
 //
+// The type tag is optional: the geometry types also implement GORM's data type
+// interfaces, so AutoMigrate creates geometry(<Type>,4326) columns on
+// PostgreSQL by itself. The srid and geography tags adjust that type, as in
+// `gorm:"geography"` for a geography(Point,4326) column.
+//
+// # Geography
+//
+// GeographyPoint, GeographyLineString, GeographyPolygon and
+// GeographyCollection store the same shapes in geography columns, where
+// PostGIS measures on the spheroid and ST_DWithin takes meters instead of
+// degrees:
+//
+//	type Store struct {
+//		ID       uint
+//		Location gogis.GeographyPoint // geography(Point,4326)
+//	}
+//
+//	db.Where("ST_DWithin(location, ST_GeogFromText(?), ?)", center.String(), 1000).Find(&stores)
+//
+// They convert to and from the geometry types, as in gogis.Point(store.Location).
+//
+// # Other Databases
+//
+// Values are written through the Dialect registered for the GORM dialector.
+// With MySQL 8 geometries are sent as WKB through ST_GeomFromWKB, and Scan
+// reads MySQL's internal geometry format; RegisterDialect switches to
+// MySQLDialect{MariaDB: true} for MariaDB. With SQLite geometries are stored
+// as SpatiaLite BLOBs, or as GeoPackage binary after registering
+// GeoPackageDialect{} for the "sqlite" dialector; Scan reads both. SQL Server
+// receives and returns the serialization format of its CLR types, which
+// stores geography points in latitude-longitude order.
+//
 // # Coordinate System
 //
 // All geometries use SRID 4326 (WGS 84) coordinate system by default.
 // Coordinates are stored as longitude (X) and latitude (Y) in decimal degrees.
+// The proj subpackage transforms geometries into other systems, such as Web
+// Mercator or UTM, for planar math in meters.
 //
 // # Indexing
 //
@@ -64,9 +98,21 @@
 //
 // All geometry types can parse and generate Well-Known Binary (WKB) format
 // as used by PostGIS, supporting both little-endian and big-endian byte orders.
+// EncodeEWKB and DecodeEWKB convert any geometry to and from the Extended WKB
+// that PostGIS returns for geometry and geography columns. EncodeTWKB and
+// DecodeTWKB convert to and from Tiny WKB, the compact format of
+// ST_AsTWKB, which stores coordinates rounded to a chosen precision.
 //
 // # Well-Known Text (WKT) Support
 //
 // All geometry types provide String() methods that generate Well-Known Text (WKT)
-// format with SRID specification for use in SQL queries.
+// format with SRID specification for use in SQL queries. ParseWKT parses WKT
+// and Extended WKT back into a geometry.
+//
+// # Encoding Interfaces
+//
+// All geometry types implement encoding.TextMarshaler with Extended WKT and
+// encoding.BinaryMarshaler with WKB, so they can be stored in caches and
+// encoded with YAML or encoding/gob, including the geometries of a
+// GeometryCollection. Their JSON encoding remains an object of their fields.
 package gogis
@@ -0,0 +1,472 @@
+package gogis
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+)
+
+// The geometry types implement encoding.TextMarshaler with Extended WKT and
+// encoding.BinaryMarshaler with WKB, so they can be stored in caches and
+// encoded with packages such as YAML and encoding/gob. They also implement
+// json.Marshaler to keep their JSON encoding an object of their fields.
+
+func init() {
+	// Registering the types lets gob encode them in Geometry values, such
+	// as the geometries of a GeometryCollection.
+	for _, g := range []Geometry{
+		&Point{}, &LineString{}, &Polygon{}, &GeometryCollection{},
+		&GeographyPoint{}, &GeographyLineString{}, &GeographyPolygon{}, &GeographyCollection{},
+	} {
+		gob.Register(g)
+	}
+}
+
+// unmarshalText parses the WKT or EWKT text for the UnmarshalText method of
+// the named type. The SRID is ignored.
+func unmarshalText(text []byte, name string) (Geometry, error) {
+	g, _, err := ParseWKT(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("gogis: cannot unmarshal text into %s: %w", name, err)
+	}
+	return g, nil
+}
+
+// unmarshalBinary decodes the WKB or EWKB data for the UnmarshalBinary
+// method of the named type. The SRID is ignored.
+func unmarshalBinary(data []byte, name string) (Geometry, error) {
+	g, _, err := DecodeEWKB(data)
+	if err != nil {
+		return nil, fmt.Errorf("gogis: cannot unmarshal binary into %s: %w", name, err)
+	}
+	return g, nil
+}
+
+func mismatch(g Geometry, name string) error {
+	return fmt.Errorf("gogis: cannot unmarshal %T into %s", g, name)
+}
+
+// MarshalText implements encoding.TextMarshaler, returning the EWKT of the
+// point.
+func (p Point) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing a WKT or EWKT
+// point.
+func (p *Point) UnmarshalText(text []byte) error {
+	g, err := unmarshalText(text, "Point")
+	if err != nil {
+		return err
+	}
+	v, ok := g.(*Point)
+	if !ok {
+		return mismatch(g, "Point")
+	}
+	*p = *v
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, returning the WKB of
+// the point.
+func (p Point) MarshalBinary() ([]byte, error) {
+	return EncodeEWKB(&p, 0)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding a WKB or
+// EWKB point.
+func (p *Point) UnmarshalBinary(data []byte) error {
+	g, err := unmarshalBinary(data, "Point")
+	if err != nil {
+		return err
+	}
+	v, ok := g.(*Point)
+	if !ok {
+		return mismatch(g, "Point")
+	}
+	*p = *v
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding the fields of the point.
+func (p Point) MarshalJSON() ([]byte, error) {
+	type point Point
+	return json.Marshal(point(p))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding the fields of the
+// point.
+func (p *Point) UnmarshalJSON(data []byte) error {
+	type point Point
+	return json.Unmarshal(data, (*point)(p))
+}
+
+// MarshalText implements encoding.TextMarshaler, returning the EWKT of the
+// line string.
+func (ls LineString) MarshalText() ([]byte, error) {
+	return []byte(ls.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing a WKT or EWKT
+// line string.
+func (ls *LineString) UnmarshalText(text []byte) error {
+	g, err := unmarshalText(text, "LineString")
+	if err != nil {
+		return err
+	}
+	v, ok := g.(*LineString)
+	if !ok {
+		return mismatch(g, "LineString")
+	}
+	*ls = *v
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, returning the WKB of
+// the line string.
+func (ls LineString) MarshalBinary() ([]byte, error) {
+	return EncodeEWKB(&ls, 0)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding a WKB or
+// EWKB line string.
+func (ls *LineString) UnmarshalBinary(data []byte) error {
+	g, err := unmarshalBinary(data, "LineString")
+	if err != nil {
+		return err
+	}
+	v, ok := g.(*LineString)
+	if !ok {
+		return mismatch(g, "LineString")
+	}
+	*ls = *v
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding the fields of the line
+// string.
+func (ls LineString) MarshalJSON() ([]byte, error) {
+	type lineString LineString
+	return json.Marshal(lineString(ls))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding the fields of the line
+// string.
+func (ls *LineString) UnmarshalJSON(data []byte) error {
+	type lineString LineString
+	return json.Unmarshal(data, (*lineString)(ls))
+}
+
+// MarshalText implements encoding.TextMarshaler, returning the EWKT of the
+// polygon.
+func (p Polygon) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing a WKT or EWKT
+// polygon.
+func (p *Polygon) UnmarshalText(text []byte) error {
+	g, err := unmarshalText(text, "Polygon")
+	if err != nil {
+		return err
+	}
+	v, ok := g.(*Polygon)
+	if !ok {
+		return mismatch(g, "Polygon")
+	}
+	*p = *v
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, returning the WKB of
+// the polygon.
+func (p Polygon) MarshalBinary() ([]byte, error) {
+	return EncodeEWKB(&p, 0)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding a WKB or
+// EWKB polygon.
+func (p *Polygon) UnmarshalBinary(data []byte) error {
+	g, err := unmarshalBinary(data, "Polygon")
+	if err != nil {
+		return err
+	}
+	v, ok := g.(*Polygon)
+	if !ok {
+		return mismatch(g, "Polygon")
+	}
+	*p = *v
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding the fields of the polygon.
+func (p Polygon) MarshalJSON() ([]byte, error) {
+	type polygon Polygon
+	return json.Marshal(polygon(p))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding the fields of the
+// polygon.
+func (p *Polygon) UnmarshalJSON(data []byte) error {
+	type polygon Polygon
+	return json.Unmarshal(data, (*polygon)(p))
+}
+
+// MarshalText implements encoding.TextMarshaler, returning the EWKT of the
+// collection.
+func (gc GeometryCollection) MarshalText() ([]byte, error) {
+	return []byte(gc.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing a WKT or EWKT
+// geometry collection or multi geometry.
+func (gc *GeometryCollection) UnmarshalText(text []byte) error {
+	g, err := unmarshalText(text, "GeometryCollection")
+	if err != nil {
+		return err
+	}
+	v, ok := g.(*GeometryCollection)
+	if !ok {
+		return mismatch(g, "GeometryCollection")
+	}
+	*gc = *v
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, returning the WKB of
+// the collection.
+func (gc GeometryCollection) MarshalBinary() ([]byte, error) {
+	return EncodeEWKB(&gc, 0)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding a WKB or
+// EWKB geometry collection or multi geometry.
+func (gc *GeometryCollection) UnmarshalBinary(data []byte) error {
+	g, err := unmarshalBinary(data, "GeometryCollection")
+	if err != nil {
+		return err
+	}
+	v, ok := g.(*GeometryCollection)
+	if !ok {
+		return mismatch(g, "GeometryCollection")
+	}
+	*gc = *v
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding the fields of the
+// collection.
+func (gc GeometryCollection) MarshalJSON() ([]byte, error) {
+	type collection GeometryCollection
+	return json.Marshal(collection(gc))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding the object that
+// MarshalJSON returns. The type of each geometry is told by its fields:
+// "lng" and "lat" for a Point, "points" for a LineString, "rings" for a
+// Polygon and "geometries" for a GeometryCollection.
+func (gc *GeometryCollection) UnmarshalJSON(data []byte) error {
+	v, err := unmarshalCollectionJSON(data, 0)
+	if err != nil {
+		return err
+	}
+	if v != nil {
+		*gc = *v
+	}
+	return nil
+}
+
+// unmarshalCollectionJSON decodes a JSON collection object, returning nil
+// for null.
+func unmarshalCollectionJSON(data []byte, depth int) (*GeometryCollection, error) {
+	if depth > maxWKTDepth {
+		return nil, fmt.Errorf("gogis: geometry collections nested too deeply")
+	}
+	var raw *struct {
+		Geometries []json.RawMessage `json:"geometries"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+	gc := &GeometryCollection{}
+	if raw.Geometries != nil {
+		gc.Geometries = make([]Geometry, len(raw.Geometries))
+	}
+	for i, child := range raw.Geometries {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(child, &fields); err != nil {
+			return nil, err
+		}
+		if fields == nil {
+			// A null geometry.
+			continue
+		}
+		var g Geometry
+		var err error
+		switch {
+		case fields["geometries"] != nil:
+			g, err = unmarshalCollectionJSON(child, depth+1)
+		case fields["rings"] != nil:
+			g = &Polygon{}
+			err = json.Unmarshal(child, g)
+		case fields["points"] != nil:
+			g = &LineString{}
+			err = json.Unmarshal(child, g)
+		case fields["lng"] != nil || fields["lat"] != nil:
+			g = &Point{}
+			err = json.Unmarshal(child, g)
+		default:
+			return nil, fmt.Errorf("gogis: cannot tell the geometry type of %s", child)
+		}
+		if err != nil {
+			return nil, err
+		}
+		gc.Geometries[i] = g
+	}
+	return gc, nil
+}
+
+// MarshalText implements encoding.TextMarshaler, returning the EWKT of the
+// point.
+func (p GeographyPoint) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing a WKT or EWKT
+// point.
+func (p *GeographyPoint) UnmarshalText(text []byte) error {
+	return (*Point)(p).UnmarshalText(text)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, returning the WKB of
+// the point.
+func (p GeographyPoint) MarshalBinary() ([]byte, error) {
+	return Point(p).MarshalBinary()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding a WKB or
+// EWKB point.
+func (p *GeographyPoint) UnmarshalBinary(data []byte) error {
+	return (*Point)(p).UnmarshalBinary(data)
+}
+
+// MarshalJSON implements json.Marshaler, encoding the fields of the point.
+func (p GeographyPoint) MarshalJSON() ([]byte, error) {
+	return Point(p).MarshalJSON()
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding the fields of the
+// point.
+func (p *GeographyPoint) UnmarshalJSON(data []byte) error {
+	return (*Point)(p).UnmarshalJSON(data)
+}
+
+// MarshalText implements encoding.TextMarshaler, returning the EWKT of the
+// line string.
+func (ls GeographyLineString) MarshalText() ([]byte, error) {
+	return []byte(ls.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing a WKT or EWKT
+// line string.
+func (ls *GeographyLineString) UnmarshalText(text []byte) error {
+	return (*LineString)(ls).UnmarshalText(text)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, returning the WKB of
+// the line string.
+func (ls GeographyLineString) MarshalBinary() ([]byte, error) {
+	return LineString(ls).MarshalBinary()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding a WKB or
+// EWKB line string.
+func (ls *GeographyLineString) UnmarshalBinary(data []byte) error {
+	return (*LineString)(ls).UnmarshalBinary(data)
+}
+
+// MarshalJSON implements json.Marshaler, encoding the fields of the line
+// string.
+func (ls GeographyLineString) MarshalJSON() ([]byte, error) {
+	return LineString(ls).MarshalJSON()
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding the fields of the line
+// string.
+func (ls *GeographyLineString) UnmarshalJSON(data []byte) error {
+	return (*LineString)(ls).UnmarshalJSON(data)
+}
+
+// MarshalText implements encoding.TextMarshaler, returning the EWKT of the
+// polygon.
+func (p GeographyPolygon) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing a WKT or EWKT
+// polygon.
+func (p *GeographyPolygon) UnmarshalText(text []byte) error {
+	return (*Polygon)(p).UnmarshalText(text)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, returning the WKB of
+// the polygon.
+func (p GeographyPolygon) MarshalBinary() ([]byte, error) {
+	return Polygon(p).MarshalBinary()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding a WKB or
+// EWKB polygon.
+func (p *GeographyPolygon) UnmarshalBinary(data []byte) error {
+	return (*Polygon)(p).UnmarshalBinary(data)
+}
+
+// MarshalJSON implements json.Marshaler, encoding the fields of the polygon.
+func (p GeographyPolygon) MarshalJSON() ([]byte, error) {
+	return Polygon(p).MarshalJSON()
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding the fields of the
+// polygon.
+func (p *GeographyPolygon) UnmarshalJSON(data []byte) error {
+	return (*Polygon)(p).UnmarshalJSON(data)
+}
+
+// MarshalText implements encoding.TextMarshaler, returning the EWKT of the
+// collection.
+func (gc GeographyCollection) MarshalText() ([]byte, error) {
+	return []byte(gc.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing a WKT or EWKT
+// geometry collection or multi geometry.
+func (gc *GeographyCollection) UnmarshalText(text []byte) error {
+	return (*GeometryCollection)(gc).UnmarshalText(text)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, returning the WKB of
+// the collection.
+func (gc GeographyCollection) MarshalBinary() ([]byte, error) {
+	return GeometryCollection(gc).MarshalBinary()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding a WKB or
+// EWKB geometry collection or multi geometry.
+func (gc *GeographyCollection) UnmarshalBinary(data []byte) error {
+	return (*GeometryCollection)(gc).UnmarshalBinary(data)
+}
+
+// MarshalJSON implements json.Marshaler, encoding the fields of the
+// collection.
+func (gc GeographyCollection) MarshalJSON() ([]byte, error) {
+	return GeometryCollection(gc).MarshalJSON()
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding the object that
+// MarshalJSON returns.
+func (gc *GeographyCollection) UnmarshalJSON(data []byte) error {
+	return (*GeometryCollection)(gc).UnmarshalJSON(data)
+}
@@ -0,0 +1,148 @@
+package gogis_test
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/restayway/gogis"
+)
+
+func TestTextAndBinaryRoundTrip(t *testing.T) {
+	line := []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3.5, Lat: -4}}
+	ring := []gogis.Point{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 0}, {Lng: 1, Lat: 1}, {Lng: 0, Lat: 0}}
+	collection := []gogis.Geometry{&gogis.Point{Lng: 1, Lat: 2}, &gogis.Polygon{Rings: [][]gogis.Point{ring}}}
+	tests := []struct {
+		in  any // value implementing the marshalers
+		out func() any
+	}{
+		{gogis.Point{Lng: -74.0445, Lat: 40.6892}, func() any { return &gogis.Point{} }},
+		{gogis.LineString{Points: line}, func() any { return &gogis.LineString{} }},
+		{gogis.Polygon{Rings: [][]gogis.Point{ring}}, func() any { return &gogis.Polygon{} }},
+		{gogis.GeometryCollection{Geometries: collection}, func() any { return &gogis.GeometryCollection{} }},
+		{gogis.GeographyPoint{Lng: 1, Lat: 2}, func() any { return &gogis.GeographyPoint{} }},
+		{gogis.GeographyLineString{Points: line}, func() any { return &gogis.GeographyLineString{} }},
+		{gogis.GeographyPolygon{Rings: [][]gogis.Point{ring}}, func() any { return &gogis.GeographyPolygon{} }},
+		{gogis.GeographyCollection{Geometries: collection}, func() any { return &gogis.GeographyCollection{} }},
+	}
+	for _, tt := range tests {
+		text, err := tt.in.(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			t.Fatalf("%T.MarshalText: %v", tt.in, err)
+		}
+		got := tt.out()
+		if err := got.(encoding.TextUnmarshaler).UnmarshalText(text); err != nil {
+			t.Fatalf("%T.UnmarshalText(%q): %v", got, text, err)
+		}
+		if v := reflect.ValueOf(got).Elem().Interface(); !reflect.DeepEqual(v, tt.in) {
+			t.Errorf("text round trip of %v = %v", tt.in, v)
+		}
+
+		data, err := tt.in.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			t.Fatalf("%T.MarshalBinary: %v", tt.in, err)
+		}
+		got = tt.out()
+		if err := got.(encoding.BinaryUnmarshaler).UnmarshalBinary(data); err != nil {
+			t.Fatalf("%T.UnmarshalBinary: %v", got, err)
+		}
+		if v := reflect.ValueOf(got).Elem().Interface(); !reflect.DeepEqual(v, tt.in) {
+			t.Errorf("binary round trip of %v = %v", tt.in, v)
+		}
+	}
+}
+
+func TestUnmarshalTypeMismatch(t *testing.T) {
+	var p gogis.Point
+	if err := p.UnmarshalText([]byte("LINESTRING(1 2,3 4)")); err == nil {
+		t.Error("Point.UnmarshalText of a line string: no error")
+	}
+	data, _ := gogis.Point{Lng: 1, Lat: 2}.MarshalBinary()
+	var ls gogis.LineString
+	if err := ls.UnmarshalBinary(data); err == nil {
+		t.Error("LineString.UnmarshalBinary of a point: no error")
+	}
+	var gc gogis.GeometryCollection
+	if err := gc.UnmarshalText([]byte("POINT(1 2)")); err == nil {
+		t.Error("GeometryCollection.UnmarshalText of a point: no error")
+	}
+}
+
+func TestGob(t *testing.T) {
+	type record struct {
+		Name     string
+		Location gogis.Point
+		Shape    gogis.Geometry
+		Parts    gogis.GeometryCollection
+	}
+	in := record{
+		Name:     "site",
+		Location: gogis.Point{Lng: 1, Lat: 2},
+		Shape:    &gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}}},
+		Parts: gogis.GeometryCollection{Geometries: []gogis.Geometry{
+			&gogis.Point{Lng: 5, Lat: 6},
+			&gogis.GeometryCollection{Geometries: []gogis.Geometry{&gogis.Point{Lng: 7, Lat: 8}}},
+		}},
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(in); err != nil {
+		t.Fatal(err)
+	}
+	var out record
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("gob round trip = %+v, want %+v", out, in)
+	}
+}
+
+func TestJSONShape(t *testing.T) {
+	b, err := json.Marshal(struct {
+		Point gogis.Point                `json:"point"`
+		Geo   *gogis.GeographyLineString `json:"geo"`
+	}{gogis.Point{Lng: 1, Lat: 2}, &gogis.GeographyLineString{Points: []gogis.Point{{Lng: 3, Lat: 4}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"point":{"lng":1,"lat":2},"geo":{"points":[{"lng":3,"lat":4}]}}`
+	if string(b) != want {
+		t.Errorf("json = %s, want %s", b, want)
+	}
+
+	var p gogis.GeographyPoint
+	if err := json.Unmarshal([]byte(`{"lng":5,"lat":6}`), &p); err != nil || p != (gogis.GeographyPoint{Lng: 5, Lat: 6}) {
+		t.Errorf("json.Unmarshal = %v, %v", p, err)
+	}
+}
+
+func TestCollectionJSONRoundTrip(t *testing.T) {
+	in := gogis.GeometryCollection{Geometries: []gogis.Geometry{
+		&gogis.Point{Lng: 1, Lat: 2},
+		&gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}}},
+		&gogis.Polygon{Rings: [][]gogis.Point{{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 0}, {Lng: 1, Lat: 1}, {Lng: 0, Lat: 0}}}},
+		&gogis.GeometryCollection{Geometries: []gogis.Geometry{}},
+	}}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out gogis.GeometryCollection
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("json.Unmarshal(%s): %v", b, err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("json round trip of %s = %v", b, out)
+	}
+
+	var geo gogis.GeographyCollection
+	if err := json.Unmarshal([]byte(`{"geometries":[]}`), &geo); err != nil || len(geo.Geometries) != 0 {
+		t.Errorf("json.Unmarshal of an empty collection = %v, %v", geo, err)
+	}
+	if err := json.Unmarshal([]byte(`{"geometries":[{"radius":1}]}`), &out); err == nil {
+		t.Error("json.Unmarshal of an unknown geometry: no error")
+	}
+}
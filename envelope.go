@@ -0,0 +1,50 @@
+package gogis
+
+import "math"
+
+// envelope is the bounding box of a geometry.
+type envelope struct {
+	minX, minY, maxX, maxY float64
+}
+
+// envelopeOf returns the bounding box of g. It reports false for geometries
+// without coordinates.
+func envelopeOf(g Geometry) (envelope, bool) {
+	e := envelope{minX: math.Inf(1), minY: math.Inf(1), maxX: math.Inf(-1), maxY: math.Inf(-1)}
+	e.extend(g)
+	return e, e.minX <= e.maxX
+}
+
+func (e *envelope) extendPoints(points []Point) {
+	for _, p := range points {
+		e.minX = math.Min(e.minX, p.Lng)
+		e.minY = math.Min(e.minY, p.Lat)
+		e.maxX = math.Max(e.maxX, p.Lng)
+		e.maxY = math.Max(e.maxY, p.Lat)
+	}
+}
+
+func (e *envelope) extend(g Geometry) {
+	switch v := g.(type) {
+	case *Point:
+		e.extendPoints([]Point{*v})
+	case *LineString:
+		e.extendPoints(v.Points)
+	case *Polygon:
+		for _, ring := range v.Rings {
+			e.extendPoints(ring)
+		}
+	case *GeometryCollection:
+		for _, child := range v.Geometries {
+			e.extend(child)
+		}
+	case *GeographyPoint:
+		e.extend((*Point)(v))
+	case *GeographyLineString:
+		e.extend((*LineString)(v))
+	case *GeographyPolygon:
+		e.extend((*Polygon)(v))
+	case *GeographyCollection:
+		e.extend((*GeometryCollection)(v))
+	}
+}
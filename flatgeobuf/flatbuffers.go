@@ -0,0 +1,391 @@
+package flatgeobuf
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// builder builds a FlatBuffers buffer back to front, as the reference
+// builders do: objects are written before the objects referring to them, and
+// offsets are measured from the end of the buffer until it is finished.
+type builder struct {
+	buf      []byte // the data is buf[head:]
+	head     int
+	minAlign int
+
+	fields   []int // end offsets of the fields of the open table, 0 if absent
+	tableEnd int   // offset at the start of the open table
+}
+
+func newBuilder(size int) *builder {
+	return &builder{buf: make([]byte, size), head: size, minAlign: 1}
+}
+
+// offset returns the size of the data written so far, which identifies the
+// last object written.
+func (b *builder) offset() int {
+	return len(b.buf) - b.head
+}
+
+// grow makes room for n more bytes in front of the data.
+func (b *builder) grow(n int) {
+	if b.head >= n {
+		return
+	}
+	size := 2 * len(b.buf)
+	if size < len(b.buf)+n {
+		size = len(b.buf) + n
+	}
+	buf := make([]byte, size)
+	copy(buf[size-b.offset():], b.buf[b.head:])
+	b.head += size - len(b.buf)
+	b.buf = buf
+}
+
+// prep aligns the data so that after additional bytes, a value of size bytes
+// can be written aligned to its size.
+func (b *builder) prep(size, additional int) {
+	if size > b.minAlign {
+		b.minAlign = size
+	}
+	pad := -(b.offset() + additional) & (size - 1)
+	b.grow(pad + size + additional)
+	for i := 0; i < pad; i++ {
+		b.head--
+		b.buf[b.head] = 0
+	}
+}
+
+func (b *builder) prependUint8(v uint8) {
+	b.prep(1, 0)
+	b.head--
+	b.buf[b.head] = v
+}
+
+func (b *builder) prependUint16(v uint16) {
+	b.prep(2, 0)
+	b.head -= 2
+	binary.LittleEndian.PutUint16(b.buf[b.head:], v)
+}
+
+func (b *builder) prependUint32(v uint32) {
+	b.prep(4, 0)
+	b.head -= 4
+	binary.LittleEndian.PutUint32(b.buf[b.head:], v)
+}
+
+func (b *builder) prependUint64(v uint64) {
+	b.prep(8, 0)
+	b.head -= 8
+	binary.LittleEndian.PutUint64(b.buf[b.head:], v)
+}
+
+// prependOffset writes a reference to the object at offset off.
+func (b *builder) prependOffset(off int) {
+	b.prep(4, 0)
+	b.head -= 4
+	binary.LittleEndian.PutUint32(b.buf[b.head:], uint32(b.offset()-off))
+}
+
+// startVector prepares a vector of n elements of size bytes, which are then
+// prepended in reverse order.
+func (b *builder) startVector(size, n, align int) {
+	b.prep(4, size*n)
+	b.prep(align, size*n)
+}
+
+func (b *builder) endVector(n int) int {
+	b.head -= 4
+	binary.LittleEndian.PutUint32(b.buf[b.head:], uint32(n))
+	return b.offset()
+}
+
+func (b *builder) createString(s string) int {
+	b.prep(4, len(s)+1)
+	b.head--
+	b.buf[b.head] = 0
+	b.head -= len(s)
+	copy(b.buf[b.head:], s)
+	return b.endVector(len(s))
+}
+
+func (b *builder) createBytes(v []byte) int {
+	b.startVector(1, len(v), 1)
+	b.head -= len(v)
+	copy(b.buf[b.head:], v)
+	return b.endVector(len(v))
+}
+
+func (b *builder) createFloat64s(v []float64) int {
+	b.startVector(8, len(v), 8)
+	for i := len(v) - 1; i >= 0; i-- {
+		b.prependUint64(math.Float64bits(v[i]))
+	}
+	return b.endVector(len(v))
+}
+
+func (b *builder) createUint32s(v []uint32) int {
+	b.startVector(4, len(v), 4)
+	for i := len(v) - 1; i >= 0; i-- {
+		b.prependUint32(v[i])
+	}
+	return b.endVector(len(v))
+}
+
+func (b *builder) createOffsets(v []int) int {
+	b.startVector(4, len(v), 4)
+	for i := len(v) - 1; i >= 0; i-- {
+		b.prependOffset(v[i])
+	}
+	return b.endVector(len(v))
+}
+
+// startTable starts a table of n fields. Its fields are added with the add
+// methods, after the objects they refer to have been created.
+func (b *builder) startTable(n int) {
+	b.fields = make([]int, n)
+	b.tableEnd = b.offset()
+}
+
+func (b *builder) addUint8(field int, v, def uint8) {
+	if v != def {
+		b.prependUint8(v)
+		b.fields[field] = b.offset()
+	}
+}
+
+func (b *builder) addUint16(field int, v, def uint16) {
+	if v != def {
+		b.prependUint16(v)
+		b.fields[field] = b.offset()
+	}
+}
+
+func (b *builder) addInt32(field int, v, def int32) {
+	if v != def {
+		b.prependUint32(uint32(v))
+		b.fields[field] = b.offset()
+	}
+}
+
+func (b *builder) addUint64(field int, v, def uint64) {
+	if v != def {
+		b.prependUint64(v)
+		b.fields[field] = b.offset()
+	}
+}
+
+// addOffset adds a reference to the object at offset off, unless off is 0.
+func (b *builder) addOffset(field int, off int) {
+	if off != 0 {
+		b.prependOffset(off)
+		b.fields[field] = b.offset()
+	}
+}
+
+// endTable writes the table with its vtable and returns its offset.
+func (b *builder) endTable() int {
+	b.prependUint32(0) // replaced by the vtable offset below
+	table := b.offset()
+
+	n := len(b.fields)
+	for n > 0 && b.fields[n-1] == 0 {
+		n--
+	}
+	for i := n - 1; i >= 0; i-- {
+		var off uint16
+		if b.fields[i] != 0 {
+			off = uint16(table - b.fields[i])
+		}
+		b.prependUint16(off)
+	}
+	b.prependUint16(uint16(table - b.tableEnd))
+	b.prependUint16(uint16(4 + 2*n))
+
+	binary.LittleEndian.PutUint32(b.buf[len(b.buf)-table:], uint32(b.offset()-table))
+	b.fields = nil
+	return table
+}
+
+// finish completes the buffer with root as its root table and returns it
+// with its size prefix.
+func (b *builder) finish(root int) []byte {
+	b.prep(b.minAlign, 8)
+	b.prependOffset(root)
+	b.prependUint32(uint32(b.offset()))
+	return b.buf[b.head:]
+}
+
+var errInvalidBuffer = errors.New("invalid FlatBuffers data")
+
+// buffer reads a FlatBuffers buffer. Reads outside the buffer return zero
+// values and set err, so a whole table can be decoded before checking it.
+type buffer struct {
+	b   []byte
+	err error
+}
+
+// table is a table of a buffer.
+type table struct {
+	buf    *buffer
+	pos    int
+	vtable int
+	vsize  int
+}
+
+func (r *buffer) check(pos, n int) bool {
+	if r.err != nil || pos < 0 || n < 0 || pos > len(r.b)-n {
+		r.err = errInvalidBuffer
+		return false
+	}
+	return true
+}
+
+func (r *buffer) uint16At(pos int) uint16 {
+	if !r.check(pos, 2) {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(r.b[pos:])
+}
+
+func (r *buffer) uint32At(pos int) uint32 {
+	if !r.check(pos, 4) {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(r.b[pos:])
+}
+
+func (r *buffer) uint64At(pos int) uint64 {
+	if !r.check(pos, 8) {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(r.b[pos:])
+}
+
+// root returns the root table of the buffer.
+func (r *buffer) root() table {
+	return r.table(int(r.uint32At(0)))
+}
+
+func (r *buffer) table(pos int) table {
+	t := table{buf: r, pos: pos}
+	t.vtable = pos - int(int32(r.uint32At(pos)))
+	t.vsize = int(r.uint16At(t.vtable))
+	return t
+}
+
+// field returns the position of a field, or 0 if it is absent.
+func (t table) field(i int) int {
+	o := 4 + 2*i
+	if o+2 > t.vsize {
+		return 0
+	}
+	off := int(t.buf.uint16At(t.vtable + o))
+	if off == 0 {
+		return 0
+	}
+	return t.pos + off
+}
+
+func (t table) uint8(i int, def uint8) uint8 {
+	pos := t.field(i)
+	if pos == 0 || !t.buf.check(pos, 1) {
+		return def
+	}
+	return t.buf.b[pos]
+}
+
+func (t table) uint16(i int, def uint16) uint16 {
+	if pos := t.field(i); pos != 0 {
+		return t.buf.uint16At(pos)
+	}
+	return def
+}
+
+func (t table) int32(i int, def int32) int32 {
+	if pos := t.field(i); pos != 0 {
+		return int32(t.buf.uint32At(pos))
+	}
+	return def
+}
+
+func (t table) uint64(i int, def uint64) uint64 {
+	if pos := t.field(i); pos != 0 {
+		return t.buf.uint64At(pos)
+	}
+	return def
+}
+
+// deref follows the reference stored in field i.
+func (t table) deref(i int) (int, bool) {
+	pos := t.field(i)
+	if pos == 0 {
+		return 0, false
+	}
+	return pos + int(t.buf.uint32At(pos)), true
+}
+
+// vector returns the position of the first element and the length of the
+// vector in field i, which holds elements of size bytes.
+func (t table) vector(i, size int) (int, int) {
+	pos, ok := t.deref(i)
+	if !ok {
+		return 0, 0
+	}
+	n := int(t.buf.uint32At(pos))
+	if !t.buf.check(pos+4, n*size) {
+		return 0, 0
+	}
+	return pos + 4, n
+}
+
+func (t table) bytes(i int) []byte {
+	pos, n := t.vector(i, 1)
+	if n == 0 {
+		return nil
+	}
+	return t.buf.b[pos : pos+n]
+}
+
+func (t table) string(i int) string {
+	return string(t.bytes(i))
+}
+
+func (t table) float64s(i int) []float64 {
+	pos, n := t.vector(i, 8)
+	v := make([]float64, n)
+	for j := range v {
+		v[j] = math.Float64frombits(binary.LittleEndian.Uint64(t.buf.b[pos+8*j:]))
+	}
+	return v
+}
+
+func (t table) uint32s(i int) []uint32 {
+	pos, n := t.vector(i, 4)
+	v := make([]uint32, n)
+	for j := range v {
+		v[j] = binary.LittleEndian.Uint32(t.buf.b[pos+4*j:])
+	}
+	return v
+}
+
+// child returns the table in field i.
+func (t table) child(i int) (table, bool) {
+	pos, ok := t.deref(i)
+	if !ok {
+		return table{}, false
+	}
+	return t.buf.table(pos), true
+}
+
+// tables returns the tables of the vector in field i.
+func (t table) tables(i int) []table {
+	pos, n := t.vector(i, 4)
+	v := make([]table, n)
+	for j := range v {
+		e := pos + 4*j
+		v[j] = t.buf.table(e + int(t.buf.uint32At(e)))
+	}
+	return v
+}
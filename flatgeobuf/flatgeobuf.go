@@ -0,0 +1,172 @@
+// Package flatgeobuf reads and writes FlatGeobuf files with gogis geometries.
+//
+// A FlatGeobuf file holds a header describing the columns of the features,
+// an optional packed Hilbert R-tree indexing their bounding boxes, and the
+// features, each a FlatBuffers message with a geometry and a binary record
+// of properties. The index lets a Reader fetch only the features
+// intersecting a bounding box, reading a few ranges of the file, which makes
+// the format well suited to files served over HTTP range requests.
+//
+// Geometries map to gogis types as follows. Z and M values are dropped and
+// features without a geometry have a nil one. When writing, collections of
+// a single geometry type are written as the matching multi geometry.
+//
+//	Point               *gogis.Point
+//	LineString          *gogis.LineString
+//	Polygon             *gogis.Polygon
+//	MultiPoint,
+//	MultiLineString,
+//	MultiPolygon,
+//	GeometryCollection  *gogis.GeometryCollection
+//
+// Example:
+//
+//	f, err := os.Open("parcels.fgb")
+//	if err != nil {
+//	    return err
+//	}
+//	defer f.Close()
+//	r, err := flatgeobuf.NewReader(f)
+//	if err != nil {
+//	    return err
+//	}
+//	features, err := r.Search(flatgeobuf.Bounds{MinX: 4.8, MinY: 52.3, MaxX: 5.0, MaxY: 52.4})
+package flatgeobuf
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/restayway/gogis"
+)
+
+// magic starts every FlatGeobuf file: "fgb", the major version 3, "fgb" and
+// the patch version.
+var magic = [8]byte{'f', 'g', 'b', 3, 'f', 'g', 'b', 0}
+
+// DefaultNodeSize is the number of children of the nodes of the index,
+// unless WriterOptions gives another.
+const DefaultNodeSize = 16
+
+// GeometryType is the type of the geometries of a file or feature.
+type GeometryType uint8
+
+const (
+	GeometryTypeUnknown            GeometryType = 0
+	GeometryTypePoint              GeometryType = 1
+	GeometryTypeLineString         GeometryType = 2
+	GeometryTypePolygon            GeometryType = 3
+	GeometryTypeMultiPoint         GeometryType = 4
+	GeometryTypeMultiLineString    GeometryType = 5
+	GeometryTypeMultiPolygon       GeometryType = 6
+	GeometryTypeGeometryCollection GeometryType = 7
+)
+
+func (t GeometryType) String() string {
+	switch t {
+	case GeometryTypeUnknown:
+		return "Unknown"
+	case GeometryTypePoint:
+		return "Point"
+	case GeometryTypeLineString:
+		return "LineString"
+	case GeometryTypePolygon:
+		return "Polygon"
+	case GeometryTypeMultiPoint:
+		return "MultiPoint"
+	case GeometryTypeMultiLineString:
+		return "MultiLineString"
+	case GeometryTypeMultiPolygon:
+		return "MultiPolygon"
+	case GeometryTypeGeometryCollection:
+		return "GeometryCollection"
+	}
+	return fmt.Sprintf("GeometryType(%d)", uint8(t))
+}
+
+// ColumnType is the type of the values of a column. Values are read as the
+// Go type given for each constant.
+type ColumnType uint8
+
+const (
+	ColumnTypeByte     ColumnType = iota // int8
+	ColumnTypeUByte                      // uint8
+	ColumnTypeBool                       // bool
+	ColumnTypeShort                      // int16
+	ColumnTypeUShort                     // uint16
+	ColumnTypeInt                        // int32
+	ColumnTypeUInt                       // uint32
+	ColumnTypeLong                       // int64
+	ColumnTypeULong                      // uint64
+	ColumnTypeFloat                      // float32
+	ColumnTypeDouble                     // float64
+	ColumnTypeString                     // string
+	ColumnTypeJSON                       // string
+	ColumnTypeDateTime                   // time.Time, or string if not ISO 8601
+	ColumnTypeBinary                     // []byte
+)
+
+func (t ColumnType) String() string {
+	names := [...]string{"Byte", "UByte", "Bool", "Short", "UShort", "Int", "UInt", "Long", "ULong", "Float", "Double", "String", "Json", "DateTime", "Binary"}
+	if int(t) < len(names) {
+		return names[t]
+	}
+	return fmt.Sprintf("ColumnType(%d)", uint8(t))
+}
+
+// Column describes a property of the features.
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// Header describes a FlatGeobuf file.
+type Header struct {
+	Name          string
+	Title         string
+	Description   string
+	GeometryType  GeometryType // GeometryTypeUnknown for mixed types
+	Columns       []Column
+	FeaturesCount uint64     // number of features, 0 when unknown
+	IndexNodeSize uint16     // node size of the index, 0 without index
+	Bounds        *Bounds    // bounding box of the features, if given
+	SRID          gogis.SRID // EPSG code of the coordinate system, 0 if unknown
+}
+
+// Feature is a geometry with its properties.
+type Feature struct {
+	Geometry   gogis.Geometry // nil for features without geometry
+	Properties map[string]any // property values by column name
+}
+
+// Bounds is a bounding box.
+type Bounds struct {
+	MinX, MinY, MaxX, MaxY float64
+}
+
+// emptyBounds is the bounding box of features without coordinates, which
+// intersects no other.
+var emptyBounds = Bounds{MinX: math.Inf(1), MinY: math.Inf(1), MaxX: math.Inf(-1), MaxY: math.Inf(-1)}
+
+// Intersects reports whether b and o share at least one point.
+func (b Bounds) Intersects(o Bounds) bool {
+	return b.MinX <= o.MaxX && o.MinX <= b.MaxX && b.MinY <= o.MaxY && o.MinY <= b.MaxY
+}
+
+// extend grows b to include o.
+func (b *Bounds) extend(o Bounds) {
+	b.MinX = math.Min(b.MinX, o.MinX)
+	b.MinY = math.Min(b.MinY, o.MinY)
+	b.MaxX = math.Max(b.MaxX, o.MaxX)
+	b.MaxY = math.Max(b.MaxY, o.MaxY)
+}
+
+func (b *Bounds) extendPoints(points []gogis.Point) {
+	for _, p := range points {
+		b.extend(Bounds{MinX: p.Lng, MinY: p.Lat, MaxX: p.Lng, MaxY: p.Lat})
+	}
+}
+
+func (b Bounds) empty() bool {
+	return b.MinX > b.MaxX
+}
@@ -0,0 +1,343 @@
+package flatgeobuf_test
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/flatgeobuf"
+)
+
+var square = [][]gogis.Point{
+	{{Lng: 0, Lat: 0}, {Lng: 4, Lat: 0}, {Lng: 4, Lat: 4}, {Lng: 0, Lat: 4}, {Lng: 0, Lat: 0}},
+	{{Lng: 1, Lat: 1}, {Lng: 1, Lat: 2}, {Lng: 2, Lat: 2}, {Lng: 1, Lat: 1}},
+}
+
+func write(t *testing.T, opts flatgeobuf.WriterOptions, features []flatgeobuf.Feature) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := flatgeobuf.NewWriter(&buf, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range features {
+		if err := w.Write(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readAll(t *testing.T, data []byte) (*flatgeobuf.Header, []*flatgeobuf.Feature) {
+	t.Helper()
+	r, err := flatgeobuf.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var features []*flatgeobuf.Feature
+	for {
+		f, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		features = append(features, f)
+	}
+	return r.Header(), features
+}
+
+func TestRoundTrip(t *testing.T) {
+	columns := []flatgeobuf.Column{
+		{Name: "byte", Type: flatgeobuf.ColumnTypeByte},
+		{Name: "ubyte", Type: flatgeobuf.ColumnTypeUByte},
+		{Name: "bool", Type: flatgeobuf.ColumnTypeBool},
+		{Name: "short", Type: flatgeobuf.ColumnTypeShort},
+		{Name: "ushort", Type: flatgeobuf.ColumnTypeUShort},
+		{Name: "int", Type: flatgeobuf.ColumnTypeInt},
+		{Name: "uint", Type: flatgeobuf.ColumnTypeUInt},
+		{Name: "long", Type: flatgeobuf.ColumnTypeLong},
+		{Name: "ulong", Type: flatgeobuf.ColumnTypeULong},
+		{Name: "float", Type: flatgeobuf.ColumnTypeFloat},
+		{Name: "double", Type: flatgeobuf.ColumnTypeDouble},
+		{Name: "string", Type: flatgeobuf.ColumnTypeString},
+		{Name: "json", Type: flatgeobuf.ColumnTypeJSON},
+		{Name: "datetime", Type: flatgeobuf.ColumnTypeDateTime},
+		{Name: "binary", Type: flatgeobuf.ColumnTypeBinary},
+	}
+	when := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	features := []flatgeobuf.Feature{
+		{
+			Geometry: &gogis.Point{Lng: 4.89, Lat: 52.37},
+			Properties: map[string]any{
+				"byte": int8(-5), "ubyte": uint8(200), "bool": true,
+				"short": int16(-300), "ushort": uint16(60000), "int": int32(-70000),
+				"uint": uint32(4000000000), "long": int64(-1 << 40), "ulong": uint64(1 << 63),
+				"float": float32(1.5), "double": 2.25, "string": "Amsterdam",
+				"json": `{"a":1}`, "datetime": when, "binary": []byte{0, 1, 2},
+			},
+		},
+		{Geometry: &gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}}}, Properties: map[string]any{"int": int32(7)}},
+		{Geometry: &gogis.Polygon{Rings: square}, Properties: map[string]any{}},
+		{Geometry: &gogis.GeometryCollection{Geometries: []gogis.Geometry{
+			&gogis.Point{Lng: 1, Lat: 1}, &gogis.Point{Lng: 2, Lat: 2},
+		}}, Properties: map[string]any{}},
+		{Geometry: &gogis.GeometryCollection{Geometries: []gogis.Geometry{
+			&gogis.LineString{Points: []gogis.Point{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 1}}},
+			&gogis.LineString{Points: []gogis.Point{{Lng: 2, Lat: 2}, {Lng: 3, Lat: 3}, {Lng: 4, Lat: 2}}},
+		}}, Properties: map[string]any{}},
+		{Geometry: &gogis.GeometryCollection{Geometries: []gogis.Geometry{
+			&gogis.Polygon{Rings: square[:1]}, &gogis.Polygon{Rings: square},
+		}}, Properties: map[string]any{}},
+		{Geometry: &gogis.GeometryCollection{Geometries: []gogis.Geometry{
+			&gogis.Point{Lng: 5, Lat: 5},
+			&gogis.GeometryCollection{Geometries: []gogis.Geometry{
+				&gogis.LineString{Points: []gogis.Point{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 1}}},
+			}},
+		}}, Properties: map[string]any{}},
+		{Properties: map[string]any{"string": "no geometry"}},
+	}
+
+	for _, noIndex := range []bool{false, true} {
+		data := write(t, flatgeobuf.WriterOptions{
+			Name:        "test",
+			Description: "all types",
+			Columns:     columns,
+			SRID:        gogis.SRIDWGS84,
+			NoIndex:     noIndex,
+		}, features)
+		if !bytes.HasPrefix(data, []byte("fgb\x03fgb\x00")) {
+			t.Fatalf("file starts with %q", data[:8])
+		}
+
+		h, got := readAll(t, data)
+		wantSize := uint16(flatgeobuf.DefaultNodeSize)
+		if noIndex {
+			wantSize = 0
+		}
+		want := &flatgeobuf.Header{
+			Name:          "test",
+			Description:   "all types",
+			GeometryType:  flatgeobuf.GeometryTypeUnknown,
+			Columns:       columns,
+			FeaturesCount: uint64(len(features)),
+			IndexNodeSize: wantSize,
+			Bounds:        &flatgeobuf.Bounds{MinX: 0, MinY: 0, MaxX: 5, MaxY: 52.37},
+			SRID:          gogis.SRIDWGS84,
+		}
+		if !reflect.DeepEqual(h, want) {
+			t.Errorf("noIndex %v: header = %+v, want %+v", noIndex, h, want)
+		}
+		if len(got) != len(features) {
+			t.Fatalf("noIndex %v: read %d features, want %d", noIndex, len(got), len(features))
+		}
+		// With an index the features are sorted along the Hilbert curve.
+		if !noIndex {
+			sort.Slice(got, func(i, j int) bool { return order(features, got[i]) < order(features, got[j]) })
+		}
+		for i, f := range got {
+			if !reflect.DeepEqual(*f, features[i]) {
+				t.Errorf("noIndex %v: feature %d = %+v, want %+v", noIndex, i, *f, features[i])
+			}
+		}
+	}
+}
+
+// order returns the index of the feature of features equal to f.
+func order(features []flatgeobuf.Feature, f *flatgeobuf.Feature) int {
+	for i := range features {
+		if reflect.DeepEqual(features[i], *f) {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestHeaderGeometryType(t *testing.T) {
+	tests := []struct {
+		geometries []gogis.Geometry
+		want       flatgeobuf.GeometryType
+	}{
+		{[]gogis.Geometry{&gogis.Point{Lng: 1, Lat: 2}, &gogis.GeographyPoint{Lng: 3, Lat: 4}}, flatgeobuf.GeometryTypePoint},
+		{[]gogis.Geometry{&gogis.Polygon{Rings: square}}, flatgeobuf.GeometryTypePolygon},
+		{[]gogis.Geometry{&gogis.GeometryCollection{Geometries: []gogis.Geometry{&gogis.Polygon{Rings: square}}}}, flatgeobuf.GeometryTypeMultiPolygon},
+		{[]gogis.Geometry{&gogis.Point{Lng: 1, Lat: 2}, &gogis.LineString{Points: []gogis.Point{{}, {Lng: 1}}}}, flatgeobuf.GeometryTypeUnknown},
+		{[]gogis.Geometry{&gogis.Point{Lng: 1, Lat: 2}, nil}, flatgeobuf.GeometryTypeUnknown},
+	}
+	for _, tt := range tests {
+		var features []flatgeobuf.Feature
+		for _, g := range tt.geometries {
+			features = append(features, flatgeobuf.Feature{Geometry: g})
+		}
+		h, _ := readAll(t, write(t, flatgeobuf.WriterOptions{}, features))
+		if h.GeometryType != tt.want {
+			t.Errorf("%v: geometry type = %v, want %v", tt.geometries, h.GeometryType, tt.want)
+		}
+	}
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.ReaderAt
+	n int
+}
+
+func (c *countingReader) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.n += n
+	return n, err
+}
+
+func TestSearch(t *testing.T) {
+	var features []flatgeobuf.Feature
+	for x := 0; x < 40; x++ {
+		for y := 0; y < 40; y++ {
+			features = append(features, flatgeobuf.Feature{
+				Geometry:   &gogis.Point{Lng: float64(x), Lat: float64(y)},
+				Properties: map[string]any{"id": int32(x*100 + y)},
+			})
+		}
+	}
+	features = append(features, flatgeobuf.Feature{
+		Geometry:   &gogis.LineString{Points: []gogis.Point{{Lng: -10, Lat: 15.5}, {Lng: 50, Lat: 15.5}}},
+		Properties: map[string]any{"id": int32(-1)},
+	}, flatgeobuf.Feature{Properties: map[string]any{"id": int32(-2)}})
+	columns := []flatgeobuf.Column{{Name: "id", Type: flatgeobuf.ColumnTypeInt}}
+
+	query := flatgeobuf.Bounds{MinX: 10.5, MinY: 12, MaxX: 13, MaxY: 16}
+	want := []int32{-1}
+	for x := 11; x <= 13; x++ {
+		for y := 12; y <= 16; y++ {
+			want = append(want, int32(x*100+y))
+		}
+	}
+
+	for _, opts := range []flatgeobuf.WriterOptions{
+		{Columns: columns},
+		{Columns: columns, NodeSize: 4},
+		{Columns: columns, NoIndex: true},
+	} {
+		data := write(t, opts, features)
+		cr := &countingReader{r: bytes.NewReader(data)}
+		r, err := flatgeobuf.NewReader(cr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found, err := r.Search(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int32
+		for _, f := range found {
+			ids = append(ids, f.Properties["id"].(int32))
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		if !reflect.DeepEqual(ids, want) {
+			t.Errorf("%+v: found %v, want %v", opts, ids, want)
+		}
+		if !opts.NoIndex && cr.n > len(data)/10 {
+			t.Errorf("%+v: search read %d of %d bytes", opts, cr.n, len(data))
+		}
+
+		empty, err := r.Search(flatgeobuf.Bounds{MinX: 100, MinY: 100, MaxX: 101, MaxY: 101})
+		if err != nil || len(empty) != 0 {
+			t.Errorf("%+v: search outside the data = %d features, %v", opts, len(empty), err)
+		}
+	}
+}
+
+func TestSingleFeature(t *testing.T) {
+	data := write(t, flatgeobuf.WriterOptions{}, []flatgeobuf.Feature{{Geometry: &gogis.Point{Lng: 1, Lat: 2}}})
+	r, err := flatgeobuf.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, err := r.Search(flatgeobuf.Bounds{MinX: 0, MinY: 0, MaxX: 1, MaxY: 2})
+	if err != nil || len(found) != 1 {
+		t.Fatalf("search = %d features, %v", len(found), err)
+	}
+	if p, ok := found[0].Geometry.(*gogis.Point); !ok || *p != (gogis.Point{Lng: 1, Lat: 2}) {
+		t.Errorf("geometry = %v", found[0].Geometry)
+	}
+
+	_, features := readAll(t, write(t, flatgeobuf.WriterOptions{}, nil))
+	if len(features) != 0 {
+		t.Errorf("empty file has %d features", len(features))
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	if _, err := flatgeobuf.NewWriter(io.Discard, flatgeobuf.WriterOptions{Columns: []flatgeobuf.Column{{Name: "a"}, {Name: "a"}}}); err == nil {
+		t.Error("duplicate columns accepted")
+	}
+	if _, err := flatgeobuf.NewWriter(io.Discard, flatgeobuf.WriterOptions{NodeSize: 1}); err == nil {
+		t.Error("node size 1 accepted")
+	}
+
+	w, err := flatgeobuf.NewWriter(io.Discard, flatgeobuf.WriterOptions{Columns: []flatgeobuf.Column{
+		{Name: "small", Type: flatgeobuf.ColumnTypeByte},
+		{Name: "name", Type: flatgeobuf.ColumnTypeString},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []flatgeobuf.Feature{
+		{Geometry: &gogis.Point{Lng: math.NaN(), Lat: 0}},
+		{Geometry: &gogis.Point{}, Properties: map[string]any{"small": 300}},
+		{Geometry: &gogis.Point{}, Properties: map[string]any{"name": 1}},
+	} {
+		if err := w.Write(f); err == nil {
+			t.Errorf("%+v: no error", f)
+		}
+	}
+	if err := w.Write(flatgeobuf.Feature{Geometry: &gogis.Point{}, Properties: map[string]any{"small": 100, "other": 1}}); err != nil {
+		t.Errorf("valid feature after errors: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(flatgeobuf.Feature{Geometry: &gogis.Point{}}); err == nil {
+		t.Error("write after Close succeeded")
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	data := write(t, flatgeobuf.WriterOptions{}, []flatgeobuf.Feature{
+		{Geometry: &gogis.Point{Lng: 1, Lat: 2}},
+		{Geometry: &gogis.Point{Lng: 3, Lat: 4}},
+	})
+	for name, b := range map[string][]byte{
+		"empty":          nil,
+		"magic":          []byte("fgc\x03fgb\x00\x00\x00\x00\x00"),
+		"version":        []byte("fgb\x02fgb\x00\x00\x00\x00\x00"),
+		"header size":    append([]byte("fgb\x03fgb\x00"), 0xFF, 0xFF, 0xFF, 0xFF),
+		"header":         data[:20],
+		"garbage header": append([]byte("fgb\x03fgb\x00\x10\x00\x00\x00"), bytes.Repeat([]byte{0xFF}, 16)...),
+	} {
+		if _, err := flatgeobuf.NewReader(bytes.NewReader(b)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+
+	r, err := flatgeobuf.NewReader(bytes.NewReader(data[:len(data)-3]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last error
+	for i := 0; i < 3 && last == nil; i++ {
+		_, last = r.Next()
+	}
+	if last == nil || last == io.EOF || !strings.Contains(last.Error(), "unexpected EOF") {
+		t.Errorf("truncated file: %v", last)
+	}
+}
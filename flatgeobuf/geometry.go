@@ -0,0 +1,255 @@
+package flatgeobuf
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/restayway/gogis"
+)
+
+// maxDepth limits the nesting of geometry collections.
+const maxDepth = 32
+
+// Fields of the Geometry table.
+const (
+	geometryEnds  = 0
+	geometryXY    = 1
+	geometryType  = 6
+	geometryParts = 7
+	geometryCount = 8
+)
+
+// writeGeometry writes g as a Geometry table and returns its offset and
+// type. bounds is extended with its coordinates.
+func writeGeometry(b *builder, g gogis.Geometry, bounds *Bounds, depth int) (int, GeometryType, error) {
+	if depth > maxDepth {
+		return 0, 0, fmt.Errorf("geometry collections nested too deeply")
+	}
+	var (
+		typ   GeometryType
+		lines [][]gogis.Point // written as xy and, when several, ends
+		parts []gogis.Geometry
+	)
+	switch v := g.(type) {
+	case *gogis.Point:
+		typ, lines = GeometryTypePoint, [][]gogis.Point{{*v}}
+	case *gogis.LineString:
+		typ, lines = GeometryTypeLineString, [][]gogis.Point{v.Points}
+	case *gogis.Polygon:
+		typ, lines = GeometryTypePolygon, v.Rings
+	case *gogis.GeometryCollection:
+		switch typ = collectionType(v); typ {
+		case GeometryTypeMultiPoint:
+			points := make([]gogis.Point, len(v.Geometries))
+			for i, child := range v.Geometries {
+				points[i] = *child.(*gogis.Point)
+			}
+			lines = [][]gogis.Point{points}
+		case GeometryTypeMultiLineString:
+			for _, child := range v.Geometries {
+				lines = append(lines, child.(*gogis.LineString).Points)
+			}
+		default:
+			parts = v.Geometries
+		}
+	case *gogis.GeographyPoint:
+		return writeGeometry(b, (*gogis.Point)(v), bounds, depth)
+	case *gogis.GeographyLineString:
+		return writeGeometry(b, (*gogis.LineString)(v), bounds, depth)
+	case *gogis.GeographyPolygon:
+		return writeGeometry(b, (*gogis.Polygon)(v), bounds, depth)
+	case *gogis.GeographyCollection:
+		return writeGeometry(b, (*gogis.GeometryCollection)(v), bounds, depth)
+	default:
+		return 0, 0, fmt.Errorf("unsupported geometry type %T", g)
+	}
+
+	var partsOff int
+	if len(parts) > 0 {
+		offsets := make([]int, len(parts))
+		for i, part := range parts {
+			off, _, err := writeGeometry(b, part, bounds, depth+1)
+			if err != nil {
+				return 0, 0, err
+			}
+			offsets[i] = off
+		}
+		partsOff = b.createOffsets(offsets)
+	}
+
+	var xyOff, endsOff int
+	if len(lines) > 0 {
+		var xy []float64
+		ends := make([]uint32, len(lines))
+		for i, line := range lines {
+			for _, p := range line {
+				if math.IsNaN(p.Lng) || math.IsNaN(p.Lat) || math.IsInf(p.Lng, 0) || math.IsInf(p.Lat, 0) {
+					return 0, 0, fmt.Errorf("invalid coordinate (%v %v)", p.Lng, p.Lat)
+				}
+				xy = append(xy, p.Lng, p.Lat)
+			}
+			bounds.extendPoints(line)
+			ends[i] = uint32(len(xy) / 2)
+		}
+		xyOff = b.createFloat64s(xy)
+		// A single part needs no ends.
+		if len(ends) > 1 {
+			endsOff = b.createUint32s(ends)
+		}
+	}
+
+	b.startTable(geometryCount)
+	b.addOffset(geometryParts, partsOff)
+	b.addOffset(geometryXY, xyOff)
+	b.addOffset(geometryEnds, endsOff)
+	b.addUint8(geometryType, uint8(typ), 0)
+	return b.endTable(), typ, nil
+}
+
+// collectionType returns the multi geometry type of a collection holding
+// geometries of one type, or GeometryTypeGeometryCollection.
+func collectionType(gc *gogis.GeometryCollection) GeometryType {
+	typ := GeometryTypeUnknown
+	for _, child := range gc.Geometries {
+		var t GeometryType
+		switch child.(type) {
+		case *gogis.Point:
+			t = GeometryTypeMultiPoint
+		case *gogis.LineString:
+			t = GeometryTypeMultiLineString
+		case *gogis.Polygon:
+			t = GeometryTypeMultiPolygon
+		default:
+			return GeometryTypeGeometryCollection
+		}
+		if typ != GeometryTypeUnknown && t != typ {
+			return GeometryTypeGeometryCollection
+		}
+		typ = t
+	}
+	if typ == GeometryTypeUnknown {
+		return GeometryTypeGeometryCollection
+	}
+	return typ
+}
+
+// readGeometry decodes a Geometry table. typ is the geometry type of the
+// file, used when the table gives none.
+func readGeometry(t table, typ GeometryType, depth int) (gogis.Geometry, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("geometry collections nested too deeply")
+	}
+	if own := GeometryType(t.uint8(geometryType, 0)); own != GeometryTypeUnknown {
+		typ = own
+	}
+
+	switch typ {
+	case GeometryTypePoint:
+		lines, err := readLines(t)
+		if err != nil {
+			return nil, err
+		}
+		if len(lines) != 1 || len(lines[0]) != 1 {
+			return nil, fmt.Errorf("Point without a single coordinate")
+		}
+		return &lines[0][0], nil
+	case GeometryTypeLineString:
+		lines, err := readLines(t)
+		if err != nil {
+			return nil, err
+		}
+		if len(lines) > 1 {
+			return nil, fmt.Errorf("LineString with %d parts", len(lines))
+		}
+		points := []gogis.Point{}
+		if len(lines) == 1 {
+			points = lines[0]
+		}
+		return &gogis.LineString{Points: points}, nil
+	case GeometryTypePolygon:
+		lines, err := readLines(t)
+		if err != nil {
+			return nil, err
+		}
+		return &gogis.Polygon{Rings: lines}, nil
+	case GeometryTypeMultiPoint, GeometryTypeMultiLineString:
+		lines, err := readLines(t)
+		if err != nil {
+			return nil, err
+		}
+		gc := &gogis.GeometryCollection{Geometries: []gogis.Geometry{}}
+		for _, line := range lines {
+			if typ == GeometryTypeMultiLineString {
+				gc.Geometries = append(gc.Geometries, &gogis.LineString{Points: line})
+				continue
+			}
+			for i := range line {
+				gc.Geometries = append(gc.Geometries, &line[i])
+			}
+		}
+		return gc, nil
+	case GeometryTypeMultiPolygon, GeometryTypeGeometryCollection:
+		partType := GeometryTypePolygon
+		if typ == GeometryTypeGeometryCollection {
+			partType = GeometryTypeUnknown
+		}
+		parts := t.tables(geometryParts)
+		gc := &gogis.GeometryCollection{Geometries: make([]gogis.Geometry, len(parts))}
+		for i, part := range parts {
+			g, err := readGeometry(part, partType, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			gc.Geometries[i] = g
+		}
+		return gc, nil
+	}
+	return nil, fmt.Errorf("unsupported geometry type %v", typ)
+}
+
+// readLines returns the coordinates of a geometry split at its ends.
+func readLines(t table) ([][]gogis.Point, error) {
+	xy := t.float64s(geometryXY)
+	ends := t.uint32s(geometryEnds)
+	if len(xy)%2 != 0 {
+		return nil, fmt.Errorf("odd number of coordinates")
+	}
+	points := make([]gogis.Point, len(xy)/2)
+	for i := range points {
+		points[i] = gogis.Point{Lng: xy[2*i], Lat: xy[2*i+1]}
+	}
+	if len(ends) == 0 {
+		if len(points) == 0 {
+			return [][]gogis.Point{}, nil
+		}
+		return [][]gogis.Point{points}, nil
+	}
+	lines := make([][]gogis.Point, len(ends))
+	start := uint32(0)
+	for i, end := range ends {
+		if end < start || end > uint32(len(points)) {
+			return nil, fmt.Errorf("invalid part end %d", end)
+		}
+		lines[i] = points[start:end:end]
+		start = end
+	}
+	return lines, nil
+}
+
+// extendGeometry grows b to include the coordinates of g.
+func extendGeometry(b *Bounds, g gogis.Geometry) {
+	switch v := g.(type) {
+	case *gogis.Point:
+		b.extendPoints([]gogis.Point{*v})
+	case *gogis.LineString:
+		b.extendPoints(v.Points)
+	case *gogis.Polygon:
+		for _, ring := range v.Rings {
+			b.extendPoints(ring)
+		}
+	case *gogis.GeometryCollection:
+		for _, child := range v.Geometries {
+			extendGeometry(b, child)
+		}
+	}
+}
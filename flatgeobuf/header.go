@@ -0,0 +1,117 @@
+package flatgeobuf
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/restayway/gogis"
+)
+
+// Fields of the Header table.
+const (
+	headerName          = 0
+	headerEnvelope      = 1
+	headerGeometryType  = 2
+	headerColumns       = 7
+	headerFeaturesCount = 8
+	headerIndexNodeSize = 9
+	headerCRS           = 10
+	headerTitle         = 11
+	headerDescription   = 12
+	headerCount         = 14
+)
+
+// Fields of the Column table.
+const (
+	columnName  = 0
+	columnType  = 1
+	columnCount = 11
+)
+
+// Fields of the Crs table.
+const (
+	crsOrg   = 0
+	crsCode  = 1
+	crsCount = 6
+)
+
+// encodeHeader returns the header table of h with its size prefix.
+func encodeHeader(h *Header) []byte {
+	b := newBuilder(256)
+	columns := make([]int, len(h.Columns))
+	for i, c := range h.Columns {
+		name := b.createString(c.Name)
+		b.startTable(columnCount)
+		b.addOffset(columnName, name)
+		b.addUint8(columnType, uint8(c.Type), 0)
+		columns[i] = b.endTable()
+	}
+	var columnsOff, envelopeOff, crsOff int
+	if len(columns) > 0 {
+		columnsOff = b.createOffsets(columns)
+	}
+	if h.Bounds != nil {
+		envelopeOff = b.createFloat64s([]float64{h.Bounds.MinX, h.Bounds.MinY, h.Bounds.MaxX, h.Bounds.MaxY})
+	}
+	if h.SRID != 0 {
+		org := b.createString("EPSG")
+		b.startTable(crsCount)
+		b.addOffset(crsOrg, org)
+		b.addInt32(crsCode, int32(h.SRID), 0)
+		crsOff = b.endTable()
+	}
+	var nameOff, titleOff, descriptionOff int
+	if h.Name != "" {
+		nameOff = b.createString(h.Name)
+	}
+	if h.Title != "" {
+		titleOff = b.createString(h.Title)
+	}
+	if h.Description != "" {
+		descriptionOff = b.createString(h.Description)
+	}
+
+	b.startTable(headerCount)
+	b.addUint64(headerFeaturesCount, h.FeaturesCount, 0)
+	b.addOffset(headerName, nameOff)
+	b.addOffset(headerEnvelope, envelopeOff)
+	b.addOffset(headerColumns, columnsOff)
+	b.addOffset(headerCRS, crsOff)
+	b.addOffset(headerTitle, titleOff)
+	b.addOffset(headerDescription, descriptionOff)
+	b.addUint16(headerIndexNodeSize, h.IndexNodeSize, DefaultNodeSize)
+	b.addUint8(headerGeometryType, uint8(h.GeometryType), 0)
+	return b.finish(b.endTable())
+}
+
+// decodeHeader decodes a header table without its size prefix.
+func decodeHeader(data []byte) (*Header, error) {
+	buf := &buffer{b: data}
+	t := buf.root()
+	h := &Header{
+		Name:          t.string(headerName),
+		Title:         t.string(headerTitle),
+		Description:   t.string(headerDescription),
+		GeometryType:  GeometryType(t.uint8(headerGeometryType, 0)),
+		FeaturesCount: t.uint64(headerFeaturesCount, 0),
+		IndexNodeSize: t.uint16(headerIndexNodeSize, DefaultNodeSize),
+	}
+	if env := t.float64s(headerEnvelope); len(env) >= 4 && !math.IsNaN(env[0]) {
+		h.Bounds = &Bounds{MinX: env[0], MinY: env[1], MaxX: env[2], MaxY: env[3]}
+	}
+	for _, c := range t.tables(headerColumns) {
+		h.Columns = append(h.Columns, Column{Name: c.string(columnName), Type: ColumnType(c.uint8(columnType, 0))})
+	}
+	if crs, ok := t.child(headerCRS); ok {
+		// Organizations other than EPSG have no SRID.
+		org := crs.string(crsOrg)
+		if code := crs.int32(crsCode, 0); code > 0 && (org == "" || strings.EqualFold(org, "EPSG")) {
+			h.SRID = gogis.SRID(code)
+		}
+	}
+	if buf.err != nil {
+		return nil, fmt.Errorf("invalid header: %w", buf.err)
+	}
+	return h, nil
+}
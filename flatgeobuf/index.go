@@ -0,0 +1,199 @@
+package flatgeobuf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// nodeSize is the size in bytes of a node of the index: its bounding box
+// and an offset, the byte offset of the feature for leaves and the index of
+// the first child for other nodes.
+const nodeSize = 40
+
+// maxIndexedFeatures bounds the number of features of an index read from a
+// header, far above any real file.
+const maxIndexedFeatures = 1 << 40
+
+type node struct {
+	Bounds
+	offset uint64
+}
+
+// levelBounds returns the start and end node of each level of a packed
+// R-tree of n leaves, leaves first. The nodes are stored root first.
+func levelBounds(n, size int) [][2]int {
+	counts := []int{n}
+	total := n
+	for n > 1 {
+		n = (n + size - 1) / size
+		counts = append(counts, n)
+		total += n
+	}
+	bounds := make([][2]int, len(counts))
+	for i, c := range counts {
+		total -= c
+		bounds[i] = [2]int{total, total + c}
+	}
+	return bounds
+}
+
+// indexSize returns the size in bytes of the index of n features.
+func indexSize(n uint64, size uint16) (int64, error) {
+	if n == 0 || size == 0 {
+		return 0, nil
+	}
+	if size < 2 {
+		return 0, fmt.Errorf("invalid index node size %d", size)
+	}
+	if n > maxIndexedFeatures {
+		return 0, fmt.Errorf("too many features for an index: %d", n)
+	}
+	levels := levelBounds(int(n), int(size))
+	return int64(levels[0][1]) * nodeSize, nil
+}
+
+// buildIndex returns the nodes of the packed R-tree over leaves, which are
+// sorted by the caller.
+func buildIndex(leaves []node, size int) []node {
+	levels := levelBounds(len(leaves), size)
+	nodes := make([]node, levels[0][1])
+	copy(nodes[levels[0][0]:], leaves)
+	for i := 0; i+1 < len(levels); i++ {
+		parent := levels[i+1][0]
+		for pos := levels[i][0]; pos < levels[i][1]; parent++ {
+			n := node{Bounds: emptyBounds, offset: uint64(pos)}
+			for j := 0; j < size && pos < levels[i][1]; j++ {
+				n.extend(nodes[pos].Bounds)
+				pos++
+			}
+			nodes[parent] = n
+		}
+	}
+	return nodes
+}
+
+func appendNode(b []byte, n node) []byte {
+	for _, v := range []float64{n.MinX, n.MinY, n.MaxX, n.MaxY} {
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+	}
+	return binary.LittleEndian.AppendUint64(b, n.offset)
+}
+
+func readNode(b []byte) node {
+	f := func(i int) float64 {
+		return math.Float64frombits(binary.LittleEndian.Uint64(b[8*i:]))
+	}
+	return node{
+		Bounds: Bounds{MinX: f(0), MinY: f(1), MaxX: f(2), MaxY: f(3)},
+		offset: binary.LittleEndian.Uint64(b[32:]),
+	}
+}
+
+// searchIndex returns the feature offsets of the leaves of the index at
+// offset start of r intersecting q, in file order. Each node and its
+// siblings are read in one ReadAt call.
+func searchIndex(r io.ReaderAt, start int64, n uint64, size uint16, q Bounds) ([]uint64, error) {
+	levels := levelBounds(int(n), int(size))
+	numNodes := levels[0][1]
+	type item struct {
+		pos, level int
+	}
+	queue := []item{{0, len(levels) - 1}}
+	var offsets []uint64
+	for len(queue) > 0 {
+		it := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		end := it.pos + int(size)
+		if end > levels[it.level][1] {
+			end = levels[it.level][1]
+		}
+		if it.pos < levels[it.level][0] || it.pos >= end {
+			return nil, fmt.Errorf("invalid index node %d", it.pos)
+		}
+		buf := make([]byte, (end-it.pos)*nodeSize)
+		if err := readAt(r, buf, start+int64(it.pos)*nodeSize); err != nil {
+			return nil, fmt.Errorf("reading index: %w", eofError(err))
+		}
+		for i := 0; i < end-it.pos; i++ {
+			nd := readNode(buf[i*nodeSize:])
+			if !nd.Intersects(q) {
+				continue
+			}
+			if it.level == 0 {
+				offsets = append(offsets, nd.offset)
+				continue
+			}
+			if nd.offset >= uint64(numNodes) {
+				return nil, fmt.Errorf("invalid index node %d", nd.offset)
+			}
+			queue = append(queue, item{int(nd.offset), it.level - 1})
+		}
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	return offsets, nil
+}
+
+// hilbert returns the position of (x, y) on the Hilbert curve filling a grid
+// of 2^16 by 2^16 cells.
+func hilbert(x, y uint32) uint32 {
+	a := x ^ y
+	b := 0xFFFF ^ a
+	c := 0xFFFF ^ (x | y)
+	d := x & (y ^ 0xFFFF)
+
+	A := a | (b >> 1)
+	B := (a >> 1) ^ a
+	C := ((c >> 1) ^ (b & (d >> 1))) ^ c
+	D := ((a & (c >> 1)) ^ (d >> 1)) ^ d
+
+	a, b, c, d = A, B, C, D
+	A = (a & (a >> 2)) ^ (b & (b >> 2))
+	B = (a & (b >> 2)) ^ (b & ((a ^ b) >> 2))
+	C ^= (a & (c >> 2)) ^ (b & (d >> 2))
+	D ^= (b & (c >> 2)) ^ ((a ^ b) & (d >> 2))
+
+	a, b, c, d = A, B, C, D
+	A = (a & (a >> 4)) ^ (b & (b >> 4))
+	B = (a & (b >> 4)) ^ (b & ((a ^ b) >> 4))
+	C ^= (a & (c >> 4)) ^ (b & (d >> 4))
+	D ^= (b & (c >> 4)) ^ ((a ^ b) & (d >> 4))
+
+	a, b, c, d = A, B, C, D
+	C ^= (a & (c >> 8)) ^ (b & (d >> 8))
+	D ^= (b & (c >> 8)) ^ ((a ^ b) & (d >> 8))
+
+	a = C ^ (C >> 1)
+	b = D ^ (D >> 1)
+
+	i0 := x ^ y
+	i1 := b | (0xFFFF ^ (i0 | a))
+
+	i0 = (i0 | (i0 << 8)) & 0x00FF00FF
+	i0 = (i0 | (i0 << 4)) & 0x0F0F0F0F
+	i0 = (i0 | (i0 << 2)) & 0x33333333
+	i0 = (i0 | (i0 << 1)) & 0x55555555
+
+	i1 = (i1 | (i1 << 8)) & 0x00FF00FF
+	i1 = (i1 | (i1 << 4)) & 0x0F0F0F0F
+	i1 = (i1 | (i1 << 2)) & 0x33333333
+	i1 = (i1 | (i1 << 1)) & 0x55555555
+
+	return (i1 << 1) | i0
+}
+
+// hilbertValue returns the Hilbert value of the center of b within extent.
+func hilbertValue(b, extent Bounds) uint32 {
+	const max = 1<<16 - 1
+	cell := func(v, min, width float64) uint32 {
+		if width == 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+			return 0
+		}
+		return uint32(math.Floor(max * (v - min) / width))
+	}
+	x := cell((b.MinX+b.MaxX)/2, extent.MinX, extent.MaxX-extent.MinX)
+	y := cell((b.MinY+b.MaxY)/2, extent.MinY, extent.MaxY-extent.MinY)
+	return hilbert(x, y)
+}
@@ -0,0 +1,240 @@
+package flatgeobuf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+// appendProperties appends the properties record of a feature: the index
+// of each column with a non-nil value, followed by the value.
+func appendProperties(b []byte, columns []Column, props map[string]any) ([]byte, error) {
+	for i, c := range columns {
+		v, ok := props[c.Name]
+		if !ok || v == nil {
+			continue
+		}
+		b = binary.LittleEndian.AppendUint16(b, uint16(i))
+		var err error
+		if b, err = appendValue(b, c, v); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func appendValue(b []byte, c Column, v any) ([]byte, error) {
+	invalid := func() ([]byte, error) {
+		return nil, fmt.Errorf("invalid value %v of type %T for %s column %q", v, v, c.Type, c.Name)
+	}
+	switch c.Type {
+	case ColumnTypeBool:
+		t, ok := v.(bool)
+		if !ok {
+			return invalid()
+		}
+		if t {
+			return append(b, 1), nil
+		}
+		return append(b, 0), nil
+	case ColumnTypeByte, ColumnTypeShort, ColumnTypeInt, ColumnTypeLong:
+		n, ok := toInt(v)
+		bits := c.bits()
+		if !ok || n < -1<<(bits-1) || n > 1<<(bits-1)-1 {
+			return invalid()
+		}
+		return appendUint(b, uint64(n), bits), nil
+	case ColumnTypeUByte, ColumnTypeUShort, ColumnTypeUInt, ColumnTypeULong:
+		n, ok := toUint(v)
+		bits := c.bits()
+		if !ok || bits < 64 && n >= 1<<bits {
+			return invalid()
+		}
+		return appendUint(b, n, bits), nil
+	case ColumnTypeFloat:
+		f, ok := toFloat(v)
+		if !ok {
+			return invalid()
+		}
+		return binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(f))), nil
+	case ColumnTypeDouble:
+		f, ok := toFloat(v)
+		if !ok {
+			return invalid()
+		}
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(f)), nil
+	case ColumnTypeString, ColumnTypeJSON:
+		s, ok := v.(string)
+		if !ok {
+			return invalid()
+		}
+		return appendBytes(b, []byte(s)), nil
+	case ColumnTypeDateTime:
+		switch t := v.(type) {
+		case time.Time:
+			return appendBytes(b, []byte(t.Format(time.RFC3339Nano))), nil
+		case string:
+			return appendBytes(b, []byte(t)), nil
+		}
+		return invalid()
+	case ColumnTypeBinary:
+		data, ok := v.([]byte)
+		if !ok {
+			return invalid()
+		}
+		return appendBytes(b, data), nil
+	}
+	return nil, fmt.Errorf("unsupported type %s of column %q", c.Type, c.Name)
+}
+
+// bits returns the size of the numeric values of c.
+func (c Column) bits() int {
+	switch c.Type {
+	case ColumnTypeByte, ColumnTypeUByte:
+		return 8
+	case ColumnTypeShort, ColumnTypeUShort:
+		return 16
+	case ColumnTypeInt, ColumnTypeUInt, ColumnTypeFloat:
+		return 32
+	}
+	return 64
+}
+
+func appendUint(b []byte, n uint64, bits int) []byte {
+	for i := 0; i < bits; i += 8 {
+		b = append(b, byte(n>>i))
+	}
+	return b
+}
+
+func appendBytes(b, data []byte) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(data)))
+	return append(b, data...)
+}
+
+func toInt(v any) (int64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(rv.Uint()), true
+	}
+	return 0, false
+}
+
+func toUint(v any) (uint64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if rv.Int() < 0 {
+			return 0, false
+		}
+		return uint64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint(), true
+	}
+	return 0, false
+}
+
+func toFloat(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	if n, ok := toInt(v); ok {
+		return float64(n), true
+	}
+	if n, ok := toUint(v); ok {
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// readProperties decodes a properties record.
+func readProperties(b []byte, columns []Column) (map[string]any, error) {
+	props := make(map[string]any)
+	for len(b) > 0 {
+		if len(b) < 2 {
+			return nil, fmt.Errorf("truncated properties")
+		}
+		i := int(binary.LittleEndian.Uint16(b))
+		b = b[2:]
+		if i >= len(columns) {
+			return nil, fmt.Errorf("property of unknown column %d", i)
+		}
+		c := columns[i]
+		var size int
+		switch c.Type {
+		case ColumnTypeString, ColumnTypeJSON, ColumnTypeDateTime, ColumnTypeBinary:
+			if len(b) < 4 {
+				return nil, fmt.Errorf("truncated value of column %q", c.Name)
+			}
+			n := binary.LittleEndian.Uint32(b)
+			if uint64(n) > uint64(len(b)-4) {
+				return nil, fmt.Errorf("truncated value of column %q", c.Name)
+			}
+			b = b[4:]
+			size = int(n)
+		case ColumnTypeBool:
+			size = 1
+		default:
+			size = c.bits() / 8
+		}
+		if len(b) < size {
+			return nil, fmt.Errorf("truncated value of column %q", c.Name)
+		}
+		v, err := parseValue(c, b[:size])
+		if err != nil {
+			return nil, err
+		}
+		props[c.Name] = v
+		b = b[size:]
+	}
+	return props, nil
+}
+
+func parseValue(c Column, b []byte) (any, error) {
+	switch c.Type {
+	case ColumnTypeByte:
+		return int8(b[0]), nil
+	case ColumnTypeUByte:
+		return b[0], nil
+	case ColumnTypeBool:
+		return b[0] != 0, nil
+	case ColumnTypeShort:
+		return int16(binary.LittleEndian.Uint16(b)), nil
+	case ColumnTypeUShort:
+		return binary.LittleEndian.Uint16(b), nil
+	case ColumnTypeInt:
+		return int32(binary.LittleEndian.Uint32(b)), nil
+	case ColumnTypeUInt:
+		return binary.LittleEndian.Uint32(b), nil
+	case ColumnTypeLong:
+		return int64(binary.LittleEndian.Uint64(b)), nil
+	case ColumnTypeULong:
+		return binary.LittleEndian.Uint64(b), nil
+	case ColumnTypeFloat:
+		return math.Float32frombits(binary.LittleEndian.Uint32(b)), nil
+	case ColumnTypeDouble:
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case ColumnTypeString, ColumnTypeJSON:
+		return string(b), nil
+	case ColumnTypeDateTime:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02"} {
+			if t, err := time.Parse(layout, string(b)); err == nil {
+				return t, nil
+			}
+		}
+		return string(b), nil
+	case ColumnTypeBinary:
+		return append([]byte{}, b...), nil
+	}
+	return nil, fmt.Errorf("unsupported type %s of column %q", c.Type, c.Name)
+}
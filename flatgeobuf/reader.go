@@ -0,0 +1,211 @@
+package flatgeobuf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Limits on sizes read from a file, against corrupt data.
+const (
+	maxHeaderSize  = 1 << 28
+	maxFeatureSize = 1 << 30
+)
+
+// Reader reads the features of a FlatGeobuf file. It reads through an
+// io.ReaderAt, such as an *os.File or a client issuing HTTP range
+// requests, and reads only the parts of the file it needs.
+type Reader struct {
+	r        io.ReaderAt
+	header   *Header
+	index    int64 // offset of the index
+	features int64 // offset of the first feature
+
+	next   int64  // offset of the next feature read by Next
+	number uint64 // features read by Next
+	err    error
+}
+
+// NewReader reads the header of the file in r.
+func NewReader(r io.ReaderAt) (*Reader, error) {
+	var head [12]byte
+	if err := readAt(r, head[:], 0); err != nil {
+		return nil, fmt.Errorf("flatgeobuf: reading header: %w", eofError(err))
+	}
+	if head[0] != 'f' || head[1] != 'g' || head[2] != 'b' || head[4] != 'f' || head[5] != 'g' || head[6] != 'b' {
+		return nil, fmt.Errorf("flatgeobuf: not a FlatGeobuf file")
+	}
+	if head[3] != magic[3] {
+		return nil, fmt.Errorf("flatgeobuf: unsupported version %d", head[3])
+	}
+	size := binary.LittleEndian.Uint32(head[8:])
+	if size < 8 || size > maxHeaderSize {
+		return nil, fmt.Errorf("flatgeobuf: invalid header size %d", size)
+	}
+	data := make([]byte, size)
+	if err := readAt(r, data, int64(len(head))); err != nil {
+		return nil, fmt.Errorf("flatgeobuf: reading header: %w", eofError(err))
+	}
+	h, err := decodeHeader(data)
+	if err != nil {
+		return nil, fmt.Errorf("flatgeobuf: %w", err)
+	}
+	n, err := indexSize(h.FeaturesCount, h.IndexNodeSize)
+	if err != nil {
+		return nil, fmt.Errorf("flatgeobuf: %w", err)
+	}
+	index := int64(len(head)) + int64(size)
+	return &Reader{r: r, header: h, index: index, features: index + n, next: index + n}, nil
+}
+
+// Header returns the header of the file.
+func (r *Reader) Header() *Header {
+	return r.header
+}
+
+// Next returns the next feature in file order, or io.EOF after the last
+// one. Once Next fails, all later calls return the same error.
+func (r *Reader) Next() (*Feature, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if count := r.header.FeaturesCount; count > 0 && r.number == count {
+		r.err = io.EOF
+		return nil, io.EOF
+	}
+	f, size, err := r.readFeature(r.next)
+	if err != nil {
+		// Files of unknown length end after a feature.
+		if err == io.EOF && r.header.FeaturesCount == 0 {
+			r.err = io.EOF
+			return nil, io.EOF
+		}
+		r.err = fmt.Errorf("flatgeobuf: feature %d: %w", r.number+1, eofError(err))
+		return nil, r.err
+	}
+	r.next += size
+	r.number++
+	return f, nil
+}
+
+// Search returns the features whose bounding box intersects b, in file
+// order. Files with an index are searched by reading the index nodes
+// intersecting b and the matching features only; files without one are
+// read entirely.
+func (r *Reader) Search(b Bounds) ([]*Feature, error) {
+	if r.header.IndexNodeSize == 0 || r.header.FeaturesCount == 0 {
+		return r.scan(b)
+	}
+	offsets, err := searchIndex(r.r, r.index, r.header.FeaturesCount, r.header.IndexNodeSize, b)
+	if err != nil {
+		return nil, fmt.Errorf("flatgeobuf: %w", err)
+	}
+	features := make([]*Feature, len(offsets))
+	for i, off := range offsets {
+		if off > math.MaxInt64-uint64(r.features) {
+			return nil, fmt.Errorf("flatgeobuf: invalid feature offset %d", off)
+		}
+		f, _, err := r.readFeature(r.features + int64(off))
+		if err != nil {
+			return nil, fmt.Errorf("flatgeobuf: feature at offset %d: %w", off, eofError(err))
+		}
+		features[i] = f
+	}
+	return features, nil
+}
+
+// scan reads all features and keeps those intersecting b.
+func (r *Reader) scan(b Bounds) ([]*Feature, error) {
+	var features []*Feature
+	off := r.features
+	for n := uint64(0); r.header.FeaturesCount == 0 || n < r.header.FeaturesCount; n++ {
+		f, size, err := r.readFeature(off)
+		if err == io.EOF && r.header.FeaturesCount == 0 {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("flatgeobuf: feature %d: %w", n+1, eofError(err))
+		}
+		off += size
+		if f.Geometry == nil {
+			continue
+		}
+		bounds := emptyBounds
+		extendGeometry(&bounds, f.Geometry)
+		if bounds.Intersects(b) {
+			features = append(features, f)
+		}
+	}
+	return features, nil
+}
+
+// readFeature reads the feature at off and returns it with its size. It
+// returns io.EOF when off is the end of the file.
+func (r *Reader) readFeature(off int64) (*Feature, int64, error) {
+	var prefix [4]byte
+	if n, err := r.r.ReadAt(prefix[:], off); n < len(prefix) {
+		if n == 0 && err == io.EOF {
+			return nil, 0, io.EOF
+		}
+		return nil, 0, eofError(err)
+	}
+	size := binary.LittleEndian.Uint32(prefix[:])
+	if size > maxFeatureSize {
+		return nil, 0, fmt.Errorf("invalid feature size %d", size)
+	}
+	data := make([]byte, size)
+	if err := readAt(r.r, data, off+4); err != nil {
+		return nil, 0, eofError(err)
+	}
+	f, err := decodeFeature(data, r.header)
+	if err != nil {
+		return nil, 0, err
+	}
+	return f, 4 + int64(size), nil
+}
+
+// decodeFeature decodes a feature table without its size prefix.
+func decodeFeature(data []byte, h *Header) (*Feature, error) {
+	buf := &buffer{b: data}
+	t := buf.root()
+	f := &Feature{}
+	if geom, ok := t.child(featureGeometry); ok && buf.err == nil {
+		g, err := readGeometry(geom, h.GeometryType, 0)
+		if buf.err != nil {
+			return nil, buf.err
+		}
+		if err != nil {
+			return nil, err
+		}
+		f.Geometry = g
+	}
+	props, err := readProperties(t.bytes(featureProperties), h.Columns)
+	if err != nil {
+		return nil, err
+	}
+	if buf.err != nil {
+		return nil, buf.err
+	}
+	f.Properties = props
+	return f, nil
+}
+
+// readAt reads len(p) bytes at off, accepting io.EOF along with them as
+// io.ReaderAt allows.
+func readAt(r io.ReaderAt, p []byte, off int64) error {
+	n, err := r.ReadAt(p, off)
+	if n == len(p) {
+		return nil
+	}
+	return err
+}
+
+// eofError turns the end of the input inside a structure into
+// io.ErrUnexpectedEOF.
+func eofError(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
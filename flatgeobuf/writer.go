@@ -0,0 +1,201 @@
+package flatgeobuf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/restayway/gogis"
+)
+
+// Fields of the Feature table.
+const (
+	featureGeometry   = 0
+	featureProperties = 1
+	featureCount      = 3
+)
+
+// WriterOptions describes the file written by a Writer.
+type WriterOptions struct {
+	Name        string
+	Title       string
+	Description string
+	Columns     []Column
+	SRID        gogis.SRID // EPSG code of the coordinate system, 0 if unknown
+
+	NodeSize int  // node size of the index, DefaultNodeSize when 0
+	NoIndex  bool // write no index, keeping the features in order
+}
+
+// Writer writes features to a FlatGeobuf file. The index is built from the
+// bounding boxes of all features and written before them, sorted along a
+// Hilbert curve, so the features are kept in memory until Close writes the
+// file.
+type Writer struct {
+	w       io.Writer
+	header  Header
+	noIndex bool
+
+	data     []byte // encoded features with their size prefixes
+	features []node // bounding box and offset in data of each feature
+	bounds   Bounds
+	err      error
+}
+
+// NewWriter returns a Writer writing a file described by opts to w.
+func NewWriter(w io.Writer, opts WriterOptions) (*Writer, error) {
+	size := opts.NodeSize
+	if size == 0 {
+		size = DefaultNodeSize
+	}
+	if size < 2 || size > 0xFFFF {
+		return nil, fmt.Errorf("flatgeobuf: invalid index node size %d", opts.NodeSize)
+	}
+	names := make(map[string]bool)
+	for _, c := range opts.Columns {
+		if c.Name == "" || names[c.Name] {
+			return nil, fmt.Errorf("flatgeobuf: invalid or duplicate column name %q", c.Name)
+		}
+		if c.Type > ColumnTypeBinary {
+			return nil, fmt.Errorf("flatgeobuf: unsupported type %s of column %q", c.Type, c.Name)
+		}
+		names[c.Name] = true
+	}
+	if len(opts.Columns) > 0xFFFF {
+		return nil, fmt.Errorf("flatgeobuf: too many columns")
+	}
+	return &Writer{
+		w: w,
+		header: Header{
+			Name:          opts.Name,
+			Title:         opts.Title,
+			Description:   opts.Description,
+			Columns:       opts.Columns,
+			IndexNodeSize: uint16(size),
+			SRID:          opts.SRID,
+		},
+		noIndex: opts.NoIndex,
+		bounds:  emptyBounds,
+	}, nil
+}
+
+// Write adds a feature. Properties are matched to the columns by name, and
+// properties without a column are ignored. A feature that cannot be encoded
+// is not added and leaves the writer usable.
+func (w *Writer) Write(f Feature) error {
+	if w.err != nil {
+		return w.err
+	}
+	n := len(w.features) + 1
+	b := newBuilder(1024)
+	bounds := emptyBounds
+	var geomOff int
+	if f.Geometry != nil {
+		off, typ, err := writeGeometry(b, f.Geometry, &bounds, 0)
+		if err != nil {
+			return fmt.Errorf("flatgeobuf: feature %d: %w", n, err)
+		}
+		geomOff = off
+		if n == 1 {
+			w.header.GeometryType = typ
+		} else if w.header.GeometryType != typ {
+			w.header.GeometryType = GeometryTypeUnknown
+		}
+	} else {
+		w.header.GeometryType = GeometryTypeUnknown
+	}
+	props, err := appendProperties(nil, w.header.Columns, f.Properties)
+	if err != nil {
+		return fmt.Errorf("flatgeobuf: feature %d: %w", n, err)
+	}
+	var propsOff int
+	if len(props) > 0 {
+		propsOff = b.createBytes(props)
+	}
+	b.startTable(featureCount)
+	b.addOffset(featureGeometry, geomOff)
+	b.addOffset(featureProperties, propsOff)
+
+	w.features = append(w.features, node{Bounds: bounds, offset: uint64(len(w.data))})
+	w.data = append(w.data, b.finish(b.endTable())...)
+	w.bounds.extend(bounds)
+	return nil
+}
+
+// Close writes the header, the index and the features. It does not close
+// the underlying writer. Features cannot be written after Close.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	w.err = fmt.Errorf("flatgeobuf: writer is closed")
+	if err := w.flush(); err != nil {
+		return fmt.Errorf("flatgeobuf: %w", err)
+	}
+	return nil
+}
+
+func (w *Writer) flush() error {
+	h := w.header
+	h.FeaturesCount = uint64(len(w.features))
+	if !w.bounds.empty() {
+		bounds := w.bounds
+		h.Bounds = &bounds
+	}
+	if w.noIndex || len(w.features) == 0 {
+		h.IndexNodeSize = 0
+	}
+
+	out := bufio.NewWriter(w.w)
+	out.Write(magic[:])
+	out.Write(encodeHeader(&h))
+
+	// The order of the features in the file.
+	order := w.features
+	if h.IndexNodeSize > 0 {
+		order = make([]node, len(w.features))
+		copy(order, w.features)
+		values := make([]uint32, len(order))
+		for i, f := range order {
+			values[i] = hilbertValue(f.Bounds, w.bounds)
+		}
+		sort.Sort(byHilbert{order, values})
+
+		leaves := make([]node, len(order))
+		var offset uint64
+		for i, f := range order {
+			leaves[i] = node{Bounds: f.Bounds, offset: offset}
+			offset += uint64(w.featureSize(f))
+		}
+		var buf []byte
+		for _, n := range buildIndex(leaves, int(h.IndexNodeSize)) {
+			buf = appendNode(buf[:0], n)
+			out.Write(buf)
+		}
+	}
+	for _, f := range order {
+		out.Write(w.data[f.offset : f.offset+uint64(w.featureSize(f))])
+	}
+	return out.Flush()
+}
+
+// featureSize returns the size of the encoded feature f with its prefix.
+func (w *Writer) featureSize(f node) int {
+	return 4 + int(binary.LittleEndian.Uint32(w.data[f.offset:]))
+}
+
+// byHilbert sorts features by decreasing Hilbert value, as the reference
+// implementation does.
+type byHilbert struct {
+	nodes  []node
+	values []uint32
+}
+
+func (s byHilbert) Len() int           { return len(s.nodes) }
+func (s byHilbert) Less(i, j int) bool { return s.values[i] > s.values[j] }
+func (s byHilbert) Swap(i, j int) {
+	s.nodes[i], s.nodes[j] = s.nodes[j], s.nodes[i]
+	s.values[i], s.values[j] = s.values[j], s.values[i]
+}
@@ -0,0 +1,228 @@
+package geobuf
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/internal/pbf"
+)
+
+// Unmarshal decodes a Geobuf Data message holding a geometry. Messages
+// holding a feature or feature collection are rejected.
+func Unmarshal(data []byte) (gogis.Geometry, error) {
+	dim, precision := uint64(2), uint64(DefaultPrecision)
+	var geom []byte
+	found := false
+	r := pbf.NewReader(data)
+	for r.Next() {
+		switch r.Field() {
+		case dataDimensions:
+			dim = r.Uint64()
+		case dataPrecision:
+			precision = r.Uint64()
+		case dataFeatureCollection, dataFeature:
+			return nil, fmt.Errorf("geobuf: message holds features, not a geometry")
+		case dataGeometry:
+			geom, found = r.Bytes(), true
+		default:
+			r.Skip()
+		}
+	}
+	if err := r.Err(); err != nil {
+		return nil, fmt.Errorf("geobuf: %w", err)
+	}
+	if !found {
+		return nil, fmt.Errorf("geobuf: message holds no geometry")
+	}
+	if dim < 2 || dim > 255 {
+		return nil, fmt.Errorf("geobuf: invalid dimensions %d", dim)
+	}
+	if precision > MaxPrecision {
+		return nil, fmt.Errorf("geobuf: invalid precision %d", precision)
+	}
+	dec := decoder{dim: int(dim), e: math.Pow10(int(precision))}
+	g, err := dec.geometry(geom, 0)
+	if err != nil {
+		return nil, fmt.Errorf("geobuf: %w", err)
+	}
+	return g, nil
+}
+
+type decoder struct {
+	dim int     // values per coordinate, of which the first two are kept
+	e   float64 // coordinate scale, 10 to the power of the precision
+}
+
+func (dec decoder) geometry(msg []byte, depth int) (gogis.Geometry, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("geometry collections nested too deeply")
+	}
+	var (
+		typ      uint64
+		lengths  []uint32
+		coords   []int64
+		children [][]byte
+	)
+	r := pbf.NewReader(msg)
+	for r.Next() {
+		switch r.Field() {
+		case geometryType:
+			typ = r.Uint64()
+		case geometryLengths:
+			lengths = append(lengths, r.PackedUint32()...)
+		case geometryCoords:
+			coords = append(coords, r.PackedSint64()...)
+		case geometryGeometries:
+			children = append(children, r.Bytes())
+		default:
+			r.Skip()
+		}
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	switch typ {
+	case typePoint:
+		points, err := dec.line(coords, false)
+		if err != nil {
+			return nil, err
+		}
+		if len(points) != 1 {
+			return nil, fmt.Errorf("Point with %d coordinates", len(points))
+		}
+		return &points[0], nil
+	case typeLineString:
+		points, err := dec.line(coords, false)
+		if err != nil {
+			return nil, err
+		}
+		return &gogis.LineString{Points: points}, nil
+	case typeMultiPoint:
+		points, err := dec.line(coords, false)
+		if err != nil {
+			return nil, err
+		}
+		gc := &gogis.GeometryCollection{Geometries: make([]gogis.Geometry, len(points))}
+		for i := range points {
+			gc.Geometries[i] = &points[i]
+		}
+		return gc, nil
+	case typeMultiLineString, typePolygon:
+		lines, err := dec.lines(coords, lengths, typ == typePolygon)
+		if err != nil {
+			return nil, err
+		}
+		if typ == typePolygon {
+			return &gogis.Polygon{Rings: lines}, nil
+		}
+		gc := &gogis.GeometryCollection{Geometries: make([]gogis.Geometry, len(lines))}
+		for i, line := range lines {
+			gc.Geometries[i] = &gogis.LineString{Points: line}
+		}
+		return gc, nil
+	case typeMultiPolygon:
+		return dec.polygons(coords, lengths)
+	case typeGeometryCollection:
+		gc := &gogis.GeometryCollection{Geometries: make([]gogis.Geometry, len(children))}
+		for i, child := range children {
+			g, err := dec.geometry(child, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			gc.Geometries[i] = g
+		}
+		return gc, nil
+	}
+	return nil, fmt.Errorf("unsupported geometry type %d", typ)
+}
+
+// line decodes delta encoded coordinates, closing the line if closed.
+func (dec decoder) line(coords []int64, closed bool) ([]gogis.Point, error) {
+	if len(coords)%dec.dim != 0 {
+		return nil, fmt.Errorf("%d values for coordinates of dimension %d", len(coords), dec.dim)
+	}
+	points := make([]gogis.Point, 0, len(coords)/dec.dim+1)
+	var x, y int64
+	for i := 0; i < len(coords); i += dec.dim {
+		x += coords[i]
+		y += coords[i+1]
+		points = append(points, gogis.Point{Lng: float64(x) / dec.e, Lat: float64(y) / dec.e})
+	}
+	if closed && len(points) > 0 {
+		points = append(points, points[0])
+	}
+	return points, nil
+}
+
+// lines splits coords into lines of the given lengths, or a single line
+// when there are none.
+func (dec decoder) lines(coords []int64, lengths []uint32, closed bool) ([][]gogis.Point, error) {
+	if len(lengths) == 0 {
+		if len(coords) == 0 {
+			return [][]gogis.Point{}, nil
+		}
+		line, err := dec.line(coords, closed)
+		if err != nil {
+			return nil, err
+		}
+		return [][]gogis.Point{line}, nil
+	}
+	lines := make([][]gogis.Point, len(lengths))
+	for i, n := range lengths {
+		var err error
+		if lines[i], coords, err = dec.next(coords, n, closed); err != nil {
+			return nil, err
+		}
+	}
+	if len(coords) > 0 {
+		return nil, fmt.Errorf("%d coordinate values beyond the lengths", len(coords))
+	}
+	return lines, nil
+}
+
+// next decodes a line of n coordinates from coords and returns it with the
+// remaining values.
+func (dec decoder) next(coords []int64, n uint32, closed bool) ([]gogis.Point, []int64, error) {
+	size := uint64(n) * uint64(dec.dim)
+	if size > uint64(len(coords)) {
+		return nil, nil, fmt.Errorf("length %d exceeds the coordinates", n)
+	}
+	line, err := dec.line(coords[:size], closed)
+	return line, coords[size:], err
+}
+
+func (dec decoder) polygons(coords []int64, lengths []uint32) (*gogis.GeometryCollection, error) {
+	if len(lengths) == 0 {
+		ring, err := dec.line(coords, true)
+		if err != nil {
+			return nil, err
+		}
+		return &gogis.GeometryCollection{Geometries: []gogis.Geometry{
+			&gogis.Polygon{Rings: [][]gogis.Point{ring}},
+		}}, nil
+	}
+	// lengths holds the number of polygons, then for each polygon its
+	// number of rings followed by their lengths.
+	gc := &gogis.GeometryCollection{Geometries: []gogis.Geometry{}}
+	rest := lengths[1:]
+	for i := uint32(0); i < lengths[0]; i++ {
+		if len(rest) == 0 || uint64(rest[0]) > uint64(len(rest)-1) {
+			return nil, fmt.Errorf("invalid polygon lengths")
+		}
+		rings := make([][]gogis.Point, rest[0])
+		for j := range rings {
+			var err error
+			if rings[j], coords, err = dec.next(coords, rest[1+j], true); err != nil {
+				return nil, err
+			}
+		}
+		rest = rest[1+len(rings):]
+		gc.Geometries = append(gc.Geometries, &gogis.Polygon{Rings: rings})
+	}
+	if len(rest) > 0 || len(coords) > 0 {
+		return nil, fmt.Errorf("invalid polygon lengths")
+	}
+	return gc, nil
+}
@@ -0,0 +1,320 @@
+// Package geobuf encodes and decodes gogis geometries as Geobuf, a compact
+// Protocol Buffers encoding of GeoJSON, for exchanging geometries between
+// services with a fixed message schema.
+//
+// Marshal and Unmarshal convert a geometry to and from a Data message of
+// the schema in geobuf.proto, version 3 of the Geobuf specification. The
+// coordinates of a message are integers: the coordinates multiplied by 10
+// to the power of its precision and rounded, delta encoded along each line.
+// Messages are compatible with the geobuf JavaScript library and its ports.
+//
+// Geometries map to gogis types as follows. Z and M values are dropped, and
+// polygon rings are closed when decoded.
+//
+//	Point               *gogis.Point
+//	LineString          *gogis.LineString
+//	Polygon             *gogis.Polygon
+//	MultiPoint,
+//	MultiLineString,
+//	MultiPolygon,
+//	GeometryCollection  *gogis.GeometryCollection
+//
+// Collections holding geometries of a single type are encoded as the
+// matching multi geometry.
+package geobuf
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/internal/pbf"
+)
+
+// DefaultPrecision is the precision of messages that give none, and the
+// highest precision Marshal chooses: 6 decimal digits, about 10 cm in
+// degrees.
+const DefaultPrecision = 6
+
+// MaxPrecision is the highest precision accepted by MarshalPrecision.
+const MaxPrecision = 15
+
+// maxDepth limits the nesting of geometry collections.
+const maxDepth = 32
+
+// Fields of the Data message.
+const (
+	dataDimensions        = 2
+	dataPrecision         = 3
+	dataFeatureCollection = 4
+	dataFeature           = 5
+	dataGeometry          = 6
+)
+
+// Fields of the Geometry message.
+const (
+	geometryType       = 1
+	geometryLengths    = 2
+	geometryCoords     = 3
+	geometryGeometries = 4
+)
+
+// Geometry types.
+const (
+	typePoint              = 0
+	typeMultiPoint         = 1
+	typeLineString         = 2
+	typeMultiLineString    = 3
+	typePolygon            = 4
+	typeMultiPolygon       = 5
+	typeGeometryCollection = 6
+)
+
+// Marshal encodes g as a Geobuf Data message, with the lowest precision up
+// to DefaultPrecision that represents its coordinates exactly, as the
+// reference encoder does.
+func Marshal(g gogis.Geometry) ([]byte, error) {
+	precision := 0
+	e := 1.0
+	err := eachPoint(g, 0, func(p gogis.Point) {
+		for _, v := range []float64{p.Lng, p.Lat} {
+			for precision < DefaultPrecision && math.Round(v*e)/e != v {
+				precision++
+				e *= 10
+			}
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("geobuf: %w", err)
+	}
+	return MarshalPrecision(g, precision)
+}
+
+// MarshalPrecision encodes g as a Geobuf Data message, rounding its
+// coordinates to the given number of decimal digits, between 0 and
+// MaxPrecision.
+func MarshalPrecision(g gogis.Geometry, precision int) ([]byte, error) {
+	if precision < 0 || precision > MaxPrecision {
+		return nil, fmt.Errorf("geobuf: precision %d out of range [0, %d]", precision, MaxPrecision)
+	}
+	enc := encoder{e: math.Pow10(precision)}
+	geom, err := enc.geometry(g, 0)
+	if err != nil {
+		return nil, fmt.Errorf("geobuf: %w", err)
+	}
+	var w pbf.Writer
+	if precision != DefaultPrecision {
+		w.Uint64(dataPrecision, uint64(precision))
+	}
+	w.Message(dataGeometry, geom)
+	return w.Bytes(), nil
+}
+
+type encoder struct {
+	e float64 // coordinate scale, 10 to the power of the precision
+}
+
+func (enc encoder) geometry(g gogis.Geometry, depth int) ([]byte, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("geometry collections nested too deeply")
+	}
+	var (
+		typ      uint64
+		lengths  []uint32
+		coords   []int64
+		children []gogis.Geometry
+		err      error
+	)
+	switch v := g.(type) {
+	case *gogis.Point:
+		typ = typePoint
+		coords, err = enc.line(nil, []gogis.Point{*v}, false)
+	case *gogis.LineString:
+		typ = typeLineString
+		coords, err = enc.line(nil, v.Points, false)
+	case *gogis.Polygon:
+		typ = typePolygon
+		lengths, coords, err = enc.lines(v.Rings, true)
+	case *gogis.GeometryCollection:
+		typ = collectionType(v)
+		switch typ {
+		case typeMultiPoint:
+			points := make([]gogis.Point, len(v.Geometries))
+			for i, child := range v.Geometries {
+				points[i] = *child.(*gogis.Point)
+			}
+			coords, err = enc.line(nil, points, false)
+		case typeMultiLineString:
+			lines := make([][]gogis.Point, len(v.Geometries))
+			for i, child := range v.Geometries {
+				lines[i] = child.(*gogis.LineString).Points
+			}
+			lengths, coords, err = enc.lines(lines, false)
+		case typeMultiPolygon:
+			lengths, coords, err = enc.polygons(v.Geometries)
+		default:
+			children = v.Geometries
+		}
+	case *gogis.GeographyPoint:
+		return enc.geometry((*gogis.Point)(v), depth)
+	case *gogis.GeographyLineString:
+		return enc.geometry((*gogis.LineString)(v), depth)
+	case *gogis.GeographyPolygon:
+		return enc.geometry((*gogis.Polygon)(v), depth)
+	case *gogis.GeographyCollection:
+		return enc.geometry((*gogis.GeometryCollection)(v), depth)
+	default:
+		return nil, fmt.Errorf("unsupported geometry type %T", g)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var w pbf.Writer
+	w.Uint64(geometryType, typ)
+	w.PackedUint32(geometryLengths, lengths)
+	w.PackedSint64(geometryCoords, coords)
+	for _, child := range children {
+		msg, err := enc.geometry(child, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		w.Message(geometryGeometries, msg)
+	}
+	return w.Bytes(), nil
+}
+
+// line appends the delta encoded coordinates of points to coords. The last
+// point of closed rings is left out.
+func (enc encoder) line(coords []int64, points []gogis.Point, closed bool) ([]int64, error) {
+	if closed && len(points) > 1 && points[0] == points[len(points)-1] {
+		points = points[:len(points)-1]
+	}
+	var x, y int64
+	for _, p := range points {
+		qx, err := enc.quantize(p.Lng)
+		if err != nil {
+			return nil, err
+		}
+		qy, err := enc.quantize(p.Lat)
+		if err != nil {
+			return nil, err
+		}
+		coords = append(coords, qx-x, qy-y)
+		x, y = qx, qy
+	}
+	return coords, nil
+}
+
+// lines encodes the lines of a multi line string or the rings of a polygon,
+// with their lengths unless there is a single one.
+func (enc encoder) lines(lines [][]gogis.Point, closed bool) ([]uint32, []int64, error) {
+	var lengths []uint32
+	var coords []int64
+	for _, line := range lines {
+		n := len(coords)
+		var err error
+		if coords, err = enc.line(coords, line, closed); err != nil {
+			return nil, nil, err
+		}
+		lengths = append(lengths, uint32((len(coords)-n)/2))
+	}
+	if len(lines) == 1 {
+		lengths = nil
+	}
+	return lengths, coords, nil
+}
+
+// polygons encodes the polygons of a multi polygon. Its lengths are the
+// number of polygons, then for each polygon the number of rings followed by
+// their lengths, and are left out for a single polygon with a single ring.
+func (enc encoder) polygons(geometries []gogis.Geometry) ([]uint32, []int64, error) {
+	lengths := []uint32{uint32(len(geometries))}
+	var coords []int64
+	for _, g := range geometries {
+		rings := g.(*gogis.Polygon).Rings
+		lengths = append(lengths, uint32(len(rings)))
+		for _, ring := range rings {
+			n := len(coords)
+			var err error
+			if coords, err = enc.line(coords, ring, true); err != nil {
+				return nil, nil, err
+			}
+			lengths = append(lengths, uint32((len(coords)-n)/2))
+		}
+	}
+	if len(geometries) == 1 && len(lengths) == 3 {
+		lengths = nil
+	}
+	return lengths, coords, nil
+}
+
+func (enc encoder) quantize(v float64) (int64, error) {
+	q := math.Round(v * enc.e)
+	if math.IsNaN(q) || math.Abs(q) >= 1<<62 {
+		return 0, fmt.Errorf("coordinate %v out of range at this precision", v)
+	}
+	return int64(q), nil
+}
+
+// collectionType returns the multi geometry type of a collection holding
+// geometries of one type, or typeGeometryCollection.
+func collectionType(gc *gogis.GeometryCollection) uint64 {
+	typ := uint64(typeGeometryCollection)
+	for i, child := range gc.Geometries {
+		var t uint64
+		switch child.(type) {
+		case *gogis.Point:
+			t = typeMultiPoint
+		case *gogis.LineString:
+			t = typeMultiLineString
+		case *gogis.Polygon:
+			t = typeMultiPolygon
+		default:
+			return typeGeometryCollection
+		}
+		if i > 0 && t != typ {
+			return typeGeometryCollection
+		}
+		typ = t
+	}
+	return typ
+}
+
+// eachPoint calls fn for each point of g.
+func eachPoint(g gogis.Geometry, depth int, fn func(gogis.Point)) error {
+	if depth > maxDepth {
+		return fmt.Errorf("geometry collections nested too deeply")
+	}
+	switch v := g.(type) {
+	case *gogis.Point:
+		fn(*v)
+	case *gogis.LineString:
+		for _, p := range v.Points {
+			fn(p)
+		}
+	case *gogis.Polygon:
+		for _, ring := range v.Rings {
+			for _, p := range ring {
+				fn(p)
+			}
+		}
+	case *gogis.GeometryCollection:
+		for _, child := range v.Geometries {
+			if err := eachPoint(child, depth+1, fn); err != nil {
+				return err
+			}
+		}
+	case *gogis.GeographyPoint:
+		fn(gogis.Point(*v))
+	case *gogis.GeographyLineString:
+		return eachPoint((*gogis.LineString)(v), depth, fn)
+	case *gogis.GeographyPolygon:
+		return eachPoint((*gogis.Polygon)(v), depth, fn)
+	case *gogis.GeographyCollection:
+		return eachPoint((*gogis.GeometryCollection)(v), depth, fn)
+	default:
+		return fmt.Errorf("unsupported geometry type %T", g)
+	}
+	return nil
+}
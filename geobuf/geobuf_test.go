@@ -0,0 +1,147 @@
+package geobuf_test
+
+import (
+	"encoding/hex"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/geobuf"
+)
+
+var ring = []gogis.Point{{Lng: 0, Lat: 0}, {Lng: 4, Lat: 0}, {Lng: 4, Lat: 4}, {Lng: 0, Lat: 4}, {Lng: 0, Lat: 0}}
+var hole = []gogis.Point{{Lng: 1, Lat: 1}, {Lng: 1, Lat: 2}, {Lng: 2, Lat: 2}, {Lng: 1, Lat: 1}}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		g    gogis.Geometry
+		want string
+	}{
+		// precision 2, geometry {type: POINT, coords: [150, 225]}
+		{"point", &gogis.Point{Lng: 1.5, Lat: 2.25}, "1802" + "3208" + "0800" + "1a04ac02c203"},
+		// precision 0, geometry {type: LINESTRING, coords: [1, 2, 2, 2]}
+		{"line", &gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}}}, "1800" + "3208" + "0802" + "1a0402040404"},
+		// closing point left out: {type: POLYGON, coords: [0,0, 4,0, 0,4, -4,0]}
+		{"polygon", &gogis.Polygon{Rings: [][]gogis.Point{ring}}, "1800" + "320c" + "0804" + "1a080000080000080700"},
+		// default precision, no precision field
+		{"precise", &gogis.Point{Lng: 0.000001, Lat: 0}, "3206" + "0800" + "1a020200"},
+	}
+	for _, tt := range tests {
+		b, err := geobuf.Marshal(tt.g)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := hex.EncodeToString(b); got != tt.want {
+			t.Errorf("%s: Marshal = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []gogis.Geometry{
+		&gogis.Point{Lng: 4.895168, Lat: 52.370216},
+		&gogis.Point{Lng: -180, Lat: -90},
+		&gogis.LineString{Points: []gogis.Point{{Lng: 1.5, Lat: 2}, {Lng: -3.25, Lat: 4}, {Lng: 5, Lat: 6.125}}},
+		&gogis.LineString{Points: []gogis.Point{}},
+		&gogis.Polygon{Rings: [][]gogis.Point{ring}},
+		&gogis.Polygon{Rings: [][]gogis.Point{ring, hole}},
+		&gogis.GeometryCollection{Geometries: []gogis.Geometry{&gogis.Point{Lng: 1, Lat: 2}, &gogis.Point{Lng: 3, Lat: 4}}},
+		&gogis.GeometryCollection{Geometries: []gogis.Geometry{
+			&gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}}},
+		}},
+		&gogis.GeometryCollection{Geometries: []gogis.Geometry{
+			&gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}}},
+			&gogis.LineString{Points: []gogis.Point{{Lng: 5, Lat: 6}, {Lng: 7, Lat: 8}, {Lng: 9, Lat: 9}}},
+		}},
+		&gogis.GeometryCollection{Geometries: []gogis.Geometry{&gogis.Polygon{Rings: [][]gogis.Point{ring}}}},
+		&gogis.GeometryCollection{Geometries: []gogis.Geometry{
+			&gogis.Polygon{Rings: [][]gogis.Point{ring, hole}},
+			&gogis.Polygon{Rings: [][]gogis.Point{hole}},
+		}},
+		&gogis.GeometryCollection{Geometries: []gogis.Geometry{
+			&gogis.Point{Lng: 1, Lat: 2},
+			&gogis.GeometryCollection{Geometries: []gogis.Geometry{&gogis.Polygon{Rings: [][]gogis.Point{ring}}}},
+		}},
+		&gogis.GeometryCollection{Geometries: []gogis.Geometry{}},
+	}
+	for _, g := range tests {
+		b, err := geobuf.Marshal(g)
+		if err != nil {
+			t.Fatalf("%v: %v", g, err)
+		}
+		got, err := geobuf.Unmarshal(b)
+		if err != nil {
+			t.Fatalf("%v: %v", g, err)
+		}
+		if !reflect.DeepEqual(got, g) {
+			t.Errorf("round trip of %v = %v", g, got)
+		}
+	}
+}
+
+func TestMarshalPrecision(t *testing.T) {
+	g := &gogis.GeographyLineString{Points: []gogis.Point{{Lng: 4.8951679, Lat: 52.3702157}, {Lng: 4.9, Lat: 52.37}}}
+	b, err := geobuf.MarshalPrecision(g, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := geobuf.Unmarshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &gogis.LineString{Points: []gogis.Point{{Lng: 4.895, Lat: 52.37}, {Lng: 4.9, Lat: 52.37}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, precision := range []int{-1, geobuf.MaxPrecision + 1} {
+		if _, err := geobuf.MarshalPrecision(g, precision); err == nil {
+			t.Errorf("precision %d accepted", precision)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	// dimensions 3, precision 0, {type: LINESTRING, coords: [10,20,5, 1,1,1]}
+	b, _ := hex.DecodeString("1003" + "1800" + "320a" + "0802" + "1a0614280a020202")
+	got, err := geobuf.Unmarshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &gogis.LineString{Points: []gogis.Point{{Lng: 10, Lat: 20}, {Lng: 11, Lat: 21}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("3D line = %v, want %v", got, want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, g := range []gogis.Geometry{
+		&gogis.Point{Lng: math.NaN(), Lat: 0},
+		&gogis.Point{Lng: math.Inf(1), Lat: 0},
+		&gogis.Point{Lng: 1e300, Lat: 0},
+		nil,
+	} {
+		if _, err := geobuf.Marshal(g); err == nil {
+			t.Errorf("Marshal(%v): no error", g)
+		}
+	}
+
+	for name, s := range map[string]string{
+		"empty":          "",
+		"truncated":      "3208" + "0800",
+		"feature":        "2a00",
+		"dimensions":     "1001" + "3200",
+		"precision":      "1810" + "3200",
+		"point coords":   "3204" + "0800" + "1a0102",
+		"unknown type":   "3202" + "0809",
+		"lengths":        "3208" + "0803" + "1202" + "0305" + "1a00",
+		"polygon counts": "320a" + "0805" + "1202" + "0203" + "1a020000",
+	} {
+		b, _ := hex.DecodeString(s)
+		if g, err := geobuf.Unmarshal(b); err == nil {
+			t.Errorf("%s: no error, got %v", name, g)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package gogis
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// GeographyPoint is a Point stored in a PostGIS geography column.
+//
+// Geography columns measure on the WGS 84 spheroid, so distances and areas
+// are in meters instead of degrees:
+//
+//	type Location struct {
+//	    ID    uint
+//	    Name  string
+//	    Point gogis.GeographyPoint // geography(Point,4326)
+//	}
+//
+//	// Find locations within 1km
+//	db.Where("ST_DWithin(point, ST_GeogFromText(?), ?)", center.String(), 1000).Find(&locations)
+//
+// The geography types convert to and from their geometry counterparts:
+//
+//	p := gogis.GeographyPoint{Lng: -74.0445, Lat: 40.6892}
+//	geom := gogis.Point(p)
+type GeographyPoint Point
+
+// Ensure GeographyPoint implements Geometry interface
+var _ Geometry = (*GeographyPoint)(nil)
+
+// String returns the EWKT representation of the point, such as
+// "SRID=4326;POINT(-74.0445 40.6892)".
+func (p *GeographyPoint) String() string {
+	return (*Point)(p).String()
+}
+
+// Scan implements the sql.Scanner interface, reading the EWKB that PostGIS
+// returns for geography and geometry columns and the binary formats of the
+// other dialects, with SQL Server values read as geography.
+func (p *GeographyPoint) Scan(val any) error {
+	g, err := scanGeography(val)
+	if err != nil || g == nil {
+		return err
+	}
+	v, ok := g.(*Point)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into GeographyPoint", g)
+	}
+	*p = GeographyPoint(*v)
+	return nil
+}
+
+// Value implements the driver.Valuer interface, returning the EWKT
+// representation which PostGIS casts to geography.
+func (p GeographyPoint) Value() (driver.Value, error) {
+	return p.String(), nil
+}
+
+// GormValue implements the gorm.Valuer interface, wrapping the value in
+// ST_GeogFromText so that it is typed as geography in any SQL context.
+func (p GeographyPoint) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	return geographyExpr(db, &p)
+}
+
+// GormDataType returns the general GORM data type of GeographyPoint fields.
+func (GeographyPoint) GormDataType() string {
+	return "geography"
+}
+
+// GormDBDataType returns the column type of GeographyPoint fields, which is
+// geography(Point,4326) on PostgreSQL. The tag options of
+// Point.GormDBDataType apply.
+func (GeographyPoint) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return gormDBDataType(db, field, "geography", "Point")
+}
+
+// GeographyLineString is a LineString stored in a PostGIS geography column.
+// See GeographyPoint.
+type GeographyLineString LineString
+
+// Ensure GeographyLineString implements Geometry interface
+var _ Geometry = (*GeographyLineString)(nil)
+
+// String returns the EWKT representation of the line string.
+func (ls *GeographyLineString) String() string {
+	return (*LineString)(ls).String()
+}
+
+// Scan implements the sql.Scanner interface, reading the EWKB that PostGIS
+// returns for geography and geometry columns and the binary formats of the
+// other dialects, with SQL Server values read as geography.
+func (ls *GeographyLineString) Scan(val any) error {
+	g, err := scanGeography(val)
+	if err != nil || g == nil {
+		return err
+	}
+	v, ok := g.(*LineString)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into GeographyLineString", g)
+	}
+	*ls = GeographyLineString(*v)
+	return nil
+}
+
+// Value implements the driver.Valuer interface, returning the EWKT
+// representation which PostGIS casts to geography.
+func (ls GeographyLineString) Value() (driver.Value, error) {
+	return ls.String(), nil
+}
+
+// GormValue implements the gorm.Valuer interface, wrapping the value in
+// ST_GeogFromText.
+func (ls GeographyLineString) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	return geographyExpr(db, &ls)
+}
+
+// GormDataType returns the general GORM data type of GeographyLineString
+// fields.
+func (GeographyLineString) GormDataType() string {
+	return "geography"
+}
+
+// GormDBDataType returns the column type of GeographyLineString fields, which
+// is geography(LineString,4326) on PostgreSQL.
+func (GeographyLineString) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return gormDBDataType(db, field, "geography", "LineString")
+}
+
+// GeographyPolygon is a Polygon stored in a PostGIS geography column.
+// See GeographyPoint.
+type GeographyPolygon Polygon
+
+// Ensure GeographyPolygon implements Geometry interface
+var _ Geometry = (*GeographyPolygon)(nil)
+
+// String returns the EWKT representation of the polygon.
+func (p *GeographyPolygon) String() string {
+	return (*Polygon)(p).String()
+}
+
+// Scan implements the sql.Scanner interface, reading the EWKB that PostGIS
+// returns for geography and geometry columns and the binary formats of the
+// other dialects, with SQL Server values read as geography.
+func (p *GeographyPolygon) Scan(val any) error {
+	g, err := scanGeography(val)
+	if err != nil || g == nil {
+		return err
+	}
+	v, ok := g.(*Polygon)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into GeographyPolygon", g)
+	}
+	*p = GeographyPolygon(*v)
+	return nil
+}
+
+// Value implements the driver.Valuer interface, returning the EWKT
+// representation which PostGIS casts to geography.
+func (p GeographyPolygon) Value() (driver.Value, error) {
+	return p.String(), nil
+}
+
+// GormValue implements the gorm.Valuer interface, wrapping the value in
+// ST_GeogFromText.
+func (p GeographyPolygon) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	return geographyExpr(db, &p)
+}
+
+// GormDataType returns the general GORM data type of GeographyPolygon fields.
+func (GeographyPolygon) GormDataType() string {
+	return "geography"
+}
+
+// GormDBDataType returns the column type of GeographyPolygon fields, which is
+// geography(Polygon,4326) on PostgreSQL.
+func (GeographyPolygon) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return gormDBDataType(db, field, "geography", "Polygon")
+}
+
+// GeographyCollection is a GeometryCollection stored in a PostGIS geography
+// column. Its members are regular geometries such as *Point. Multi geometries
+// scanned from the database become nested collections. See GeographyPoint.
+type GeographyCollection GeometryCollection
+
+// Ensure GeographyCollection implements Geometry interface
+var _ Geometry = (*GeographyCollection)(nil)
+
+// String returns the EWKT representation of the collection.
+func (gc *GeographyCollection) String() string {
+	return (*GeometryCollection)(gc).String()
+}
+
+// Scan implements the sql.Scanner interface, reading the EWKB that PostGIS
+// returns for geography and geometry columns and the binary formats of the
+// other dialects, with SQL Server values read as geography.
+func (gc *GeographyCollection) Scan(val any) error {
+	g, err := scanGeography(val)
+	if err != nil || g == nil {
+		return err
+	}
+	v, ok := g.(*GeometryCollection)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into GeographyCollection", g)
+	}
+	*gc = GeographyCollection(*v)
+	return nil
+}
+
+// Value implements the driver.Valuer interface, returning the EWKT
+// representation which PostGIS casts to geography.
+func (gc GeographyCollection) Value() (driver.Value, error) {
+	return gc.String(), nil
+}
+
+// GormValue implements the gorm.Valuer interface, wrapping the value in
+// ST_GeogFromText.
+func (gc GeographyCollection) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	return geographyExpr(db, &gc)
+}
+
+// GormDataType returns the general GORM data type of GeographyCollection
+// fields.
+func (GeographyCollection) GormDataType() string {
+	return "geography"
+}
+
+// GormDBDataType returns the column type of GeographyCollection fields, which
+// is geography(GeometryCollection,4326) on PostgreSQL.
+func (GeographyCollection) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return gormDBDataType(db, field, "geography", "GeometryCollection")
+}
+
+// scanGeography decodes a database value holding EWKB, returning nil for
+// NULL.
+func scanGeography(val any) (Geometry, error) {
+	if val == nil {
+		return nil, nil
+	}
+	if g, ok, err := scanBinary(val, true); ok {
+		return g, err
+	}
+	b, err := scanBytes(val)
+	if err != nil {
+		return nil, err
+	}
+	g, _, err := DecodeEWKB(b)
+	return g, err
+}
+
+// geographyExpr casts the EWKT of g to geography on PostgreSQL. Other
+// databases get the value of their Dialect.
+func geographyExpr(db *gorm.DB, g Geometry) clause.Expr {
+	if db != nil && db.Dialector != nil && db.Dialector.Name() != "postgres" {
+		return gormValue(db, g)
+	}
+	return clause.Expr{SQL: "ST_GeogFromText(?)", Vars: []interface{}{g.String()}}
+}
@@ -0,0 +1,150 @@
+package gogis_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+
+	"github.com/restayway/gogis"
+)
+
+func TestGeographyValue(t *testing.T) {
+	ring := []gogis.Point{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 0}, {Lng: 1, Lat: 1}, {Lng: 0, Lat: 0}}
+	tests := []struct {
+		name     string
+		value    driver.Valuer
+		expected string
+	}{
+		{
+			name:     "point",
+			value:    gogis.GeographyPoint{Lng: -74.0445, Lat: 40.6892},
+			expected: "SRID=4326;POINT(-74.0445 40.6892)",
+		},
+		{
+			name:     "linestring",
+			value:    gogis.GeographyLineString{Points: ring[:2]},
+			expected: "SRID=4326;LINESTRING(0 0,1 0)",
+		},
+		{
+			name:     "polygon",
+			value:    gogis.GeographyPolygon{Rings: [][]gogis.Point{ring}},
+			expected: "SRID=4326;POLYGON((0 0,1 0,1 1,0 0))",
+		},
+		{
+			name:     "collection",
+			value:    gogis.GeographyCollection{Geometries: []gogis.Geometry{&ring[1]}},
+			expected: "SRID=4326;GEOMETRYCOLLECTION(POINT(1 0))",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.value.Value()
+			if err != nil {
+				t.Fatalf("Value() unexpected error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Value() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGeographyGormValue(t *testing.T) {
+	p := gogis.GeographyPoint{Lng: 2.2945, Lat: 48.8584}
+	expr := p.GormValue(context.Background(), nil)
+	if expr.SQL != "ST_GeogFromText(?)" {
+		t.Errorf("GormValue() SQL = %q, want %q", expr.SQL, "ST_GeogFromText(?)")
+	}
+	if want := []interface{}{"SRID=4326;POINT(2.2945 48.8584)"}; !reflect.DeepEqual(expr.Vars, want) {
+		t.Errorf("GormValue() Vars = %v, want %v", expr.Vars, want)
+	}
+}
+
+func TestGeographyScan(t *testing.T) {
+	t.Run("point from PostGIS hex EWKB", func(t *testing.T) {
+		// SELECT 'SRID=4326;POINT(1 2)'::geography
+		var p gogis.GeographyPoint
+		if err := p.Scan("0101000020E6100000000000000000F03F0000000000000040"); err != nil {
+			t.Fatalf("Scan() unexpected error = %v", err)
+		}
+		if p != (gogis.GeographyPoint{Lng: 1, Lat: 2}) {
+			t.Errorf("Scan() = %+v, want {Lng:1 Lat:2}", p)
+		}
+	})
+
+	t.Run("point from binary EWKB", func(t *testing.T) {
+		b, err := gogis.EncodeEWKB(&gogis.Point{Lng: -74.0445, Lat: 40.6892}, gogis.SRIDWGS84)
+		if err != nil {
+			t.Fatalf("EncodeEWKB() unexpected error = %v", err)
+		}
+		var p gogis.GeographyPoint
+		if err := p.Scan(b); err != nil {
+			t.Fatalf("Scan() unexpected error = %v", err)
+		}
+		if p != (gogis.GeographyPoint{Lng: -74.0445, Lat: 40.6892}) {
+			t.Errorf("Scan() = %+v", p)
+		}
+	})
+
+	t.Run("linestring", func(t *testing.T) {
+		want := gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}}}
+		b, _ := gogis.EncodeEWKB(&want, gogis.SRIDWGS84)
+		var ls gogis.GeographyLineString
+		if err := ls.Scan(b); err != nil {
+			t.Fatalf("Scan() unexpected error = %v", err)
+		}
+		if !reflect.DeepEqual(gogis.LineString(ls), want) {
+			t.Errorf("Scan() = %+v, want %+v", ls, want)
+		}
+	})
+
+	t.Run("polygon", func(t *testing.T) {
+		want := gogis.Polygon{Rings: [][]gogis.Point{{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 0}, {Lng: 1, Lat: 1}, {Lng: 0, Lat: 0}}}}
+		b, _ := gogis.EncodeEWKB(&want, gogis.SRIDWGS84)
+		var p gogis.GeographyPolygon
+		if err := p.Scan(b); err != nil {
+			t.Fatalf("Scan() unexpected error = %v", err)
+		}
+		if !reflect.DeepEqual(gogis.Polygon(p), want) {
+			t.Errorf("Scan() = %+v, want %+v", p, want)
+		}
+	})
+
+	t.Run("collection", func(t *testing.T) {
+		want := gogis.GeometryCollection{Geometries: []gogis.Geometry{
+			&gogis.Point{Lng: 1, Lat: 2},
+			&gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}}},
+		}}
+		b, _ := gogis.EncodeEWKB(&want, gogis.SRIDWGS84)
+		var gc gogis.GeographyCollection
+		if err := gc.Scan(b); err != nil {
+			t.Fatalf("Scan() unexpected error = %v", err)
+		}
+		if !reflect.DeepEqual(gogis.GeometryCollection(gc), want) {
+			t.Errorf("Scan() = %+v, want %+v", gc, want)
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		p := gogis.GeographyPoint{Lng: 1, Lat: 2}
+		if err := p.Scan(nil); err != nil {
+			t.Fatalf("Scan(nil) unexpected error = %v", err)
+		}
+		if p != (gogis.GeographyPoint{Lng: 1, Lat: 2}) {
+			t.Errorf("Scan(nil) modified the point: %+v", p)
+		}
+	})
+
+	t.Run("wrong type", func(t *testing.T) {
+		var p gogis.GeographyPoint
+		b, _ := gogis.EncodeEWKB(&gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}}}, gogis.SRIDWGS84)
+		if err := p.Scan(b); err == nil {
+			t.Error("Scan() expected error for a line string, got nil")
+		}
+		if err := p.Scan(42); err == nil {
+			t.Error("Scan() expected error for an int, got nil")
+		}
+	})
+}
@@ -0,0 +1,328 @@
+// Package geojson reads and writes gogis geometries as GeoJSON (RFC 7946).
+//
+// Geometries and features convert with MarshalGeometry, UnmarshalGeometry and
+// the JSON methods of Feature. Large FeatureCollections are streamed one
+// feature at a time with Decoder and Encoder, which also read and write
+// GeoJSON text sequences (RFC 8142):
+//
+//	dec := geojson.NewDecoder(file)
+//	for {
+//	    f, err := dec.Next()
+//	    if err == io.EOF {
+//	        break
+//	    }
+//	    if err != nil {
+//	        return err
+//	    }
+//	    // use f.Geometry and f.Properties
+//	}
+//
+// GeoJSON has no SRID: coordinates are longitude and latitude in EPSG:4326.
+// MultiPoint, MultiLineString and MultiPolygon geometries decode to a
+// *gogis.GeometryCollection of their parts, and altitudes are dropped.
+package geojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/restayway/gogis"
+)
+
+// maxDepth limits the nesting of geometry collections.
+const maxDepth = 32
+
+// Feature is a geometry with properties.
+type Feature struct {
+	// ID is the optional feature id, a string or a number. Decoded numbers
+	// are float64.
+	ID any
+
+	// Geometry is the feature geometry, nil for features without one.
+	Geometry gogis.Geometry
+
+	// Properties are the feature properties, decoded as by encoding/json.
+	Properties map[string]any
+}
+
+// MarshalJSON encodes the feature as a GeoJSON Feature object.
+func (f Feature) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(`{"type":"Feature"`)
+	if f.ID != nil {
+		id, err := json.Marshal(f.ID)
+		if err != nil {
+			return nil, fmt.Errorf("geojson: feature id: %w", err)
+		}
+		buf.WriteString(`,"id":`)
+		buf.Write(id)
+	}
+	buf.WriteString(`,"geometry":`)
+	if f.Geometry == nil {
+		buf.WriteString("null")
+	} else if err := writeGeometry(&buf, f.Geometry); err != nil {
+		return nil, err
+	}
+	props, err := json.Marshal(f.Properties)
+	if err != nil {
+		return nil, fmt.Errorf("geojson: feature properties: %w", err)
+	}
+	buf.WriteString(`,"properties":`)
+	buf.Write(props)
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes a GeoJSON Feature object.
+func (f *Feature) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Type       string          `json:"type"`
+		ID         any             `json:"id"`
+		Geometry   json.RawMessage `json:"geometry"`
+		Properties map[string]any  `json:"properties"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("geojson: %w", err)
+	}
+	if raw.Type != "Feature" {
+		return fmt.Errorf("geojson: expected a Feature, got type %q", raw.Type)
+	}
+	var g gogis.Geometry
+	if len(raw.Geometry) > 0 && string(raw.Geometry) != "null" {
+		var err error
+		if g, err = UnmarshalGeometry(raw.Geometry); err != nil {
+			return err
+		}
+	}
+	*f = Feature{ID: raw.ID, Geometry: g, Properties: raw.Properties}
+	return nil
+}
+
+// MarshalGeometry encodes g as a GeoJSON geometry object. Point, LineString,
+// Polygon, GeometryCollection and their geography variants are supported.
+func MarshalGeometry(g gogis.Geometry) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeGeometry(&buf, g); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeGeometry(buf *bytes.Buffer, g gogis.Geometry) error {
+	var err error
+	switch v := g.(type) {
+	case *gogis.Point:
+		buf.WriteString(`{"type":"Point","coordinates":`)
+		err = writePosition(buf, *v)
+	case *gogis.LineString:
+		buf.WriteString(`{"type":"LineString","coordinates":`)
+		err = writePositions(buf, v.Points)
+	case *gogis.Polygon:
+		buf.WriteString(`{"type":"Polygon","coordinates":[`)
+		for i, ring := range v.Rings {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err = writePositions(buf, ring); err != nil {
+				break
+			}
+		}
+		buf.WriteByte(']')
+	case *gogis.GeometryCollection:
+		buf.WriteString(`{"type":"GeometryCollection","geometries":[`)
+		for i, child := range v.Geometries {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err = writeGeometry(buf, child); err != nil {
+				break
+			}
+		}
+		buf.WriteByte(']')
+	case *gogis.GeographyPoint:
+		return writeGeometry(buf, (*gogis.Point)(v))
+	case *gogis.GeographyLineString:
+		return writeGeometry(buf, (*gogis.LineString)(v))
+	case *gogis.GeographyPolygon:
+		return writeGeometry(buf, (*gogis.Polygon)(v))
+	case *gogis.GeographyCollection:
+		return writeGeometry(buf, (*gogis.GeometryCollection)(v))
+	default:
+		return fmt.Errorf("geojson: unsupported geometry type %T", g)
+	}
+	if err != nil {
+		return err
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+func writePosition(buf *bytes.Buffer, p gogis.Point) error {
+	for _, v := range []float64{p.Lng, p.Lat} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("geojson: invalid coordinate %v", v)
+		}
+	}
+	var scratch [64]byte
+	b := append(scratch[:0], '[')
+	b = strconv.AppendFloat(b, p.Lng, 'f', -1, 64)
+	b = append(b, ',')
+	b = strconv.AppendFloat(b, p.Lat, 'f', -1, 64)
+	b = append(b, ']')
+	buf.Write(b)
+	return nil
+}
+
+func writePositions(buf *bytes.Buffer, points []gogis.Point) error {
+	buf.WriteByte('[')
+	for i, p := range points {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := writePosition(buf, p); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte(']')
+	return nil
+}
+
+// UnmarshalGeometry decodes a GeoJSON geometry object.
+func UnmarshalGeometry(data []byte) (gogis.Geometry, error) {
+	g, err := unmarshalGeometry(data, 0)
+	if err != nil {
+		return nil, fmt.Errorf("geojson: %w", err)
+	}
+	return g, nil
+}
+
+func unmarshalGeometry(data []byte, depth int) (gogis.Geometry, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("geometry collections nested too deeply")
+	}
+	var raw struct {
+		Type        string            `json:"type"`
+		Coordinates json.RawMessage   `json:"coordinates"`
+		Geometries  []json.RawMessage `json:"geometries"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	switch raw.Type {
+	case "Point":
+		var pos []float64
+		if err := json.Unmarshal(raw.Coordinates, &pos); err != nil {
+			return nil, fmt.Errorf("coordinates of Point: %w", err)
+		}
+		p, err := position(pos)
+		if err != nil {
+			return nil, err
+		}
+		return &p, nil
+	case "LineString":
+		var coords [][]float64
+		if err := json.Unmarshal(raw.Coordinates, &coords); err != nil {
+			return nil, fmt.Errorf("coordinates of LineString: %w", err)
+		}
+		points, err := positions(coords)
+		if err != nil {
+			return nil, err
+		}
+		return &gogis.LineString{Points: points}, nil
+	case "Polygon":
+		var coords [][][]float64
+		if err := json.Unmarshal(raw.Coordinates, &coords); err != nil {
+			return nil, fmt.Errorf("coordinates of Polygon: %w", err)
+		}
+		return polygon(coords)
+	case "MultiPoint":
+		var coords [][]float64
+		if err := json.Unmarshal(raw.Coordinates, &coords); err != nil {
+			return nil, fmt.Errorf("coordinates of MultiPoint: %w", err)
+		}
+		gc := &gogis.GeometryCollection{Geometries: make([]gogis.Geometry, len(coords))}
+		for i, pos := range coords {
+			p, err := position(pos)
+			if err != nil {
+				return nil, err
+			}
+			gc.Geometries[i] = &p
+		}
+		return gc, nil
+	case "MultiLineString":
+		var coords [][][]float64
+		if err := json.Unmarshal(raw.Coordinates, &coords); err != nil {
+			return nil, fmt.Errorf("coordinates of MultiLineString: %w", err)
+		}
+		gc := &gogis.GeometryCollection{Geometries: make([]gogis.Geometry, len(coords))}
+		for i, line := range coords {
+			points, err := positions(line)
+			if err != nil {
+				return nil, err
+			}
+			gc.Geometries[i] = &gogis.LineString{Points: points}
+		}
+		return gc, nil
+	case "MultiPolygon":
+		var coords [][][][]float64
+		if err := json.Unmarshal(raw.Coordinates, &coords); err != nil {
+			return nil, fmt.Errorf("coordinates of MultiPolygon: %w", err)
+		}
+		gc := &gogis.GeometryCollection{Geometries: make([]gogis.Geometry, len(coords))}
+		for i, rings := range coords {
+			p, err := polygon(rings)
+			if err != nil {
+				return nil, err
+			}
+			gc.Geometries[i] = p
+		}
+		return gc, nil
+	case "GeometryCollection":
+		gc := &gogis.GeometryCollection{Geometries: make([]gogis.Geometry, len(raw.Geometries))}
+		for i, child := range raw.Geometries {
+			g, err := unmarshalGeometry(child, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			gc.Geometries[i] = g
+		}
+		return gc, nil
+	default:
+		return nil, fmt.Errorf("unsupported geometry type %q", raw.Type)
+	}
+}
+
+func position(pos []float64) (gogis.Point, error) {
+	if len(pos) < 2 {
+		return gogis.Point{}, fmt.Errorf("position with %d coordinates", len(pos))
+	}
+	return gogis.Point{Lng: pos[0], Lat: pos[1]}, nil
+}
+
+func positions(coords [][]float64) ([]gogis.Point, error) {
+	points := make([]gogis.Point, len(coords))
+	for i, pos := range coords {
+		p, err := position(pos)
+		if err != nil {
+			return nil, err
+		}
+		points[i] = p
+	}
+	return points, nil
+}
+
+func polygon(coords [][][]float64) (*gogis.Polygon, error) {
+	p := &gogis.Polygon{Rings: make([][]gogis.Point, len(coords))}
+	for i, ring := range coords {
+		points, err := positions(ring)
+		if err != nil {
+			return nil, err
+		}
+		p.Rings[i] = points
+	}
+	return p, nil
+}
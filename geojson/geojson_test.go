@@ -0,0 +1,171 @@
+package geojson_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/geojson"
+)
+
+func TestMarshalGeometry(t *testing.T) {
+	ring := []gogis.Point{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 0}, {Lng: 1, Lat: 1}, {Lng: 0, Lat: 0}}
+	tests := []struct {
+		name string
+		geom gogis.Geometry
+		want string
+	}{
+		{
+			name: "point",
+			geom: &gogis.Point{Lng: -73.9857, Lat: 40.7484},
+			want: `{"type":"Point","coordinates":[-73.9857,40.7484]}`,
+		},
+		{
+			name: "linestring",
+			geom: &gogis.LineString{Points: ring[:2]},
+			want: `{"type":"LineString","coordinates":[[0,0],[1,0]]}`,
+		},
+		{
+			name: "polygon",
+			geom: &gogis.Polygon{Rings: [][]gogis.Point{ring}},
+			want: `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`,
+		},
+		{
+			name: "collection",
+			geom: &gogis.GeometryCollection{Geometries: []gogis.Geometry{&ring[1], &gogis.GeometryCollection{}}},
+			want: `{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[1,0]},{"type":"GeometryCollection","geometries":[]}]}`,
+		},
+		{
+			name: "geography",
+			geom: &gogis.GeographyPoint{Lng: 1e-7, Lat: 2},
+			want: `{"type":"Point","coordinates":[0.0000001,2]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := geojson.MarshalGeometry(tt.geom)
+			if err != nil {
+				t.Fatalf("MarshalGeometry() unexpected error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("MarshalGeometry() = %s, want %s", got, tt.want)
+			}
+			if !json.Valid(got) {
+				t.Errorf("MarshalGeometry() produced invalid JSON")
+			}
+		})
+	}
+}
+
+func TestUnmarshalGeometry(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want gogis.Geometry
+	}{
+		{
+			name: "point with altitude",
+			json: `{"type":"Point","coordinates":[1.5,2,100]}`,
+			want: &gogis.Point{Lng: 1.5, Lat: 2},
+		},
+		{
+			name: "multipoint",
+			json: `{"type":"MultiPoint","coordinates":[[1,2],[3,4]]}`,
+			want: &gogis.GeometryCollection{Geometries: []gogis.Geometry{&gogis.Point{Lng: 1, Lat: 2}, &gogis.Point{Lng: 3, Lat: 4}}},
+		},
+		{
+			name: "multilinestring",
+			json: `{"type":"MultiLineString","coordinates":[[[1,2],[3,4]]]}`,
+			want: &gogis.GeometryCollection{Geometries: []gogis.Geometry{
+				&gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}}},
+			}},
+		},
+		{
+			name: "multipolygon",
+			json: `{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]]],"bbox":[0,0,1,1]}`,
+			want: &gogis.GeometryCollection{Geometries: []gogis.Geometry{
+				&gogis.Polygon{Rings: [][]gogis.Point{{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 0}, {Lng: 1, Lat: 1}, {Lng: 0, Lat: 0}}}},
+			}},
+		},
+		{
+			name: "collection",
+			json: `{"type":"GeometryCollection","geometries":[{"type":"LineString","coordinates":[]}]}`,
+			want: &gogis.GeometryCollection{Geometries: []gogis.Geometry{&gogis.LineString{Points: []gogis.Point{}}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := geojson.UnmarshalGeometry([]byte(tt.json))
+			if err != nil {
+				t.Fatalf("UnmarshalGeometry() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UnmarshalGeometry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, tt := range []struct {
+		name string
+		json string
+	}{
+		{name: "invalid json", json: `{"type":`},
+		{name: "unknown type", json: `{"type":"Circle","coordinates":[1,2]}`},
+		{name: "short position", json: `{"type":"Point","coordinates":[1]}`},
+		{name: "wrong nesting", json: `{"type":"Polygon","coordinates":[[1,2]]}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := geojson.UnmarshalGeometry([]byte(tt.json)); err == nil {
+				t.Error("UnmarshalGeometry() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestFeatureJSON(t *testing.T) {
+	f := geojson.Feature{
+		ID:         "a1",
+		Geometry:   &gogis.Point{Lng: 1, Lat: 2},
+		Properties: map[string]any{"name": "Dinagat Islands", "rank": 3.0},
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error = %v", err)
+	}
+	want := `{"type":"Feature","id":"a1","geometry":{"type":"Point","coordinates":[1,2]},"properties":{"name":"Dinagat Islands","rank":3}}`
+	if string(data) != want {
+		t.Errorf("json.Marshal() = %s, want %s", data, want)
+	}
+
+	var got geojson.Feature
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("json.Unmarshal() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(got, f) {
+		t.Errorf("json.Unmarshal() = %+v, want %+v", got, f)
+	}
+
+	t.Run("null geometry", func(t *testing.T) {
+		var f geojson.Feature
+		if err := json.Unmarshal([]byte(`{"type":"Feature","id":7,"geometry":null,"properties":null}`), &f); err != nil {
+			t.Fatalf("json.Unmarshal() unexpected error = %v", err)
+		}
+		if f.Geometry != nil || f.ID != 7.0 || f.Properties != nil {
+			t.Errorf("json.Unmarshal() = %+v", f)
+		}
+		data, _ := json.Marshal(geojson.Feature{})
+		if string(data) != `{"type":"Feature","geometry":null,"properties":null}` {
+			t.Errorf("json.Marshal() = %s", data)
+		}
+	})
+
+	t.Run("not a feature", func(t *testing.T) {
+		var f geojson.Feature
+		if err := json.Unmarshal([]byte(`{"type":"Point","coordinates":[1,2]}`), &f); err == nil {
+			t.Error("json.Unmarshal() expected error, got nil")
+		}
+	})
+}
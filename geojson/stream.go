@@ -0,0 +1,259 @@
+package geojson
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// recordSeparator starts each text of a GeoJSON text sequence.
+const recordSeparator = 0x1E
+
+// Decoder reads features one at a time from a FeatureCollection or a GeoJSON
+// text sequence, holding a single feature in memory.
+type Decoder struct {
+	dec   *json.Decoder
+	seq   bool
+	state decoderState
+	err   error
+}
+
+type decoderState int
+
+const (
+	stateStart    decoderState = iota // before the collection object
+	stateMembers                      // among the members of the collection
+	stateFeatures                     // inside the features array
+)
+
+// NewDecoder returns a decoder reading the features of the FeatureCollection
+// in r. The members of the collection may come in any order; members other
+// than "type" and "features" are skipped.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{dec: json.NewDecoder(r)}
+}
+
+// NewSeqDecoder returns a decoder reading a GeoJSON text sequence (RFC 8142)
+// from r, where each text is preceded by an ASCII record separator. Newline
+// delimited GeoJSON without separators is accepted as well. Texts holding a
+// bare geometry are returned as features without id and properties.
+func NewSeqDecoder(r io.Reader) *Decoder {
+	return &Decoder{dec: json.NewDecoder(separatorReader{r}), seq: true}
+}
+
+// Next returns the next feature. It returns io.EOF after the last feature.
+// Once Next fails, all later calls return the same error.
+func (d *Decoder) Next() (*Feature, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	var f *Feature
+	if d.seq {
+		f, d.err = d.nextText()
+	} else {
+		f, d.err = d.nextFeature()
+	}
+	return f, d.err
+}
+
+func (d *Decoder) nextText() (*Feature, error) {
+	var raw json.RawMessage
+	if err := d.dec.Decode(&raw); err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, fmt.Errorf("geojson: %w", err)
+	}
+	var head struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &head); err != nil {
+		return nil, fmt.Errorf("geojson: %w", err)
+	}
+	if head.Type == "Feature" {
+		f := &Feature{}
+		if err := f.UnmarshalJSON(raw); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	g, err := UnmarshalGeometry(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &Feature{Geometry: g}, nil
+}
+
+func (d *Decoder) nextFeature() (*Feature, error) {
+	if d.state == stateStart {
+		if err := d.expect(json.Delim('{')); err != nil {
+			return nil, err
+		}
+		d.state = stateMembers
+	}
+
+	for {
+		if d.state == stateFeatures {
+			if d.dec.More() {
+				var raw json.RawMessage
+				if err := d.dec.Decode(&raw); err != nil {
+					return nil, jsonError(err)
+				}
+				f := &Feature{}
+				if err := f.UnmarshalJSON(raw); err != nil {
+					return nil, err
+				}
+				return f, nil
+			}
+			if err := d.expect(json.Delim(']')); err != nil {
+				return nil, err
+			}
+			d.state = stateMembers
+		}
+
+		tok, err := d.dec.Token()
+		if err != nil {
+			return nil, jsonError(err)
+		}
+		switch tok {
+		case json.Delim('}'):
+			return nil, io.EOF
+		case "features":
+			if err := d.expect(json.Delim('[')); err != nil {
+				return nil, err
+			}
+			d.state = stateFeatures
+		case "type":
+			var typ string
+			if err := d.dec.Decode(&typ); err != nil {
+				return nil, jsonError(err)
+			}
+			if typ != "FeatureCollection" {
+				return nil, fmt.Errorf("geojson: expected a FeatureCollection, got type %q", typ)
+			}
+		default:
+			var skip json.RawMessage
+			if err := d.dec.Decode(&skip); err != nil {
+				return nil, jsonError(err)
+			}
+		}
+	}
+}
+
+// expect reads the next token and checks that it is delim.
+func (d *Decoder) expect(delim json.Delim) error {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return jsonError(err)
+	}
+	if tok != delim {
+		return fmt.Errorf("geojson: expected %v, got %v", delim, tok)
+	}
+	return nil
+}
+
+// jsonError wraps an error of the JSON decoder, reporting a truncated
+// document as io.ErrUnexpectedEOF.
+func jsonError(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("geojson: %w", err)
+}
+
+// separatorReader turns record separators into whitespace, so that a text
+// sequence reads as a stream of JSON values.
+type separatorReader struct {
+	r io.Reader
+}
+
+func (s separatorReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	for i, c := range p[:n] {
+		if c == recordSeparator {
+			p[i] = ' '
+		}
+	}
+	return n, err
+}
+
+// Encoder writes features one at a time as a FeatureCollection or a GeoJSON
+// text sequence. Writes go directly to the underlying writer, which callers
+// may buffer.
+type Encoder struct {
+	w      io.Writer
+	seq    bool
+	count  int
+	closed bool
+	err    error
+}
+
+// NewEncoder returns an encoder writing a FeatureCollection to w, one feature
+// per line. Close must be called to terminate the collection.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// NewSeqEncoder returns an encoder writing a GeoJSON text sequence (RFC 8142)
+// to w: each feature is preceded by an ASCII record separator and followed by
+// a line feed.
+func NewSeqEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, seq: true}
+}
+
+// Encode writes f. A feature that cannot be encoded is not written and
+// leaves the encoder usable; write errors are returned by all later calls.
+func (e *Encoder) Encode(f Feature) error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.closed {
+		return fmt.Errorf("geojson: encoder is closed")
+	}
+	data, err := f.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	var prefix string
+	switch {
+	case e.seq:
+		prefix = string(rune(recordSeparator))
+	case e.count == 0:
+		prefix = `{"type":"FeatureCollection","features":[` + "\n"
+	default:
+		prefix = ",\n"
+	}
+	buf := make([]byte, 0, len(prefix)+len(data)+1)
+	buf = append(buf, prefix...)
+	buf = append(buf, data...)
+	if e.seq {
+		buf = append(buf, '\n')
+	}
+	e.count++
+	return e.write(buf)
+}
+
+// Close terminates the FeatureCollection. It does not close the underlying
+// writer. For text sequences it only prevents further writes.
+func (e *Encoder) Close() error {
+	if e.err != nil || e.closed {
+		return e.err
+	}
+	e.closed = true
+	switch {
+	case e.seq:
+		return nil
+	case e.count == 0:
+		return e.write([]byte(`{"type":"FeatureCollection","features":[]}` + "\n"))
+	default:
+		return e.write([]byte("\n]}\n"))
+	}
+}
+
+func (e *Encoder) write(b []byte) error {
+	if _, err := e.w.Write(b); err != nil {
+		e.err = fmt.Errorf("geojson: %w", err)
+	}
+	return e.err
+}
@@ -0,0 +1,223 @@
+package geojson_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/geojson"
+)
+
+func decodeAll(t *testing.T, dec *geojson.Decoder) []*geojson.Feature {
+	t.Helper()
+	var features []*geojson.Feature
+	for {
+		f, err := dec.Next()
+		if err == io.EOF {
+			return features
+		}
+		if err != nil {
+			t.Fatalf("Next() unexpected error = %v", err)
+		}
+		features = append(features, f)
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want int
+	}{
+		{
+			name: "features last",
+			json: `{"type":"FeatureCollection","bbox":[0,0,1,1],"features":[
+				{"type":"Feature","geometry":{"type":"Point","coordinates":[0,0]},"properties":{"n":0}},
+				{"type":"Feature","geometry":{"type":"Point","coordinates":[1,1]},"properties":{"n":1}}
+			]}`,
+			want: 2,
+		},
+		{
+			name: "features first",
+			json: `{"features":[{"type":"Feature","geometry":null,"properties":{"n":0}}],"crs":{"type":"name"},"type":"FeatureCollection"}`,
+			want: 1,
+		},
+		{
+			name: "empty",
+			json: `{"type":"FeatureCollection","features":[]}`,
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			features := decodeAll(t, geojson.NewDecoder(strings.NewReader(tt.json)))
+			if len(features) != tt.want {
+				t.Fatalf("decoded %d features, want %d", len(features), tt.want)
+			}
+			for i, f := range features {
+				if f.Properties["n"] != float64(i) {
+					t.Errorf("feature %d properties = %v", i, f.Properties)
+				}
+			}
+		})
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want error
+	}{
+		{name: "not an object", json: `[1,2]`},
+		{name: "wrong type", json: `{"type":"Feature","features":[]}`},
+		{name: "bad feature", json: `{"features":[{"type":"Point","coordinates":[1,2]}]}`},
+		{name: "truncated", json: `{"type":"FeatureCollection","features":[{"type":"Feature"`},
+		{name: "truncated collection", json: `{"type":"FeatureCollection"`, want: io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := geojson.NewDecoder(strings.NewReader(tt.json))
+			var err error
+			for err == nil {
+				_, err = dec.Next()
+			}
+			if err == io.EOF {
+				t.Fatal("Next() expected error, got io.EOF")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Next() error = %v, want %v", err, tt.want)
+			}
+			if _, again := dec.Next(); again != err {
+				t.Errorf("Next() after error = %v, want %v", again, err)
+			}
+		})
+	}
+}
+
+func TestSeqDecoder(t *testing.T) {
+	input := "\x1e{\"type\":\"Feature\",\"id\":1,\"geometry\":{\"type\":\"Point\",\"coordinates\":[1,2]},\"properties\":{}}\n" +
+		"\x1e{\n  \"type\": \"LineString\",\n  \"coordinates\": [[1,2],[3,4]]\n}\n" +
+		"{\"type\":\"Feature\",\"geometry\":null,\"properties\":null}\n"
+	features := decodeAll(t, geojson.NewSeqDecoder(strings.NewReader(input)))
+	want := []*geojson.Feature{
+		{ID: 1.0, Geometry: &gogis.Point{Lng: 1, Lat: 2}, Properties: map[string]any{}},
+		{Geometry: &gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}}}},
+		{},
+	}
+	if !reflect.DeepEqual(features, want) {
+		t.Errorf("decoded %+v, want %+v", features, want)
+	}
+
+	if _, err := geojson.NewSeqDecoder(strings.NewReader("\x1e{\"type\":\"Feat")).Next(); err == nil || err == io.EOF {
+		t.Errorf("Next() of a truncated text = %v, want error", err)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	features := []geojson.Feature{
+		{ID: 1, Geometry: &gogis.Point{Lng: 1, Lat: 2}, Properties: map[string]any{"name": "a"}},
+		{Geometry: &gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}}}},
+	}
+
+	var buf bytes.Buffer
+	enc := geojson.NewEncoder(&buf)
+	for _, f := range features {
+		if err := enc.Encode(f); err != nil {
+			t.Fatalf("Encode() unexpected error = %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close() unexpected error = %v", err)
+	}
+	want := `{"type":"FeatureCollection","features":[
+{"type":"Feature","id":1,"geometry":{"type":"Point","coordinates":[1,2]},"properties":{"name":"a"}},
+{"type":"Feature","geometry":{"type":"LineString","coordinates":[[1,2],[3,4]]},"properties":null}
+]}
+`
+	if buf.String() != want {
+		t.Errorf("encoded %s, want %s", buf.String(), want)
+	}
+	if got := decodeAll(t, geojson.NewDecoder(&buf)); len(got) != 2 {
+		t.Errorf("decoded %d features, want 2", len(got))
+	}
+	if err := enc.Encode(features[0]); err == nil {
+		t.Error("Encode() after Close() expected error, got nil")
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := geojson.NewEncoder(&buf).Close(); err != nil {
+			t.Fatalf("Close() unexpected error = %v", err)
+		}
+		if buf.String() != `{"type":"FeatureCollection","features":[]}`+"\n" {
+			t.Errorf("encoded %s", buf.String())
+		}
+	})
+
+	t.Run("invalid feature", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := geojson.NewEncoder(&buf)
+		if err := enc.Encode(geojson.Feature{Properties: map[string]any{"f": func() {}}}); err == nil {
+			t.Fatal("Encode() expected error, got nil")
+		}
+		if err := enc.Encode(features[1]); err != nil {
+			t.Fatalf("Encode() after invalid feature unexpected error = %v", err)
+		}
+		_ = enc.Close()
+		if got := decodeAll(t, geojson.NewDecoder(&buf)); len(got) != 1 {
+			t.Errorf("decoded %d features, want 1", len(got))
+		}
+	})
+}
+
+func TestSeqEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc := geojson.NewSeqEncoder(&buf)
+	for i := 0; i < 3; i++ {
+		if err := enc.Encode(geojson.Feature{ID: i, Geometry: &gogis.Point{Lng: float64(i), Lat: 0}}); err != nil {
+			t.Fatalf("Encode() unexpected error = %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close() unexpected error = %v", err)
+	}
+	lines := strings.SplitAfter(buf.String(), "\n")
+	if len(lines) != 4 || lines[3] != "" {
+		t.Fatalf("encoded %q, want 3 lines", buf.String())
+	}
+	for i, line := range lines[:3] {
+		want := fmt.Sprintf("\x1e{\"type\":\"Feature\",\"id\":%d,\"geometry\":{\"type\":\"Point\",\"coordinates\":[%d,0]},\"properties\":null}\n", i, i)
+		if line != want {
+			t.Errorf("line %d = %q, want %q", i, line, want)
+		}
+	}
+	if got := decodeAll(t, geojson.NewSeqDecoder(&buf)); len(got) != 3 {
+		t.Errorf("decoded %d features, want 3", len(got))
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestEncoderWriteError(t *testing.T) {
+	enc := geojson.NewEncoder(failingWriter{})
+	f := geojson.Feature{Geometry: &gogis.Point{}}
+	if err := enc.Encode(f); err == nil {
+		t.Fatal("Encode() expected error, got nil")
+	}
+	if err := enc.Encode(f); err == nil {
+		t.Error("Encode() after write error expected error, got nil")
+	}
+	if err := enc.Close(); err == nil {
+		t.Error("Close() after write error expected error, got nil")
+	}
+}
@@ -53,6 +53,17 @@ func (gc *GeometryCollection) Scan(val any) error {
 	if val == nil {
 		return nil
 	}
+	if g, ok, err := scanBinary(val, false); ok {
+		if err != nil {
+			return err
+		}
+		v, ok := g.(*GeometryCollection)
+		if !ok {
+			return fmt.Errorf("cannot scan %T into GeometryCollection", g)
+		}
+		*gc = *v
+		return nil
+	}
 
 	var decode string
 	switch v := val.(type) {
@@ -0,0 +1,102 @@
+package gogis
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"gorm.io/gorm/clause"
+)
+
+// GeoPackage binary header flags.
+const (
+	gpkgLittleEndian = 0x01
+	gpkgEnvelopeXY   = 1 << 1
+	gpkgEmpty        = 1 << 4
+	gpkgExtended     = 1 << 5
+)
+
+// GeoPackageDialect writes geometries as GeoPackage binary with SRID 4326,
+// for GeoPackage files opened through a GORM sqlite driver. Register it for
+// the sqlite dialector to use it instead of SpatiaLiteDialect:
+//
+//	gogis.RegisterDialect("sqlite", gogis.GeoPackageDialect{})
+type GeoPackageDialect struct{}
+
+// GeometryExpr returns a parameter holding the GeoPackage binary of g.
+func (GeoPackageDialect) GeometryExpr(g Geometry) (clause.Expr, error) {
+	b, err := EncodeGeoPackage(g, SRIDWGS84)
+	if err != nil {
+		return clause.Expr{}, err
+	}
+	return clause.Expr{SQL: "?", Vars: []interface{}{wkbValue(b)}}, nil
+}
+
+// EncodeGeoPackage encodes g in the GeoPackage binary format: the "GP"
+// header with the SRS ID and the XY envelope of g, followed by its WKB.
+// Geometries without coordinates are flagged as empty and have no envelope.
+func EncodeGeoPackage(g Geometry, srid SRID) ([]byte, error) {
+	wkb, err := EncodeEWKB(g, 0)
+	if err != nil {
+		return nil, err
+	}
+	e, ok := envelopeOf(g)
+
+	var buf bytes.Buffer
+	buf.WriteString("GP")
+	buf.WriteByte(0) // version 1
+	if ok {
+		buf.WriteByte(gpkgLittleEndian | gpkgEnvelopeXY)
+	} else {
+		buf.WriteByte(gpkgLittleEndian | gpkgEmpty)
+	}
+	writeUint32(&buf, uint32(srid))
+	if ok {
+		writeFloat64(&buf, e.minX)
+		writeFloat64(&buf, e.maxX)
+		writeFloat64(&buf, e.minY)
+		writeFloat64(&buf, e.maxY)
+	}
+	buf.Write(wkb)
+	return buf.Bytes(), nil
+}
+
+// DecodeGeoPackage decodes a GeoPackage binary geometry and returns the
+// geometry and its SRS ID. The envelope is skipped; the geometry is decoded
+// as by DecodeEWKB. Extended GeoPackage geometries are not supported.
+func DecodeGeoPackage(b []byte) (Geometry, SRID, error) {
+	if len(b) < 8 || b[0] != 'G' || b[1] != 'P' {
+		return nil, 0, fmt.Errorf("gogis: decoding GeoPackage geometry: invalid header")
+	}
+	if b[2] != 0 {
+		return nil, 0, fmt.Errorf("gogis: decoding GeoPackage geometry: unsupported version %d", b[2])
+	}
+	flags := b[3]
+	if flags&gpkgExtended != 0 {
+		return nil, 0, fmt.Errorf("gogis: decoding GeoPackage geometry: extended geometries are not supported")
+	}
+	var order binary.ByteOrder = binary.BigEndian
+	if flags&gpkgLittleEndian != 0 {
+		order = binary.LittleEndian
+	}
+	var size int
+	switch (flags >> 1) & 0x07 {
+	case 0:
+	case 1:
+		size = 32
+	case 2, 3:
+		size = 48
+	case 4:
+		size = 64
+	default:
+		return nil, 0, fmt.Errorf("gogis: decoding GeoPackage geometry: invalid envelope type %d", (flags>>1)&0x07)
+	}
+	if len(b) < 8+size {
+		return nil, 0, fmt.Errorf("gogis: decoding GeoPackage geometry: %d bytes is too short", len(b))
+	}
+	g, _, err := DecodeEWKB(b[8+size:])
+	if err != nil {
+		return nil, 0, err
+	}
+	return g, SRID(order.Uint32(b[4:])), nil
+}
@@ -0,0 +1,125 @@
+package gogis_test
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/restayway/gogis"
+)
+
+func TestEncodeGeoPackage(t *testing.T) {
+	tests := []struct {
+		name string
+		geom gogis.Geometry
+		want string
+	}{
+		{
+			name: "point",
+			geom: &gogis.Point{Lng: 1, Lat: 2},
+			want: "4750000" + "3e6100000" +
+				"000000000000f03f000000000000f03f00000000000000400000000000000040" +
+				"0101000000000000000000f03f0000000000000040",
+		},
+		{
+			name: "empty",
+			geom: &gogis.GeometryCollection{},
+			want: "47500011e6100000010700000000000000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := gogis.EncodeGeoPackage(tt.geom, gogis.SRIDWGS84)
+			if err != nil {
+				t.Fatalf("EncodeGeoPackage() unexpected error = %v", err)
+			}
+			if got := hex.EncodeToString(b); got != tt.want {
+				t.Errorf("EncodeGeoPackage() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeGeoPackage(t *testing.T) {
+	ring := []gogis.Point{{Lng: 0, Lat: 0}, {Lng: 4, Lat: 0}, {Lng: 4, Lat: 3}, {Lng: 0, Lat: 0}}
+	tests := []struct {
+		name string
+		hex  string
+		want gogis.Geometry
+	}{
+		{
+			name: "big-endian header without envelope",
+			hex:  "47500000000010E6" + "0101000000000000000000F03F0000000000000040",
+			want: &gogis.Point{Lng: 1, Lat: 2},
+		},
+		{
+			name: "xyz envelope",
+			hex: "47500005E6100000" +
+				"000000000000F03F000000000000F03F0000000000000040000000000000004000000000000008400000000000000840" +
+				"01E9030000000000000000F03F00000000000000400000000000000840",
+			want: &gogis.Point{Lng: 1, Lat: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := hex.DecodeString(tt.hex)
+			g, srid, err := gogis.DecodeGeoPackage(b)
+			if err != nil {
+				t.Fatalf("DecodeGeoPackage() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(g, tt.want) || srid != gogis.SRIDWGS84 {
+				t.Errorf("DecodeGeoPackage() = %v, %d, want %v", g, srid, tt.want)
+			}
+		})
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		want := &gogis.Polygon{Rings: [][]gogis.Point{ring}}
+		b, _ := gogis.EncodeGeoPackage(want, 3857)
+		g, srid, err := gogis.DecodeGeoPackage(b)
+		if err != nil {
+			t.Fatalf("DecodeGeoPackage() unexpected error = %v", err)
+		}
+		if !reflect.DeepEqual(g, want) || srid != 3857 {
+			t.Errorf("DecodeGeoPackage() = %v, %d, want %v", g, srid, want)
+		}
+	})
+
+	for _, tt := range []struct {
+		name string
+		hex  string
+	}{
+		{name: "bad magic", hex: "4750"},
+		{name: "version", hex: "47500100E6100000"},
+		{name: "extended", hex: "47500021E6100000"},
+		{name: "envelope type", hex: "4750000BE6100000"},
+		{name: "truncated envelope", hex: "47500003E6100000000000000000F03F"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := hex.DecodeString(tt.hex)
+			if _, _, err := gogis.DecodeGeoPackage(b); err == nil {
+				t.Error("DecodeGeoPackage() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestScanSQLite(t *testing.T) {
+	want := &gogis.Polygon{Rings: [][]gogis.Point{{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 0}, {Lng: 1, Lat: 1}, {Lng: 0, Lat: 0}}}}
+	spatialite, _ := gogis.EncodeSpatiaLite(want, gogis.SRIDWGS84)
+	gpkg, _ := gogis.EncodeGeoPackage(want, gogis.SRIDWGS84)
+
+	for name, b := range map[string][]byte{"spatialite": spatialite, "geopackage": gpkg} {
+		t.Run(name, func(t *testing.T) {
+			var p gogis.Polygon
+			if err := p.Scan(b); err != nil {
+				t.Fatalf("Scan() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(&p, want) {
+				t.Errorf("Scan() = %v, want %v", &p, want)
+			}
+		})
+	}
+}
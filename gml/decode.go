@@ -0,0 +1,331 @@
+package gml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/restayway/gogis"
+)
+
+// decode decodes the geometry element start and returns it with its SRID.
+func decode(d *xml.Decoder, start xml.StartElement) (gogis.Geometry, gogis.SRID, error) {
+	ctx, err := crs{dim: 2}.with(start)
+	if err != nil {
+		return nil, 0, fmt.Errorf("gml: %w", err)
+	}
+	g, err := decodeGeometry(d, start, ctx, 0)
+	if err != nil {
+		return nil, 0, fmt.Errorf("gml: %w", err)
+	}
+	return g, ctx.srid, nil
+}
+
+// with returns the context of element start, updated from its srsName and
+// srsDimension attributes.
+func (c crs) with(start xml.StartElement) (crs, error) {
+	if name := attr(start, "srsName"); name != "" {
+		srid, latLon, err := parseSRSName(name)
+		if err != nil {
+			return c, err
+		}
+		c.srid, c.latLon = srid, latLon
+	}
+	if s := attr(start, "srsDimension"); s != "" {
+		dim, err := strconv.Atoi(s)
+		if err != nil || dim < 2 || dim > 4 {
+			return c, fmt.Errorf("invalid srsDimension %q", s)
+		}
+		c.dim = dim
+	}
+	return c, nil
+}
+
+func decodeGeometry(d *xml.Decoder, start xml.StartElement, ctx crs, depth int) (gogis.Geometry, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("geometries nested too deeply")
+	}
+	ctx, err := ctx.with(start)
+	if err != nil {
+		return nil, err
+	}
+
+	switch start.Name.Local {
+	case "Point":
+		points, err := positions(d, ctx, depth)
+		if err != nil {
+			return nil, err
+		}
+		if len(points) != 1 {
+			return nil, fmt.Errorf("Point with %d positions", len(points))
+		}
+		return &points[0], nil
+	case "LineString", "LinearRing":
+		points, err := positions(d, ctx, depth)
+		if err != nil {
+			return nil, err
+		}
+		return &gogis.LineString{Points: points}, nil
+	case "Curve":
+		var points []gogis.Point
+		err := children(d, func(child xml.StartElement) error {
+			if child.Name.Local != "segments" {
+				return d.Skip()
+			}
+			return children(d, func(seg xml.StartElement) error {
+				if seg.Name.Local != "LineStringSegment" {
+					return fmt.Errorf("unsupported curve segment %s", seg.Name.Local)
+				}
+				segCtx, err := ctx.with(seg)
+				if err != nil {
+					return err
+				}
+				next, err := positions(d, segCtx, depth)
+				if err != nil {
+					return err
+				}
+				// Segments share their end points.
+				if len(points) > 0 && len(next) > 0 && points[len(points)-1] == next[0] {
+					next = next[1:]
+				}
+				points = append(points, next...)
+				return nil
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+		if points == nil {
+			points = []gogis.Point{}
+		}
+		return &gogis.LineString{Points: points}, nil
+	case "Polygon", "PolygonPatch":
+		return polygon(d, ctx, depth)
+	case "Surface":
+		var polygons []gogis.Geometry
+		err := children(d, func(child xml.StartElement) error {
+			if child.Name.Local != "patches" {
+				return d.Skip()
+			}
+			return children(d, func(patch xml.StartElement) error {
+				if patch.Name.Local != "PolygonPatch" {
+					return fmt.Errorf("unsupported surface patch %s", patch.Name.Local)
+				}
+				p, err := decodeGeometry(d, patch, ctx, depth+1)
+				polygons = append(polygons, p)
+				return err
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(polygons) == 1 {
+			return polygons[0], nil
+		}
+		return &gogis.GeometryCollection{Geometries: polygons}, nil
+	case "MultiSurface", "MultiCurve", "MultiPoint", "MultiGeometry", "MultiPolygon", "MultiLineString":
+		gc := &gogis.GeometryCollection{Geometries: []gogis.Geometry{}}
+		err := children(d, func(member xml.StartElement) error {
+			// Members such as surfaceMember hold one geometry, and
+			// arrays such as surfaceMembers any number of them.
+			if !strings.HasSuffix(member.Name.Local, "Member") && !strings.HasSuffix(member.Name.Local, "Members") {
+				return d.Skip()
+			}
+			return children(d, func(child xml.StartElement) error {
+				g, err := decodeGeometry(d, child, ctx, depth+1)
+				if err != nil {
+					return err
+				}
+				gc.Geometries = append(gc.Geometries, g)
+				return nil
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+		return gc, nil
+	}
+	return nil, fmt.Errorf("unsupported geometry %s", start.Name.Local)
+}
+
+// polygon decodes the boundaries of a Polygon or PolygonPatch.
+func polygon(d *xml.Decoder, ctx crs, depth int) (*gogis.Polygon, error) {
+	var exterior []gogis.Point
+	var interiors [][]gogis.Point
+	hasExterior := false
+	err := children(d, func(child xml.StartElement) error {
+		local := child.Name.Local
+		switch local {
+		case "exterior", "interior", "outerBoundaryIs", "innerBoundaryIs":
+		default:
+			return d.Skip()
+		}
+		return children(d, func(ring xml.StartElement) error {
+			if ring.Name.Local != "LinearRing" {
+				return fmt.Errorf("unsupported ring %s", ring.Name.Local)
+			}
+			ringCtx, err := ctx.with(ring)
+			if err != nil {
+				return err
+			}
+			points, err := positions(d, ringCtx, depth)
+			if err != nil {
+				return err
+			}
+			if local == "exterior" || local == "outerBoundaryIs" {
+				exterior, hasExterior = points, true
+			} else {
+				interiors = append(interiors, points)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !hasExterior {
+		return nil, fmt.Errorf("Polygon without exterior")
+	}
+	return &gogis.Polygon{Rings: append([][]gogis.Point{exterior}, interiors...)}, nil
+}
+
+// positions reads the coordinates of the current element, given as a
+// posList, a sequence of pos or pointProperty elements, or GML 2
+// coordinates.
+func positions(d *xml.Decoder, ctx crs, depth int) ([]gogis.Point, error) {
+	points := []gogis.Point{}
+	err := children(d, func(child xml.StartElement) error {
+		switch child.Name.Local {
+		case "pos":
+			values, err := floats(d, child)
+			if err != nil {
+				return err
+			}
+			if len(values) < 2 {
+				return fmt.Errorf("pos with %d values", len(values))
+			}
+			points = append(points, ctx.point(values[0], values[1]))
+		case "posList":
+			listCtx, err := ctx.with(child)
+			if err != nil {
+				return err
+			}
+			values, err := floats(d, child)
+			if err != nil {
+				return err
+			}
+			if len(values)%listCtx.dim != 0 {
+				return fmt.Errorf("posList of %d values in dimension %d", len(values), listCtx.dim)
+			}
+			for i := 0; i < len(values); i += listCtx.dim {
+				points = append(points, ctx.point(values[i], values[i+1]))
+			}
+		case "coordinates":
+			s, err := text(d, child)
+			if err != nil {
+				return err
+			}
+			for _, tuple := range strings.Fields(s) {
+				values := strings.Split(tuple, ",")
+				if len(values) < 2 {
+					return fmt.Errorf("invalid coordinate tuple %q", tuple)
+				}
+				a, errA := strconv.ParseFloat(values[0], 64)
+				b, errB := strconv.ParseFloat(values[1], 64)
+				if errA != nil || errB != nil {
+					return fmt.Errorf("invalid coordinate tuple %q", tuple)
+				}
+				points = append(points, ctx.point(a, b))
+			}
+		case "pointProperty", "pointRep":
+			return children(d, func(elem xml.StartElement) error {
+				g, err := decodeGeometry(d, elem, ctx, depth+1)
+				if err != nil {
+					return err
+				}
+				p, ok := g.(*gogis.Point)
+				if !ok {
+					return fmt.Errorf("%s holds a %s", child.Name.Local, elem.Name.Local)
+				}
+				points = append(points, *p)
+				return nil
+			})
+		default:
+			return d.Skip()
+		}
+		return nil
+	})
+	return points, err
+}
+
+// point returns the point of the first two coordinates of a position, in
+// the axis order of the context.
+func (c crs) point(a, b float64) gogis.Point {
+	if c.latLon {
+		return gogis.Point{Lng: b, Lat: a}
+	}
+	return gogis.Point{Lng: a, Lat: b}
+}
+
+func floats(d *xml.Decoder, start xml.StartElement) ([]float64, error) {
+	s, err := text(d, start)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(s)
+	values := make([]float64, len(fields))
+	for i, f := range fields {
+		if values[i], err = strconv.ParseFloat(f, 64); err != nil {
+			return nil, fmt.Errorf("invalid coordinate %q", f)
+		}
+	}
+	return values, nil
+}
+
+// children calls fn for each child element of the current element, up to
+// its end. fn must consume the child up to its end element.
+func children(d *xml.Decoder, fn func(xml.StartElement) error) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return eofError(err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if err := fn(t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// text returns the character data of element start.
+func text(d *xml.Decoder, start xml.StartElement) (string, error) {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return "", err
+	}
+	return s, nil
+}
+
+func attr(start xml.StartElement, name string) string {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// eofError turns the end of the input inside an element into
+// io.ErrUnexpectedEOF.
+func eofError(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,171 @@
+package gml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/restayway/gogis"
+)
+
+// encoder writes geometries as tokens with the gml prefix.
+type encoder struct {
+	e      *xml.Encoder
+	latLon bool
+}
+
+// encode writes g as the top geometry element, carrying the namespace
+// declaration, srsName and gml:id.
+func encode(e *xml.Encoder, g gogis.Geometry, srid gogis.SRID, id string) error {
+	attrs := []xml.Attr{{Name: xml.Name{Local: "xmlns:gml"}, Value: Namespace}}
+	if id != "" {
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "gml:id"}, Value: id})
+	}
+	enc := &encoder{e: e}
+	if srid != 0 {
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "srsName"}, Value: SRSName(srid)})
+		enc.latLon = northFirst(srid)
+	}
+	if err := enc.geometry(g, attrs); err != nil {
+		return fmt.Errorf("gml: %w", err)
+	}
+	return nil
+}
+
+func (enc *encoder) geometry(g gogis.Geometry, attrs []xml.Attr) error {
+	switch v := plain(g).(type) {
+	case *gogis.Point:
+		return enc.element("Point", attrs, func() error {
+			return enc.coordinates("pos", []gogis.Point{*v})
+		})
+	case *gogis.LineString:
+		return enc.element("LineString", attrs, func() error {
+			return enc.coordinates("posList", v.Points)
+		})
+	case *gogis.Polygon:
+		if len(v.Rings) == 0 {
+			return fmt.Errorf("empty Polygon")
+		}
+		return enc.element("Polygon", attrs, func() error {
+			for i, ring := range v.Rings {
+				boundary := "interior"
+				if i == 0 {
+					boundary = "exterior"
+				}
+				err := enc.element(boundary, nil, func() error {
+					return enc.element("LinearRing", nil, func() error {
+						return enc.coordinates("posList", ring)
+					})
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	case *gogis.GeometryCollection:
+		multi, member := "MultiGeometry", "geometryMember"
+		switch collectionType(v) {
+		case gogis.GeometryTypePoint:
+			multi, member = "MultiPoint", "pointMember"
+		case gogis.GeometryTypeLineString:
+			multi, member = "MultiCurve", "curveMember"
+		case gogis.GeometryTypePolygon:
+			multi, member = "MultiSurface", "surfaceMember"
+		}
+		return enc.element(multi, attrs, func() error {
+			for _, child := range v.Geometries {
+				err := enc.element(member, nil, func() error {
+					return enc.geometry(child, nil)
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return fmt.Errorf("unsupported geometry type %T", g)
+}
+
+// plain returns the geometry type of the geography variants.
+func plain(g gogis.Geometry) gogis.Geometry {
+	switch v := g.(type) {
+	case *gogis.GeographyPoint:
+		return (*gogis.Point)(v)
+	case *gogis.GeographyLineString:
+		return (*gogis.LineString)(v)
+	case *gogis.GeographyPolygon:
+		return (*gogis.Polygon)(v)
+	case *gogis.GeographyCollection:
+		return (*gogis.GeometryCollection)(v)
+	}
+	return g
+}
+
+// collectionType returns the type shared by all members of a non-empty
+// collection, or GeometryTypeGeometryCollection when the members differ.
+func collectionType(gc *gogis.GeometryCollection) gogis.GeometryType {
+	typ := gogis.GeometryTypeGeometryCollection
+	for i, child := range gc.Geometries {
+		var t gogis.GeometryType
+		switch plain(child).(type) {
+		case *gogis.Point:
+			t = gogis.GeometryTypePoint
+		case *gogis.LineString:
+			t = gogis.GeometryTypeLineString
+		case *gogis.Polygon:
+			t = gogis.GeometryTypePolygon
+		default:
+			return gogis.GeometryTypeGeometryCollection
+		}
+		if i > 0 && t != typ {
+			return gogis.GeometryTypeGeometryCollection
+		}
+		typ = t
+	}
+	return typ
+}
+
+// element writes an element of the gml namespace around the content
+// written by fn.
+func (enc *encoder) element(local string, attrs []xml.Attr, fn func() error) error {
+	start := xml.StartElement{Name: xml.Name{Local: "gml:" + local}, Attr: attrs}
+	if err := enc.e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	return enc.e.EncodeToken(start.End())
+}
+
+// coordinates writes the points as a pos or posList element.
+func (enc *encoder) coordinates(local string, points []gogis.Point) error {
+	var b []byte
+	for i, p := range points {
+		for _, v := range []float64{p.Lng, p.Lat} {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("invalid coordinate %v", v)
+			}
+		}
+		first, second := p.Lng, p.Lat
+		if enc.latLon {
+			first, second = p.Lat, p.Lng
+		}
+		if i > 0 {
+			b = append(b, ' ')
+		}
+		b = strconv.AppendFloat(b, first, 'f', -1, 64)
+		b = append(b, ' ')
+		b = strconv.AppendFloat(b, second, 'f', -1, 64)
+	}
+	var attrs []xml.Attr
+	if local == "posList" {
+		attrs = []xml.Attr{{Name: xml.Name{Local: "srsDimension"}, Value: "2"}}
+	}
+	return enc.element(local, attrs, func() error {
+		return enc.e.EncodeToken(xml.CharData(b))
+	})
+}
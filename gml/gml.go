@@ -0,0 +1,217 @@
+// Package gml encodes and decodes gogis geometries as GML 3.2, the geometry
+// encoding of WFS services and INSPIRE datasets.
+//
+// Marshal and Unmarshal convert single geometry elements. Property is a
+// geometry property for structs decoded with encoding/xml, such as the
+// features of a WFS response:
+//
+//	type Parcel struct {
+//	    ID       string       `xml:"id,attr"`
+//	    Label    string       `xml:"label"`
+//	    Geometry gml.Property `xml:"geometry"`
+//	}
+//
+// Geometries map to gogis types as follows. Z values are dropped.
+//
+//	Point                         *gogis.Point
+//	LineString, Curve             *gogis.LineString
+//	LinearRing                    *gogis.LineString
+//	Polygon, Surface              *gogis.Polygon
+//	MultiSurface, MultiCurve,
+//	MultiPoint, MultiGeometry     *gogis.GeometryCollection
+//
+// # Axis Order
+//
+// The srsName of a geometry gives both its SRID and its axis order. Systems
+// named by URI or URN, such as "http://www.opengis.net/def/crs/EPSG/0/4326"
+// and "urn:ogc:def:crs:EPSG::4326", use the EPSG axis order, which is
+// latitude before longitude for geographic systems, and northing before
+// easting for projected systems defined that way, such as the ETRS89-LAEA
+// grid EPSG:3035 used by INSPIRE. The older "EPSG:4326"
+// form and CRS84 use longitude first. Coordinates are swapped as needed so
+// that Lng and Lat of decoded points always hold longitude and latitude, or
+// easting and northing. Marshal writes URI srsNames.
+package gml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/proj"
+)
+
+// Namespace is the XML namespace of GML 3.2.
+const Namespace = "http://www.opengis.net/gml/3.2"
+
+// maxDepth limits the nesting of geometry elements.
+const maxDepth = 32
+
+// Marshal encodes g as a GML 3.2 geometry element. A non-zero srid is
+// written as the srsName, and sets the axis order of the coordinates.
+// Collections of polygons are written as a MultiSurface, collections of
+// lines as a MultiCurve, collections of points as a MultiPoint and other
+// collections as a MultiGeometry.
+func Marshal(g gogis.Geometry, srid gogis.SRID) ([]byte, error) {
+	var buf bytes.Buffer
+	e := xml.NewEncoder(&buf)
+	if err := encode(e, g, srid, ""); err != nil {
+		return nil, err
+	}
+	if err := e.Flush(); err != nil {
+		return nil, fmt.Errorf("gml: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes a GML geometry element and returns it with the SRID of
+// its srsName, or 0 when it has none. GML 3.1 and the GML 2 elements
+// coordinates, outerBoundaryIs and innerBoundaryIs are accepted as well.
+func Unmarshal(data []byte) (gogis.Geometry, gogis.SRID, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, 0, fmt.Errorf("gml: %w", eofError(err))
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return decode(d, start)
+		}
+	}
+}
+
+// Property is a GML geometry property: an element, such as the geometry
+// member of a feature, holding a single geometry element.
+type Property struct {
+	Geometry gogis.Geometry // nil for empty properties
+	SRID     gogis.SRID     // SRID of the srsName, 0 when there is none
+	ID       string         // gml:id of the geometry element, optional
+}
+
+// UnmarshalXML implements xml.Unmarshaler, decoding the first geometry
+// element inside the property element.
+func (p *Property) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	*p = Property{}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return fmt.Errorf("gml: %w", eofError(err))
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if p.Geometry != nil {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			p.ID = attr(t, "id")
+			if p.Geometry, p.SRID, err = decode(d, t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// MarshalXML implements xml.Marshaler, writing the geometry inside the
+// property element. Empty properties are written without content.
+func (p Property) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if p.Geometry != nil {
+		if err := encode(e, p.Geometry, p.SRID, p.ID); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// crs is the coordinate system context of an element, inherited by its
+// children.
+type crs struct {
+	srid   gogis.SRID
+	latLon bool // coordinates are stored latitude first
+	dim    int  // srsDimension, 2 unless given
+}
+
+// SRSName returns the srsName Marshal writes for an SRID, such as
+// "http://www.opengis.net/def/crs/EPSG/0/4326".
+func SRSName(srid gogis.SRID) string {
+	return "http://www.opengis.net/def/crs/EPSG/0/" + strconv.Itoa(int(srid))
+}
+
+// ParseSRSName returns the SRID of an srsName, and whether the coordinates
+// of geometries using it have latitude, or northing, first. It reports an
+// error for srsNames it cannot identify.
+func ParseSRSName(name string) (srid gogis.SRID, latLon bool, err error) {
+	if srid, latLon, err = parseSRSName(name); err != nil {
+		return 0, false, fmt.Errorf("gml: %w", err)
+	}
+	return srid, latLon, nil
+}
+
+func parseSRSName(name string) (gogis.SRID, bool, error) {
+	s := strings.TrimSpace(name)
+	if strings.HasSuffix(s, "CRS84") && (strings.HasPrefix(s, "urn:ogc:def:crs:OGC:") || strings.HasPrefix(s, "http://www.opengis.net/def/crs/OGC/")) {
+		return gogis.SRIDWGS84, false, nil
+	}
+
+	var code string
+	authorityOrder := true
+	switch {
+	case strings.HasPrefix(s, "EPSG:"):
+		code, authorityOrder = s[len("EPSG:"):], false
+	case strings.HasPrefix(s, "http://www.opengis.net/gml/srs/epsg.xml#"):
+		code, authorityOrder = s[len("http://www.opengis.net/gml/srs/epsg.xml#"):], false
+	case strings.HasPrefix(s, "urn:ogc:def:crs:EPSG:"), strings.HasPrefix(s, "urn:x-ogc:def:crs:EPSG:"):
+		// urn:ogc:def:crs:EPSG:[version]:code
+		code = s[strings.LastIndexByte(s, ':')+1:]
+	case strings.HasPrefix(s, "http://www.opengis.net/def/crs/EPSG/"):
+		// http://www.opengis.net/def/crs/EPSG/version/code
+		code = s[strings.LastIndexByte(s, '/')+1:]
+	default:
+		return 0, false, fmt.Errorf("unsupported srsName %q", name)
+	}
+	n, err := strconv.ParseUint(code, 10, 32)
+	if err != nil || n == 0 {
+		return 0, false, fmt.Errorf("unsupported srsName %q", name)
+	}
+	srid := gogis.SRID(n)
+	return srid, authorityOrder && northFirst(srid), nil
+}
+
+// northFirst reports whether the EPSG axis order of srid is latitude or
+// northing first: geographic systems, and the projected systems of
+// northingFirst.
+func northFirst(srid gogis.SRID) bool {
+	if c, err := proj.Lookup(srid); err == nil && c.Geographic() {
+		return true
+	}
+	for _, r := range northingFirst {
+		if srid >= r[0] && srid <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// northingFirst lists the ranges of projected EPSG codes whose axis order
+// is northing, easting.
+var northingFirst = [][2]gogis.SRID{
+	{2176, 2180},   // ETRS89 / Poland CS2000 zones and CS92
+	{3006, 3018},   // SWEREF99 TM and local zones
+	{3034, 3035},   // ETRS89-LCC and ETRS89-LAEA Europe
+	{3040, 3051},   // ETRS89 / ETRS-TM26 to TM39
+	{3059, 3059},   // LKS92 / Latvia TM
+	{3301, 3301},   // Estonian Coordinate System of 1997
+	{3346, 3346},   // LKS94 / Lithuania TM
+	{3844, 3844},   // Pulkovo 1942(58) / Stereo70
+	{28402, 28432}, // Pulkovo 1942 / Gauss-Kruger zones
+	{31466, 31469}, // DHDN / 3-degree Gauss-Kruger zones 2 to 5
+}
@@ -0,0 +1,272 @@
+package gml_test
+
+import (
+	"encoding/xml"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/gml"
+)
+
+const ns = `xmlns:gml="http://www.opengis.net/gml/3.2"`
+
+func TestMarshal(t *testing.T) {
+	ring := []gogis.Point{{Lng: 0, Lat: 0}, {Lng: 10, Lat: 0}, {Lng: 10, Lat: 10}, {Lng: 0, Lat: 0}}
+	tests := []struct {
+		name string
+		geom gogis.Geometry
+		srid gogis.SRID
+		want string
+	}{
+		{
+			name: "point lat lon",
+			geom: &gogis.Point{Lng: 13.4, Lat: 52.5},
+			srid: 4326,
+			want: `<gml:Point ` + ns + ` srsName="http://www.opengis.net/def/crs/EPSG/0/4326"><gml:pos>52.5 13.4</gml:pos></gml:Point>`,
+		},
+		{
+			name: "linestring projected",
+			geom: &gogis.LineString{Points: []gogis.Point{{Lng: 500000, Lat: 5800000}, {Lng: 500010.5, Lat: 5800020}}},
+			srid: 32633,
+			want: `<gml:LineString ` + ns + ` srsName="http://www.opengis.net/def/crs/EPSG/0/32633">` +
+				`<gml:posList srsDimension="2">500000 5800000 500010.5 5800020</gml:posList></gml:LineString>`,
+		},
+		{
+			name: "point northing easting",
+			geom: &gogis.Point{Lng: 4321000, Lat: 3210000},
+			srid: 3035,
+			want: `<gml:Point ` + ns + ` srsName="http://www.opengis.net/def/crs/EPSG/0/3035"><gml:pos>3210000 4321000</gml:pos></gml:Point>`,
+		},
+		{
+			name: "polygon without srid",
+			geom: &gogis.Polygon{Rings: [][]gogis.Point{ring, ring}},
+			want: `<gml:Polygon ` + ns + `><gml:exterior><gml:LinearRing><gml:posList srsDimension="2">0 0 10 0 10 10 0 0</gml:posList></gml:LinearRing></gml:exterior>` +
+				`<gml:interior><gml:LinearRing><gml:posList srsDimension="2">0 0 10 0 10 10 0 0</gml:posList></gml:LinearRing></gml:interior></gml:Polygon>`,
+		},
+		{
+			name: "multisurface",
+			geom: &gogis.GeographyCollection{Geometries: []gogis.Geometry{&gogis.GeographyPolygon{Rings: [][]gogis.Point{ring}}}},
+			want: `<gml:MultiSurface ` + ns + `><gml:surfaceMember><gml:Polygon><gml:exterior><gml:LinearRing>` +
+				`<gml:posList srsDimension="2">0 0 10 0 10 10 0 0</gml:posList></gml:LinearRing></gml:exterior></gml:Polygon></gml:surfaceMember></gml:MultiSurface>`,
+		},
+		{
+			name: "multigeometry",
+			geom: &gogis.GeometryCollection{Geometries: []gogis.Geometry{&ring[1], &gogis.LineString{Points: ring[:2]}}},
+			want: `<gml:MultiGeometry ` + ns + `><gml:geometryMember><gml:Point><gml:pos>10 0</gml:pos></gml:Point></gml:geometryMember>` +
+				`<gml:geometryMember><gml:LineString><gml:posList srsDimension="2">0 0 10 0</gml:posList></gml:LineString></gml:geometryMember></gml:MultiGeometry>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := gml.Marshal(tt.geom, tt.srid)
+			if err != nil {
+				t.Fatalf("Marshal() unexpected error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal() = %s\nwant %s", got, tt.want)
+			}
+			back, srid, err := gml.Unmarshal(got)
+			if err != nil {
+				t.Fatalf("Unmarshal() unexpected error = %v", err)
+			}
+			if srid != tt.srid {
+				t.Errorf("Unmarshal() SRID = %d, want %d", srid, tt.srid)
+			}
+			if _, ok := tt.geom.(*gogis.GeographyCollection); !ok && !reflect.DeepEqual(back, tt.geom) {
+				t.Errorf("Unmarshal(Marshal()) = %v, want %v", back, tt.geom)
+			}
+		})
+	}
+
+	for _, geom := range []gogis.Geometry{&gogis.Polygon{}, &gogis.Point{Lng: math.NaN()}} {
+		if _, err := gml.Marshal(geom, 0); err == nil {
+			t.Errorf("Marshal(%v) expected error, got nil", geom)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		gml  string
+		want gogis.Geometry
+		srid gogis.SRID
+	}{
+		{
+			name: "legacy srsName is lon lat",
+			gml:  `<gml:Point srsName="EPSG:4326"><gml:pos>13.4 52.5</gml:pos></gml:Point>`,
+			want: &gogis.Point{Lng: 13.4, Lat: 52.5},
+			srid: 4326,
+		},
+		{
+			name: "urn is lat lon",
+			gml:  `<gml:Point srsName="urn:ogc:def:crs:EPSG::4258"><gml:pos>52.5 13.4</gml:pos></gml:Point>`,
+			want: &gogis.Point{Lng: 13.4, Lat: 52.5},
+			srid: 4258,
+		},
+		{
+			name: "urn is northing easting for laea",
+			gml:  `<gml:Point srsName="urn:ogc:def:crs:EPSG::3035"><gml:pos>3210000 4321000</gml:pos></gml:Point>`,
+			want: &gogis.Point{Lng: 4321000, Lat: 3210000},
+			srid: 3035,
+		},
+		{
+			name: "legacy srsName is easting northing for laea",
+			gml:  `<gml:Point srsName="EPSG:3035"><gml:pos>4321000 3210000</gml:pos></gml:Point>`,
+			want: &gogis.Point{Lng: 4321000, Lat: 3210000},
+			srid: 3035,
+		},
+		{
+			name: "crs84",
+			gml:  `<Point srsName="http://www.opengis.net/def/crs/OGC/1.3/CRS84"><pos>13.4 52.5</pos></Point>`,
+			want: &gogis.Point{Lng: 13.4, Lat: 52.5},
+			srid: 4326,
+		},
+		{
+			name: "3d posList",
+			gml:  `<gml:LineString srsName="EPSG:3857" srsDimension="3"><gml:posList>1 2 3 4 5 6</gml:posList></gml:LineString>`,
+			want: &gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 4, Lat: 5}}},
+			srid: 3857,
+		},
+		{
+			name: "pos sequence",
+			gml:  `<gml:LineString><gml:pos>1 2</gml:pos><gml:pointProperty><gml:Point><gml:pos>3 4</gml:pos></gml:Point></gml:pointProperty></gml:LineString>`,
+			want: &gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}}},
+		},
+		{
+			name: "curve segments",
+			gml: `<gml:Curve><gml:segments><gml:LineStringSegment><gml:posList>0 0 1 1</gml:posList></gml:LineStringSegment>` +
+				`<gml:LineStringSegment><gml:posList>1 1 2 0</gml:posList></gml:LineStringSegment></gml:segments></gml:Curve>`,
+			want: &gogis.LineString{Points: []gogis.Point{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 1}, {Lng: 2, Lat: 0}}},
+		},
+		{
+			name: "gml 2 polygon",
+			gml: `<gml:Polygon srsName="http://www.opengis.net/gml/srs/epsg.xml#4326"><gml:outerBoundaryIs><gml:LinearRing>` +
+				`<gml:coordinates>0,0 4,0 4,4 0,0</gml:coordinates></gml:LinearRing></gml:outerBoundaryIs></gml:Polygon>`,
+			want: &gogis.Polygon{Rings: [][]gogis.Point{{{Lng: 0, Lat: 0}, {Lng: 4, Lat: 0}, {Lng: 4, Lat: 4}, {Lng: 0, Lat: 0}}}},
+			srid: 4326,
+		},
+		{
+			name: "inspire multisurface",
+			gml: `<gml:MultiSurface xmlns:gml="http://www.opengis.net/gml/3.2" gml:id="ms1" srsName="http://www.opengis.net/def/crs/EPSG/0/4258">` +
+				`<gml:surfaceMember><gml:Surface gml:id="s1"><gml:patches><gml:PolygonPatch><gml:exterior><gml:LinearRing>` +
+				`<gml:posList>50 10 50 11 51 11 50 10</gml:posList></gml:LinearRing></gml:exterior></gml:PolygonPatch></gml:patches></gml:Surface></gml:surfaceMember>` +
+				`<gml:surfaceMembers><gml:Polygon><gml:exterior><gml:LinearRing><gml:posList>0 0 0 1 1 1 0 0</gml:posList></gml:LinearRing></gml:exterior>` +
+				`<gml:interior><gml:LinearRing><gml:posList>0.1 0.1 0.1 0.2 0.2 0.2 0.1 0.1</gml:posList></gml:LinearRing></gml:interior></gml:Polygon></gml:surfaceMembers>` +
+				`</gml:MultiSurface>`,
+			want: &gogis.GeometryCollection{Geometries: []gogis.Geometry{
+				&gogis.Polygon{Rings: [][]gogis.Point{{{Lng: 10, Lat: 50}, {Lng: 11, Lat: 50}, {Lng: 11, Lat: 51}, {Lng: 10, Lat: 50}}}},
+				&gogis.Polygon{Rings: [][]gogis.Point{
+					{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 0}, {Lng: 1, Lat: 1}, {Lng: 0, Lat: 0}},
+					{{Lng: 0.1, Lat: 0.1}, {Lng: 0.2, Lat: 0.1}, {Lng: 0.2, Lat: 0.2}, {Lng: 0.1, Lat: 0.1}},
+				}},
+			}},
+			srid: 4258,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, srid, err := gml.Unmarshal([]byte(tt.gml))
+			if err != nil {
+				t.Fatalf("Unmarshal() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) || srid != tt.srid {
+				t.Errorf("Unmarshal() = %v, %d, want %v, %d", got, srid, tt.want, tt.srid)
+			}
+		})
+	}
+
+	for _, tt := range []struct {
+		name string
+		gml  string
+	}{
+		{name: "invalid xml", gml: `<gml:Point><gml:pos>1 2</gml:Point>`},
+		{name: "truncated", gml: `<gml:Point><gml:pos>1 2</gml:pos>`},
+		{name: "unknown srsName", gml: `<gml:Point srsName="#crs1"><gml:pos>1 2</gml:pos></gml:Point>`},
+		{name: "bad srsDimension", gml: `<gml:LineString><gml:posList srsDimension="x">1 2</gml:posList></gml:LineString>`},
+		{name: "odd posList", gml: `<gml:LineString><gml:posList>1 2 3</gml:posList></gml:LineString>`},
+		{name: "bad number", gml: `<gml:Point><gml:pos>1 north</gml:pos></gml:Point>`},
+		{name: "short pos", gml: `<gml:Point><gml:pos>1</gml:pos></gml:Point>`},
+		{name: "polygon without exterior", gml: `<gml:Polygon></gml:Polygon>`},
+		{name: "arc", gml: `<gml:Curve><gml:segments><gml:Arc><gml:posList>0 0 1 1 2 0</gml:posList></gml:Arc></gml:segments></gml:Curve>`},
+		{name: "unknown geometry", gml: `<gml:Solid/>`},
+		{name: "nested too deeply", gml: strings.Repeat("<MultiGeometry><geometryMember>", 40) + strings.Repeat("</geometryMember></MultiGeometry>", 40)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := gml.Unmarshal([]byte(tt.gml)); err == nil {
+				t.Error("Unmarshal() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestProperty(t *testing.T) {
+	type parcel struct {
+		XMLName  xml.Name     `xml:"Parcel"`
+		Label    string       `xml:"label"`
+		Geometry gml.Property `xml:"geometry"`
+	}
+
+	data := `<Parcel><label>A-12</label><geometry><gml:Point xmlns:gml="http://www.opengis.net/gml/3.2" gml:id="p1"
+		srsName="http://www.opengis.net/def/crs/EPSG/0/4326"><gml:pos>48.2 16.37</gml:pos></gml:Point></geometry></Parcel>`
+	var p parcel
+	if err := xml.Unmarshal([]byte(data), &p); err != nil {
+		t.Fatalf("xml.Unmarshal() unexpected error = %v", err)
+	}
+	want := gml.Property{Geometry: &gogis.Point{Lng: 16.37, Lat: 48.2}, SRID: 4326, ID: "p1"}
+	if p.Label != "A-12" || !reflect.DeepEqual(p.Geometry, want) {
+		t.Errorf("xml.Unmarshal() = %+v, want geometry %+v", p, want)
+	}
+
+	out, err := xml.Marshal(p)
+	if err != nil {
+		t.Fatalf("xml.Marshal() unexpected error = %v", err)
+	}
+	wantXML := `<Parcel><label>A-12</label><geometry><gml:Point ` + ns + ` gml:id="p1" srsName="http://www.opengis.net/def/crs/EPSG/0/4326">` +
+		`<gml:pos>48.2 16.37</gml:pos></gml:Point></geometry></Parcel>`
+	if string(out) != wantXML {
+		t.Errorf("xml.Marshal() = %s\nwant %s", out, wantXML)
+	}
+
+	var empty parcel
+	if err := xml.Unmarshal([]byte(`<Parcel><geometry/></Parcel>`), &empty); err != nil || empty.Geometry.Geometry != nil {
+		t.Errorf("xml.Unmarshal() of an empty property = %+v, %v", empty.Geometry, err)
+	}
+}
+
+func TestParseSRSName(t *testing.T) {
+	tests := []struct {
+		name   string
+		srid   gogis.SRID
+		latLon bool
+	}{
+		{name: "EPSG:4326", srid: 4326},
+		{name: "urn:ogc:def:crs:EPSG:6.6:4326", srid: 4326, latLon: true},
+		{name: "urn:x-ogc:def:crs:EPSG:4269", srid: 4269, latLon: true},
+		{name: "http://www.opengis.net/def/crs/EPSG/0/3035", srid: 3035, latLon: true},
+		{name: "urn:ogc:def:crs:EPSG::31467", srid: 31467, latLon: true},
+		{name: "urn:ogc:def:crs:EPSG::2180", srid: 2180, latLon: true},
+		{name: "EPSG:3035", srid: 3035},
+		{name: "urn:ogc:def:crs:EPSG::25832", srid: 25832},
+		{name: "urn:ogc:def:crs:OGC:1.3:CRS84", srid: 4326},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srid, latLon, err := gml.ParseSRSName(tt.name)
+			if err != nil || srid != tt.srid || latLon != tt.latLon {
+				t.Errorf("ParseSRSName() = %d, %v, %v, want %d, %v", srid, latLon, err, tt.srid, tt.latLon)
+			}
+		})
+	}
+	if _, _, err := gml.ParseSRSName("EPSG:abc"); err == nil {
+		t.Error("ParseSRSName() of an invalid code expected error, got nil")
+	}
+	if got := gml.SRSName(2154); got != "http://www.opengis.net/def/crs/EPSG/0/2154" {
+		t.Errorf("SRSName() = %s", got)
+	}
+}
@@ -1,3 +1,18 @@
 module github.com/restayway/gogis
 
 go 1.19
+
+require (
+	github.com/jackc/pgx/v5 v5.5.5
+	go.mongodb.org/mongo-driver/v2 v2.8.0
+	gorm.io/gorm v1.30.0
+)
+
+require (
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20221227161230-091c0ba34f0a // indirect
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	golang.org/x/crypto v0.33.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
+)
@@ -0,0 +1,96 @@
+package gogis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// GormDataType returns the general GORM data type of Point fields.
+func (Point) GormDataType() string {
+	return "geometry"
+}
+
+// GormDBDataType returns the column type of Point fields, so that AutoMigrate
+// creates a typed PostGIS column without a type tag. The geography tag
+// option changes the column type:
+//
+//	type Location struct {
+//	    ID     uint
+//	    Point  gogis.Point                         // geometry(Point,4326)
+//	    Reach  gogis.Polygon `gorm:"geography"`    // geography(Polygon,4326)
+//	    Legacy gogis.Point   `gorm:"type:geometry"` // explicit type tag wins
+//	}
+//
+// An explicit type tag always takes precedence. Other databases get the
+// generic "geometry" data type. Values are written as SRID 4326 EWKT, which
+// PostGIS refuses for columns of another SRID, so an srid tag other than
+// 4326 is reported as an error.
+func (Point) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return gormDBDataType(db, field, "geometry", "Point")
+}
+
+// GormDataType returns the general GORM data type of LineString fields.
+func (LineString) GormDataType() string {
+	return "geometry"
+}
+
+// GormDBDataType returns the column type of LineString fields, which is
+// geometry(LineString,4326) on PostgreSQL. The tag options of
+// Point.GormDBDataType apply.
+func (LineString) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return gormDBDataType(db, field, "geometry", "LineString")
+}
+
+// GormDataType returns the general GORM data type of Polygon fields.
+func (Polygon) GormDataType() string {
+	return "geometry"
+}
+
+// GormDBDataType returns the column type of Polygon fields, which is
+// geometry(Polygon,4326) on PostgreSQL. The tag options of
+// Point.GormDBDataType apply.
+func (Polygon) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return gormDBDataType(db, field, "geometry", "Polygon")
+}
+
+// GormDataType returns the general GORM data type of GeometryCollection
+// fields.
+func (GeometryCollection) GormDataType() string {
+	return "geometry"
+}
+
+// GormDBDataType returns the column type of GeometryCollection fields, which
+// is geometry(GeometryCollection,4326) on PostgreSQL. The tag options of
+// Point.GormDBDataType apply.
+func (GeometryCollection) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return gormDBDataType(db, field, "geometry", "GeometryCollection")
+}
+
+// gormDBDataType builds the PostGIS column type for a geometry or geography
+// field. The geography tag turns a geometry field into a geography column.
+func gormDBDataType(db *gorm.DB, field *schema.Field, kind, geometryType string) string {
+	if field.TagSettings["TYPE"] != "" || db.Dialector.Name() != "postgres" {
+		return ""
+	}
+
+	if v, ok := field.TagSettings["GEOGRAPHY"]; ok && !strings.EqualFold(v, "false") {
+		kind = "geography"
+	}
+
+	srid := strconv.Itoa(int(SRIDWGS84))
+	if v, ok := field.TagSettings["SRID"]; ok {
+		// An invalid SRID is still passed through, so that the migration
+		// fails instead of creating a column with the wrong type.
+		if n, err := strconv.Atoi(v); err != nil || n <= 0 {
+			_ = db.AddError(fmt.Errorf("gogis: invalid srid tag %q on field %s", v, field.Name))
+		} else if SRID(n) != SRIDWGS84 {
+			_ = db.AddError(fmt.Errorf("gogis: srid tag %q on field %s: values are written with SRID %d", v, field.Name, SRIDWGS84))
+		}
+		srid = v
+	}
+	return fmt.Sprintf("%s(%s,%s)", kind, geometryType, srid)
+}
@@ -0,0 +1,151 @@
+package gogis_test
+
+import (
+	"testing"
+
+	"github.com/restayway/gogis"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// testDialector stands in for a GORM driver, so that column types can be
+// resolved the way AutoMigrate does without a database.
+type testDialector struct {
+	name string
+}
+
+func (d testDialector) Name() string { return d.name }
+
+func (testDialector) Initialize(db *gorm.DB) error {
+	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{})
+	return nil
+}
+
+func (d testDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return migrator.Migrator{Config: migrator.Config{DB: db, Dialector: d}}
+}
+
+func (testDialector) DataTypeOf(field *schema.Field) string { return string(field.DataType) }
+
+func (testDialector) DefaultValueOf(field *schema.Field) clause.Expression {
+	return clause.Expr{SQL: "DEFAULT"}
+}
+
+func (testDialector) BindVarTo(writer clause.Writer, stmt *gorm.Statement, v interface{}) {
+	writer.WriteByte('?')
+}
+
+func (testDialector) QuoteTo(writer clause.Writer, str string) {
+	writer.WriteString(str)
+}
+
+func (testDialector) Explain(sql string, vars ...interface{}) string { return sql }
+
+type typedModel struct {
+	ID         uint
+	Point      gogis.Point
+	Path       gogis.LineString
+	Area       gogis.Polygon
+	Collection gogis.GeometryCollection
+	Reach      gogis.Polygon `gorm:"geography"`
+	Legacy     gogis.Point   `gorm:"type:geometry(Point,4269)"`
+	Optional   *gogis.Point  `gorm:"geography;srid:4326"`
+	GeoPoint   gogis.GeographyPoint
+	GeoPath    gogis.GeographyLineString
+	GeoArea    gogis.GeographyPolygon `gorm:"srid:4326"`
+	GeoGroup   gogis.GeographyCollection
+}
+
+func columnTypes(t *testing.T, dialect string, model interface{}) (map[string]string, error) {
+	t.Helper()
+	db, err := gorm.Open(testDialector{name: dialect}, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("gorm.Open() unexpected error = %v", err)
+	}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		t.Fatalf("Parse() unexpected error = %v", err)
+	}
+
+	m := db.Migrator().(migrator.Migrator)
+	types := make(map[string]string)
+	for _, field := range stmt.Schema.Fields {
+		types[field.Name] = m.DataTypeOf(field)
+	}
+	return types, m.DB.Error
+}
+
+func TestGormDBDataType(t *testing.T) {
+	tests := []struct {
+		dialect string
+		want    map[string]string
+	}{
+		{
+			dialect: "postgres",
+			want: map[string]string{
+				"Point":      "geometry(Point,4326)",
+				"Path":       "geometry(LineString,4326)",
+				"Area":       "geometry(Polygon,4326)",
+				"Collection": "geometry(GeometryCollection,4326)",
+				"Reach":      "geography(Polygon,4326)",
+				"Legacy":     "geometry(Point,4269)",
+				"Optional":   "geography(Point,4326)",
+				"GeoPoint":   "geography(Point,4326)",
+				"GeoPath":    "geography(LineString,4326)",
+				"GeoArea":    "geography(Polygon,4326)",
+				"GeoGroup":   "geography(GeometryCollection,4326)",
+			},
+		},
+		{
+			dialect: "mysql",
+			want: map[string]string{
+				"Point":    "geometry",
+				"Path":     "geometry",
+				"Reach":    "geometry",
+				"Legacy":   "geometry(Point,4269)",
+				"GeoPoint": "geography",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dialect, func(t *testing.T) {
+			got, err := columnTypes(t, tt.dialect, &typedModel{})
+			if err != nil {
+				t.Fatalf("columnTypes() unexpected error = %v", err)
+			}
+			for field, want := range tt.want {
+				if got[field] != want {
+					t.Errorf("%s column type = %q, want %q", field, got[field], want)
+				}
+			}
+		})
+	}
+}
+
+func TestGormDBDataTypeInvalidSRID(t *testing.T) {
+	type badModel struct {
+		ID    uint
+		Point gogis.Point `gorm:"srid:web"`
+	}
+	types, err := columnTypes(t, "postgres", &badModel{})
+	if err == nil {
+		t.Errorf("columnTypes() expected error for an invalid srid tag, got nil")
+	}
+	if types["Point"] != "geometry(Point,web)" {
+		t.Errorf("Point column type = %q, want the invalid SRID passed through", types["Point"])
+	}
+
+	// Values are always written as SRID 4326.
+	type projectedModel struct {
+		ID   uint
+		Tile gogis.Polygon `gorm:"srid:3857"`
+	}
+	if _, err := columnTypes(t, "postgres", &projectedModel{}); err == nil {
+		t.Errorf("columnTypes() expected error for an srid tag other than 4326, got nil")
+	}
+}
@@ -0,0 +1,168 @@
+// Package gormgis provides GORM clause expressions and scopes for PostGIS
+// spatial queries on gogis geometries.
+//
+// Instead of hand written conditions such as
+//
+//	db.Where("ST_DWithin(point, ST_Point(?, ?), ?)", lng, lat, 0.01)
+//
+// queries are composed from typed expressions that take a column name and a
+// gogis geometry:
+//
+//	center := gogis.Point{Lng: -73.9857, Lat: 40.7484}
+//	db.Where(gormgis.DWithin("point", &center, 0.01)).
+//	    Order(gormgis.OrderByDistance("point", &center)).
+//	    Find(&locations)
+//
+// Geometries are sent as EWKT parameters wrapped in ST_GeomFromEWKT, so they
+// keep their SRID. Geography values, such as *gogis.GeographyPoint, are
+// wrapped in ST_GeogFromText instead, which makes distances meters. Column
+// names may be qualified with a table name, as in "locations.point", and are
+// quoted by the GORM dialector.
+//
+// The expressions can be combined with clause.And, clause.Or and clause.Not,
+// and the Where and Nearest functions wrap them as reusable GORM scopes:
+//
+//	db.Scopes(gormgis.Nearest("point", &center, 10)).Find(&locations)
+//
+// AutoMigrate migrates models and creates spatial indexes for their geometry
+// fields; see SpatialIndex for the tag options.
+package gormgis
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/restayway/gogis"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Predicate is a spatial predicate calling a PostGIS function with a column,
+// a geometry and optional extra arguments, such as
+// ST_Intersects(column, geometry).
+type Predicate struct {
+	Function string         // PostGIS function name, e.g. "ST_Intersects"
+	Column   clause.Column  // Column holding the stored geometry
+	Geometry gogis.Geometry // Geometry the column is compared with
+	Args     []interface{}  // Arguments following the geometry
+}
+
+// Build writes the predicate to the GORM statement.
+func (p Predicate) Build(builder clause.Builder) {
+	builder.WriteString(p.Function)
+	builder.WriteByte('(')
+	builder.WriteQuoted(p.Column)
+	builder.WriteByte(',')
+	writeGeometry(builder, p.Geometry)
+	for _, arg := range p.Args {
+		builder.WriteByte(',')
+		builder.AddVar(builder, arg)
+	}
+	builder.WriteByte(')')
+}
+
+// DWithin matches rows whose column is within distance of g, using
+// ST_DWithin. The distance is in the units of the column's SRID, which are
+// degrees for SRID 4326 geometry columns and meters for geography columns.
+func DWithin(column string, g gogis.Geometry, distance float64) Predicate {
+	return Predicate{Function: "ST_DWithin", Column: toColumn(column), Geometry: g, Args: []interface{}{distance}}
+}
+
+// Intersects matches rows whose column shares any portion of space with g,
+// using ST_Intersects.
+func Intersects(column string, g gogis.Geometry) Predicate {
+	return Predicate{Function: "ST_Intersects", Column: toColumn(column), Geometry: g}
+}
+
+// Within matches rows whose column lies completely inside g, using
+// ST_Within.
+func Within(column string, g gogis.Geometry) Predicate {
+	return Predicate{Function: "ST_Within", Column: toColumn(column), Geometry: g}
+}
+
+// Contains matches rows whose column completely contains g, using
+// ST_Contains.
+func Contains(column string, g gogis.Geometry) Predicate {
+	return Predicate{Function: "ST_Contains", Column: toColumn(column), Geometry: g}
+}
+
+// Operator applies a PostGIS operator to a column and a geometry, as in
+// "column && geometry".
+type Operator struct {
+	Operator string         // SQL operator, e.g. "&&" or "<->"
+	Column   clause.Column  // Column holding the stored geometry
+	Geometry gogis.Geometry // Right-hand operand
+}
+
+// Build writes the operator expression to the GORM statement.
+func (o Operator) Build(builder clause.Builder) {
+	builder.WriteQuoted(o.Column)
+	builder.WriteByte(' ')
+	builder.WriteString(o.Operator)
+	builder.WriteByte(' ')
+	writeGeometry(builder, o.Geometry)
+}
+
+// EnvelopeIntersects matches rows whose bounding box intersects the bounding
+// box of g, using the && operator. It is answered from a GiST index alone and
+// is typically used as a cheap pre-filter.
+func EnvelopeIntersects(column string, g gogis.Geometry) Operator {
+	return Operator{Operator: "&&", Column: toColumn(column), Geometry: g}
+}
+
+// Distance is the index assisted distance between the column and g, using the
+// <-> operator. It can be selected or used for ordering.
+func Distance(column string, g gogis.Geometry) Operator {
+	return Operator{Operator: "<->", Column: toColumn(column), Geometry: g}
+}
+
+// OrderByDistance orders rows by increasing distance from g. It can be passed
+// to db.Order or db.Clauses; with a GiST index on the column PostgreSQL
+// answers it as a nearest neighbour search.
+func OrderByDistance(column string, g gogis.Geometry) clause.OrderBy {
+	return clause.OrderBy{Expression: Distance(column, g)}
+}
+
+// Where returns a scope adding the spatial conditions to a query.
+//
+// Example:
+//
+//	inArea := gormgis.Where(gormgis.Within("point", &area))
+//	db.Scopes(inArea).Find(&locations)
+func Where(exprs ...clause.Expression) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(clause.And(exprs...))
+	}
+}
+
+// Nearest returns a scope selecting the limit rows closest to g.
+func Nearest(column string, g gogis.Geometry, limit int) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Order(OrderByDistance(column, g)).Limit(limit)
+	}
+}
+
+// writeGeometry writes g as an EWKT parameter, or NULL for a nil geometry,
+// including a nil pointer such as (*gogis.Point)(nil). Geography values such
+// as *gogis.GeographyPoint are cast to geography.
+func writeGeometry(builder clause.Builder, g gogis.Geometry) {
+	if v := reflect.ValueOf(g); g == nil || v.Kind() == reflect.Pointer && v.IsNil() {
+		builder.WriteString("NULL")
+		return
+	}
+	if t, ok := g.(interface{ GormDataType() string }); ok && t.GormDataType() == "geography" {
+		builder.WriteString("ST_GeogFromText(")
+	} else {
+		builder.WriteString("ST_GeomFromEWKT(")
+	}
+	builder.AddVar(builder, g.String())
+	builder.WriteByte(')')
+}
+
+// toColumn converts a possibly table qualified column name to a clause.Column.
+func toColumn(name string) clause.Column {
+	if table, column, ok := strings.Cut(name, "."); ok {
+		return clause.Column{Table: table, Name: column}
+	}
+	return clause.Column{Name: name}
+}
@@ -0,0 +1,189 @@
+package gormgis_test
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/gormgis"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// testDialector is a minimal PostgreSQL flavoured dialector for building SQL
+// without a database. Statements run against pool when it is set.
+type testDialector struct {
+	pool     gorm.ConnPool
+	migrator gorm.Migrator
+}
+
+func (testDialector) Name() string { return "postgres" }
+
+func (d testDialector) Initialize(db *gorm.DB) error {
+	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{})
+	if d.pool != nil {
+		db.ConnPool = d.pool
+	}
+	return nil
+}
+
+func (d testDialector) Migrator(db *gorm.DB) gorm.Migrator { return d.migrator }
+
+func (testDialector) DataTypeOf(field *schema.Field) string { return string(field.DataType) }
+
+func (testDialector) DefaultValueOf(field *schema.Field) clause.Expression {
+	return clause.Expr{SQL: "DEFAULT"}
+}
+
+func (testDialector) BindVarTo(writer clause.Writer, stmt *gorm.Statement, v interface{}) {
+	writer.WriteByte('$')
+	writer.WriteString(strconv.Itoa(len(stmt.Vars)))
+}
+
+func (testDialector) QuoteTo(writer clause.Writer, str string) {
+	writer.WriteByte('"')
+	writer.WriteString(str)
+	writer.WriteByte('"')
+}
+
+func (testDialector) Explain(sql string, vars ...interface{}) string {
+	return logger.ExplainSQL(sql, nil, `'`, vars...)
+}
+
+type Location struct {
+	ID    uint
+	Name  string
+	Point gogis.Point `gorm:"type:geometry(Point,4326)"`
+}
+
+func dryRun(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(testDialector{}, &gorm.Config{DryRun: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("gorm.Open() unexpected error = %v", err)
+	}
+	return db
+}
+
+func TestExpressions(t *testing.T) {
+	center := &gogis.Point{Lng: -73.9857, Lat: 40.7484}
+	area := &gogis.Polygon{Rings: [][]gogis.Point{{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 0}, {Lng: 1, Lat: 1}, {Lng: 0, Lat: 0}}}}
+
+	tests := []struct {
+		name     string
+		query    func(db *gorm.DB) *gorm.DB
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{
+			name: "dwithin",
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where(gormgis.DWithin("point", center, 0.01))
+			},
+			wantSQL:  `SELECT * FROM "locations" WHERE ST_DWithin("point",ST_GeomFromEWKT($1),$2)`,
+			wantVars: []interface{}{center.String(), 0.01},
+		},
+		{
+			name: "dwithin geography",
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where(gormgis.DWithin("point", (*gogis.GeographyPoint)(center), 1000))
+			},
+			wantSQL:  `SELECT * FROM "locations" WHERE ST_DWithin("point",ST_GeogFromText($1),$2)`,
+			wantVars: []interface{}{center.String(), 1000.0},
+		},
+		{
+			name: "intersects with qualified column",
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where(gormgis.Intersects("locations.point", area))
+			},
+			wantSQL:  `SELECT * FROM "locations" WHERE ST_Intersects("locations"."point",ST_GeomFromEWKT($1))`,
+			wantVars: []interface{}{area.String()},
+		},
+		{
+			name: "within and contains",
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where(gormgis.Within("point", area)).Where(gormgis.Contains("point", center))
+			},
+			wantSQL: `SELECT * FROM "locations" WHERE ST_Within("point",ST_GeomFromEWKT($1)) ` +
+				`AND ST_Contains("point",ST_GeomFromEWKT($2))`,
+			wantVars: []interface{}{area.String(), center.String()},
+		},
+		{
+			name: "envelope",
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where(gormgis.EnvelopeIntersects("point", area))
+			},
+			wantSQL:  `SELECT * FROM "locations" WHERE "point" && ST_GeomFromEWKT($1)`,
+			wantVars: []interface{}{area.String()},
+		},
+		{
+			name: "order by distance",
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where("name <> ?", "x").Order(gormgis.OrderByDistance("point", center))
+			},
+			wantSQL:  `SELECT * FROM "locations" WHERE name <> $1 ORDER BY "point" <-> ST_GeomFromEWKT($2)`,
+			wantVars: []interface{}{"x", center.String()},
+		},
+		{
+			name: "negated",
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where(clause.Not(gormgis.Within("point", area)))
+			},
+			wantSQL:  `SELECT * FROM "locations" WHERE NOT ST_Within("point",ST_GeomFromEWKT($1))`,
+			wantVars: []interface{}{area.String()},
+		},
+		{
+			name: "nil geometry",
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where(gormgis.Intersects("point", nil))
+			},
+			wantSQL: `SELECT * FROM "locations" WHERE ST_Intersects("point",NULL)`,
+		},
+		{
+			name: "nil pointer geometry",
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where(gormgis.Intersects("point", (*gogis.Point)(nil)))
+			},
+			wantSQL: `SELECT * FROM "locations" WHERE ST_Intersects("point",NULL)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out []Location
+			stmt := tt.query(dryRun(t).Model(&Location{})).Find(&out).Statement
+
+			if got := stmt.SQL.String(); got != tt.wantSQL {
+				t.Errorf("SQL = %s, want %s", got, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(stmt.Vars, tt.wantVars) && (len(stmt.Vars) > 0 || len(tt.wantVars) > 0) {
+				t.Errorf("Vars = %v, want %v", stmt.Vars, tt.wantVars)
+			}
+		})
+	}
+}
+
+func TestScopes(t *testing.T) {
+	center := &gogis.Point{Lng: 2.35, Lat: 48.85}
+
+	var out []Location
+	stmt := dryRun(t).
+		Scopes(
+			gormgis.Where(gormgis.DWithin("point", center, 1), gormgis.EnvelopeIntersects("point", center)),
+			gormgis.Nearest("point", center, 5),
+		).
+		Find(&out).Statement
+
+	want := `SELECT * FROM "locations" WHERE ST_DWithin("point",ST_GeomFromEWKT($1),$2) ` +
+		`AND "point" && ST_GeomFromEWKT($3) ORDER BY "point" <-> ST_GeomFromEWKT($4) LIMIT $5`
+	if got := stmt.SQL.String(); got != want {
+		t.Errorf("SQL = %s, want %s", got, want)
+	}
+	if len(stmt.Vars) != 5 || stmt.Vars[4] != 5 {
+		t.Errorf("Vars = %v, want 5 vars ending with the limit", stmt.Vars)
+	}
+}
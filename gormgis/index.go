@@ -0,0 +1,231 @@
+package gormgis
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// SpatialIndex describes the index of a geometry or geography column.
+//
+// Every gogis geometry field of a model gets a GiST index by default. The
+// spatialIndex tag names the index and sets its options, separated by
+// commas:
+//
+//	type Location struct {
+//	    ID        uint
+//	    Name      string
+//	    Category  string
+//	    DeletedAt gorm.DeletedAt
+//	    Point     gogis.Point `gorm:"spatialIndex:idx_live_points,type:spgist,where:deleted_at IS NULL,include:name,include:category"`
+//	    Area      gogis.Polygon `gorm:"spatialIndex:-"` // no index
+//	}
+//
+// As with GORM's own index tag, the WHERE expression cannot contain commas.
+type SpatialIndex struct {
+	Name    string   // Index name, idx_<table>_<column> by default
+	Table   string   // Table name, optionally schema qualified
+	Column  string   // Indexed column
+	Type    string   // Access method: "gist" (default), "spgist" or "brin"
+	Where   string   // Optional predicate of a partial index
+	Include []string // Optional non-key columns of a covering index
+}
+
+// SpatialIndexes returns the spatial indexes declared by the geometry fields
+// of a model.
+func SpatialIndexes(db *gorm.DB, model interface{}) ([]SpatialIndex, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, fmt.Errorf("gormgis: %w", err)
+	}
+
+	var indexes []SpatialIndex
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName == "" || !isSpatialField(field) {
+			continue
+		}
+		tag := field.TagSettings["SPATIALINDEX"]
+		if tag == "-" {
+			continue
+		}
+
+		idx := SpatialIndex{Table: stmt.Table, Column: field.DBName, Type: "gist"}
+		options := strings.Split(tag, ",")
+		idx.Name = strings.TrimSpace(options[0])
+		for _, option := range options[1:] {
+			key, value, _ := strings.Cut(option, ":")
+			value = strings.TrimSpace(value)
+			switch strings.ToLower(strings.TrimSpace(key)) {
+			case "type":
+				idx.Type = strings.ToLower(value)
+			case "where":
+				idx.Where = value
+			case "include":
+				idx.Include = append(idx.Include, value)
+			default:
+				return nil, fmt.Errorf("gormgis: unknown spatialIndex option %q on field %s", option, field.Name)
+			}
+		}
+		switch idx.Type {
+		case "gist", "spgist", "brin":
+		default:
+			return nil, fmt.Errorf("gormgis: unsupported spatial index type %q on field %s", idx.Type, field.Name)
+		}
+		if idx.Name == "" {
+			idx.Name = db.NamingStrategy.IndexName(stmt.Table, field.DBName)
+		}
+		indexes = append(indexes, idx)
+	}
+	return indexes, nil
+}
+
+// isSpatialField reports whether a field holds a gogis geometry, including
+// fields whose column type was overridden with a type tag.
+func isSpatialField(field *schema.Field) bool {
+	return field.GORMDataType == "geometry" || field.GORMDataType == "geography"
+}
+
+// CreateSpatialIndexes creates the missing spatial indexes of the models.
+//
+// An index is considered present when an index with its name exists, or when
+// the column already has a single column index with the same access method.
+// An existing index with the requested name but another access method is
+// reported as an error rather than replaced.
+//
+// Spatial indexes are only managed on PostgreSQL; for other dialects
+// CreateSpatialIndexes does nothing.
+func CreateSpatialIndexes(db *gorm.DB, models ...interface{}) error {
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+	for _, model := range models {
+		indexes, err := SpatialIndexes(db, model)
+		if err != nil {
+			return err
+		}
+		for _, idx := range indexes {
+			if err := ensureIndex(db, idx); err != nil {
+				return fmt.Errorf("gormgis: index %s: %w", idx.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// existingIndex is a row of pg_indexes.
+type existingIndex struct {
+	Indexname string
+	Indexdef  string
+}
+
+func ensureIndex(db *gorm.DB, idx SpatialIndex) error {
+	tableSchema, table := "", idx.Table
+	if i := strings.LastIndexByte(table, '.'); i >= 0 {
+		tableSchema, table = table[:i], table[i+1:]
+	}
+
+	query := db.Table("pg_indexes").Select("indexname, indexdef").Where("tablename = ?", table)
+	if tableSchema != "" {
+		query = query.Where("schemaname = ?", tableSchema)
+	} else {
+		query = query.Where("schemaname = CURRENT_SCHEMA()")
+	}
+	var existing []existingIndex
+	if err := query.Find(&existing).Error; err != nil {
+		return err
+	}
+
+	for _, e := range existing {
+		method, columns := parseIndexDef(e.Indexdef)
+		if e.Indexname == idx.Name {
+			if method != idx.Type {
+				return fmt.Errorf("exists with access method %q, want %q", method, idx.Type)
+			}
+			return nil
+		}
+		if method == idx.Type && len(columns) == 1 && columns[0] == idx.Column {
+			return nil
+		}
+	}
+
+	sql := "CREATE INDEX IF NOT EXISTS ? ON ? USING " + strings.ToUpper(idx.Type) + " (?)"
+	vars := []interface{}{
+		clause.Column{Name: idx.Name},
+		clause.Table{Name: idx.Table},
+		clause.Column{Name: idx.Column},
+	}
+	if len(idx.Include) > 0 {
+		include := make([]interface{}, len(idx.Include))
+		for i, column := range idx.Include {
+			include[i] = clause.Column{Name: column}
+		}
+		sql += " INCLUDE ?"
+		vars = append(vars, include)
+	}
+	if idx.Where != "" {
+		sql += " WHERE " + idx.Where
+	}
+	return db.Exec(sql, vars...).Error
+}
+
+// parseIndexDef extracts the access method and the key columns from an
+// index definition as reported by pg_indexes, such as
+// "CREATE INDEX idx ON public.locations USING gist (point)".
+func parseIndexDef(def string) (method string, columns []string) {
+	i := strings.Index(def, " USING ")
+	if i < 0 {
+		return "", nil
+	}
+	rest := def[i+len(" USING "):]
+	open := strings.IndexByte(rest, '(')
+	if open < 0 {
+		return "", nil
+	}
+	method = strings.ToLower(strings.TrimSpace(rest[:open]))
+
+	depth := 0
+	start := open + 1
+	for j := open; j < len(rest); j++ {
+		switch rest[j] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				columns = append(columns, strings.Trim(strings.TrimSpace(rest[start:j]), `"`))
+				return method, columns
+			}
+		case ',':
+			if depth == 1 {
+				columns = append(columns, strings.Trim(strings.TrimSpace(rest[start:j]), `"`))
+				start = j + 1
+			}
+		}
+	}
+	return method, columns
+}
+
+// AutoMigrate runs db.AutoMigrate for the models and then creates their
+// spatial indexes with CreateSpatialIndexes. GORM has no hook that runs after
+// AutoMigrate, so it is called in its place; the dialector is left
+// untouched.
+//
+// Example:
+//
+//	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+//	if err != nil {
+//	    return err
+//	}
+//	// Also creates idx_locations_point USING GIST.
+//	if err := gormgis.AutoMigrate(db, &Location{}); err != nil {
+//	    return err
+//	}
+func AutoMigrate(db *gorm.DB, models ...interface{}) error {
+	if err := db.AutoMigrate(models...); err != nil {
+		return err
+	}
+	return CreateSpatialIndexes(db, models...)
+}
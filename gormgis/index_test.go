@@ -0,0 +1,205 @@
+package gormgis_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/gormgis"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeDB is an in-process database/sql driver that records executed
+// statements and answers pg_indexes queries from a fixed list.
+type fakeDB struct {
+	mu       sync.Mutex
+	execs    []string
+	existing [][2]string // indexname, indexdef
+}
+
+func (f *fakeDB) Open(string) (driver.Conn, error) { return fakeConn{f}, nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+func (c fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.execs = append(c.db.execs, query)
+	return driver.RowsAffected(0), nil
+}
+
+func (c fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	rows := &fakeRows{}
+	if strings.Contains(query, "pg_indexes") {
+		rows.values = c.db.existing
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	values [][2]string
+	i      int
+}
+
+func (r *fakeRows) Columns() []string { return []string{"indexname", "indexdef"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.values) {
+		return io.EOF
+	}
+	dest[0], dest[1] = r.values[r.i][0], r.values[r.i][1]
+	r.i++
+	return nil
+}
+
+func openFake(t *testing.T, f *fakeDB, migrator gorm.Migrator) *gorm.DB {
+	t.Helper()
+	name := "gormgis-fake-" + t.Name()
+	sql.Register(name, f)
+	pool, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("sql.Open() unexpected error = %v", err)
+	}
+	db, err := gorm.Open(testDialector{pool: pool, migrator: migrator}, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("gorm.Open() unexpected error = %v", err)
+	}
+	return db
+}
+
+type Region struct {
+	ID        uint
+	Name      string
+	Category  string
+	DeletedAt gorm.DeletedAt
+	Center    gogis.Point
+	Area      gogis.Polygon    `gorm:"spatialIndex:idx_live_areas,type:spgist,where:deleted_at IS NULL,include:name,include:category"`
+	Outline   gogis.LineString `gorm:"spatialIndex:-"`
+	Legacy    gogis.Point      `gorm:"type:geometry(Point,4326)"`
+	Parts     *gogis.GeometryCollection
+}
+
+func TestSpatialIndexes(t *testing.T) {
+	got, err := gormgis.SpatialIndexes(dryRun(t), &Region{})
+	if err != nil {
+		t.Fatalf("SpatialIndexes() unexpected error = %v", err)
+	}
+	want := []gormgis.SpatialIndex{
+		{Name: "idx_regions_center", Table: "regions", Column: "center", Type: "gist"},
+		{
+			Name:    "idx_live_areas",
+			Table:   "regions",
+			Column:  "area",
+			Type:    "spgist",
+			Where:   "deleted_at IS NULL",
+			Include: []string{"name", "category"},
+		},
+		{Name: "idx_regions_legacy", Table: "regions", Column: "legacy", Type: "gist"},
+		{Name: "idx_regions_parts", Table: "regions", Column: "parts", Type: "gist"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SpatialIndexes() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSpatialIndexesErrors(t *testing.T) {
+	type badType struct {
+		ID    uint
+		Point gogis.Point `gorm:"spatialIndex:,type:btree"`
+	}
+	type badOption struct {
+		ID    uint
+		Point gogis.Point `gorm:"spatialIndex:,fillfactor:70"`
+	}
+
+	for _, model := range []interface{}{&badType{}, &badOption{}} {
+		if _, err := gormgis.SpatialIndexes(dryRun(t), model); err == nil {
+			t.Errorf("SpatialIndexes(%T) expected error, got nil", model)
+		}
+	}
+}
+
+func TestCreateSpatialIndexes(t *testing.T) {
+	f := &fakeDB{existing: [][2]string{
+		{"regions_pkey", "CREATE UNIQUE INDEX regions_pkey ON public.regions USING btree (id)"},
+		// A hand made index on legacy satisfies the default GiST index.
+		{"legacy_gist", "CREATE INDEX legacy_gist ON public.regions USING gist (legacy)"},
+	}}
+	db := openFake(t, f, nil)
+
+	if err := gormgis.CreateSpatialIndexes(db, &Region{}); err != nil {
+		t.Fatalf("CreateSpatialIndexes() unexpected error = %v", err)
+	}
+	want := []string{
+		`CREATE INDEX IF NOT EXISTS "idx_regions_center" ON "regions" USING GIST ("center")`,
+		`CREATE INDEX IF NOT EXISTS "idx_live_areas" ON "regions" USING SPGIST ("area") INCLUDE ("name","category") WHERE deleted_at IS NULL`,
+		`CREATE INDEX IF NOT EXISTS "idx_regions_parts" ON "regions" USING GIST ("parts")`,
+	}
+	if !reflect.DeepEqual(f.execs, want) {
+		t.Errorf("executed:\n%s\nwant:\n%s", strings.Join(f.execs, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCreateSpatialIndexesWrongMethod(t *testing.T) {
+	f := &fakeDB{existing: [][2]string{
+		{"idx_regions_center", "CREATE INDEX idx_regions_center ON public.regions USING btree (center)"},
+	}}
+	db := openFake(t, f, nil)
+
+	if err := gormgis.CreateSpatialIndexes(db, &Region{}); err == nil {
+		t.Errorf("CreateSpatialIndexes() expected error for a btree index, got nil")
+	}
+	if len(f.execs) != 0 {
+		t.Errorf("CreateSpatialIndexes() executed %v, want nothing", f.execs)
+	}
+}
+
+// recordingMigrator stands in for the driver's migrator.
+type recordingMigrator struct {
+	gorm.Migrator
+	migrated *[]interface{}
+}
+
+func (m recordingMigrator) AutoMigrate(dst ...interface{}) error {
+	*m.migrated = append(*m.migrated, dst...)
+	return nil
+}
+
+func TestAutoMigrate(t *testing.T) {
+	var migrated []interface{}
+	f := &fakeDB{}
+	db := openFake(t, f, recordingMigrator{migrated: &migrated})
+	dialector := db.Dialector
+
+	type Location struct {
+		ID    uint
+		Point gogis.Point
+	}
+	if err := gormgis.AutoMigrate(db, &Location{}); err != nil {
+		t.Fatalf("AutoMigrate() unexpected error = %v", err)
+	}
+	if len(migrated) != 1 {
+		t.Errorf("AutoMigrate() migrated %d models, want 1", len(migrated))
+	}
+	want := []string{`CREATE INDEX IF NOT EXISTS "idx_locations_point" ON "locations" USING GIST ("point")`}
+	if !reflect.DeepEqual(f.execs, want) {
+		t.Errorf("executed %v, want %v", f.execs, want)
+	}
+	if db.Dialector != dialector {
+		t.Errorf("AutoMigrate() replaced the dialector")
+	}
+}
@@ -0,0 +1,521 @@
+// Package gpx reads and writes GPS Exchange Format (GPX) files with gogis
+// geometries.
+//
+// Decode reads GPX 1.1 and 1.0 files. Waypoints (wpt) become Points, and
+// routes (rte) and track segments (trkseg) become LineStrings. gogis
+// geometries are two-dimensional, so the elevation and time of route and
+// track points are kept next to the line in a Path, one entry per point:
+//
+//	doc, err := gpx.Decode(file)
+//	if err != nil {
+//	    return err
+//	}
+//	for _, trk := range doc.Tracks {
+//	    for _, seg := range trk.Segments {
+//	        fmt.Println(trk.Name, len(seg.Line.Points), seg.Times != nil)
+//	    }
+//	}
+//
+// Encode writes GPX 1.1. Extensions and the less common point elements, such
+// as magvar, fix and dop, are dropped, as are the names of route points.
+package gpx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/restayway/gogis"
+)
+
+// Namespace is the XML namespace of GPX 1.1.
+const Namespace = "http://www.topografix.com/GPX/1/1"
+
+// Document is the content of a GPX file.
+type Document struct {
+	Creator   string // creator attribute, "gogis" when written empty
+	Metadata  Metadata
+	Waypoints []Waypoint
+	Routes    []Route
+	Tracks    []Track
+}
+
+// Metadata describes a GPX file.
+type Metadata struct {
+	Name        string
+	Description string
+	Author      string // name of the author
+	Links       []Link
+	Time        time.Time // creation time, zero when unknown
+	Keywords    string
+}
+
+// Link is a link to an external resource.
+type Link struct {
+	Href string
+	Text string
+}
+
+// Waypoint is a point of interest.
+type Waypoint struct {
+	Point       gogis.Point
+	Elevation   *float64  // meters above sea level, nil when unknown
+	Time        time.Time // zero when unknown
+	Name        string
+	Comment     string
+	Description string
+	Source      string
+	Links       []Link
+	Symbol      string
+	Type        string
+}
+
+// Path is a line with the elevation and time of its points.
+type Path struct {
+	Line gogis.LineString
+
+	// Elevations holds the elevation of each point in meters, NaN for the
+	// points without one. It is nil when no point has an elevation.
+	Elevations []float64
+
+	// Times holds the time of each point, zero for the points without one.
+	// It is nil when no point has a time.
+	Times []time.Time
+}
+
+// Route is an ordered list of points leading to a destination.
+type Route struct {
+	Name        string
+	Comment     string
+	Description string
+	Source      string
+	Links       []Link
+	Number      int
+	Type        string
+	Path
+}
+
+// Track is a recorded path made of segments, split where reception was lost
+// or the recording was paused.
+type Track struct {
+	Name        string
+	Comment     string
+	Description string
+	Source      string
+	Links       []Link
+	Number      int
+	Type        string
+	Segments    []Path
+}
+
+// Geometry returns the track as a *gogis.LineString when it has a single
+// segment, and as a *gogis.GeometryCollection of its segments otherwise.
+func (t *Track) Geometry() gogis.Geometry {
+	if len(t.Segments) == 1 {
+		return &gogis.LineString{Points: t.Segments[0].Line.Points}
+	}
+	gc := &gogis.GeometryCollection{Geometries: make([]gogis.Geometry, len(t.Segments))}
+	for i, seg := range t.Segments {
+		gc.Geometries[i] = &gogis.LineString{Points: seg.Line.Points}
+	}
+	return gc
+}
+
+// The XML structures follow the element order of the GPX 1.1 schema.
+
+type gpxXML struct {
+	XMLName   xml.Name     `xml:"gpx"`
+	Version   string       `xml:"version,attr"`
+	Creator   string       `xml:"creator,attr"`
+	Xmlns     string       `xml:"xmlns,attr,omitempty"`
+	Metadata  *metadataXML `xml:"metadata"`
+	Waypoints []pointXML   `xml:"wpt"`
+	Routes    []routeXML   `xml:"rte"`
+	Tracks    []trackXML   `xml:"trk"`
+
+	// GPX 1.0 has the metadata at the top level.
+	Name     string `xml:"name,omitempty"`
+	Desc     string `xml:"desc,omitempty"`
+	Author   string `xml:"author,omitempty"`
+	Time     string `xml:"time,omitempty"`
+	Keywords string `xml:"keywords,omitempty"`
+}
+
+type metadataXML struct {
+	Name     string     `xml:"name,omitempty"`
+	Desc     string     `xml:"desc,omitempty"`
+	Author   *personXML `xml:"author"`
+	Links    []linkXML  `xml:"link"`
+	Time     string     `xml:"time,omitempty"`
+	Keywords string     `xml:"keywords,omitempty"`
+}
+
+type personXML struct {
+	Name string `xml:"name,omitempty"`
+}
+
+type linkXML struct {
+	Href string `xml:"href,attr"`
+	Text string `xml:"text,omitempty"`
+}
+
+type pointXML struct {
+	Lat   string    `xml:"lat,attr"`
+	Lon   string    `xml:"lon,attr"`
+	Ele   string    `xml:"ele,omitempty"`
+	Time  string    `xml:"time,omitempty"`
+	Name  string    `xml:"name,omitempty"`
+	Cmt   string    `xml:"cmt,omitempty"`
+	Desc  string    `xml:"desc,omitempty"`
+	Src   string    `xml:"src,omitempty"`
+	Links []linkXML `xml:"link"`
+	Sym   string    `xml:"sym,omitempty"`
+	Type  string    `xml:"type,omitempty"`
+}
+
+type routeXML struct {
+	Name   string     `xml:"name,omitempty"`
+	Cmt    string     `xml:"cmt,omitempty"`
+	Desc   string     `xml:"desc,omitempty"`
+	Src    string     `xml:"src,omitempty"`
+	Links  []linkXML  `xml:"link"`
+	Number string     `xml:"number,omitempty"`
+	Type   string     `xml:"type,omitempty"`
+	Points []pointXML `xml:"rtept"`
+}
+
+type trackXML struct {
+	Name     string       `xml:"name,omitempty"`
+	Cmt      string       `xml:"cmt,omitempty"`
+	Desc     string       `xml:"desc,omitempty"`
+	Src      string       `xml:"src,omitempty"`
+	Links    []linkXML    `xml:"link"`
+	Number   string       `xml:"number,omitempty"`
+	Type     string       `xml:"type,omitempty"`
+	Segments []segmentXML `xml:"trkseg"`
+}
+
+type segmentXML struct {
+	Points []pointXML `xml:"trkpt"`
+}
+
+// Decode reads a GPX document.
+func Decode(r io.Reader) (*Document, error) {
+	var x gpxXML
+	if err := xml.NewDecoder(r).Decode(&x); err != nil {
+		return nil, fmt.Errorf("gpx: %w", err)
+	}
+	doc, err := x.document()
+	if err != nil {
+		return nil, fmt.Errorf("gpx: %w", err)
+	}
+	return doc, nil
+}
+
+func (x *gpxXML) document() (*Document, error) {
+	doc := &Document{Creator: x.Creator}
+	var err error
+	if m := x.Metadata; m != nil {
+		doc.Metadata = Metadata{Name: m.Name, Description: m.Desc, Links: links(m.Links), Keywords: m.Keywords}
+		if m.Author != nil {
+			doc.Metadata.Author = m.Author.Name
+		}
+		if doc.Metadata.Time, err = parseTime(m.Time); err != nil {
+			return nil, err
+		}
+	} else {
+		doc.Metadata = Metadata{Name: x.Name, Description: x.Desc, Author: x.Author, Keywords: x.Keywords}
+		if doc.Metadata.Time, err = parseTime(x.Time); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, p := range x.Waypoints {
+		w := Waypoint{
+			Name:        p.Name,
+			Comment:     p.Cmt,
+			Description: p.Desc,
+			Source:      p.Src,
+			Links:       links(p.Links),
+			Symbol:      p.Sym,
+			Type:        p.Type,
+		}
+		var ele float64
+		if w.Point, ele, w.Time, err = p.parse(); err != nil {
+			return nil, fmt.Errorf("wpt %d: %w", len(doc.Waypoints)+1, err)
+		}
+		if !math.IsNaN(ele) {
+			w.Elevation = &ele
+		}
+		doc.Waypoints = append(doc.Waypoints, w)
+	}
+
+	for i, r := range x.Routes {
+		route := Route{Name: r.Name, Comment: r.Cmt, Description: r.Desc, Source: r.Src, Links: links(r.Links), Type: r.Type}
+		if route.Number, err = parseNumber(r.Number); err != nil {
+			return nil, fmt.Errorf("rte %d: %w", i+1, err)
+		}
+		if route.Path, err = path(r.Points); err != nil {
+			return nil, fmt.Errorf("rte %d: %w", i+1, err)
+		}
+		doc.Routes = append(doc.Routes, route)
+	}
+
+	for i, t := range x.Tracks {
+		track := Track{Name: t.Name, Comment: t.Cmt, Description: t.Desc, Source: t.Src, Links: links(t.Links), Type: t.Type}
+		if track.Number, err = parseNumber(t.Number); err != nil {
+			return nil, fmt.Errorf("trk %d: %w", i+1, err)
+		}
+		for _, seg := range t.Segments {
+			p, err := path(seg.Points)
+			if err != nil {
+				return nil, fmt.Errorf("trk %d: %w", i+1, err)
+			}
+			track.Segments = append(track.Segments, p)
+		}
+		doc.Tracks = append(doc.Tracks, track)
+	}
+	return doc, nil
+}
+
+// parse returns the position, elevation and time of the point, with a NaN
+// elevation when it has none.
+func (p *pointXML) parse() (gogis.Point, float64, time.Time, error) {
+	lat, err := strconv.ParseFloat(strings.TrimSpace(p.Lat), 64)
+	if err != nil {
+		return gogis.Point{}, 0, time.Time{}, fmt.Errorf("invalid lat %q", p.Lat)
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(p.Lon), 64)
+	if err != nil {
+		return gogis.Point{}, 0, time.Time{}, fmt.Errorf("invalid lon %q", p.Lon)
+	}
+	ele := math.NaN()
+	if s := strings.TrimSpace(p.Ele); s != "" {
+		if ele, err = strconv.ParseFloat(s, 64); err != nil {
+			return gogis.Point{}, 0, time.Time{}, fmt.Errorf("invalid ele %q", p.Ele)
+		}
+	}
+	t, err := parseTime(p.Time)
+	if err != nil {
+		return gogis.Point{}, 0, time.Time{}, err
+	}
+	return gogis.Point{Lng: lon, Lat: lat}, ele, t, nil
+}
+
+// path converts route or track points. The elevation and time slices are
+// only kept when at least one point has a value.
+func path(points []pointXML) (Path, error) {
+	p := Path{Line: gogis.LineString{Points: make([]gogis.Point, len(points))}}
+	elevations := make([]float64, len(points))
+	times := make([]time.Time, len(points))
+	hasElevation, hasTime := false, false
+	for i := range points {
+		var err error
+		if p.Line.Points[i], elevations[i], times[i], err = points[i].parse(); err != nil {
+			return Path{}, fmt.Errorf("point %d: %w", i+1, err)
+		}
+		hasElevation = hasElevation || !math.IsNaN(elevations[i])
+		hasTime = hasTime || !times[i].IsZero()
+	}
+	if hasElevation {
+		p.Elevations = elevations
+	}
+	if hasTime {
+		p.Times = times
+	}
+	return p, nil
+}
+
+// timeLayouts are the xsd:dateTime forms found in GPX files. Times without
+// a zone are taken as UTC.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999"}
+
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+func parseNumber(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return n, nil
+}
+
+func links(x []linkXML) []Link {
+	if len(x) == 0 {
+		return nil
+	}
+	l := make([]Link, len(x))
+	for i, link := range x {
+		l[i] = Link{Href: link.Href, Text: link.Text}
+	}
+	return l
+}
+
+// Encode writes the document as GPX 1.1.
+func Encode(w io.Writer, doc *Document) error {
+	x, err := doc.xml()
+	if err != nil {
+		return fmt.Errorf("gpx: %w", err)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("gpx: %w", err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(x); err != nil {
+		return fmt.Errorf("gpx: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("gpx: %w", err)
+	}
+	return nil
+}
+
+func (doc *Document) xml() (*gpxXML, error) {
+	x := &gpxXML{Version: "1.1", Creator: doc.Creator, Xmlns: Namespace}
+	if x.Creator == "" {
+		x.Creator = "gogis"
+	}
+	if m := doc.Metadata; !m.isZero() {
+		x.Metadata = &metadataXML{Name: m.Name, Desc: m.Description, Links: linksXML(m.Links), Time: formatTime(m.Time), Keywords: m.Keywords}
+		if m.Author != "" {
+			x.Metadata.Author = &personXML{Name: m.Author}
+		}
+	}
+
+	for i, w := range doc.Waypoints {
+		ele := math.NaN()
+		if w.Elevation != nil {
+			ele = *w.Elevation
+		}
+		p, err := pointToXML(w.Point, ele, w.Time)
+		if err != nil {
+			return nil, fmt.Errorf("waypoint %d: %w", i+1, err)
+		}
+		p.Name, p.Cmt, p.Desc, p.Src, p.Links, p.Sym, p.Type = w.Name, w.Comment, w.Description, w.Source, linksXML(w.Links), w.Symbol, w.Type
+		x.Waypoints = append(x.Waypoints, p)
+	}
+
+	for i, r := range doc.Routes {
+		points, err := r.Path.xml()
+		if err != nil {
+			return nil, fmt.Errorf("route %d: %w", i+1, err)
+		}
+		x.Routes = append(x.Routes, routeXML{
+			Name: r.Name, Cmt: r.Comment, Desc: r.Description, Src: r.Source, Links: linksXML(r.Links),
+			Number: formatNumber(r.Number), Type: r.Type, Points: points,
+		})
+	}
+
+	for i, t := range doc.Tracks {
+		trk := trackXML{
+			Name: t.Name, Cmt: t.Comment, Desc: t.Description, Src: t.Source, Links: linksXML(t.Links),
+			Number: formatNumber(t.Number), Type: t.Type,
+		}
+		for _, seg := range t.Segments {
+			points, err := seg.xml()
+			if err != nil {
+				return nil, fmt.Errorf("track %d: %w", i+1, err)
+			}
+			trk.Segments = append(trk.Segments, segmentXML{Points: points})
+		}
+		x.Tracks = append(x.Tracks, trk)
+	}
+	return x, nil
+}
+
+func (m *Metadata) isZero() bool {
+	return m.Name == "" && m.Description == "" && m.Author == "" && len(m.Links) == 0 && m.Time.IsZero() && m.Keywords == ""
+}
+
+func (p *Path) xml() ([]pointXML, error) {
+	n := len(p.Line.Points)
+	if p.Elevations != nil && len(p.Elevations) != n {
+		return nil, fmt.Errorf("%d elevations for %d points", len(p.Elevations), n)
+	}
+	if p.Times != nil && len(p.Times) != n {
+		return nil, fmt.Errorf("%d times for %d points", len(p.Times), n)
+	}
+	points := make([]pointXML, n)
+	for i, pt := range p.Line.Points {
+		ele := math.NaN()
+		if p.Elevations != nil {
+			ele = p.Elevations[i]
+		}
+		var t time.Time
+		if p.Times != nil {
+			t = p.Times[i]
+		}
+		var err error
+		if points[i], err = pointToXML(pt, ele, t); err != nil {
+			return nil, fmt.Errorf("point %d: %w", i+1, err)
+		}
+	}
+	return points, nil
+}
+
+// pointToXML converts a position, skipping NaN elevations and zero times.
+func pointToXML(p gogis.Point, ele float64, t time.Time) (pointXML, error) {
+	for _, v := range []float64{p.Lng, p.Lat} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return pointXML{}, fmt.Errorf("invalid coordinate %v", v)
+		}
+	}
+	x := pointXML{Lat: formatFloat(p.Lat), Lon: formatFloat(p.Lng), Time: formatTime(t)}
+	if !math.IsNaN(ele) {
+		if math.IsInf(ele, 0) {
+			return pointXML{}, fmt.Errorf("invalid elevation %v", ele)
+		}
+		x.Ele = formatFloat(ele)
+	}
+	return x, nil
+}
+
+// formatFloat formats v as an xsd:decimal, which has no exponent.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func formatNumber(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func linksXML(l []Link) []linkXML {
+	if len(l) == 0 {
+		return nil
+	}
+	x := make([]linkXML, len(l))
+	for i, link := range l {
+		x[i] = linkXML{Href: link.Href, Text: link.Text}
+	}
+	return x
+}
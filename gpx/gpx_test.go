@@ -0,0 +1,197 @@
+package gpx_test
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/gpx"
+)
+
+const ride = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="Bike Computer" xmlns="http://www.topografix.com/GPX/1/1">
+  <metadata>
+    <name>Morning ride</name>
+    <author><name>Ana</name></author>
+    <link href="https://example.com/rides/1"><text>Ride 1</text></link>
+    <time>2024-05-01T06:00:00Z</time>
+  </metadata>
+  <wpt lat="47.3769" lon="8.5417">
+    <ele>408</ele>
+    <name>Start</name>
+    <sym>Flag</sym>
+  </wpt>
+  <rte>
+    <name>Planned</name>
+    <number>2</number>
+    <rtept lat="47.3769" lon="8.5417"><name>A</name></rtept>
+    <rtept lat="47.38" lon="8.55"/>
+  </rte>
+  <trk>
+    <name>Recorded</name>
+    <type>cycling</type>
+    <trkseg>
+      <trkpt lat="47.3769" lon="8.5417"><ele>408.2</ele><time>2024-05-01T06:00:00Z</time></trkpt>
+      <trkpt lat="47.3772" lon="8.5425"><time>2024-05-01T06:00:05.5Z</time></trkpt>
+    </trkseg>
+    <trkseg>
+      <trkpt lat="47.38" lon="8.55"/>
+    </trkseg>
+  </trk>
+</gpx>`
+
+func TestDecode(t *testing.T) {
+	doc, err := gpx.Decode(strings.NewReader(ride))
+	if err != nil {
+		t.Fatalf("Decode() unexpected error = %v", err)
+	}
+	start := time.Date(2024, 5, 1, 6, 0, 0, 0, time.UTC)
+	ele := 408.0
+
+	if doc.Creator != "Bike Computer" {
+		t.Errorf("Creator = %q", doc.Creator)
+	}
+	wantMeta := gpx.Metadata{
+		Name:   "Morning ride",
+		Author: "Ana",
+		Links:  []gpx.Link{{Href: "https://example.com/rides/1", Text: "Ride 1"}},
+		Time:   start,
+	}
+	if !reflect.DeepEqual(doc.Metadata, wantMeta) {
+		t.Errorf("Metadata = %+v, want %+v", doc.Metadata, wantMeta)
+	}
+	wantWpt := []gpx.Waypoint{{Point: gogis.Point{Lng: 8.5417, Lat: 47.3769}, Elevation: &ele, Name: "Start", Symbol: "Flag"}}
+	if !reflect.DeepEqual(doc.Waypoints, wantWpt) {
+		t.Errorf("Waypoints = %+v, want %+v", doc.Waypoints, wantWpt)
+	}
+	wantRte := []gpx.Route{{
+		Name:   "Planned",
+		Number: 2,
+		Path:   gpx.Path{Line: gogis.LineString{Points: []gogis.Point{{Lng: 8.5417, Lat: 47.3769}, {Lng: 8.55, Lat: 47.38}}}},
+	}}
+	if !reflect.DeepEqual(doc.Routes, wantRte) {
+		t.Errorf("Routes = %+v, want %+v", doc.Routes, wantRte)
+	}
+
+	if len(doc.Tracks) != 1 || len(doc.Tracks[0].Segments) != 2 {
+		t.Fatalf("Tracks = %+v", doc.Tracks)
+	}
+	trk := doc.Tracks[0]
+	if trk.Name != "Recorded" || trk.Type != "cycling" {
+		t.Errorf("track = %q %q", trk.Name, trk.Type)
+	}
+	seg := trk.Segments[0]
+	if len(seg.Elevations) != 2 || seg.Elevations[0] != 408.2 || !math.IsNaN(seg.Elevations[1]) {
+		t.Errorf("Elevations = %v, want [408.2 NaN]", seg.Elevations)
+	}
+	wantTimes := []time.Time{start, start.Add(5500 * time.Millisecond)}
+	if !reflect.DeepEqual(seg.Times, wantTimes) {
+		t.Errorf("Times = %v, want %v", seg.Times, wantTimes)
+	}
+	if last := trk.Segments[1]; last.Elevations != nil || last.Times != nil {
+		t.Errorf("segment without elevation and time = %+v", last)
+	}
+	wantGeom := &gogis.GeometryCollection{Geometries: []gogis.Geometry{
+		&gogis.LineString{Points: []gogis.Point{{Lng: 8.5417, Lat: 47.3769}, {Lng: 8.5425, Lat: 47.3772}}},
+		&gogis.LineString{Points: []gogis.Point{{Lng: 8.55, Lat: 47.38}}},
+	}}
+	if got := trk.Geometry(); !reflect.DeepEqual(got, wantGeom) {
+		t.Errorf("Geometry() = %v, want %v", got, wantGeom)
+	}
+}
+
+func TestDecodeGPX10(t *testing.T) {
+	doc, err := gpx.Decode(strings.NewReader(`<gpx version="1.0" creator="old" xmlns="http://www.topografix.com/GPX/1/0">
+<name>Legacy</name><author>Bo</author><time>2003-02-01T10:00:00</time>
+<trk><trkseg><trkpt lat="1" lon="2"/><trkpt lat="3" lon="4"/></trkseg></trk></gpx>`))
+	if err != nil {
+		t.Fatalf("Decode() unexpected error = %v", err)
+	}
+	want := gpx.Metadata{Name: "Legacy", Author: "Bo", Time: time.Date(2003, 2, 1, 10, 0, 0, 0, time.UTC)}
+	if !reflect.DeepEqual(doc.Metadata, want) {
+		t.Errorf("Metadata = %+v, want %+v", doc.Metadata, want)
+	}
+	line := &gogis.LineString{Points: []gogis.Point{{Lng: 2, Lat: 1}, {Lng: 4, Lat: 3}}}
+	if got := doc.Tracks[0].Geometry(); !reflect.DeepEqual(got, line) {
+		t.Errorf("Geometry() = %v, want %v", got, line)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		gpx  string
+	}{
+		{name: "invalid xml", gpx: `<gpx><wpt lat="1" lon="2"></gpx>`},
+		{name: "not gpx", gpx: `<kml></kml>`},
+		{name: "missing lon", gpx: `<gpx><wpt lat="1"/></gpx>`},
+		{name: "bad elevation", gpx: `<gpx><trk><trkseg><trkpt lat="1" lon="2"><ele>high</ele></trkpt></trkseg></trk></gpx>`},
+		{name: "bad time", gpx: `<gpx><rte><rtept lat="1" lon="2"><time>yesterday</time></rtept></rte></gpx>`},
+		{name: "bad number", gpx: `<gpx><trk><number>one</number></trk></gpx>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := gpx.Decode(strings.NewReader(tt.gpx)); err == nil {
+				t.Error("Decode() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	start := time.Date(2024, 5, 1, 6, 0, 0, 0, time.UTC)
+	ele := -1.5
+	doc := &gpx.Document{
+		Creator:  "fleet",
+		Metadata: gpx.Metadata{Name: "Shift <1>", Time: start, Keywords: "delivery"},
+		Waypoints: []gpx.Waypoint{
+			{Point: gogis.Point{Lng: 1e-7, Lat: 2}, Elevation: &ele, Time: start, Name: "Depot", Links: []gpx.Link{{Href: "https://example.com"}}},
+		},
+		Routes: []gpx.Route{{Name: "plan", Path: gpx.Path{Line: gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}}}}}},
+		Tracks: []gpx.Track{{
+			Name:   "driven",
+			Number: 7,
+			Segments: []gpx.Path{{
+				Line:       gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}}},
+				Elevations: []float64{10, 12.25},
+				Times:      []time.Time{start, start.Add(time.Minute)},
+			}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := gpx.Encode(&buf, doc); err != nil {
+		t.Fatalf("Encode() unexpected error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{`xmlns="http://www.topografix.com/GPX/1/1"`, `lat="2" lon="0.0000001"`, `<time>2024-05-01T06:01:00Z</time>`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Encode() output lacks %s:\n%s", want, out)
+		}
+	}
+
+	got, err := gpx.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(got, doc) {
+		t.Errorf("Decode(Encode()) = %+v, want %+v", got, doc)
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	line := gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}}}
+	for _, doc := range []*gpx.Document{
+		{Waypoints: []gpx.Waypoint{{Point: gogis.Point{Lng: math.NaN()}}}},
+		{Routes: []gpx.Route{{Path: gpx.Path{Line: line, Elevations: []float64{1, 2}}}}},
+		{Tracks: []gpx.Track{{Segments: []gpx.Path{{Line: line, Times: []time.Time{}}}}}},
+	} {
+		if err := gpx.Encode(&bytes.Buffer{}, doc); err == nil {
+			t.Errorf("Encode(%+v) expected error, got nil", doc)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+// Package pbf implements the subset of the Protocol Buffers wire format used
+// by the vector tile and Geobuf encoders, without generated code or external
+// dependencies.
+package pbf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Wire types.
+const (
+	Varint  = 0
+	Fixed64 = 1
+	Bytes   = 2
+	Fixed32 = 5
+)
+
+// ErrTruncated is returned when a message ends in the middle of a field.
+var ErrTruncated = errors.New("pbf: truncated message")
+
+// Writer appends protobuf encoded fields to a byte slice.
+type Writer struct {
+	buf []byte
+}
+
+// Bytes returns the encoded message.
+func (w *Writer) Bytes() []byte {
+	return w.buf
+}
+
+// Len returns the number of bytes written so far.
+func (w *Writer) Len() int {
+	return len(w.buf)
+}
+
+func (w *Writer) key(field, wireType int) {
+	w.buf = binary.AppendUvarint(w.buf, uint64(field)<<3|uint64(wireType))
+}
+
+// Uint64 writes a varint field.
+func (w *Writer) Uint64(field int, v uint64) {
+	w.key(field, Varint)
+	w.buf = binary.AppendUvarint(w.buf, v)
+}
+
+// Int64 writes an int64 field, which protobuf encodes as a two's complement
+// varint.
+func (w *Writer) Int64(field int, v int64) {
+	w.Uint64(field, uint64(v))
+}
+
+// Sint64 writes a zigzag encoded sint64 field.
+func (w *Writer) Sint64(field int, v int64) {
+	w.Uint64(field, ZigZag(v))
+}
+
+// Bool writes a bool field.
+func (w *Writer) Bool(field int, v bool) {
+	var b uint64
+	if v {
+		b = 1
+	}
+	w.Uint64(field, b)
+}
+
+// Float writes a fixed32 float field.
+func (w *Writer) Float(field int, v float32) {
+	w.key(field, Fixed32)
+	w.buf = binary.LittleEndian.AppendUint32(w.buf, math.Float32bits(v))
+}
+
+// Double writes a fixed64 double field.
+func (w *Writer) Double(field int, v float64) {
+	w.key(field, Fixed64)
+	w.buf = binary.LittleEndian.AppendUint64(w.buf, math.Float64bits(v))
+}
+
+// String writes a string field.
+func (w *Writer) String(field int, s string) {
+	w.key(field, Bytes)
+	w.buf = binary.AppendUvarint(w.buf, uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+// Message writes an embedded message field.
+func (w *Writer) Message(field int, msg []byte) {
+	w.key(field, Bytes)
+	w.buf = binary.AppendUvarint(w.buf, uint64(len(msg)))
+	w.buf = append(w.buf, msg...)
+}
+
+// PackedUint32 writes a packed repeated uint32 field. Empty slices are
+// omitted.
+func (w *Writer) PackedUint32(field int, vs []uint32) {
+	if len(vs) == 0 {
+		return
+	}
+	var packed []byte
+	for _, v := range vs {
+		packed = binary.AppendUvarint(packed, uint64(v))
+	}
+	w.Message(field, packed)
+}
+
+// PackedSint64 writes a packed repeated, zigzag encoded sint64 field. Empty
+// slices are omitted.
+func (w *Writer) PackedSint64(field int, vs []int64) {
+	if len(vs) == 0 {
+		return
+	}
+	var packed []byte
+	for _, v := range vs {
+		packed = binary.AppendUvarint(packed, ZigZag(v))
+	}
+	w.Message(field, packed)
+}
+
+// ZigZag maps signed integers to unsigned ones so that values of small
+// magnitude have small encodings.
+func ZigZag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+// UnZigZag reverses ZigZag.
+func UnZigZag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+// Reader iterates over the fields of an encoded message.
+type Reader struct {
+	buf []byte
+	pos int
+
+	field    int
+	wireType int
+	err      error
+}
+
+// NewReader returns a Reader over msg.
+func NewReader(msg []byte) *Reader {
+	return &Reader{buf: msg}
+}
+
+// Next advances to the next field and reports whether there is one. Callers
+// must consume or Skip the field value before calling Next again.
+func (r *Reader) Next() bool {
+	if r.err != nil || r.pos >= len(r.buf) {
+		return false
+	}
+	key, err := r.uvarint()
+	if err != nil {
+		r.err = err
+		return false
+	}
+	r.field = int(key >> 3)
+	r.wireType = int(key & 7)
+	return true
+}
+
+// Field returns the number of the current field.
+func (r *Reader) Field() int {
+	return r.field
+}
+
+// WireType returns the wire type of the current field.
+func (r *Reader) WireType() int {
+	return r.wireType
+}
+
+// Err returns the first error encountered while reading.
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// Uint64 reads a varint value.
+func (r *Reader) Uint64() uint64 {
+	v, err := r.uvarint()
+	r.setErr(err)
+	return v
+}
+
+// Int64 reads an int64 value.
+func (r *Reader) Int64() int64 {
+	return int64(r.Uint64())
+}
+
+// Sint64 reads a zigzag encoded sint64 value.
+func (r *Reader) Sint64() int64 {
+	return UnZigZag(r.Uint64())
+}
+
+// Bool reads a bool value.
+func (r *Reader) Bool() bool {
+	return r.Uint64() != 0
+}
+
+// Float reads a fixed32 float value.
+func (r *Reader) Float() float32 {
+	b := r.take(4)
+	if b == nil {
+		return 0
+	}
+	return math.Float32frombits(binary.LittleEndian.Uint32(b))
+}
+
+// Double reads a fixed64 double value.
+func (r *Reader) Double() float64 {
+	b := r.take(8)
+	if b == nil {
+		return 0
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b))
+}
+
+// Bytes reads a length-delimited value. The returned slice aliases the
+// message buffer.
+func (r *Reader) Bytes() []byte {
+	n, err := r.uvarint()
+	if err != nil {
+		r.setErr(err)
+		return nil
+	}
+	return r.take(int(n))
+}
+
+// String reads a length-delimited value as a string.
+func (r *Reader) String() string {
+	return string(r.Bytes())
+}
+
+// PackedUint32 reads a packed repeated uint32 field. A single unpacked value
+// is also accepted, as required by the protobuf specification.
+func (r *Reader) PackedUint32() []uint32 {
+	if r.wireType == Varint {
+		return []uint32{uint32(r.Uint64())}
+	}
+	inner := NewReader(r.Bytes())
+	var vs []uint32
+	for inner.pos < len(inner.buf) {
+		v, err := inner.uvarint()
+		if err != nil {
+			r.setErr(err)
+			return nil
+		}
+		vs = append(vs, uint32(v))
+	}
+	return vs
+}
+
+// PackedSint64 reads a packed repeated sint64 field. A single unpacked value
+// is also accepted.
+func (r *Reader) PackedSint64() []int64 {
+	if r.wireType == Varint {
+		return []int64{r.Sint64()}
+	}
+	inner := NewReader(r.Bytes())
+	var vs []int64
+	for inner.pos < len(inner.buf) {
+		v, err := inner.uvarint()
+		if err != nil {
+			r.setErr(err)
+			return nil
+		}
+		vs = append(vs, UnZigZag(v))
+	}
+	return vs
+}
+
+// Skip discards the value of the current field.
+func (r *Reader) Skip() {
+	switch r.wireType {
+	case Varint:
+		r.Uint64()
+	case Fixed64:
+		r.take(8)
+	case Bytes:
+		r.Bytes()
+	case Fixed32:
+		r.take(4)
+	default:
+		r.setErr(fmt.Errorf("pbf: unsupported wire type %d", r.wireType))
+	}
+}
+
+func (r *Reader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		return 0, ErrTruncated
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *Reader) take(n int) []byte {
+	if n < 0 || r.pos+n > len(r.buf) {
+		r.setErr(ErrTruncated)
+		return nil
+	}
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *Reader) setErr(err error) {
+	if r.err == nil && err != nil {
+		r.err = err
+	}
+}
@@ -0,0 +1,117 @@
+package pbf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestZigZag(t *testing.T) {
+	tests := []struct {
+		in   int64
+		want uint64
+	}{
+		{0, 0},
+		{-1, 1},
+		{1, 2},
+		{-2, 3},
+		{2147483647, 4294967294},
+		{-2147483648, 4294967295},
+	}
+	for _, tt := range tests {
+		if got := ZigZag(tt.in); got != tt.want {
+			t.Errorf("ZigZag(%d) = %d, want %d", tt.in, got, tt.want)
+		}
+		if got := UnZigZag(tt.want); got != tt.in {
+			t.Errorf("UnZigZag(%d) = %d, want %d", tt.want, got, tt.in)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	var inner Writer
+	inner.String(1, "nested")
+
+	var w Writer
+	w.Uint64(1, 300)
+	w.Int64(2, -5)
+	w.Sint64(3, -5)
+	w.Bool(4, true)
+	w.Float(5, 1.5)
+	w.Double(6, -2.25)
+	w.String(7, "hello")
+	w.Message(8, inner.Bytes())
+	w.PackedUint32(9, []uint32{9, 1, 300})
+	w.PackedSint64(10, []int64{-1, 0, 1})
+	w.PackedUint32(11, nil)
+
+	r := NewReader(w.Bytes())
+	seen := map[int]bool{}
+	for r.Next() {
+		seen[r.Field()] = true
+		switch r.Field() {
+		case 1:
+			if v := r.Uint64(); v != 300 {
+				t.Errorf("Uint64() = %d, want 300", v)
+			}
+		case 2:
+			if v := r.Int64(); v != -5 {
+				t.Errorf("Int64() = %d, want -5", v)
+			}
+		case 3:
+			if v := r.Sint64(); v != -5 {
+				t.Errorf("Sint64() = %d, want -5", v)
+			}
+		case 4:
+			if !r.Bool() {
+				t.Errorf("Bool() = false, want true")
+			}
+		case 5:
+			if v := r.Float(); v != 1.5 {
+				t.Errorf("Float() = %v, want 1.5", v)
+			}
+		case 6:
+			if v := r.Double(); v != -2.25 {
+				t.Errorf("Double() = %v, want -2.25", v)
+			}
+		case 7:
+			if v := r.String(); v != "hello" {
+				t.Errorf("String() = %q, want hello", v)
+			}
+		case 8:
+			nested := NewReader(r.Bytes())
+			if !nested.Next() || nested.String() != "nested" {
+				t.Errorf("nested message not decoded")
+			}
+		case 9:
+			if v := r.PackedUint32(); !reflect.DeepEqual(v, []uint32{9, 1, 300}) {
+				t.Errorf("PackedUint32() = %v", v)
+			}
+		case 10:
+			if v := r.PackedSint64(); !reflect.DeepEqual(v, []int64{-1, 0, 1}) {
+				t.Errorf("PackedSint64() = %v", v)
+			}
+		default:
+			r.Skip()
+		}
+	}
+	if err := r.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+	if len(seen) != 10 || seen[11] {
+		t.Errorf("fields seen = %v, want 1 to 10", seen)
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	var w Writer
+	w.String(1, "hello")
+	msg := w.Bytes()[:4]
+
+	r := NewReader(msg)
+	for r.Next() {
+		r.Skip()
+	}
+	if r.Err() != ErrTruncated {
+		t.Errorf("Err() = %v, want ErrTruncated", r.Err())
+	}
+}
@@ -0,0 +1,465 @@
+// Package kml reads and writes gogis geometries as KML 2.2, the format of
+// Google Earth, and its zipped KMZ form.
+//
+// Decode returns the Placemarks of a document, wherever they are nested in
+// Documents and Folders, and Encode writes Placemarks into a Document:
+//
+//	placemarks, err := kml.Decode(file)
+//	if err != nil {
+//	    return err
+//	}
+//	for _, pm := range placemarks {
+//	    fmt.Println(pm.Name, pm.Geometry, pm.Properties["owner"])
+//	}
+//
+// Geometries map to gogis types as follows. Coordinates are longitude and
+// latitude in EPSG:4326, and altitudes are dropped.
+//
+//	Point          *gogis.Point
+//	LineString     *gogis.LineString
+//	LinearRing     *gogis.LineString
+//	Polygon        *gogis.Polygon, outerBoundaryIs first
+//	MultiGeometry  *gogis.GeometryCollection
+//
+// Styles, views and geometries of other kinds, such as models and tracks,
+// are skipped: Placemarks holding them have a nil geometry.
+package kml
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/restayway/gogis"
+)
+
+// maxDepth limits the nesting of MultiGeometry elements.
+const maxDepth = 32
+
+// Namespace is the XML namespace of KML 2.2.
+const Namespace = "http://www.opengis.net/kml/2.2"
+
+// Placemark is a KML feature with a geometry.
+type Placemark struct {
+	ID          string         // id attribute, optional
+	Name        string         // name element
+	Description string         // description element, often HTML
+	Geometry    gogis.Geometry // geometry, nil for Placemarks without one
+
+	// Properties are the ExtendedData values by name, from Data elements
+	// and from the SimpleData elements of SchemaData. Encode writes them as
+	// Data elements in key order.
+	Properties map[string]string
+}
+
+// Decode reads the Placemarks of a KML document in document order.
+func Decode(r io.Reader) ([]Placemark, error) {
+	d := xml.NewDecoder(r)
+	var placemarks []Placemark
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return placemarks, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("kml: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "Placemark" {
+			continue
+		}
+		pm, err := decodePlacemark(d, start)
+		if err != nil {
+			return nil, fmt.Errorf("kml: Placemark %d: %w", len(placemarks)+1, err)
+		}
+		placemarks = append(placemarks, pm)
+	}
+}
+
+func decodePlacemark(d *xml.Decoder, start xml.StartElement) (Placemark, error) {
+	pm := Placemark{ID: attr(start, "id")}
+	err := children(d, func(child xml.StartElement) error {
+		var err error
+		switch child.Name.Local {
+		case "name":
+			pm.Name, err = text(d, child)
+		case "description":
+			pm.Description, err = text(d, child)
+		case "ExtendedData":
+			if pm.Properties == nil {
+				pm.Properties = make(map[string]string)
+			}
+			err = decodeExtendedData(d, pm.Properties)
+		case "Point", "LineString", "LinearRing", "Polygon", "MultiGeometry":
+			pm.Geometry, err = decodeGeometry(d, child, 0)
+		default:
+			err = d.Skip()
+		}
+		return err
+	})
+	return pm, err
+}
+
+func decodeExtendedData(d *xml.Decoder, props map[string]string) error {
+	return children(d, func(child xml.StartElement) error {
+		switch child.Name.Local {
+		case "Data":
+			name := attr(child, "name")
+			return children(d, func(elem xml.StartElement) error {
+				if elem.Name.Local != "value" {
+					return d.Skip()
+				}
+				v, err := text(d, elem)
+				props[name] = v
+				return err
+			})
+		case "SchemaData":
+			return children(d, func(elem xml.StartElement) error {
+				if elem.Name.Local != "SimpleData" {
+					return d.Skip()
+				}
+				v, err := text(d, elem)
+				props[attr(elem, "name")] = v
+				return err
+			})
+		}
+		return d.Skip()
+	})
+}
+
+// decodeGeometry decodes the geometry element start.
+func decodeGeometry(d *xml.Decoder, start xml.StartElement, depth int) (gogis.Geometry, error) {
+	switch start.Name.Local {
+	case "Point":
+		points, err := coordinates(d)
+		if err != nil {
+			return nil, err
+		}
+		if len(points) != 1 {
+			return nil, fmt.Errorf("Point with %d coordinates", len(points))
+		}
+		return &points[0], nil
+	case "LineString", "LinearRing":
+		points, err := coordinates(d)
+		if err != nil {
+			return nil, err
+		}
+		return &gogis.LineString{Points: points}, nil
+	case "Polygon":
+		var outer []gogis.Point
+		var inner [][]gogis.Point
+		hasOuter := false
+		err := children(d, func(child xml.StartElement) error {
+			switch child.Name.Local {
+			case "outerBoundaryIs", "innerBoundaryIs":
+				return children(d, func(elem xml.StartElement) error {
+					if elem.Name.Local != "LinearRing" {
+						return d.Skip()
+					}
+					ring, err := coordinates(d)
+					if err != nil {
+						return err
+					}
+					if child.Name.Local == "innerBoundaryIs" {
+						inner = append(inner, ring)
+					} else {
+						outer, hasOuter = ring, true
+					}
+					return nil
+				})
+			}
+			return d.Skip()
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !hasOuter {
+			return nil, fmt.Errorf("Polygon without outerBoundaryIs")
+		}
+		return &gogis.Polygon{Rings: append([][]gogis.Point{outer}, inner...)}, nil
+	case "MultiGeometry":
+		if depth >= maxDepth {
+			return nil, fmt.Errorf("MultiGeometry nested too deeply")
+		}
+		gc := &gogis.GeometryCollection{}
+		err := children(d, func(child xml.StartElement) error {
+			switch child.Name.Local {
+			case "Point", "LineString", "LinearRing", "Polygon", "MultiGeometry":
+				g, err := decodeGeometry(d, child, depth+1)
+				if err != nil {
+					return err
+				}
+				gc.Geometries = append(gc.Geometries, g)
+				return nil
+			}
+			return d.Skip()
+		})
+		if err != nil {
+			return nil, err
+		}
+		return gc, nil
+	}
+	return nil, fmt.Errorf("unsupported geometry %s", start.Name.Local)
+}
+
+// coordinates reads the coordinates child of the current element, skipping
+// its other children.
+func coordinates(d *xml.Decoder) ([]gogis.Point, error) {
+	var points []gogis.Point
+	err := children(d, func(child xml.StartElement) error {
+		if child.Name.Local != "coordinates" {
+			return d.Skip()
+		}
+		s, err := text(d, child)
+		if err != nil {
+			return err
+		}
+		points, err = parseCoordinates(s)
+		return err
+	})
+	return points, err
+}
+
+// parseCoordinates parses whitespace separated lng,lat[,alt] tuples.
+func parseCoordinates(s string) ([]gogis.Point, error) {
+	tuples := strings.Fields(s)
+	points := make([]gogis.Point, len(tuples))
+	for i, tuple := range tuples {
+		values := strings.Split(tuple, ",")
+		if len(values) != 2 && len(values) != 3 {
+			return nil, fmt.Errorf("invalid coordinate tuple %q", tuple)
+		}
+		lng, err := strconv.ParseFloat(values[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid coordinate tuple %q", tuple)
+		}
+		lat, err := strconv.ParseFloat(values[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid coordinate tuple %q", tuple)
+		}
+		points[i] = gogis.Point{Lng: lng, Lat: lat}
+	}
+	return points, nil
+}
+
+// children calls fn for each child element of the current element, up to
+// its end. fn must consume the child up to its end element.
+func children(d *xml.Decoder, fn func(xml.StartElement) error) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if err := fn(t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// text returns the character data of element start, trimmed of surrounding
+// whitespace.
+func text(d *xml.Decoder, start xml.StartElement) (string, error) {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(s), nil
+}
+
+func attr(start xml.StartElement, name string) string {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// Encode writes the Placemarks as a KML document.
+func Encode(w io.Writer, placemarks []Placemark) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+	bw.WriteString(`<kml xmlns="` + Namespace + `">` + "\n<Document>\n")
+	var buf bytes.Buffer
+	for i, pm := range placemarks {
+		buf.Reset()
+		if err := writePlacemark(&buf, pm); err != nil {
+			return fmt.Errorf("kml: Placemark %d: %w", i+1, err)
+		}
+		buf.WriteByte('\n')
+		bw.Write(buf.Bytes())
+	}
+	bw.WriteString("</Document>\n</kml>\n")
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("kml: %w", err)
+	}
+	return nil
+}
+
+func writePlacemark(buf *bytes.Buffer, pm Placemark) error {
+	buf.WriteString("<Placemark")
+	if pm.ID != "" {
+		buf.WriteString(` id="`)
+		escape(buf, pm.ID)
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('>')
+	if pm.Name != "" {
+		buf.WriteString("<name>")
+		escape(buf, pm.Name)
+		buf.WriteString("</name>")
+	}
+	if pm.Description != "" {
+		buf.WriteString("<description>")
+		escape(buf, pm.Description)
+		buf.WriteString("</description>")
+	}
+	if len(pm.Properties) > 0 {
+		keys := make([]string, 0, len(pm.Properties))
+		for k := range pm.Properties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf.WriteString("<ExtendedData>")
+		for _, k := range keys {
+			buf.WriteString(`<Data name="`)
+			escape(buf, k)
+			buf.WriteString(`"><value>`)
+			escape(buf, pm.Properties[k])
+			buf.WriteString("</value></Data>")
+		}
+		buf.WriteString("</ExtendedData>")
+	}
+	if pm.Geometry != nil {
+		if err := writeGeometry(buf, pm.Geometry); err != nil {
+			return err
+		}
+	}
+	buf.WriteString("</Placemark>")
+	return nil
+}
+
+func escape(buf *bytes.Buffer, s string) {
+	// EscapeText only fails when the writer does.
+	_ = xml.EscapeText(buf, []byte(s))
+}
+
+// MarshalGeometry encodes g as a KML geometry element. Point, LineString,
+// Polygon, GeometryCollection and their geography variants are supported.
+func MarshalGeometry(g gogis.Geometry) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeGeometry(&buf, g); err != nil {
+		return nil, fmt.Errorf("kml: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func writeGeometry(buf *bytes.Buffer, g gogis.Geometry) error {
+	switch v := g.(type) {
+	case *gogis.Point:
+		buf.WriteString("<Point>")
+		if err := writeCoordinates(buf, []gogis.Point{*v}); err != nil {
+			return err
+		}
+		buf.WriteString("</Point>")
+	case *gogis.LineString:
+		buf.WriteString("<LineString>")
+		if err := writeCoordinates(buf, v.Points); err != nil {
+			return err
+		}
+		buf.WriteString("</LineString>")
+	case *gogis.Polygon:
+		if len(v.Rings) == 0 {
+			return fmt.Errorf("empty Polygon")
+		}
+		buf.WriteString("<Polygon>")
+		for i, ring := range v.Rings {
+			boundary := "innerBoundaryIs"
+			if i == 0 {
+				boundary = "outerBoundaryIs"
+			}
+			buf.WriteString("<" + boundary + "><LinearRing>")
+			if err := writeCoordinates(buf, ring); err != nil {
+				return err
+			}
+			buf.WriteString("</LinearRing></" + boundary + ">")
+		}
+		buf.WriteString("</Polygon>")
+	case *gogis.GeometryCollection:
+		buf.WriteString("<MultiGeometry>")
+		for _, child := range v.Geometries {
+			if err := writeGeometry(buf, child); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("</MultiGeometry>")
+	case *gogis.GeographyPoint:
+		return writeGeometry(buf, (*gogis.Point)(v))
+	case *gogis.GeographyLineString:
+		return writeGeometry(buf, (*gogis.LineString)(v))
+	case *gogis.GeographyPolygon:
+		return writeGeometry(buf, (*gogis.Polygon)(v))
+	case *gogis.GeographyCollection:
+		return writeGeometry(buf, (*gogis.GeometryCollection)(v))
+	default:
+		return fmt.Errorf("unsupported geometry type %T", g)
+	}
+	return nil
+}
+
+func writeCoordinates(buf *bytes.Buffer, points []gogis.Point) error {
+	var scratch [64]byte
+	buf.WriteString("<coordinates>")
+	for i, p := range points {
+		for _, v := range []float64{p.Lng, p.Lat} {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("invalid coordinate %v", v)
+			}
+		}
+		b := scratch[:0]
+		if i > 0 {
+			b = append(b, ' ')
+		}
+		b = strconv.AppendFloat(b, p.Lng, 'f', -1, 64)
+		b = append(b, ',')
+		b = strconv.AppendFloat(b, p.Lat, 'f', -1, 64)
+		buf.Write(b)
+	}
+	buf.WriteString("</coordinates>")
+	return nil
+}
+
+// UnmarshalGeometry decodes a KML geometry element.
+func UnmarshalGeometry(data []byte) (gogis.Geometry, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("kml: %w", err)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			g, err := decodeGeometry(d, start, 0)
+			if err != nil {
+				return nil, fmt.Errorf("kml: %w", err)
+			}
+			return g, nil
+		}
+	}
+}
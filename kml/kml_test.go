@@ -0,0 +1,216 @@
+package kml_test
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/kml"
+)
+
+func TestMarshalGeometry(t *testing.T) {
+	ring := []gogis.Point{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 0}, {Lng: 1, Lat: 1}, {Lng: 0, Lat: 0}}
+	hole := []gogis.Point{{Lng: 0.2, Lat: 0.1}, {Lng: 0.8, Lat: 0.1}, {Lng: 0.8, Lat: 0.7}, {Lng: 0.2, Lat: 0.1}}
+	tests := []struct {
+		name string
+		geom gogis.Geometry
+		want string
+	}{
+		{
+			name: "point",
+			geom: &gogis.Point{Lng: -122.0822035425683, Lat: 37.42228990140251},
+			want: `<Point><coordinates>-122.0822035425683,37.42228990140251</coordinates></Point>`,
+		},
+		{
+			name: "linestring",
+			geom: &gogis.LineString{Points: ring[:2]},
+			want: `<LineString><coordinates>0,0 1,0</coordinates></LineString>`,
+		},
+		{
+			name: "polygon",
+			geom: &gogis.Polygon{Rings: [][]gogis.Point{ring, hole}},
+			want: `<Polygon><outerBoundaryIs><LinearRing><coordinates>0,0 1,0 1,1 0,0</coordinates></LinearRing></outerBoundaryIs>` +
+				`<innerBoundaryIs><LinearRing><coordinates>0.2,0.1 0.8,0.1 0.8,0.7 0.2,0.1</coordinates></LinearRing></innerBoundaryIs></Polygon>`,
+		},
+		{
+			name: "collection",
+			geom: &gogis.GeographyCollection{Geometries: []gogis.Geometry{&ring[1], &gogis.GeometryCollection{}}},
+			want: `<MultiGeometry><Point><coordinates>1,0</coordinates></Point><MultiGeometry></MultiGeometry></MultiGeometry>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := kml.MarshalGeometry(tt.geom)
+			if err != nil {
+				t.Fatalf("MarshalGeometry() unexpected error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("MarshalGeometry() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	for _, geom := range []gogis.Geometry{&gogis.Polygon{}, &gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: math.Inf(1)}}}} {
+		if _, err := kml.MarshalGeometry(geom); err == nil {
+			t.Errorf("MarshalGeometry(%v) expected error, got nil", geom)
+		}
+	}
+}
+
+func TestUnmarshalGeometry(t *testing.T) {
+	tests := []struct {
+		name string
+		kml  string
+		want gogis.Geometry
+	}{
+		{
+			name: "point with altitude",
+			kml:  `<Point><extrude>1</extrude><coordinates> 1.5,2,100 </coordinates></Point>`,
+			want: &gogis.Point{Lng: 1.5, Lat: 2},
+		},
+		{
+			name: "linestring over lines",
+			kml:  "<LineString><tessellate>1</tessellate><coordinates>\n  1,2,0\n  3,4,0\n</coordinates></LineString>",
+			want: &gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}}},
+		},
+		{
+			name: "polygon with holes",
+			kml: `<Polygon><innerBoundaryIs><LinearRing><coordinates>1,1 2,1 2,2 1,1</coordinates></LinearRing>` +
+				`<LinearRing><coordinates>3,3 4,3 4,4 3,3</coordinates></LinearRing></innerBoundaryIs>` +
+				`<outerBoundaryIs><LinearRing><coordinates>0,0 9,0 9,9 0,0</coordinates></LinearRing></outerBoundaryIs></Polygon>`,
+			want: &gogis.Polygon{Rings: [][]gogis.Point{
+				{{Lng: 0, Lat: 0}, {Lng: 9, Lat: 0}, {Lng: 9, Lat: 9}, {Lng: 0, Lat: 0}},
+				{{Lng: 1, Lat: 1}, {Lng: 2, Lat: 1}, {Lng: 2, Lat: 2}, {Lng: 1, Lat: 1}},
+				{{Lng: 3, Lat: 3}, {Lng: 4, Lat: 3}, {Lng: 4, Lat: 4}, {Lng: 3, Lat: 3}},
+			}},
+		},
+		{
+			name: "multigeometry",
+			kml: `<kml:MultiGeometry xmlns:kml="http://www.opengis.net/kml/2.2"><kml:Point><kml:coordinates>1,2</kml:coordinates></kml:Point>` +
+				`<kml:MultiGeometry><kml:LinearRing><kml:coordinates>0,0 1,0 0,0</kml:coordinates></kml:LinearRing></kml:MultiGeometry></kml:MultiGeometry>`,
+			want: &gogis.GeometryCollection{Geometries: []gogis.Geometry{
+				&gogis.Point{Lng: 1, Lat: 2},
+				&gogis.GeometryCollection{Geometries: []gogis.Geometry{
+					&gogis.LineString{Points: []gogis.Point{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 0}, {Lng: 0, Lat: 0}}},
+				}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := kml.UnmarshalGeometry([]byte(tt.kml))
+			if err != nil {
+				t.Fatalf("UnmarshalGeometry() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UnmarshalGeometry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, tt := range []struct {
+		name string
+		kml  string
+	}{
+		{name: "invalid xml", kml: `<Point><coordinates>1,2</Point>`},
+		{name: "truncated", kml: `<Point><coordinates>1,2</coordinates>`},
+		{name: "unknown geometry", kml: `<Model><Location/></Model>`},
+		{name: "bad tuple", kml: `<Point><coordinates>1;2</coordinates></Point>`},
+		{name: "bad number", kml: `<LineString><coordinates>1,2 x,3</coordinates></LineString>`},
+		{name: "point without coordinates", kml: `<Point></Point>`},
+		{name: "polygon without outer", kml: `<Polygon></Polygon>`},
+		{name: "nested too deeply", kml: strings.Repeat("<MultiGeometry>", 40) + strings.Repeat("</MultiGeometry>", 40)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := kml.UnmarshalGeometry([]byte(tt.kml)); err == nil {
+				t.Error("UnmarshalGeometry() expected error, got nil")
+			}
+		})
+	}
+}
+
+const document = `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+  <name>Survey</name>
+  <Style id="red"><LineStyle><color>ff0000ff</color></LineStyle></Style>
+  <Folder>
+    <name>Day 1</name>
+    <Placemark id="p1">
+      <name>Well &amp; pump</name>
+      <description><![CDATA[<b>Checked</b>]]></description>
+      <styleUrl>#red</styleUrl>
+      <ExtendedData>
+        <Data name="owner"><displayName>Owner</displayName><value>Ana</value></Data>
+        <SchemaData schemaUrl="#survey"><SimpleData name="depth">12.5</SimpleData></SchemaData>
+      </ExtendedData>
+      <Point><coordinates>13.4,52.5,34</coordinates></Point>
+    </Placemark>
+  </Folder>
+  <Placemark><name>Camera</name><Model><Location/></Model></Placemark>
+</Document>
+</kml>`
+
+func TestDecode(t *testing.T) {
+	got, err := kml.Decode(strings.NewReader(document))
+	if err != nil {
+		t.Fatalf("Decode() unexpected error = %v", err)
+	}
+	want := []kml.Placemark{
+		{
+			ID:          "p1",
+			Name:        "Well & pump",
+			Description: "<b>Checked</b>",
+			Geometry:    &gogis.Point{Lng: 13.4, Lat: 52.5},
+			Properties:  map[string]string{"owner": "Ana", "depth": "12.5"},
+		},
+		{Name: "Camera"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() = %+v, want %+v", got, want)
+	}
+
+	if _, err := kml.Decode(strings.NewReader(`<kml><Placemark><Point><coordinates>1</coordinates></Point></Placemark></kml>`)); err == nil {
+		t.Error("Decode() of an invalid Placemark expected error, got nil")
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	placemarks := []kml.Placemark{
+		{
+			ID:          "a<1>",
+			Name:        `"Quoted" name`,
+			Description: "<p>HTML</p>",
+			Geometry: &gogis.GeometryCollection{Geometries: []gogis.Geometry{
+				&gogis.Point{Lng: 1, Lat: 2},
+				&gogis.Polygon{Rings: [][]gogis.Point{{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 0}, {Lng: 1, Lat: 1}, {Lng: 0, Lat: 0}}}},
+			}},
+			Properties: map[string]string{"b": "2", "a": "x & y"},
+		},
+		{Name: "no geometry"},
+	}
+
+	var buf bytes.Buffer
+	if err := kml.Encode(&buf, placemarks); err != nil {
+		t.Fatalf("Encode() unexpected error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `<ExtendedData><Data name="a"><value>x &amp; y</value></Data><Data name="b">`) {
+		t.Errorf("Encode() wrote ExtendedData out of order:\n%s", buf.String())
+	}
+	got, err := kml.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(got, placemarks) {
+		t.Errorf("Decode(Encode()) = %+v, want %+v", got, placemarks)
+	}
+
+	if err := kml.Encode(&buf, []kml.Placemark{{Geometry: &gogis.Polygon{}}}); err == nil {
+		t.Error("Encode() of an empty polygon expected error, got nil")
+	}
+}
@@ -0,0 +1,59 @@
+package kml
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// kmzDocument is the name of the KML document written to KMZ archives.
+const kmzDocument = "doc.kml"
+
+// DecodeKMZ reads the Placemarks of a KMZ archive of the given size. Like
+// Google Earth, it reads doc.kml, or the first .kml file at the root of the
+// archive when there is no doc.kml. Other files, such as icons and overlay
+// images, are ignored.
+func DecodeKMZ(r io.ReaderAt, size int64) ([]Placemark, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("kml: %w", err)
+	}
+	var doc *zip.File
+	for _, f := range zr.File {
+		if f.Name == kmzDocument {
+			doc = f
+			break
+		}
+		if doc == nil && !strings.Contains(f.Name, "/") && strings.EqualFold(path.Ext(f.Name), ".kml") {
+			doc = f
+		}
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("kml: no KML document in the KMZ archive")
+	}
+
+	rc, err := doc.Open()
+	if err != nil {
+		return nil, fmt.Errorf("kml: %w", err)
+	}
+	defer rc.Close()
+	return Decode(rc)
+}
+
+// EncodeKMZ writes the Placemarks as a KMZ archive holding a doc.kml file.
+func EncodeKMZ(w io.Writer, placemarks []Placemark) error {
+	zw := zip.NewWriter(w)
+	doc, err := zw.Create(kmzDocument)
+	if err != nil {
+		return fmt.Errorf("kml: %w", err)
+	}
+	if err := Encode(doc, placemarks); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("kml: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package kml_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/kml"
+)
+
+func TestKMZRoundTrip(t *testing.T) {
+	placemarks := []kml.Placemark{{Name: "site", Geometry: &gogis.Point{Lng: 8.5, Lat: 47.4}}}
+	var buf bytes.Buffer
+	if err := kml.EncodeKMZ(&buf, placemarks); err != nil {
+		t.Fatalf("EncodeKMZ() unexpected error = %v", err)
+	}
+	got, err := kml.DecodeKMZ(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("DecodeKMZ() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(got, placemarks) {
+		t.Errorf("DecodeKMZ() = %+v, want %+v", got, placemarks)
+	}
+}
+
+func TestDecodeKMZ(t *testing.T) {
+	archive := func(files map[string]string, order ...string) []byte {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for _, name := range order {
+			w, _ := zw.Create(name)
+			w.Write([]byte(files[name]))
+		}
+		zw.Close()
+		return buf.Bytes()
+	}
+	placemark := func(name string) string {
+		return `<kml><Placemark><name>` + name + `</name></Placemark></kml>`
+	}
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		order []string
+		want  string
+	}{
+		{
+			name:  "doc.kml preferred",
+			files: map[string]string{"a.kml": placemark("a"), "doc.kml": placemark("doc")},
+			order: []string{"a.kml", "doc.kml"},
+			want:  "doc",
+		},
+		{
+			name:  "first root kml",
+			files: map[string]string{"files/x.kml": placemark("x"), "icon.png": "png", "Main.KML": placemark("main")},
+			order: []string{"files/x.kml", "icon.png", "Main.KML"},
+			want:  "main",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := archive(tt.files, tt.order...)
+			got, err := kml.DecodeKMZ(bytes.NewReader(b), int64(len(b)))
+			if err != nil {
+				t.Fatalf("DecodeKMZ() unexpected error = %v", err)
+			}
+			if len(got) != 1 || got[0].Name != tt.want {
+				t.Errorf("DecodeKMZ() = %+v, want Placemark %q", got, tt.want)
+			}
+		})
+	}
+
+	b := archive(map[string]string{"icon.png": "png"}, "icon.png")
+	if _, err := kml.DecodeKMZ(bytes.NewReader(b), int64(len(b))); err == nil {
+		t.Error("DecodeKMZ() without a KML document expected error, got nil")
+	}
+	if _, err := kml.DecodeKMZ(bytes.NewReader([]byte("not a zip")), 9); err == nil {
+		t.Error("DecodeKMZ() of invalid data expected error, got nil")
+	}
+}
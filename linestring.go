@@ -99,6 +99,17 @@ func (ls *LineString) Scan(val any) error {
 	if val == nil {
 		return nil
 	}
+	if g, ok, err := scanBinary(val, false); ok {
+		if err != nil {
+			return err
+		}
+		v, ok := g.(*LineString)
+		if !ok {
+			return fmt.Errorf("cannot scan %T into LineString", g)
+		}
+		*ls = *v
+		return nil
+	}
 
 	var decode string
 	switch v := val.(type) {
@@ -0,0 +1,248 @@
+package mongogis
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/restayway/gogis"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// maxDepth limits the nesting of geometry collections.
+const maxDepth = 32
+
+// Document returns g as a GeoJSON document, such as
+// {type: "Point", coordinates: [lng, lat]}. Point, LineString, Polygon,
+// GeometryCollection and their geography variants are supported.
+func Document(g gogis.Geometry) (bson.D, error) {
+	doc, err := document(g, 0)
+	if err != nil {
+		return nil, fmt.Errorf("mongogis: %w", err)
+	}
+	return doc, nil
+}
+
+func document(g gogis.Geometry, depth int) (bson.D, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("geometry collections nested too deeply")
+	}
+	switch v := g.(type) {
+	case *gogis.Point:
+		pos, err := position(*v)
+		if err != nil {
+			return nil, err
+		}
+		return bson.D{{Key: "type", Value: "Point"}, {Key: "coordinates", Value: pos}}, nil
+	case *gogis.LineString:
+		coords, err := positions(v.Points)
+		if err != nil {
+			return nil, err
+		}
+		return bson.D{{Key: "type", Value: "LineString"}, {Key: "coordinates", Value: coords}}, nil
+	case *gogis.Polygon:
+		coords, err := rings(v)
+		if err != nil {
+			return nil, err
+		}
+		return bson.D{{Key: "type", Value: "Polygon"}, {Key: "coordinates", Value: coords}}, nil
+	case *gogis.GeometryCollection:
+		geometries := make(bson.A, len(v.Geometries))
+		for i, child := range v.Geometries {
+			doc, err := document(child, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			geometries[i] = doc
+		}
+		return bson.D{{Key: "type", Value: "GeometryCollection"}, {Key: "geometries", Value: geometries}}, nil
+	case *gogis.GeographyPoint:
+		return document((*gogis.Point)(v), depth)
+	case *gogis.GeographyLineString:
+		return document((*gogis.LineString)(v), depth)
+	case *gogis.GeographyPolygon:
+		return document((*gogis.Polygon)(v), depth)
+	case *gogis.GeographyCollection:
+		return document((*gogis.GeometryCollection)(v), depth)
+	}
+	return nil, fmt.Errorf("unsupported geometry type %T", g)
+}
+
+func position(p gogis.Point) ([]float64, error) {
+	for _, v := range []float64{p.Lng, p.Lat} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("invalid coordinate %v", v)
+		}
+	}
+	return []float64{p.Lng, p.Lat}, nil
+}
+
+func positions(points []gogis.Point) ([][]float64, error) {
+	coords := make([][]float64, len(points))
+	for i, p := range points {
+		var err error
+		if coords[i], err = position(p); err != nil {
+			return nil, err
+		}
+	}
+	return coords, nil
+}
+
+// rings returns the coordinates of a polygon.
+func rings(p *gogis.Polygon) ([][][]float64, error) {
+	rings := make([][][]float64, len(p.Rings))
+	for i, ring := range p.Rings {
+		var err error
+		if rings[i], err = positions(ring); err != nil {
+			return nil, err
+		}
+	}
+	return rings, nil
+}
+
+// FromDocument decodes a GeoJSON document, as stored by MongoDB, into a
+// gogis geometry. Multi geometries decode as a *gogis.GeometryCollection of
+// their parts.
+func FromDocument(doc bson.Raw) (gogis.Geometry, error) {
+	g, err := fromDocument(doc, 0)
+	if err != nil {
+		return nil, fmt.Errorf("mongogis: %w", err)
+	}
+	return g, nil
+}
+
+func fromDocument(doc bson.Raw, depth int) (gogis.Geometry, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("geometry collections nested too deeply")
+	}
+	var raw struct {
+		Type        string        `bson:"type"`
+		Coordinates bson.RawValue `bson:"coordinates"`
+		Geometries  []bson.Raw    `bson:"geometries"`
+	}
+	if err := bson.Unmarshal(doc, &raw); err != nil {
+		return nil, err
+	}
+
+	switch raw.Type {
+	case "Point":
+		var pos []float64
+		if err := coordinates(raw.Type, raw.Coordinates, &pos); err != nil {
+			return nil, err
+		}
+		p, err := point(pos)
+		if err != nil {
+			return nil, err
+		}
+		return &p, nil
+	case "LineString":
+		var coords [][]float64
+		if err := coordinates(raw.Type, raw.Coordinates, &coords); err != nil {
+			return nil, err
+		}
+		points, err := points(coords)
+		if err != nil {
+			return nil, err
+		}
+		return &gogis.LineString{Points: points}, nil
+	case "Polygon":
+		var coords [][][]float64
+		if err := coordinates(raw.Type, raw.Coordinates, &coords); err != nil {
+			return nil, err
+		}
+		return polygon(coords)
+	case "MultiPoint":
+		var coords [][]float64
+		if err := coordinates(raw.Type, raw.Coordinates, &coords); err != nil {
+			return nil, err
+		}
+		gc := &gogis.GeometryCollection{Geometries: make([]gogis.Geometry, len(coords))}
+		for i, pos := range coords {
+			p, err := point(pos)
+			if err != nil {
+				return nil, err
+			}
+			gc.Geometries[i] = &p
+		}
+		return gc, nil
+	case "MultiLineString":
+		var coords [][][]float64
+		if err := coordinates(raw.Type, raw.Coordinates, &coords); err != nil {
+			return nil, err
+		}
+		gc := &gogis.GeometryCollection{Geometries: make([]gogis.Geometry, len(coords))}
+		for i, line := range coords {
+			points, err := points(line)
+			if err != nil {
+				return nil, err
+			}
+			gc.Geometries[i] = &gogis.LineString{Points: points}
+		}
+		return gc, nil
+	case "MultiPolygon":
+		var coords [][][][]float64
+		if err := coordinates(raw.Type, raw.Coordinates, &coords); err != nil {
+			return nil, err
+		}
+		gc := &gogis.GeometryCollection{Geometries: make([]gogis.Geometry, len(coords))}
+		for i, rings := range coords {
+			p, err := polygon(rings)
+			if err != nil {
+				return nil, err
+			}
+			gc.Geometries[i] = p
+		}
+		return gc, nil
+	case "GeometryCollection":
+		gc := &gogis.GeometryCollection{Geometries: make([]gogis.Geometry, len(raw.Geometries))}
+		for i, child := range raw.Geometries {
+			g, err := fromDocument(child, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			gc.Geometries[i] = g
+		}
+		return gc, nil
+	}
+	return nil, fmt.Errorf("unsupported geometry type %q", raw.Type)
+}
+
+// coordinates decodes the coordinates of a geometry of type typ into dst.
+// Integer coordinates are accepted.
+func coordinates(typ string, v bson.RawValue, dst any) error {
+	if v.Type != bson.TypeArray {
+		return fmt.Errorf("coordinates of %s are not an array", typ)
+	}
+	if err := v.Unmarshal(dst); err != nil {
+		return fmt.Errorf("coordinates of %s: %w", typ, err)
+	}
+	return nil
+}
+
+func point(pos []float64) (gogis.Point, error) {
+	if len(pos) < 2 {
+		return gogis.Point{}, fmt.Errorf("position with %d coordinates", len(pos))
+	}
+	return gogis.Point{Lng: pos[0], Lat: pos[1]}, nil
+}
+
+func points(coords [][]float64) ([]gogis.Point, error) {
+	points := make([]gogis.Point, len(coords))
+	for i, pos := range coords {
+		var err error
+		if points[i], err = point(pos); err != nil {
+			return nil, err
+		}
+	}
+	return points, nil
+}
+
+func polygon(coords [][][]float64) (*gogis.Polygon, error) {
+	p := &gogis.Polygon{Rings: make([][]gogis.Point, len(coords))}
+	for i, ring := range coords {
+		var err error
+		if p.Rings[i], err = points(ring); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,76 @@
+package mongogis
+
+import (
+	"fmt"
+
+	"github.com/restayway/gogis"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// GeoWithin returns a filter selecting documents whose field lies entirely
+// within g, which must be a Polygon or a collection of polygons. MongoDB
+// accepts only Polygon and MultiPolygon for $geoWithin, so a collection is
+// written as a MultiPolygon:
+//
+//	{field: {$geoWithin: {$geometry: g}}}
+func GeoWithin(field string, g gogis.Geometry) (bson.D, error) {
+	doc, err := within(g)
+	if err != nil {
+		return nil, fmt.Errorf("mongogis: %w", err)
+	}
+	return bson.D{{Key: field, Value: bson.D{
+		{Key: "$geoWithin", Value: bson.D{{Key: "$geometry", Value: doc}}},
+	}}}, nil
+}
+
+// within returns the $geoWithin geometry of a polygon or a collection of
+// polygons.
+func within(g gogis.Geometry) (bson.D, error) {
+	switch v := g.(type) {
+	case *gogis.Polygon:
+		return document(v, 0)
+	case *gogis.GeographyPolygon:
+		return document((*gogis.Polygon)(v), 0)
+	case *gogis.GeographyCollection:
+		return within((*gogis.GeometryCollection)(v))
+	case *gogis.GeometryCollection:
+		polygons := make([][][][]float64, len(v.Geometries))
+		for i, child := range v.Geometries {
+			var p *gogis.Polygon
+			switch c := child.(type) {
+			case *gogis.Polygon:
+				p = c
+			case *gogis.GeographyPolygon:
+				p = (*gogis.Polygon)(c)
+			default:
+				return nil, fmt.Errorf("$geoWithin collection holds %T, not a polygon", child)
+			}
+			var err error
+			if polygons[i], err = rings(p); err != nil {
+				return nil, err
+			}
+		}
+		return bson.D{{Key: "type", Value: "MultiPolygon"}, {Key: "coordinates", Value: polygons}}, nil
+	}
+	return nil, fmt.Errorf("$geoWithin needs a polygon, not %T", g)
+}
+
+// Near returns a filter selecting documents whose field is between
+// minDistance and maxDistance meters from p, sorted from nearest to
+// farthest. A distance of zero leaves out that bound. The field needs a
+// 2dsphere index:
+//
+//	{field: {$near: {$geometry: p, $minDistance: minDistance, $maxDistance: maxDistance}}}
+func Near(field string, p gogis.Point, minDistance, maxDistance float64) bson.D {
+	near := bson.D{{Key: "$geometry", Value: bson.D{
+		{Key: "type", Value: "Point"},
+		{Key: "coordinates", Value: []float64{p.Lng, p.Lat}},
+	}}}
+	if minDistance > 0 {
+		near = append(near, bson.E{Key: "$minDistance", Value: minDistance})
+	}
+	if maxDistance > 0 {
+		near = append(near, bson.E{Key: "$maxDistance", Value: maxDistance})
+	}
+	return bson.D{{Key: field, Value: bson.D{{Key: "$near", Value: near}}}}
+}
@@ -0,0 +1,150 @@
+// Package mongogis stores gogis geometries in MongoDB as GeoJSON objects,
+// the shape indexed by 2dsphere indexes, and builds geospatial query
+// filters.
+//
+// Register adds BSON codecs for the gogis types to a registry, which is then
+// set on the client:
+//
+//	client, err := mongo.Connect(options.Client().
+//	    ApplyURI(uri).
+//	    SetRegistry(mongogis.NewRegistry()))
+//
+// Afterwards gogis values can be used directly as document fields:
+//
+//	type Location struct {
+//	    Name  string      `bson:"name"`
+//	    Point gogis.Point `bson:"point"`
+//	}
+//
+//	_, err = coll.InsertOne(ctx, Location{Name: "Statue of Liberty", Point: p})
+//
+// stores the point as {type: "Point", coordinates: [-74.0445, 40.6892]}.
+// Fields of type gogis.Geometry decode to the gogis type of the stored
+// geometry, and MultiPoint, MultiLineString and MultiPolygon objects decode
+// as a *gogis.GeometryCollection. Coordinates beyond the longitude and
+// latitude are dropped.
+//
+// GeoWithin and Near return filter documents for the $geoWithin and $near
+// operators:
+//
+//	filter := mongogis.Near("point", center, 0, 1000)
+//	cursor, err := coll.Find(ctx, filter)
+package mongogis
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/restayway/gogis"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Codec is the BSON codec of the gogis geometry types, encoding them as
+// GeoJSON objects.
+type Codec struct{}
+
+var (
+	tGeometry = reflect.TypeOf((*gogis.Geometry)(nil)).Elem()
+	tRaw      = reflect.TypeOf(bson.Raw(nil))
+	tDocument = reflect.TypeOf(bson.D(nil))
+)
+
+// types are the gogis types handled by Codec, besides gogis.Geometry.
+var types = []reflect.Type{
+	reflect.TypeOf(gogis.Point{}),
+	reflect.TypeOf(gogis.LineString{}),
+	reflect.TypeOf(gogis.Polygon{}),
+	reflect.TypeOf(gogis.GeometryCollection{}),
+	reflect.TypeOf(gogis.GeographyPoint{}),
+	reflect.TypeOf(gogis.GeographyLineString{}),
+	reflect.TypeOf(gogis.GeographyPolygon{}),
+	reflect.TypeOf(gogis.GeographyCollection{}),
+}
+
+// Register registers Codec in r for the gogis geometry types and the
+// gogis.Geometry interface. Pointers to the types are handled by the
+// pointer codec of the registry.
+func Register(r *bson.Registry) {
+	for _, t := range append(types, tGeometry) {
+		r.RegisterTypeEncoder(t, Codec{})
+		r.RegisterTypeDecoder(t, Codec{})
+	}
+}
+
+// NewRegistry returns the default BSON registry with Codec registered.
+func NewRegistry() *bson.Registry {
+	r := bson.NewRegistry()
+	Register(r)
+	return r
+}
+
+// EncodeValue encodes a gogis geometry value as a GeoJSON document.
+func (Codec) EncodeValue(ec bson.EncodeContext, vw bson.ValueWriter, val reflect.Value) error {
+	if val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return vw.WriteNull()
+		}
+		val = val.Elem()
+	}
+	var g gogis.Geometry
+	if val.Kind() == reflect.Pointer {
+		if val.IsNil() {
+			return vw.WriteNull()
+		}
+		g, _ = val.Interface().(gogis.Geometry)
+	} else {
+		// String and the other Geometry methods have pointer receivers.
+		p := reflect.New(val.Type())
+		p.Elem().Set(val)
+		g, _ = p.Interface().(gogis.Geometry)
+	}
+	if g == nil {
+		return fmt.Errorf("mongogis: cannot encode %s", val.Type())
+	}
+	doc, err := Document(g)
+	if err != nil {
+		return err
+	}
+	enc, err := ec.LookupEncoder(tDocument)
+	if err != nil {
+		return err
+	}
+	return enc.EncodeValue(ec, vw, reflect.ValueOf(doc))
+}
+
+// DecodeValue decodes a GeoJSON document into a gogis geometry value. A
+// null value sets it to its zero value.
+func (Codec) DecodeValue(dc bson.DecodeContext, vr bson.ValueReader, val reflect.Value) error {
+	if !val.CanSet() {
+		return fmt.Errorf("mongogis: cannot decode into unsettable %s", val.Type())
+	}
+	if vr.Type() == bson.TypeNull {
+		val.Set(reflect.Zero(val.Type()))
+		return vr.ReadNull()
+	}
+	dec, err := dc.LookupDecoder(tRaw)
+	if err != nil {
+		return err
+	}
+	raw := reflect.New(tRaw).Elem()
+	if err := dec.DecodeValue(dc, vr, raw); err != nil {
+		return err
+	}
+	g, err := FromDocument(raw.Interface().(bson.Raw))
+	if err != nil {
+		return err
+	}
+
+	if val.Type() == tGeometry {
+		val.Set(reflect.ValueOf(g))
+		return nil
+	}
+	// The geography types are defined on the geometry types, so a decoded
+	// geometry converts to the geography variant of its type.
+	v := reflect.ValueOf(g).Elem()
+	if !v.Type().ConvertibleTo(val.Type()) {
+		return fmt.Errorf("mongogis: cannot decode %T into %s", g, val.Type())
+	}
+	val.Set(v.Convert(val.Type()))
+	return nil
+}
@@ -0,0 +1,211 @@
+package mongogis_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/mongogis"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var ring = []gogis.Point{{Lng: 0, Lat: 0}, {Lng: 4, Lat: 0}, {Lng: 4, Lat: 4}, {Lng: 0, Lat: 0}}
+
+func marshal(t *testing.T, v any) bson.Raw {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := bson.NewEncoder(bson.NewDocumentWriter(&buf))
+	enc.SetRegistry(mongogis.NewRegistry())
+	if err := enc.Encode(v); err != nil {
+		t.Fatalf("encoding %+v: %v", v, err)
+	}
+	return buf.Bytes()
+}
+
+// relaxed returns the document as relaxed Extended JSON.
+func relaxed(t *testing.T, data bson.Raw) string {
+	t.Helper()
+	b, err := bson.MarshalExtJSON(data, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func unmarshal(t *testing.T, data bson.Raw, v any) {
+	t.Helper()
+	dec := bson.NewDecoder(bson.NewDocumentReader(bytes.NewReader(data)))
+	dec.SetRegistry(mongogis.NewRegistry())
+	if err := dec.Decode(v); err != nil {
+		t.Fatalf("decoding %s: %v", data, err)
+	}
+}
+
+func TestCodec(t *testing.T) {
+	type location struct {
+		Name    string                     `bson:"name"`
+		Point   gogis.Point                `bson:"point"`
+		Path    *gogis.LineString          `bson:"path"`
+		Area    gogis.GeographyPolygon     `bson:"area"`
+		Parts   gogis.GeometryCollection   `bson:"parts"`
+		Shape   gogis.Geometry             `bson:"shape"`
+		Missing *gogis.GeographyLineString `bson:"missing"`
+	}
+	in := location{
+		Name:  "site",
+		Point: gogis.Point{Lng: -74.0445, Lat: 40.6892},
+		Path:  &gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}}},
+		Area:  gogis.GeographyPolygon{Rings: [][]gogis.Point{ring}},
+		Parts: gogis.GeometryCollection{Geometries: []gogis.Geometry{
+			&gogis.Point{Lng: 1, Lat: 2},
+			&gogis.Polygon{Rings: [][]gogis.Point{ring}},
+		}},
+		Shape: &gogis.LineString{Points: []gogis.Point{{Lng: 5, Lat: 6}, {Lng: 7, Lat: 8}}},
+	}
+	data := marshal(t, in)
+
+	want := `{"name":"site",` +
+		`"point":{"type":"Point","coordinates":[-74.0445,40.6892]},` +
+		`"path":{"type":"LineString","coordinates":[[1.0,2.0],[3.0,4.0]]},` +
+		`"area":{"type":"Polygon","coordinates":[[[0.0,0.0],[4.0,0.0],[4.0,4.0],[0.0,0.0]]]},` +
+		`"parts":{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[1.0,2.0]},` +
+		`{"type":"Polygon","coordinates":[[[0.0,0.0],[4.0,0.0],[4.0,4.0],[0.0,0.0]]]}]},` +
+		`"shape":{"type":"LineString","coordinates":[[5.0,6.0],[7.0,8.0]]},` +
+		`"missing":null}`
+	if got := relaxed(t, data); got != want {
+		t.Errorf("encoded\n%s\nwant\n%s", got, want)
+	}
+
+	var out location
+	unmarshal(t, data, &out)
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+}
+
+func TestFromDocument(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  bson.D
+		want gogis.Geometry
+	}{
+		{
+			"integer coordinates",
+			bson.D{{Key: "type", Value: "Point"}, {Key: "coordinates", Value: bson.A{int32(1), int64(2), 3.5}}},
+			&gogis.Point{Lng: 1, Lat: 2},
+		},
+		{
+			"multipoint",
+			bson.D{{Key: "type", Value: "MultiPoint"}, {Key: "coordinates", Value: bson.A{bson.A{1.0, 2.0}, bson.A{3.0, 4.0}}}},
+			&gogis.GeometryCollection{Geometries: []gogis.Geometry{&gogis.Point{Lng: 1, Lat: 2}, &gogis.Point{Lng: 3, Lat: 4}}},
+		},
+		{
+			"multilinestring",
+			bson.D{{Key: "type", Value: "MultiLineString"}, {Key: "coordinates", Value: bson.A{bson.A{bson.A{1.0, 2.0}, bson.A{3.0, 4.0}}}}},
+			&gogis.GeometryCollection{Geometries: []gogis.Geometry{
+				&gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}}},
+			}},
+		},
+		{
+			"multipolygon",
+			bson.D{{Key: "type", Value: "MultiPolygon"}, {Key: "coordinates", Value: bson.A{bson.A{bson.A{
+				bson.A{0, 0}, bson.A{4, 0}, bson.A{4, 4}, bson.A{0, 0},
+			}}}}},
+			&gogis.GeometryCollection{Geometries: []gogis.Geometry{&gogis.Polygon{Rings: [][]gogis.Point{ring}}}},
+		},
+	}
+	for _, tt := range tests {
+		data, err := bson.Marshal(tt.doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := mongogis.FromDocument(data)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, doc := range []bson.D{
+		{{Key: "type", Value: "Circle"}},
+		{{Key: "type", Value: "Point"}},
+		{{Key: "type", Value: "Point"}, {Key: "coordinates", Value: bson.A{1.0}}},
+		{{Key: "type", Value: "Point"}, {Key: "coordinates", Value: bson.A{"1", "2"}}},
+		{{Key: "type", Value: "LineString"}, {Key: "coordinates", Value: bson.A{1.0, 2.0}}},
+	} {
+		data, _ := bson.Marshal(doc)
+		if g, err := mongogis.FromDocument(data); err == nil {
+			t.Errorf("FromDocument(%s) = %v, want error", bson.Raw(data), g)
+		}
+	}
+
+	// A stored line string does not decode into a point.
+	data := marshal(t, bson.D{{Key: "g", Value: gogis.LineString{Points: []gogis.Point{{}, {}}}}})
+	var out struct {
+		G gogis.Point `bson:"g"`
+	}
+	dec := bson.NewDecoder(bson.NewDocumentReader(bytes.NewReader(data)))
+	dec.SetRegistry(mongogis.NewRegistry())
+	if err := dec.Decode(&out); err == nil {
+		t.Error("decoding a LineString into a Point: no error")
+	}
+
+	if _, err := mongogis.Document(nil); err == nil {
+		t.Error("Document(nil): no error")
+	}
+}
+
+func TestFilters(t *testing.T) {
+	area := &gogis.Polygon{Rings: [][]gogis.Point{ring}}
+	filter, err := mongogis.GeoWithin("point", area)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := bson.Marshal(filter)
+	want := `{"point":{"$geoWithin":{"$geometry":{"type":"Polygon","coordinates":[[[0.0,0.0],[4.0,0.0],[4.0,4.0],[0.0,0.0]]]}}}}`
+	if got := relaxed(t, data); got != want {
+		t.Errorf("GeoWithin = %s, want %s", got, want)
+	}
+
+	collection := &gogis.GeometryCollection{Geometries: []gogis.Geometry{area, &gogis.GeographyPolygon{Rings: [][]gogis.Point{ring}}}}
+	filter, err = mongogis.GeoWithin("point", collection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ = bson.Marshal(filter)
+	want = `{"point":{"$geoWithin":{"$geometry":{"type":"MultiPolygon","coordinates":[` +
+		`[[[0.0,0.0],[4.0,0.0],[4.0,4.0],[0.0,0.0]]],[[[0.0,0.0],[4.0,0.0],[4.0,4.0],[0.0,0.0]]]]}}}}`
+	if got := relaxed(t, data); got != want {
+		t.Errorf("GeoWithin of a collection = %s, want %s", got, want)
+	}
+
+	for _, g := range []gogis.Geometry{
+		&gogis.LineString{Points: ring},
+		&gogis.Point{},
+		&gogis.GeometryCollection{Geometries: []gogis.Geometry{area, &gogis.Point{}}},
+		nil,
+	} {
+		if _, err := mongogis.GeoWithin("point", g); err == nil {
+			t.Errorf("GeoWithin(%v): no error", g)
+		}
+	}
+
+	tests := []struct {
+		min, max float64
+		want     string
+	}{
+		{0, 1000, `{"point":{"$near":{"$geometry":{"type":"Point","coordinates":[1.5,2.0]},"$maxDistance":1000.0}}}`},
+		{10, 0, `{"point":{"$near":{"$geometry":{"type":"Point","coordinates":[1.5,2.0]},"$minDistance":10.0}}}`},
+	}
+	for _, tt := range tests {
+		data, _ := bson.Marshal(mongogis.Near("point", gogis.Point{Lng: 1.5, Lat: 2}, tt.min, tt.max))
+		if got := relaxed(t, data); got != tt.want {
+			t.Errorf("Near(%v, %v) = %s, want %s", tt.min, tt.max, got, tt.want)
+		}
+	}
+}
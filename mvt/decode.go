@@ -0,0 +1,259 @@
+package mvt
+
+import (
+	"fmt"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/internal/pbf"
+)
+
+// Unmarshal decodes a vector tile into its layers.
+//
+// Geometries are returned in tile coordinates: the Lng and Lat fields of every
+// Point hold the integer X and Y tile coordinates, with Y pointing down.
+// Single geometries decode to *Point, *LineString or *Polygon and multi
+// geometries to a *GeometryCollection of those. Property values decode to
+// string, float32, float64, int64, uint64 or bool.
+//
+// Unmarshal is mainly intended for testing and debugging encoded tiles.
+func Unmarshal(data []byte) ([]Layer, error) {
+	var layers []Layer
+	r := pbf.NewReader(data)
+	for r.Next() {
+		if r.Field() != 3 {
+			r.Skip()
+			continue
+		}
+		l, err := unmarshalLayer(r.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, l)
+	}
+	if err := r.Err(); err != nil {
+		return nil, fmt.Errorf("mvt: %w", err)
+	}
+	return layers, nil
+}
+
+type rawFeature struct {
+	id       uint64
+	tags     []uint32
+	typ      GeomType
+	commands []uint32
+}
+
+func unmarshalLayer(msg []byte) (Layer, error) {
+	var (
+		l        = Layer{Extent: DefaultExtent}
+		keys     []string
+		values   []any
+		features []rawFeature
+	)
+
+	r := pbf.NewReader(msg)
+	for r.Next() {
+		switch r.Field() {
+		case 1:
+			l.Name = r.String()
+		case 2:
+			f, err := unmarshalFeature(r.Bytes())
+			if err != nil {
+				return Layer{}, err
+			}
+			features = append(features, f)
+		case 3:
+			keys = append(keys, r.String())
+		case 4:
+			v, err := unmarshalValue(r.Bytes())
+			if err != nil {
+				return Layer{}, err
+			}
+			values = append(values, v)
+		case 5:
+			l.Extent = uint32(r.Uint64())
+		default:
+			r.Skip()
+		}
+	}
+	if err := r.Err(); err != nil {
+		return Layer{}, fmt.Errorf("mvt: layer %q: %w", l.Name, err)
+	}
+
+	for _, raw := range features {
+		if len(raw.tags)%2 != 0 {
+			return Layer{}, fmt.Errorf("mvt: layer %q: odd number of feature tags", l.Name)
+		}
+		f := Feature{ID: raw.id}
+		if len(raw.tags) > 0 {
+			f.Properties = make(map[string]any, len(raw.tags)/2)
+		}
+		for i := 0; i < len(raw.tags); i += 2 {
+			k, v := int(raw.tags[i]), int(raw.tags[i+1])
+			if k >= len(keys) || v >= len(values) {
+				return Layer{}, fmt.Errorf("mvt: layer %q: feature tag out of range", l.Name)
+			}
+			f.Properties[keys[k]] = values[v]
+		}
+
+		g, err := decodeGeometry(raw.typ, raw.commands)
+		if err != nil {
+			return Layer{}, fmt.Errorf("mvt: layer %q: %w", l.Name, err)
+		}
+		f.Geometry = g
+		l.Features = append(l.Features, f)
+	}
+	return l, nil
+}
+
+func unmarshalFeature(msg []byte) (rawFeature, error) {
+	var f rawFeature
+	r := pbf.NewReader(msg)
+	for r.Next() {
+		switch r.Field() {
+		case 1:
+			f.id = r.Uint64()
+		case 2:
+			f.tags = append(f.tags, r.PackedUint32()...)
+		case 3:
+			f.typ = GeomType(r.Uint64())
+		case 4:
+			f.commands = append(f.commands, r.PackedUint32()...)
+		default:
+			r.Skip()
+		}
+	}
+	if err := r.Err(); err != nil {
+		return rawFeature{}, fmt.Errorf("mvt: feature: %w", err)
+	}
+	return f, nil
+}
+
+func unmarshalValue(msg []byte) (any, error) {
+	var v any
+	r := pbf.NewReader(msg)
+	for r.Next() {
+		switch r.Field() {
+		case valueString:
+			v = r.String()
+		case valueFloat:
+			v = r.Float()
+		case valueDouble:
+			v = r.Double()
+		case valueInt:
+			v = r.Int64()
+		case valueUint:
+			v = r.Uint64()
+		case valueSint:
+			v = r.Sint64()
+		case valueBool:
+			v = r.Bool()
+		default:
+			r.Skip()
+		}
+	}
+	if err := r.Err(); err != nil {
+		return nil, fmt.Errorf("mvt: value: %w", err)
+	}
+	return v, nil
+}
+
+// decodeGeometry interprets a command stream as geometry parts, each part
+// starting with a MoveTo command.
+func decodeGeometry(typ GeomType, commands []uint32) (gogis.Geometry, error) {
+	var (
+		parts  [][]gogis.Point
+		closed []bool
+		x, y   int64
+	)
+	for i := 0; i < len(commands); {
+		id, count := int(commands[i]&0x7), int(commands[i]>>3)
+		i++
+		switch id {
+		case cmdMoveTo, cmdLineTo:
+			if i+2*count > len(commands) {
+				return nil, fmt.Errorf("truncated geometry command")
+			}
+			for j := 0; j < count; j++ {
+				x += pbf.UnZigZag(uint64(commands[i]))
+				y += pbf.UnZigZag(uint64(commands[i+1]))
+				i += 2
+				p := gogis.Point{Lng: float64(x), Lat: float64(y)}
+				if id == cmdMoveTo && (typ != GeomTypePoint || len(parts) == 0) {
+					parts = append(parts, nil)
+					closed = append(closed, false)
+				}
+				if len(parts) == 0 {
+					return nil, fmt.Errorf("LineTo before MoveTo")
+				}
+				parts[len(parts)-1] = append(parts[len(parts)-1], p)
+			}
+		case cmdClosePath:
+			if len(parts) == 0 || len(parts[len(parts)-1]) == 0 {
+				return nil, fmt.Errorf("ClosePath before MoveTo")
+			}
+			last := parts[len(parts)-1]
+			parts[len(parts)-1] = append(last, last[0])
+			closed[len(closed)-1] = true
+		default:
+			return nil, fmt.Errorf("unknown geometry command %d", id)
+		}
+	}
+
+	switch typ {
+	case GeomTypePoint:
+		if len(parts) == 0 {
+			return nil, fmt.Errorf("empty point geometry")
+		}
+		if len(parts[0]) == 1 {
+			return &parts[0][0], nil
+		}
+		gc := &gogis.GeometryCollection{}
+		for i := range parts[0] {
+			gc.Geometries = append(gc.Geometries, &parts[0][i])
+		}
+		return gc, nil
+	case GeomTypeLineString:
+		if len(parts) == 1 {
+			return &gogis.LineString{Points: parts[0]}, nil
+		}
+		gc := &gogis.GeometryCollection{}
+		for _, part := range parts {
+			gc.Geometries = append(gc.Geometries, &gogis.LineString{Points: part})
+		}
+		return gc, nil
+	case GeomTypePolygon:
+		return decodePolygons(parts, closed)
+	default:
+		return nil, fmt.Errorf("unsupported geometry type %d", typ)
+	}
+}
+
+// decodePolygons groups rings into polygons: every ring with a positive area
+// starts a new polygon and rings with a negative area are its holes.
+func decodePolygons(rings [][]gogis.Point, closed []bool) (gogis.Geometry, error) {
+	var polygons []*gogis.Polygon
+	for i, ring := range rings {
+		if !closed[i] {
+			return nil, fmt.Errorf("polygon ring is not closed")
+		}
+		var area float64
+		for j := 1; j < len(ring); j++ {
+			area += ring[j-1].Lng*ring[j].Lat - ring[j].Lng*ring[j-1].Lat
+		}
+		if area > 0 || len(polygons) == 0 {
+			polygons = append(polygons, &gogis.Polygon{})
+		}
+		p := polygons[len(polygons)-1]
+		p.Rings = append(p.Rings, ring)
+	}
+
+	if len(polygons) == 1 {
+		return polygons[0], nil
+	}
+	gc := &gogis.GeometryCollection{}
+	for _, p := range polygons {
+		gc.Geometries = append(gc.Geometries, p)
+	}
+	return gc, nil
+}
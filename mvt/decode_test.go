@@ -0,0 +1,99 @@
+package mvt_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/mvt"
+)
+
+// tileBytes builds a tile with a single layer holding one feature with the
+// given geometry type and command stream, following the examples of the
+// vector tile specification.
+func tileBytes(typ byte, commands []byte) []byte {
+	feature := []byte{0x18, typ, 0x22, byte(len(commands))}
+	feature = append(feature, commands...)
+
+	layer := []byte{0x78, 0x02, 0x0a, 0x01, 'l', 0x12, byte(len(feature))}
+	layer = append(layer, feature...)
+	layer = append(layer, 0x28, 0x80, 0x20)
+
+	return append([]byte{0x1a, byte(len(layer))}, layer...)
+}
+
+func TestUnmarshalSpecExamples(t *testing.T) {
+	tests := []struct {
+		name     string
+		typ      byte
+		commands []byte
+		expected gogis.Geometry
+	}{
+		{
+			name:     "point",
+			typ:      1,
+			commands: []byte{9, 50, 34},
+			expected: &gogis.Point{Lng: 25, Lat: 17},
+		},
+		{
+			name:     "multi point",
+			typ:      1,
+			commands: []byte{17, 10, 14, 3, 9},
+			expected: &gogis.GeometryCollection{Geometries: []gogis.Geometry{
+				&gogis.Point{Lng: 5, Lat: 7},
+				&gogis.Point{Lng: 3, Lat: 2},
+			}},
+		},
+		{
+			name:     "linestring",
+			typ:      2,
+			commands: []byte{9, 4, 4, 18, 0, 16, 16, 0},
+			expected: &gogis.LineString{Points: []gogis.Point{
+				{Lng: 2, Lat: 2}, {Lng: 2, Lat: 10}, {Lng: 10, Lat: 10},
+			}},
+		},
+		{
+			name:     "polygon",
+			typ:      3,
+			commands: []byte{9, 6, 12, 18, 10, 12, 24, 44, 15},
+			expected: &gogis.Polygon{Rings: [][]gogis.Point{{
+				{Lng: 3, Lat: 6}, {Lng: 8, Lat: 12}, {Lng: 20, Lat: 34}, {Lng: 3, Lat: 6},
+			}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layers, err := mvt.Unmarshal(tileBytes(tt.typ, tt.commands))
+			if err != nil {
+				t.Fatalf("Unmarshal() unexpected error = %v", err)
+			}
+			if len(layers) != 1 || len(layers[0].Features) != 1 {
+				t.Fatalf("Unmarshal() = %v, want one layer with one feature", layers)
+			}
+			if got := layers[0].Features[0].Geometry; !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("geometry = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "truncated tile", data: []byte{0x1a, 0x10, 0x78}},
+		{name: "truncated command", data: tileBytes(1, []byte{9, 50})},
+		{name: "unknown command", data: tileBytes(2, []byte{12, 0, 0})},
+		{name: "unclosed polygon", data: tileBytes(3, []byte{9, 6, 12, 18, 10, 12, 24, 44})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := mvt.Unmarshal(tt.data); err == nil {
+				t.Errorf("Unmarshal() expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,384 @@
+package mvt
+
+import (
+	"math"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/internal/pbf"
+	"github.com/restayway/gogis/tiles"
+)
+
+// Geometry command ids.
+const (
+	cmdMoveTo    = 1
+	cmdLineTo    = 2
+	cmdClosePath = 7
+)
+
+// fpoint is a point in fractional tile coordinates.
+type fpoint struct {
+	x, y float64
+}
+
+// ipoint is a point in quantized tile coordinates.
+type ipoint struct {
+	x, y int64
+}
+
+// encodedGeometry is a single feature geometry ready to be written.
+type encodedGeometry struct {
+	typ      GeomType
+	commands []uint32
+}
+
+// projector converts EPSG:4326 geometries into clipped, quantized tile
+// coordinates.
+type projector struct {
+	tile     tiles.Tile
+	n        float64 // Number of tiles per side at the tile's zoom level
+	extent   float64
+	min, max float64 // Clip box, identical on both axes
+}
+
+func newProjector(tile tiles.Tile, extent, buffer uint32) projector {
+	return projector{
+		tile:   tile,
+		n:      float64(uint64(1) << tile.Z),
+		extent: float64(extent),
+		min:    -float64(buffer),
+		max:    float64(extent) + float64(buffer),
+	}
+}
+
+func (p projector) project(pt gogis.Point) fpoint {
+	lat := math.Max(-tiles.MaxLatitude, math.Min(tiles.MaxLatitude, pt.Lat)) * math.Pi / 180
+	x := (pt.Lng + 180) / 360 * p.n
+	y := (1 - math.Log(math.Tan(lat)+1/math.Cos(lat))/math.Pi) / 2 * p.n
+	return fpoint{
+		x: (x - float64(p.tile.X)) * p.extent,
+		y: (y - float64(p.tile.Y)) * p.extent,
+	}
+}
+
+func (p projector) projectAll(points []gogis.Point) []fpoint {
+	fs := make([]fpoint, len(points))
+	for i, pt := range points {
+		fs[i] = p.project(pt)
+	}
+	return fs
+}
+
+func (p projector) inside(f fpoint) bool {
+	return f.x >= p.min && f.x <= p.max && f.y >= p.min && f.y <= p.max
+}
+
+// parts collects the clipped, quantized pieces of a geometry by type.
+type parts struct {
+	points   []ipoint
+	lines    [][]ipoint
+	polygons [][][]ipoint
+}
+
+// geometries returns the encoded geometries for g, one per geometry type
+// present after clipping.
+func (p projector) geometries(g gogis.Geometry) []encodedGeometry {
+	var ps parts
+	p.collect(g, &ps)
+
+	var out []encodedGeometry
+	if len(ps.points) > 0 {
+		out = append(out, encodedGeometry{GeomTypePoint, encodePoints(ps.points)})
+	}
+	if len(ps.lines) > 0 {
+		out = append(out, encodedGeometry{GeomTypeLineString, encodeLines(ps.lines)})
+	}
+	if len(ps.polygons) > 0 {
+		out = append(out, encodedGeometry{GeomTypePolygon, encodePolygons(ps.polygons)})
+	}
+	return out
+}
+
+func (p projector) collect(g gogis.Geometry, ps *parts) {
+	switch v := g.(type) {
+	case *gogis.Point:
+		if f := p.project(*v); p.inside(f) {
+			ps.points = append(ps.points, quantize(f))
+		}
+	case *gogis.LineString:
+		for _, line := range p.clipLine(p.projectAll(v.Points)) {
+			if q := quantizeLine(line); len(q) >= 2 {
+				ps.lines = append(ps.lines, q)
+			}
+		}
+	case *gogis.Polygon:
+		if polygon := p.polygon(v.Rings); polygon != nil {
+			ps.polygons = append(ps.polygons, polygon)
+		}
+	case *gogis.GeometryCollection:
+		for _, child := range v.Geometries {
+			p.collect(child, ps)
+		}
+	case *gogis.GeographyPoint:
+		p.collect((*gogis.Point)(v), ps)
+	case *gogis.GeographyLineString:
+		p.collect((*gogis.LineString)(v), ps)
+	case *gogis.GeographyPolygon:
+		p.collect((*gogis.Polygon)(v), ps)
+	case *gogis.GeographyCollection:
+		p.collect((*gogis.GeometryCollection)(v), ps)
+	}
+}
+
+// polygon clips and quantizes the rings of a polygon and fixes their winding
+// order. Exterior rings must have a positive area in tile coordinates
+// (clockwise with Y pointing down) and holes a negative one. Rings that
+// collapse during quantization are dropped, and so is the polygon if its
+// exterior ring collapses.
+func (p projector) polygon(rings [][]gogis.Point) [][]ipoint {
+	var out [][]ipoint
+	for i, ring := range rings {
+		q := quantizeRing(p.clipRing(p.projectAll(ring)))
+		area := ringArea(q)
+		if area == 0 {
+			if i == 0 {
+				return nil
+			}
+			continue
+		}
+		if (i == 0) != (area > 0) {
+			reverse(q)
+		}
+		out = append(out, q)
+	}
+	return out
+}
+
+// clipLine clips a polyline to the clip box, splitting it into several parts
+// where it leaves and re-enters the box.
+func (p projector) clipLine(points []fpoint) [][]fpoint {
+	if len(points) == 1 && p.inside(points[0]) {
+		return [][]fpoint{points}
+	}
+
+	var out [][]fpoint
+	var cur []fpoint
+	for i := 1; i < len(points); i++ {
+		a, b := points[i-1], points[i]
+		ca, cb, ok := p.clipSegment(a, b)
+		if !ok {
+			if len(cur) > 0 {
+				out = append(out, cur)
+				cur = nil
+			}
+			continue
+		}
+		if len(cur) == 0 {
+			cur = append(cur, ca)
+		}
+		cur = append(cur, cb)
+		if cb != b {
+			out = append(out, cur)
+			cur = nil
+		}
+	}
+	if len(cur) > 0 {
+		out = append(out, cur)
+	}
+	return out
+}
+
+// clipSegment clips the segment from a to b to the clip box using the
+// Liang-Barsky algorithm.
+func (p projector) clipSegment(a, b fpoint) (fpoint, fpoint, bool) {
+	t0, t1 := 0.0, 1.0
+	dx, dy := b.x-a.x, b.y-a.y
+	for _, e := range [4][2]float64{
+		{-dx, a.x - p.min},
+		{dx, p.max - a.x},
+		{-dy, a.y - p.min},
+		{dy, p.max - a.y},
+	} {
+		q, r := e[0], e[1]
+		if q == 0 {
+			if r < 0 {
+				return a, b, false
+			}
+			continue
+		}
+		t := r / q
+		if q < 0 {
+			t0 = math.Max(t0, t)
+		} else {
+			t1 = math.Min(t1, t)
+		}
+	}
+	if t0 > t1 {
+		return a, b, false
+	}
+	ca, cb := a, b
+	if t0 > 0 {
+		ca = fpoint{a.x + t0*dx, a.y + t0*dy}
+	}
+	if t1 < 1 {
+		cb = fpoint{a.x + t1*dx, a.y + t1*dy}
+	}
+	return ca, cb, true
+}
+
+// clipRing clips a ring to the clip box with the Sutherland-Hodgman
+// algorithm. The result is an open ring (the closing vertex is implied).
+func (p projector) clipRing(ring []fpoint) []fpoint {
+	if n := len(ring); n > 1 && ring[0] == ring[n-1] {
+		ring = ring[:n-1]
+	}
+	edges := []struct {
+		inside func(fpoint) bool
+		cross  func(a, b fpoint) fpoint
+	}{
+		{
+			func(f fpoint) bool { return f.x >= p.min },
+			func(a, b fpoint) fpoint { return intersectX(a, b, p.min) },
+		},
+		{
+			func(f fpoint) bool { return f.x <= p.max },
+			func(a, b fpoint) fpoint { return intersectX(a, b, p.max) },
+		},
+		{
+			func(f fpoint) bool { return f.y >= p.min },
+			func(a, b fpoint) fpoint { return intersectY(a, b, p.min) },
+		},
+		{
+			func(f fpoint) bool { return f.y <= p.max },
+			func(a, b fpoint) fpoint { return intersectY(a, b, p.max) },
+		},
+	}
+
+	for _, e := range edges {
+		var out []fpoint
+		for i := range ring {
+			a, b := ring[i], ring[(i+1)%len(ring)]
+			inA, inB := e.inside(a), e.inside(b)
+			if inA {
+				out = append(out, a)
+			}
+			if inA != inB {
+				out = append(out, e.cross(a, b))
+			}
+		}
+		ring = out
+	}
+	return ring
+}
+
+func intersectX(a, b fpoint, x float64) fpoint {
+	return fpoint{x, a.y + (x-a.x)*(b.y-a.y)/(b.x-a.x)}
+}
+
+func intersectY(a, b fpoint, y float64) fpoint {
+	return fpoint{a.x + (y-a.y)*(b.x-a.x)/(b.y-a.y), y}
+}
+
+func quantize(f fpoint) ipoint {
+	return ipoint{int64(math.Round(f.x)), int64(math.Round(f.y))}
+}
+
+// quantizeLine rounds the points and drops consecutive duplicates.
+func quantizeLine(points []fpoint) []ipoint {
+	out := make([]ipoint, 0, len(points))
+	for _, f := range points {
+		q := quantize(f)
+		if len(out) > 0 && out[len(out)-1] == q {
+			continue
+		}
+		out = append(out, q)
+	}
+	return out
+}
+
+// quantizeRing rounds an open ring and drops repeated vertices, including a
+// last vertex equal to the first.
+func quantizeRing(ring []fpoint) []ipoint {
+	out := quantizeLine(ring)
+	for len(out) > 1 && out[0] == out[len(out)-1] {
+		out = out[:len(out)-1]
+	}
+	if len(out) < 3 {
+		return nil
+	}
+	return out
+}
+
+// ringArea returns twice the signed area of an open ring using the
+// surveyor's formula.
+func ringArea(ring []ipoint) int64 {
+	var sum int64
+	for i := range ring {
+		a, b := ring[i], ring[(i+1)%len(ring)]
+		sum += a.x*b.y - b.x*a.y
+	}
+	return sum
+}
+
+func reverse(ring []ipoint) {
+	for i, j := 0, len(ring)-1; i < j; i, j = i+1, j-1 {
+		ring[i], ring[j] = ring[j], ring[i]
+	}
+}
+
+// commandWriter builds a geometry command stream. Coordinates are written as
+// zigzag encoded deltas from the previous cursor position, which persists
+// across the parts of a feature.
+type commandWriter struct {
+	commands []uint32
+	cursor   ipoint
+}
+
+func (w *commandWriter) command(id, count int) {
+	w.commands = append(w.commands, uint32(id&0x7)|uint32(count)<<3)
+}
+
+func (w *commandWriter) point(p ipoint) {
+	w.commands = append(w.commands,
+		uint32(pbf.ZigZag(p.x-w.cursor.x)),
+		uint32(pbf.ZigZag(p.y-w.cursor.y)),
+	)
+	w.cursor = p
+}
+
+func encodePoints(points []ipoint) []uint32 {
+	var w commandWriter
+	w.command(cmdMoveTo, len(points))
+	for _, p := range points {
+		w.point(p)
+	}
+	return w.commands
+}
+
+func encodeLines(lines [][]ipoint) []uint32 {
+	var w commandWriter
+	for _, line := range lines {
+		w.command(cmdMoveTo, 1)
+		w.point(line[0])
+		w.command(cmdLineTo, len(line)-1)
+		for _, p := range line[1:] {
+			w.point(p)
+		}
+	}
+	return w.commands
+}
+
+func encodePolygons(polygons [][][]ipoint) []uint32 {
+	var w commandWriter
+	for _, polygon := range polygons {
+		for _, ring := range polygon {
+			w.command(cmdMoveTo, 1)
+			w.point(ring[0])
+			w.command(cmdLineTo, len(ring)-1)
+			for _, p := range ring[1:] {
+				w.point(p)
+			}
+			w.command(cmdClosePath, 1)
+		}
+	}
+	return w.commands
+}
@@ -0,0 +1,256 @@
+// Package mvt encodes gogis geometries as Mapbox Vector Tiles (MVT).
+//
+// It produces the same kind of output as PostGIS' ST_AsMVT(ST_AsMVTGeom(...))
+// without a database round trip: features are projected to Web Mercator,
+// clipped to the tile extent plus a buffer, quantized to integer tile
+// coordinates and written as version 2 vector tile protobuf messages.
+//
+// Example:
+//
+//	tile := tiles.Tile{X: 1206, Y: 1539, Z: 12}
+//	data, err := mvt.Marshal(tile, mvt.Layer{
+//	    Name:   "places",
+//	    Buffer: 64,
+//	    Features: []mvt.Feature{
+//	        {ID: 1, Geometry: &place.Point, Properties: map[string]any{"name": place.Name}},
+//	    },
+//	})
+//
+// Input geometries are expected in EPSG:4326 (longitude and latitude in
+// decimal degrees).
+package mvt
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/internal/pbf"
+	"github.com/restayway/gogis/tiles"
+)
+
+// DefaultExtent is the number of tile coordinate units along each side of a
+// tile when a Layer does not set Extent.
+const DefaultExtent = 4096
+
+// Version is the vector tile specification version written by Marshal.
+const Version = 2
+
+// GeomType is the geometry type of an encoded vector tile feature.
+type GeomType int
+
+const (
+	GeomTypeUnknown    GeomType = 0
+	GeomTypePoint      GeomType = 1
+	GeomTypeLineString GeomType = 2
+	GeomTypePolygon    GeomType = 3
+)
+
+// Layer is a named set of features in a vector tile.
+type Layer struct {
+	Name     string    // Layer name, required and unique within a tile
+	Extent   uint32    // Tile coordinate units per tile side, 0 means DefaultExtent
+	Buffer   uint32    // Extra tile units kept around the tile when clipping
+	Features []Feature // Features to encode
+}
+
+// Feature is a single geometry with attributes.
+type Feature struct {
+	// ID is the feature id. Zero is treated as "no id" and is not written.
+	ID uint64
+
+	// Geometry is the feature geometry. When encoding it must be a Point,
+	// LineString, Polygon or GeometryCollection in EPSG:4326, or one of their
+	// geography variants. When decoding it
+	// holds integer tile coordinates in the Lng (X) and Lat (Y) fields.
+	Geometry gogis.Geometry
+
+	// Properties are the feature attributes. Supported value types are
+	// string, bool, all integer types, float32 and float64. Nil values are
+	// skipped.
+	Properties map[string]any
+}
+
+// Marshal encodes the layers as a vector tile for the given tile.
+//
+// Features whose geometry lies entirely outside the buffered tile are
+// dropped. A GeometryCollection mixing points, lines and polygons is written
+// as one feature per geometry type, sharing the same id and properties, since
+// a vector tile feature can only hold a single geometry type.
+func Marshal(tile tiles.Tile, layers ...Layer) ([]byte, error) {
+	var w pbf.Writer
+	for _, l := range layers {
+		msg, err := l.marshal(tile)
+		if err != nil {
+			return nil, err
+		}
+		w.Message(3, msg)
+	}
+	return w.Bytes(), nil
+}
+
+// valueKey identifies a property value in a layer's value table.
+type valueKey struct {
+	kind int
+	s    string
+	bits uint64
+}
+
+// layerEncoder builds the key and value tables of a layer.
+type layerEncoder struct {
+	keys      []string
+	keyIndex  map[string]uint32
+	values    []valueKey
+	valIndex  map[valueKey]uint32
+	projector projector
+}
+
+func (l Layer) marshal(tile tiles.Tile) ([]byte, error) {
+	if l.Name == "" {
+		return nil, fmt.Errorf("mvt: layer name is required")
+	}
+	extent := l.Extent
+	if extent == 0 {
+		extent = DefaultExtent
+	}
+
+	enc := &layerEncoder{
+		keyIndex:  map[string]uint32{},
+		valIndex:  map[valueKey]uint32{},
+		projector: newProjector(tile, extent, l.Buffer),
+	}
+
+	var w pbf.Writer
+	w.Uint64(15, Version)
+	w.String(1, l.Name)
+	for _, f := range l.Features {
+		tags, err := enc.tags(f.Properties)
+		if err != nil {
+			return nil, fmt.Errorf("mvt: layer %q: %w", l.Name, err)
+		}
+		for _, g := range enc.projector.geometries(f.Geometry) {
+			var fw pbf.Writer
+			if f.ID != 0 {
+				fw.Uint64(1, f.ID)
+			}
+			fw.PackedUint32(2, tags)
+			fw.Uint64(3, uint64(g.typ))
+			fw.PackedUint32(4, g.commands)
+			w.Message(2, fw.Bytes())
+		}
+	}
+	for _, k := range enc.keys {
+		w.String(3, k)
+	}
+	for _, v := range enc.values {
+		w.Message(4, v.marshal())
+	}
+	w.Uint64(5, uint64(extent))
+	return w.Bytes(), nil
+}
+
+// tags returns the alternating key and value indexes for the properties.
+func (e *layerEncoder) tags(props map[string]any) ([]uint32, error) {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	// Sorting keeps the output deterministic for identical input.
+	sort.Strings(keys)
+
+	var tags []uint32
+	for _, k := range keys {
+		v := props[k]
+		if v == nil {
+			continue
+		}
+		vk, err := newValueKey(v)
+		if err != nil {
+			return nil, fmt.Errorf("property %q: %w", k, err)
+		}
+		ki, ok := e.keyIndex[k]
+		if !ok {
+			ki = uint32(len(e.keys))
+			e.keys = append(e.keys, k)
+			e.keyIndex[k] = ki
+		}
+		vi, ok := e.valIndex[vk]
+		if !ok {
+			vi = uint32(len(e.values))
+			e.values = append(e.values, vk)
+			e.valIndex[vk] = vi
+		}
+		tags = append(tags, ki, vi)
+	}
+	return tags, nil
+}
+
+// Value field numbers, which double as the kind of a valueKey.
+const (
+	valueString = 1
+	valueFloat  = 2
+	valueDouble = 3
+	valueInt    = 4
+	valueUint   = 5
+	valueSint   = 6
+	valueBool   = 7
+)
+
+func newValueKey(v any) (valueKey, error) {
+	switch x := v.(type) {
+	case string:
+		return valueKey{kind: valueString, s: x}, nil
+	case bool:
+		var b uint64
+		if x {
+			b = 1
+		}
+		return valueKey{kind: valueBool, bits: b}, nil
+	case float32:
+		return valueKey{kind: valueFloat, bits: uint64(math.Float32bits(x))}, nil
+	case float64:
+		return valueKey{kind: valueDouble, bits: math.Float64bits(x)}, nil
+	case int:
+		return valueKey{kind: valueSint, bits: uint64(x)}, nil
+	case int8:
+		return valueKey{kind: valueSint, bits: uint64(x)}, nil
+	case int16:
+		return valueKey{kind: valueSint, bits: uint64(x)}, nil
+	case int32:
+		return valueKey{kind: valueSint, bits: uint64(x)}, nil
+	case int64:
+		return valueKey{kind: valueSint, bits: uint64(x)}, nil
+	case uint:
+		return valueKey{kind: valueUint, bits: uint64(x)}, nil
+	case uint8:
+		return valueKey{kind: valueUint, bits: uint64(x)}, nil
+	case uint16:
+		return valueKey{kind: valueUint, bits: uint64(x)}, nil
+	case uint32:
+		return valueKey{kind: valueUint, bits: uint64(x)}, nil
+	case uint64:
+		return valueKey{kind: valueUint, bits: x}, nil
+	default:
+		return valueKey{}, fmt.Errorf("unsupported property type %T", v)
+	}
+}
+
+func (v valueKey) marshal() []byte {
+	var w pbf.Writer
+	switch v.kind {
+	case valueString:
+		w.String(valueString, v.s)
+	case valueFloat:
+		w.Float(valueFloat, math.Float32frombits(uint32(v.bits)))
+	case valueDouble:
+		w.Double(valueDouble, math.Float64frombits(v.bits))
+	case valueUint:
+		w.Uint64(valueUint, v.bits)
+	case valueSint:
+		w.Sint64(valueSint, int64(v.bits))
+	case valueBool:
+		w.Bool(valueBool, v.bits != 0)
+	}
+	return w.Bytes()
+}
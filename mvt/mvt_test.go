@@ -0,0 +1,243 @@
+package mvt_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/mvt"
+	"github.com/restayway/gogis/tiles"
+)
+
+func decodeOne(t *testing.T, data []byte) mvt.Layer {
+	t.Helper()
+	layers, err := mvt.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("Unmarshal() unexpected error = %v", err)
+	}
+	if len(layers) != 1 {
+		t.Fatalf("Unmarshal() returned %d layers, want 1", len(layers))
+	}
+	return layers[0]
+}
+
+func TestMarshalPoint(t *testing.T) {
+	data, err := mvt.Marshal(tiles.Tile{}, mvt.Layer{
+		Name: "points",
+		Features: []mvt.Feature{
+			{
+				ID:       7,
+				Geometry: &gogis.Point{Lng: 0, Lat: 0},
+				Properties: map[string]any{
+					"name":   "null island",
+					"rank":   3,
+					"count":  uint32(12),
+					"score":  1.5,
+					"ratio":  float32(0.25),
+					"active": true,
+					"skip":   nil,
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error = %v", err)
+	}
+
+	layer := decodeOne(t, data)
+	if layer.Name != "points" || layer.Extent != mvt.DefaultExtent {
+		t.Errorf("layer = %q extent %d, want points extent %d", layer.Name, layer.Extent, mvt.DefaultExtent)
+	}
+	if len(layer.Features) != 1 {
+		t.Fatalf("layer has %d features, want 1", len(layer.Features))
+	}
+
+	f := layer.Features[0]
+	if f.ID != 7 {
+		t.Errorf("feature ID = %d, want 7", f.ID)
+	}
+	if got, want := f.Geometry, (&gogis.Point{Lng: 2048, Lat: 2048}); !reflect.DeepEqual(got, want) {
+		t.Errorf("feature geometry = %v, want %v", got, want)
+	}
+	wantProps := map[string]any{
+		"name":   "null island",
+		"rank":   int64(3),
+		"count":  uint64(12),
+		"score":  1.5,
+		"ratio":  float32(0.25),
+		"active": true,
+	}
+	if !reflect.DeepEqual(f.Properties, wantProps) {
+		t.Errorf("feature properties = %v, want %v", f.Properties, wantProps)
+	}
+}
+
+func TestMarshalLineStringClipping(t *testing.T) {
+	// At zoom 1 the top-left tile spans longitudes -180 to 0. The line runs
+	// from well inside that tile far into the neighbouring one.
+	tile := tiles.Tile{X: 0, Y: 0, Z: 1}
+	line := &gogis.LineString{
+		Points: []gogis.Point{
+			{Lng: -90, Lat: tiles.MaxLatitude / 2},
+			{Lng: 90, Lat: tiles.MaxLatitude / 2},
+		},
+	}
+
+	data, err := mvt.Marshal(tile, mvt.Layer{
+		Name:     "lines",
+		Extent:   256,
+		Buffer:   16,
+		Features: []mvt.Feature{{Geometry: line}},
+	})
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error = %v", err)
+	}
+
+	layer := decodeOne(t, data)
+	ls, ok := layer.Features[0].Geometry.(*gogis.LineString)
+	if !ok {
+		t.Fatalf("feature geometry = %T, want *gogis.LineString", layer.Features[0].Geometry)
+	}
+	if len(ls.Points) != 2 {
+		t.Fatalf("LineString has %d points, want 2", len(ls.Points))
+	}
+	if ls.Points[0].Lng != 128 || ls.Points[1].Lng != 256+16 {
+		t.Errorf("LineString x = %v..%v, want 128..272 (clipped at the buffer)", ls.Points[0].Lng, ls.Points[1].Lng)
+	}
+}
+
+func TestMarshalPolygonWinding(t *testing.T) {
+	// Counter-clockwise exterior and clockwise hole in EPSG:4326, which must be
+	// flipped to the vector tile convention.
+	polygon := &gogis.Polygon{
+		Rings: [][]gogis.Point{
+			{{Lng: -100, Lat: -60}, {Lng: 100, Lat: -60}, {Lng: 100, Lat: 60}, {Lng: -100, Lat: 60}, {Lng: -100, Lat: -60}},
+			{{Lng: -50, Lat: -30}, {Lng: -50, Lat: 30}, {Lng: 50, Lat: 30}, {Lng: 50, Lat: -30}, {Lng: -50, Lat: -30}},
+		},
+	}
+
+	data, err := mvt.Marshal(tiles.Tile{}, mvt.Layer{
+		Name:     "areas",
+		Features: []mvt.Feature{{Geometry: polygon}},
+	})
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error = %v", err)
+	}
+
+	layer := decodeOne(t, data)
+	got, ok := layer.Features[0].Geometry.(*gogis.Polygon)
+	if !ok {
+		t.Fatalf("feature geometry = %T, want *gogis.Polygon", layer.Features[0].Geometry)
+	}
+	if len(got.Rings) != 2 {
+		t.Fatalf("Polygon has %d rings, want 2", len(got.Rings))
+	}
+	if area := signedArea(got.Rings[0]); area <= 0 {
+		t.Errorf("exterior ring area = %v, want positive", area)
+	}
+	if area := signedArea(got.Rings[1]); area >= 0 {
+		t.Errorf("interior ring area = %v, want negative", area)
+	}
+	for _, ring := range got.Rings {
+		if ring[0] != ring[len(ring)-1] {
+			t.Errorf("ring %v is not closed", ring)
+		}
+	}
+}
+
+func TestMarshalPolygonClipping(t *testing.T) {
+	// A polygon covering the whole world is clipped to the buffered tile.
+	polygon := &gogis.Polygon{
+		Rings: [][]gogis.Point{
+			{{Lng: -179, Lat: -80}, {Lng: 179, Lat: -80}, {Lng: 179, Lat: 80}, {Lng: -179, Lat: 80}, {Lng: -179, Lat: -80}},
+		},
+	}
+	data, err := mvt.Marshal(tiles.Tile{X: 3, Y: 3, Z: 3}, mvt.Layer{
+		Name:     "areas",
+		Extent:   4096,
+		Buffer:   64,
+		Features: []mvt.Feature{{Geometry: polygon}},
+	})
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error = %v", err)
+	}
+
+	got := decodeOne(t, data).Features[0].Geometry.(*gogis.Polygon)
+	for _, p := range got.Rings[0] {
+		if p.Lng < -64 || p.Lng > 4160 || p.Lat < -64 || p.Lat > 4160 {
+			t.Errorf("vertex %v lies outside the buffered tile", p)
+		}
+	}
+}
+
+func TestMarshalGeometryCollection(t *testing.T) {
+	gc := &gogis.GeometryCollection{
+		Geometries: []gogis.Geometry{
+			&gogis.Point{Lng: 10, Lat: 10},
+			&gogis.Point{Lng: 20, Lat: 20},
+			&gogis.LineString{Points: []gogis.Point{{Lng: 0, Lat: 0}, {Lng: 30, Lat: 30}}},
+			&gogis.Point{Lng: 500, Lat: 0}, // Outside the tile, dropped
+		},
+	}
+
+	data, err := mvt.Marshal(tiles.Tile{}, mvt.Layer{
+		Name:     "mixed",
+		Features: []mvt.Feature{{ID: 3, Geometry: gc, Properties: map[string]any{"kind": "site"}}},
+	})
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error = %v", err)
+	}
+
+	layer := decodeOne(t, data)
+	if len(layer.Features) != 2 {
+		t.Fatalf("layer has %d features, want 2", len(layer.Features))
+	}
+	points, ok := layer.Features[0].Geometry.(*gogis.GeometryCollection)
+	if !ok || len(points.Geometries) != 2 {
+		t.Errorf("first feature = %v, want two points", layer.Features[0].Geometry)
+	}
+	if _, ok := layer.Features[1].Geometry.(*gogis.LineString); !ok {
+		t.Errorf("second feature = %T, want *gogis.LineString", layer.Features[1].Geometry)
+	}
+	for _, f := range layer.Features {
+		if f.ID != 3 || f.Properties["kind"] != "site" {
+			t.Errorf("feature %v does not share the collection id and properties", f)
+		}
+	}
+
+	// Geography values encode like their geometry counterparts.
+	geo, err := mvt.Marshal(tiles.Tile{}, mvt.Layer{
+		Name:     "mixed",
+		Features: []mvt.Feature{{ID: 3, Geometry: (*gogis.GeographyCollection)(gc), Properties: map[string]any{"kind": "site"}}},
+	})
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(geo, data) {
+		t.Errorf("Marshal() of GeographyCollection = %x, want %x", geo, data)
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	if _, err := mvt.Marshal(tiles.Tile{}, mvt.Layer{}); err == nil {
+		t.Errorf("Marshal() expected error for unnamed layer, got nil")
+	}
+
+	_, err := mvt.Marshal(tiles.Tile{}, mvt.Layer{
+		Name: "bad",
+		Features: []mvt.Feature{
+			{Geometry: &gogis.Point{}, Properties: map[string]any{"tags": []string{"a"}}},
+		},
+	})
+	if err == nil {
+		t.Errorf("Marshal() expected error for unsupported property type, got nil")
+	}
+}
+
+func signedArea(ring []gogis.Point) float64 {
+	var sum float64
+	for i := 1; i < len(ring); i++ {
+		sum += ring[i-1].Lng*ring[i].Lat - ring[i].Lng*ring[i-1].Lat
+	}
+	return sum
+}
@@ -0,0 +1,71 @@
+package gogis
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+
+	"gorm.io/gorm/clause"
+)
+
+// MySQLDialect writes geometries for MySQL 8 and MariaDB spatial columns, as
+// WKB wrapped in ST_GeomFromWKB with SRID 4326.
+//
+// MySQL follows the axis order of the spatial reference system, which is
+// latitude-longitude for SRID 4326, so the WKB is passed with the
+// 'axis-order=long-lat' option. MariaDB always uses longitude-latitude order
+// and does not support the option.
+//
+// Selecting such columns returns MySQL's internal geometry format, a 4 byte
+// SRID followed by WKB in longitude-latitude order, which the Scan methods
+// of the geometry types decode.
+type MySQLDialect struct {
+	MariaDB bool // Omit the axis order option for MariaDB
+}
+
+// GeometryExpr returns g as ST_GeomFromWKB(?, 4326, 'axis-order=long-lat').
+func (d MySQLDialect) GeometryExpr(g Geometry) (clause.Expr, error) {
+	wkb, err := EncodeEWKB(g, 0)
+	if err != nil {
+		return clause.Expr{}, err
+	}
+	if d.MariaDB {
+		return clause.Expr{SQL: "ST_GeomFromWKB(?, 4326)", Vars: []interface{}{wkbValue(wkb)}}, nil
+	}
+	return clause.Expr{SQL: "ST_GeomFromWKB(?, 4326, 'axis-order=long-lat')", Vars: []interface{}{wkbValue(wkb)}}, nil
+}
+
+// wkbValue is a binary parameter. Unlike a plain []byte, GORM does not
+// expand it into a list of parameters inside parentheses.
+type wkbValue []byte
+
+func (v wkbValue) Value() (driver.Value, error) {
+	return []byte(v), nil
+}
+
+// EncodeMySQL encodes g in the internal geometry format of MySQL and
+// MariaDB: the SRID as a little-endian 32 bit integer followed by the WKB of
+// g. Coordinates are in longitude-latitude order.
+func EncodeMySQL(g Geometry, srid SRID) ([]byte, error) {
+	wkb, err := EncodeEWKB(g, 0)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 4, 4+len(wkb))
+	binary.LittleEndian.PutUint32(b, uint32(srid))
+	return append(b, wkb...), nil
+}
+
+// DecodeMySQL decodes the internal geometry format of MySQL and MariaDB, as
+// returned when selecting a geometry column, and returns the geometry and
+// its SRID.
+func DecodeMySQL(b []byte) (Geometry, SRID, error) {
+	if len(b) < 9 {
+		return nil, 0, fmt.Errorf("gogis: decoding MySQL geometry: %d bytes is too short", len(b))
+	}
+	g, _, err := DecodeEWKB(b[4:])
+	if err != nil {
+		return nil, 0, err
+	}
+	return g, SRID(binary.LittleEndian.Uint32(b)), nil
+}
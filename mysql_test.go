@@ -0,0 +1,184 @@
+package gogis_test
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/restayway/gogis"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestEncodeMySQL(t *testing.T) {
+	b, err := gogis.EncodeMySQL(&gogis.Point{Lng: 1, Lat: 2}, gogis.SRIDWGS84)
+	if err != nil {
+		t.Fatalf("EncodeMySQL() unexpected error = %v", err)
+	}
+	// SELECT HEX(ST_GeomFromText('POINT(2 1)', 4326))
+	want := "e61000000101000000000000000000f03f0000000000000040"
+	if got := hex.EncodeToString(b); got != want {
+		t.Errorf("EncodeMySQL() = %s, want %s", got, want)
+	}
+}
+
+func TestDecodeMySQL(t *testing.T) {
+	b, _ := hex.DecodeString("E61000000101000000000000000000F03F0000000000000040")
+	g, srid, err := gogis.DecodeMySQL(b)
+	if err != nil {
+		t.Fatalf("DecodeMySQL() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(g, &gogis.Point{Lng: 1, Lat: 2}) || srid != gogis.SRIDWGS84 {
+		t.Errorf("DecodeMySQL() = %v, %d", g, srid)
+	}
+
+	if _, _, err := gogis.DecodeMySQL(b[:6]); err == nil {
+		t.Error("DecodeMySQL() expected error for truncated data, got nil")
+	}
+}
+
+func TestScanMySQL(t *testing.T) {
+	ring := []gogis.Point{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 0}, {Lng: 1, Lat: 1}, {Lng: 0, Lat: 0}}
+	tests := []struct {
+		name   string
+		value  gogis.Geometry
+		target interface {
+			gogis.Geometry
+			Scan(any) error
+		}
+	}{
+		{name: "point", value: &gogis.Point{Lng: -3.7038, Lat: 40.4168}, target: &gogis.Point{}},
+		{name: "linestring", value: &gogis.LineString{Points: ring}, target: &gogis.LineString{}},
+		{name: "polygon", value: &gogis.Polygon{Rings: [][]gogis.Point{ring}}, target: &gogis.Polygon{}},
+		{
+			name:   "collection",
+			value:  &gogis.GeometryCollection{Geometries: []gogis.Geometry{&ring[1], &gogis.LineString{Points: ring}}},
+			target: &gogis.GeometryCollection{},
+		},
+		{name: "geography point", value: &gogis.Point{Lng: 1, Lat: 2}, target: &gogis.GeographyPoint{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := gogis.EncodeMySQL(tt.value, gogis.SRIDWGS84)
+			if err != nil {
+				t.Fatalf("EncodeMySQL() unexpected error = %v", err)
+			}
+			if err := tt.target.Scan(b); err != nil {
+				t.Fatalf("Scan() unexpected error = %v", err)
+			}
+			if tt.target.String() != tt.value.String() {
+				t.Errorf("Scan() = %s, want %s", tt.target, tt.value)
+			}
+		})
+	}
+
+	t.Run("wrong type", func(t *testing.T) {
+		b, _ := gogis.EncodeMySQL(&gogis.Point{Lng: 1, Lat: 2}, gogis.SRIDWGS84)
+		var ls gogis.LineString
+		if err := ls.Scan(b); err == nil {
+			t.Error("Scan() of a point into LineString expected error, got nil")
+		}
+	})
+
+	t.Run("garbage", func(t *testing.T) {
+		var p gogis.Point
+		if err := p.Scan([]byte{0xe6, 0x10, 0, 0, 7}); err == nil {
+			t.Error("Scan() of invalid binary data expected error, got nil")
+		}
+	})
+}
+
+type dialectModel struct {
+	ID    uint
+	Point gogis.Point
+	Path  *gogis.LineString
+}
+
+func TestGormValueDialects(t *testing.T) {
+	p := gogis.Point{Lng: 1, Lat: 2}
+	wkb, _ := gogis.EncodeEWKB(&p, 0)
+	spatialite, _ := gogis.EncodeSpatiaLite(&p, gogis.SRIDWGS84)
+	gpkg, _ := gogis.EncodeGeoPackage(&p, gogis.SRIDWGS84)
+	clr, _ := gogis.EncodeSQLServer(&p, gogis.SRIDWGS84)
+
+	tests := []struct {
+		name     string
+		dialect  string
+		register gogis.Dialect
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{
+			name:     "postgres",
+			dialect:  "postgres",
+			wantSQL:  "INSERT INTO dialect_models (point,path) VALUES (?,?)",
+			wantVars: []interface{}{"SRID=4326;POINT(1 2)", nil},
+		},
+		{
+			name:     "mysql",
+			dialect:  "mysql",
+			wantSQL:  "INSERT INTO dialect_models (point,path) VALUES (ST_GeomFromWKB(?, 4326, 'axis-order=long-lat'),?)",
+			wantVars: []interface{}{wkb, nil},
+		},
+		{
+			name:     "mariadb",
+			dialect:  "mariadb-test",
+			register: gogis.MySQLDialect{MariaDB: true},
+			wantSQL:  "INSERT INTO dialect_models (point,path) VALUES (ST_GeomFromWKB(?, 4326),?)",
+			wantVars: []interface{}{wkb, nil},
+		},
+		{
+			name:     "sqlite",
+			dialect:  "sqlite",
+			wantSQL:  "INSERT INTO dialect_models (point,path) VALUES (?,?)",
+			wantVars: []interface{}{spatialite, nil},
+		},
+		{
+			name:     "geopackage",
+			dialect:  "geopackage-test",
+			register: gogis.GeoPackageDialect{},
+			wantSQL:  "INSERT INTO dialect_models (point,path) VALUES (?,?)",
+			wantVars: []interface{}{gpkg, nil},
+		},
+		{
+			name:     "sqlserver",
+			dialect:  "sqlserver",
+			wantSQL:  "INSERT INTO dialect_models (point,path) VALUES (CAST(? AS geometry),?)",
+			wantVars: []interface{}{clr, nil},
+		},
+		{
+			name:     "unregistered dialector",
+			dialect:  "oracle-test",
+			wantSQL:  "INSERT INTO dialect_models (point,path) VALUES (?,?)",
+			wantVars: []interface{}{"SRID=4326;POINT(1 2)", nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.register != nil {
+				gogis.RegisterDialect(tt.dialect, tt.register)
+			}
+			db, err := gorm.Open(testDialector{name: tt.dialect}, &gorm.Config{Logger: logger.Discard, DryRun: true})
+			if err != nil {
+				t.Fatalf("gorm.Open() unexpected error = %v", err)
+			}
+			stmt := db.Create(&dialectModel{Point: p}).Statement
+			if got := stmt.SQL.String(); got != tt.wantSQL {
+				t.Errorf("SQL = %s, want %s", got, tt.wantSQL)
+			}
+			vars := make([]interface{}, len(stmt.Vars))
+			for i, v := range stmt.Vars {
+				if valuer, ok := v.(driver.Valuer); ok {
+					v, _ = valuer.Value()
+				}
+				vars[i] = v
+			}
+			if !reflect.DeepEqual(vars, tt.wantVars) {
+				t.Errorf("Vars = %v, want %v", vars, tt.wantVars)
+			}
+		})
+	}
+}
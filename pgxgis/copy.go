@@ -0,0 +1,337 @@
+package pgxgis
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DefaultBatchSize is the number of rows per COPY statement of a Loader
+// without a BatchSize.
+const DefaultBatchSize = 10000
+
+// CopyConn runs a COPY ... FROM STDIN statement, streaming the data from r.
+// *pgconn.PgConn implements it; for a *pgx.Conn use conn.PgConn().
+type CopyConn interface {
+	CopyFrom(ctx context.Context, r io.Reader, sql string) (pgconn.CommandTag, error)
+}
+
+// Column is a target column of a Loader.
+type Column struct {
+	Name string // Column name
+	Type string // PostgreSQL type name, e.g. "geometry", "int8" or "text"
+}
+
+// Loader bulk loads rows with COPY ... FROM STDIN (FORMAT binary), sending
+// gogis geometries as EWKB. It is much faster than inserting rows one by one,
+// since PostGIS neither parses WKT nor plans a statement per row.
+//
+// Rows are read from a channel and copied in batches of BatchSize rows, each
+// batch in its own COPY statement. Encoded rows are streamed to the
+// connection as they arrive, so a producer blocks while the database is
+// busy instead of buffering a whole batch in memory.
+//
+// Example:
+//
+//	loader := pgxgis.Loader{
+//	    Table: "locations",
+//	    Columns: []pgxgis.Column{
+//	        {Name: "name", Type: "text"},
+//	        {Name: "point", Type: "geometry"},
+//	    },
+//	    ContinueOnError: true,
+//	}
+//
+//	rows := make(chan []any)
+//	go func() {
+//	    defer close(rows)
+//	    for _, place := range places {
+//	        rows <- []any{place.Name, &place.Point}
+//	    }
+//	}()
+//	copied, err := loader.Load(ctx, conn.PgConn(), rows)
+//
+// The values of geometry and geography columns may be any gogis geometry.
+// Other columns are encoded with TypeMap, so the Go values must match the
+// column type exactly, as in int32 for an int4 column. A nil value, or a nil
+// pointer, is written as NULL.
+type Loader struct {
+	Table           string            // Target table, optionally schema qualified
+	Columns         []Column          // Target columns, in the order of the row values
+	BatchSize       int               // Rows per COPY statement, 0 means DefaultBatchSize
+	ContinueOnError bool              // Keep loading after a failed batch
+	TypeMap         *pgtype.Map       // Encodes non-geometry values, nil means pgtype.NewMap()
+	OnBatch         func(BatchResult) // Optional callback after every batch
+}
+
+// BatchResult reports the outcome of one COPY statement of a Loader.
+type BatchResult struct {
+	Index    int   // Zero based batch number
+	FirstRow int64 // Position of the first row of the batch in the input
+	Rows     int64 // Number of rows read into the batch
+	Copied   int64 // Number of rows the database reported as copied
+	Err      error // Why the batch failed, nil on success
+}
+
+// BatchError is the error of a failed batch. COPY is atomic, so none of the
+// rows of the batch were stored.
+type BatchError struct {
+	BatchResult
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("pgxgis: batch %d (rows %d-%d): %v", e.Index, e.FirstRow, e.FirstRow+e.Rows-1, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// BatchErrors lists the failed batches of a Loader with ContinueOnError.
+type BatchErrors []*BatchError
+
+func (e BatchErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more failed batches)", e[0].Error(), len(e)-1)
+}
+
+// Load copies the rows received from rows until the channel is closed and
+// returns the number of rows stored. Every row must hold one value per
+// column.
+//
+// A failed batch stops loading with a *BatchError, unless ContinueOnError is
+// set, in which case the remaining batches are loaded and the failures are
+// returned as BatchErrors. Cancelling ctx aborts the current batch and returns
+// the context error.
+func (l Loader) Load(ctx context.Context, conn CopyConn, rows <-chan []any) (int64, error) {
+	enc, err := l.encoder()
+	if err != nil {
+		return 0, err
+	}
+	batchSize := l.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	sql := l.copySQL()
+
+	var (
+		copied  int64
+		offset  int64
+		failed  BatchErrors
+		pending []any // first row of the next batch
+	)
+	for index := 0; ; index++ {
+		if pending == nil {
+			select {
+			case row, ok := <-rows:
+				if !ok {
+					if len(failed) > 0 {
+						return copied, failed
+					}
+					return copied, nil
+				}
+				pending = row
+			case <-ctx.Done():
+				return copied, ctx.Err()
+			}
+		}
+
+		result, more := l.copyBatch(ctx, conn, sql, enc, pending, rows, batchSize)
+		pending = nil
+		result.Index = index
+		result.FirstRow = offset
+		offset += result.Rows
+		copied += result.Copied
+		if l.OnBatch != nil {
+			l.OnBatch(result)
+		}
+
+		if ctx.Err() != nil {
+			return copied, ctx.Err()
+		}
+		if result.Err != nil {
+			batchErr := &BatchError{BatchResult: result}
+			if !l.ContinueOnError {
+				return copied, batchErr
+			}
+			failed = append(failed, batchErr)
+		}
+		if !more {
+			if len(failed) > 0 {
+				return copied, failed
+			}
+			return copied, nil
+		}
+	}
+}
+
+// copyBatch copies first and up to batchSize-1 further rows in one COPY
+// statement. It reports whether rows may hold more rows.
+func (l Loader) copyBatch(ctx context.Context, conn CopyConn, sql string, enc *rowEncoder,
+	first []any, rows <-chan []any, batchSize int) (result BatchResult, more bool) {
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	var tag pgconn.CommandTag
+	var copyErr error
+	go func() {
+		defer close(done)
+		tag, copyErr = conn.CopyFrom(ctx, pr, sql)
+		// Unblock the writer if the copy ended before reading all data.
+		pr.CloseWithError(errCopyEnded)
+	}()
+
+	w := bufio.NewWriterSize(pw, 64*1024)
+	writeErr := func() error {
+		if _, err := w.Write(copyHeader); err != nil {
+			return err
+		}
+		row, ok := first, true
+		for {
+			result.Rows++
+			buf, err := enc.encode(enc.buf[:0], row)
+			if err != nil {
+				return fmt.Errorf("row %d: %w", result.Rows-1, err)
+			}
+			enc.buf = buf
+			if _, err := w.Write(buf); err != nil {
+				return err
+			}
+			if result.Rows == int64(batchSize) {
+				break
+			}
+			select {
+			case row, ok = <-rows:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if !ok {
+				break
+			}
+		}
+		if _, err := w.Write(copyTrailer); err != nil {
+			return err
+		}
+		return w.Flush()
+	}()
+	// Rows are only taken from the channel until the batch is full or the
+	// channel is closed; more is false only in the latter case.
+	more = writeErr != nil || result.Rows == int64(batchSize)
+	if writeErr != nil && errors.Is(writeErr, errCopyEnded) {
+		writeErr = nil
+	}
+	if writeErr != nil {
+		pw.CloseWithError(writeErr)
+	} else {
+		pw.Close()
+	}
+	<-done
+
+	switch {
+	case writeErr != nil:
+		result.Err = writeErr
+	case copyErr != nil:
+		result.Err = copyErr
+	default:
+		result.Copied = tag.RowsAffected()
+	}
+	return result, more
+}
+
+// errCopyEnded unblocks writes to a COPY that ended early; the error of the
+// COPY itself is reported instead.
+var errCopyEnded = errors.New("pgxgis: copy ended")
+
+func (l Loader) copySQL() string {
+	columns := make([]string, len(l.Columns))
+	for i, c := range l.Columns {
+		columns[i] = pgx.Identifier{c.Name}.Sanitize()
+	}
+	table := pgx.Identifier(strings.Split(l.Table, ".")).Sanitize()
+	return fmt.Sprintf("COPY %s (%s) FROM STDIN (FORMAT binary)", table, strings.Join(columns, ", "))
+}
+
+// The binary COPY format: a signature, 32 bit flags and header extension
+// length, tuples, and a field count of -1 as trailer.
+var (
+	copyHeader  = []byte("PGCOPY\n\377\r\n\000\000\000\000\000\000\000\000\000")
+	copyTrailer = []byte{0xff, 0xff}
+)
+
+// rowEncoder encodes rows as binary COPY tuples.
+type rowEncoder struct {
+	m       *pgtype.Map
+	columns []Column
+	oids    []uint32 // 0 for geometry and geography columns
+	buf     []byte
+}
+
+func (l Loader) encoder() (*rowEncoder, error) {
+	if l.Table == "" || len(l.Columns) == 0 {
+		return nil, fmt.Errorf("pgxgis: loader needs a table and columns")
+	}
+	m := l.TypeMap
+	if m == nil {
+		m = pgtype.NewMap()
+	}
+	enc := &rowEncoder{m: m, columns: l.Columns, oids: make([]uint32, len(l.Columns))}
+	for i, c := range l.Columns {
+		switch c.Type {
+		case "geometry", "geography":
+			continue
+		}
+		t, ok := m.TypeForName(c.Type)
+		if !ok {
+			return nil, fmt.Errorf("pgxgis: column %s: unknown type %q", c.Name, c.Type)
+		}
+		enc.oids[i] = t.OID
+	}
+	return enc, nil
+}
+
+func (e *rowEncoder) encode(buf []byte, row []any) ([]byte, error) {
+	if len(row) != len(e.columns) {
+		return nil, fmt.Errorf("got %d values for %d columns", len(row), len(e.columns))
+	}
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(row)))
+	for i, v := range row {
+		lenPos := len(buf)
+		buf = append(buf, 0, 0, 0, 0)
+
+		var data []byte
+		var err error
+		if e.oids[i] == 0 {
+			if v != nil {
+				if _, ok := toGeometry(v); !ok {
+					return nil, fmt.Errorf("column %s: unsupported value %T", e.columns[i].Name, v)
+				}
+				data, err = encode(v)
+			}
+			buf = append(buf, data...)
+		} else {
+			data, err = e.m.Encode(e.oids[i], pgtype.BinaryFormatCode, v, buf)
+			if data != nil {
+				buf = data
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", e.columns[i].Name, err)
+		}
+
+		if data == nil {
+			binary.BigEndian.PutUint32(buf[lenPos:], 0xffffffff)
+		} else {
+			binary.BigEndian.PutUint32(buf[lenPos:], uint32(len(buf)-lenPos-4))
+		}
+	}
+	return buf, nil
+}
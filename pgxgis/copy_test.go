@@ -0,0 +1,358 @@
+package pgxgis_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/pgxgis"
+)
+
+// fakeCopy consumes binary COPY streams in process, the way PostgreSQL
+// would, and records the decoded tuples of every statement.
+type fakeCopy struct {
+	mu         sync.Mutex
+	statements []string
+	batches    [][][][]byte // fields of the rows of each batch, nil for NULL
+
+	fail func(batch int) error // optional failure of a batch
+	gate chan struct{}         // if set, reading starts once it is closed
+}
+
+func (f *fakeCopy) CopyFrom(ctx context.Context, r io.Reader, sql string) (pgconn.CommandTag, error) {
+	if f.gate != nil {
+		<-f.gate
+	}
+	data, err := io.ReadAll(r)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	batch := len(f.statements)
+	f.statements = append(f.statements, sql)
+	if err != nil {
+		f.batches = append(f.batches, nil)
+		return pgconn.CommandTag{}, fmt.Errorf("COPY from stdin failed: %w", err)
+	}
+	rows, err := parseCopy(data)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	if f.fail != nil {
+		if err := f.fail(batch); err != nil {
+			f.batches = append(f.batches, nil)
+			return pgconn.CommandTag{}, err
+		}
+	}
+	f.batches = append(f.batches, rows)
+	return pgconn.NewCommandTag(fmt.Sprintf("COPY %d", len(rows))), nil
+}
+
+func parseCopy(data []byte) ([][][]byte, error) {
+	header := []byte("PGCOPY\n\377\r\n\000")
+	if !bytes.HasPrefix(data, header) || len(data) < len(header)+8 {
+		return nil, errors.New("invalid COPY header")
+	}
+	data = data[len(header)+8:]
+
+	var rows [][][]byte
+	for {
+		if len(data) < 2 {
+			return nil, errors.New("missing COPY trailer")
+		}
+		n := int16(binary.BigEndian.Uint16(data))
+		data = data[2:]
+		if n == -1 {
+			if len(data) != 0 {
+				return nil, errors.New("data after COPY trailer")
+			}
+			return rows, nil
+		}
+		fields := make([][]byte, n)
+		for i := range fields {
+			size := int32(binary.BigEndian.Uint32(data))
+			data = data[4:]
+			if size < 0 {
+				continue
+			}
+			fields[i] = data[:size]
+			data = data[size:]
+		}
+		rows = append(rows, fields)
+	}
+}
+
+var locationColumns = []pgxgis.Column{
+	{Name: "id", Type: "int8"},
+	{Name: "name", Type: "text"},
+	{Name: "point", Type: "geometry"},
+}
+
+func sendRows(rows [][]any) <-chan []any {
+	ch := make(chan []any)
+	go func() {
+		defer close(ch)
+		for _, row := range rows {
+			ch <- row
+		}
+	}()
+	return ch
+}
+
+func locationRows(n int) [][]any {
+	rows := make([][]any, n)
+	for i := range rows {
+		rows[i] = []any{int64(i), fmt.Sprintf("place %d", i), &gogis.Point{Lng: float64(i), Lat: 1}}
+	}
+	return rows
+}
+
+func TestLoaderLoad(t *testing.T) {
+	fake := &fakeCopy{}
+	var results []pgxgis.BatchResult
+	loader := pgxgis.Loader{
+		Table:     "public.locations",
+		Columns:   locationColumns,
+		BatchSize: 10,
+		OnBatch:   func(r pgxgis.BatchResult) { results = append(results, r) },
+	}
+
+	rows := locationRows(24)
+	rows = append(rows, []any{int64(24), nil, (*gogis.Point)(nil)})
+	copied, err := loader.Load(context.Background(), fake, sendRows(rows))
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if copied != 25 {
+		t.Errorf("Load() copied = %d, want 25", copied)
+	}
+
+	wantSQL := `COPY "public"."locations" ("id", "name", "point") FROM STDIN (FORMAT binary)`
+	if len(fake.statements) != 3 {
+		t.Fatalf("got %d COPY statements, want 3", len(fake.statements))
+	}
+	if fake.statements[0] != wantSQL {
+		t.Errorf("statement = %s, want %s", fake.statements[0], wantSQL)
+	}
+
+	wantResults := []pgxgis.BatchResult{
+		{Index: 0, FirstRow: 0, Rows: 10, Copied: 10},
+		{Index: 1, FirstRow: 10, Rows: 10, Copied: 10},
+		{Index: 2, FirstRow: 20, Rows: 5, Copied: 5},
+	}
+	if !reflect.DeepEqual(results, wantResults) {
+		t.Errorf("OnBatch results = %+v, want %+v", results, wantResults)
+	}
+
+	row := fake.batches[1][3] // row 13
+	if id := int64(binary.BigEndian.Uint64(row[0])); id != 13 {
+		t.Errorf("id = %d, want 13", id)
+	}
+	if string(row[1]) != "place 13" {
+		t.Errorf("name = %q, want %q", row[1], "place 13")
+	}
+	g, srid, err := gogis.DecodeEWKB(row[2])
+	if err != nil {
+		t.Fatalf("DecodeEWKB() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(g, &gogis.Point{Lng: 13, Lat: 1}) || srid != gogis.SRIDWGS84 {
+		t.Errorf("point = %v (SRID %d), want POINT(13 1) with SRID 4326", g, srid)
+	}
+
+	last := fake.batches[2][4]
+	if last[1] != nil || last[2] != nil {
+		t.Errorf("nil values = %v, want NULLs", last[1:])
+	}
+}
+
+func TestLoaderBatchError(t *testing.T) {
+	serverErr := errors.New("duplicate key value violates unique constraint")
+	fail := func(batch int) error {
+		if batch == 1 {
+			return serverErr
+		}
+		return nil
+	}
+
+	t.Run("stop", func(t *testing.T) {
+		fake := &fakeCopy{fail: fail}
+		loader := pgxgis.Loader{Table: "locations", Columns: locationColumns, BatchSize: 10}
+		rows := make(chan []any, 30)
+		for _, row := range locationRows(30) {
+			rows <- row
+		}
+		close(rows)
+
+		copied, err := loader.Load(context.Background(), fake, rows)
+		if copied != 10 {
+			t.Errorf("Load() copied = %d, want 10", copied)
+		}
+		var batchErr *pgxgis.BatchError
+		if !errors.As(err, &batchErr) {
+			t.Fatalf("Load() error = %v, want *BatchError", err)
+		}
+		if batchErr.Index != 1 || batchErr.FirstRow != 10 || batchErr.Rows != 10 {
+			t.Errorf("BatchError = %+v, want batch 1 with rows 10-19", batchErr.BatchResult)
+		}
+		if !errors.Is(err, serverErr) {
+			t.Errorf("Load() error = %v, want it to wrap the COPY error", err)
+		}
+		if len(fake.statements) != 2 {
+			t.Errorf("got %d COPY statements, want 2", len(fake.statements))
+		}
+	})
+
+	t.Run("continue", func(t *testing.T) {
+		fake := &fakeCopy{fail: fail}
+		loader := pgxgis.Loader{Table: "locations", Columns: locationColumns, BatchSize: 10, ContinueOnError: true}
+
+		copied, err := loader.Load(context.Background(), fake, sendRows(locationRows(30)))
+		if copied != 20 {
+			t.Errorf("Load() copied = %d, want 20", copied)
+		}
+		var batchErrs pgxgis.BatchErrors
+		if !errors.As(err, &batchErrs) || len(batchErrs) != 1 {
+			t.Fatalf("Load() error = %v, want one BatchErrors entry", err)
+		}
+		if batchErrs[0].FirstRow != 10 {
+			t.Errorf("failed batch starts at row %d, want 10", batchErrs[0].FirstRow)
+		}
+		if len(fake.statements) != 3 {
+			t.Errorf("got %d COPY statements, want 3", len(fake.statements))
+		}
+	})
+}
+
+func TestLoaderEncodeError(t *testing.T) {
+	fake := &fakeCopy{}
+	loader := pgxgis.Loader{Table: "locations", Columns: locationColumns, BatchSize: 10, ContinueOnError: true}
+
+	rows := locationRows(15)
+	rows[12][2] = "POINT(1 2)"
+	copied, err := loader.Load(context.Background(), fake, sendRows(rows))
+	if copied != 12 {
+		t.Errorf("Load() copied = %d, want 12", copied)
+	}
+	if err == nil || !strings.Contains(err.Error(), "column point: unsupported value string") {
+		t.Fatalf("Load() error = %v, want an unsupported value error", err)
+	}
+	var batchErrs pgxgis.BatchErrors
+	if !errors.As(err, &batchErrs) || batchErrs[0].FirstRow != 10 || batchErrs[0].Rows != 3 {
+		t.Errorf("Load() error = %#v, want a failed batch of rows 10-12", err)
+	}
+	// The rows after the failing one are loaded in the next batch.
+	if len(fake.batches) != 3 || len(fake.batches[2]) != 2 {
+		t.Errorf("batches = %d, want the last 2 rows in a third batch", len(fake.batches))
+	}
+}
+
+func TestLoaderBackpressure(t *testing.T) {
+	fake := &fakeCopy{gate: make(chan struct{})}
+	loader := pgxgis.Loader{
+		Table:   "routes",
+		Columns: []pgxgis.Column{{Name: "path", Type: "geometry"}},
+	}
+
+	// Each row is about 16KB of EWKB.
+	path := &gogis.LineString{Points: make([]gogis.Point, 1000)}
+	rows := make(chan []any)
+	var copied int64
+	var loadErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		copied, loadErr = loader.Load(context.Background(), fake, rows)
+	}()
+
+	// While the database does not read, the loader stops accepting rows once
+	// its buffers are full.
+	sent := 0
+	for sent < 100 {
+		select {
+		case rows <- []any{path}:
+			sent++
+			continue
+		case <-time.After(50 * time.Millisecond):
+		}
+		break
+	}
+	if sent >= 100 {
+		t.Fatalf("sent %d rows to a stalled COPY, want the loader to block", sent)
+	}
+
+	close(fake.gate)
+	for ; sent < 100; sent++ {
+		rows <- []any{path}
+	}
+	close(rows)
+	<-done
+	if loadErr != nil || copied != 100 {
+		t.Errorf("Load() = %d, %v, want 100 rows", copied, loadErr)
+	}
+}
+
+func TestLoaderCancel(t *testing.T) {
+	fake := &fakeCopy{}
+	loader := pgxgis.Loader{Table: "locations", Columns: locationColumns}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	rows := make(chan []any)
+	go func() {
+		for _, row := range locationRows(5) {
+			rows <- row
+		}
+		cancel()
+	}()
+	copied, err := loader.Load(ctx, fake, rows)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Load() error = %v, want context.Canceled", err)
+	}
+	if copied != 0 {
+		t.Errorf("Load() copied = %d, want 0", copied)
+	}
+}
+
+func TestLoaderConfigErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		loader pgxgis.Loader
+		row    []any
+		error  string
+	}{
+		{name: "no columns", loader: pgxgis.Loader{Table: "t"}, error: "needs a table and columns"},
+		{
+			name:   "unknown type",
+			loader: pgxgis.Loader{Table: "t", Columns: []pgxgis.Column{{Name: "a", Type: "nosuchtype"}}},
+			error:  `unknown type "nosuchtype"`,
+		},
+		{
+			name:   "wrong value count",
+			loader: pgxgis.Loader{Table: "t", Columns: locationColumns},
+			row:    []any{int64(1)},
+			error:  "got 1 values for 3 columns",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := make(chan []any, 1)
+			if tt.row != nil {
+				rows <- tt.row
+			}
+			close(rows)
+			_, err := tt.loader.Load(context.Background(), &fakeCopy{}, rows)
+			if err == nil || !strings.Contains(err.Error(), tt.error) {
+				t.Errorf("Load() error = %v, want error containing %q", err, tt.error)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+// Package pgxgis registers gogis geometries with pgx, so that PostGIS
+// geometry and geography values are transferred as binary EWKB instead of
+// hex encoded text and WKT.
+//
+// PostGIS types are created by the extension, so their OIDs differ between
+// databases. RegisterTypes looks them up once per connection, typically from
+// the AfterConnect hook of a pool:
+//
+//	config, err := pgxpool.ParseConfig(dsn)
+//	if err != nil {
+//	    return err
+//	}
+//	config.AfterConnect = pgxgis.RegisterTypes
+//
+// Afterwards gogis values can be used directly as query arguments and scan
+// targets:
+//
+//	var p gogis.Point
+//	err := conn.QueryRow(ctx, "SELECT point FROM locations WHERE id = $1", id).Scan(&p)
+//
+//	_, err = conn.Exec(ctx, "INSERT INTO locations (point) VALUES ($1)", &p)
+//
+// Any gogis geometry, including the geography variants, can be written to
+// either column type. Scanning accepts the matching gogis type, or a
+// *gogis.Geometry for columns of mixed geometry types. Multi geometries scan
+// as a *gogis.GeometryCollection.
+//
+// Loader bulk loads rows through COPY ... FROM STDIN in binary format, which
+// is the fastest way to import large amounts of geometries.
+package pgxgis
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/restayway/gogis"
+)
+
+// Codec is the pgx codec of the PostGIS geometry and geography types. Values
+// are encoded as EWKB with SRID 4326; the text format carries the same EWKB
+// hex encoded.
+type Codec struct{}
+
+// geometryValues and geographyValues are the Go types mapped to the PostGIS
+// types when a query does not state the parameter type, as with the simple
+// protocol.
+var (
+	geometryValues = []any{
+		gogis.Point{}, &gogis.Point{},
+		gogis.LineString{}, &gogis.LineString{},
+		gogis.Polygon{}, &gogis.Polygon{},
+		gogis.GeometryCollection{}, &gogis.GeometryCollection{},
+	}
+	geographyValues = []any{
+		gogis.GeographyPoint{}, &gogis.GeographyPoint{},
+		gogis.GeographyLineString{}, &gogis.GeographyLineString{},
+		gogis.GeographyPolygon{}, &gogis.GeographyPolygon{},
+		gogis.GeographyCollection{}, &gogis.GeographyCollection{},
+	}
+)
+
+// Register registers the geometry and geography types with the given OIDs in
+// m. An OID of 0 skips the type, for databases without geography support.
+func Register(m *pgtype.Map, geometryOID, geographyOID uint32) {
+	if geometryOID != 0 {
+		m.RegisterType(&pgtype.Type{Name: "geometry", OID: geometryOID, Codec: Codec{}})
+		for _, v := range geometryValues {
+			m.RegisterDefaultPgType(v, "geometry")
+		}
+	}
+	if geographyOID != 0 {
+		m.RegisterType(&pgtype.Type{Name: "geography", OID: geographyOID, Codec: Codec{}})
+		for _, v := range geographyValues {
+			m.RegisterDefaultPgType(v, "geography")
+		}
+	}
+}
+
+// RegisterTypes looks up the OIDs of the PostGIS types and registers them in
+// the type map of conn. It has the signature of pgxpool.Config.AfterConnect.
+func RegisterTypes(ctx context.Context, conn *pgx.Conn) error {
+	var geometryOID, geographyOID pgtype.Uint32
+	err := conn.QueryRow(ctx, "SELECT to_regtype('geometry')::oid, to_regtype('geography')::oid").
+		Scan(&geometryOID, &geographyOID)
+	if err != nil {
+		return fmt.Errorf("pgxgis: looking up PostGIS types: %w", err)
+	}
+	if !geometryOID.Valid {
+		return fmt.Errorf("pgxgis: type geometry does not exist, is the postgis extension installed?")
+	}
+	Register(conn.TypeMap(), geometryOID.Uint32, geographyOID.Uint32)
+	return nil
+}
+
+// FormatSupported reports whether format is the text or binary format.
+func (Codec) FormatSupported(format int16) bool {
+	return format == pgtype.TextFormatCode || format == pgtype.BinaryFormatCode
+}
+
+// PreferredFormat returns the binary format.
+func (Codec) PreferredFormat() int16 {
+	return pgtype.BinaryFormatCode
+}
+
+// PlanEncode returns a plan encoding gogis geometries, or nil for other
+// values.
+func (Codec) PlanEncode(m *pgtype.Map, oid uint32, format int16, value any) pgtype.EncodePlan {
+	if _, ok := toGeometry(value); !ok {
+		return nil
+	}
+	switch format {
+	case pgtype.BinaryFormatCode:
+		return encodePlanBinary{}
+	case pgtype.TextFormatCode:
+		return encodePlanText{}
+	}
+	return nil
+}
+
+type encodePlanBinary struct{}
+
+func (encodePlanBinary) Encode(value any, buf []byte) ([]byte, error) {
+	b, err := encode(value)
+	if b == nil || err != nil {
+		return nil, err
+	}
+	return append(buf, b...), nil
+}
+
+type encodePlanText struct{}
+
+func (encodePlanText) Encode(value any, buf []byte) ([]byte, error) {
+	b, err := encode(value)
+	if b == nil || err != nil {
+		return nil, err
+	}
+	return append(buf, hex.EncodeToString(b)...), nil
+}
+
+// encode returns the EWKB of value, or nil for a nil pointer.
+func encode(value any) ([]byte, error) {
+	g, _ := toGeometry(value)
+	if reflect.ValueOf(g).IsNil() {
+		return nil, nil
+	}
+	b, err := gogis.EncodeEWKB(g, gogis.SRIDWGS84)
+	if err != nil {
+		return nil, fmt.Errorf("pgxgis: %w", err)
+	}
+	return b, nil
+}
+
+// toGeometry returns the gogis geometry held by value, which may be a
+// geometry or a pointer to one.
+func toGeometry(value any) (gogis.Geometry, bool) {
+	switch v := value.(type) {
+	case *gogis.Point, *gogis.LineString, *gogis.Polygon, *gogis.GeometryCollection,
+		*gogis.GeographyPoint, *gogis.GeographyLineString, *gogis.GeographyPolygon, *gogis.GeographyCollection:
+		return v.(gogis.Geometry), true
+	case gogis.Point:
+		return &v, true
+	case gogis.LineString:
+		return &v, true
+	case gogis.Polygon:
+		return &v, true
+	case gogis.GeometryCollection:
+		return &v, true
+	case gogis.GeographyPoint:
+		return &v, true
+	case gogis.GeographyLineString:
+		return &v, true
+	case gogis.GeographyPolygon:
+		return &v, true
+	case gogis.GeographyCollection:
+		return &v, true
+	}
+	return nil, false
+}
+
+// PlanScan returns a plan scanning into gogis geometries, or nil for other
+// targets.
+func (Codec) PlanScan(m *pgtype.Map, oid uint32, format int16, target any) pgtype.ScanPlan {
+	switch target.(type) {
+	case *gogis.Geometry, *gogis.Point, *gogis.LineString, *gogis.Polygon, *gogis.GeometryCollection,
+		*gogis.GeographyPoint, *gogis.GeographyLineString, *gogis.GeographyPolygon, *gogis.GeographyCollection:
+		return scanPlan{format: format}
+	}
+	return nil
+}
+
+type scanPlan struct {
+	format int16
+}
+
+func (p scanPlan) Scan(src []byte, target any) error {
+	if src == nil {
+		if g, ok := target.(*gogis.Geometry); ok {
+			*g = nil
+			return nil
+		}
+		return fmt.Errorf("pgxgis: cannot scan NULL into %T", target)
+	}
+	g, err := decode(p.format, src)
+	if err != nil {
+		return err
+	}
+	return assign(g, target)
+}
+
+func decode(format int16, src []byte) (gogis.Geometry, error) {
+	if format == pgtype.TextFormatCode {
+		b := make([]byte, hex.DecodedLen(len(src)))
+		if _, err := hex.Decode(b, src); err != nil {
+			return nil, fmt.Errorf("pgxgis: %w", err)
+		}
+		src = b
+	}
+	g, _, err := gogis.DecodeEWKB(src)
+	return g, err
+}
+
+// assign stores g in target, converting to the geography variants.
+func assign(g gogis.Geometry, target any) error {
+	switch t := target.(type) {
+	case *gogis.Geometry:
+		*t = g
+		return nil
+	case *gogis.Point:
+		if v, ok := g.(*gogis.Point); ok {
+			*t = *v
+			return nil
+		}
+	case *gogis.GeographyPoint:
+		if v, ok := g.(*gogis.Point); ok {
+			*t = gogis.GeographyPoint(*v)
+			return nil
+		}
+	case *gogis.LineString:
+		if v, ok := g.(*gogis.LineString); ok {
+			*t = *v
+			return nil
+		}
+	case *gogis.GeographyLineString:
+		if v, ok := g.(*gogis.LineString); ok {
+			*t = gogis.GeographyLineString(*v)
+			return nil
+		}
+	case *gogis.Polygon:
+		if v, ok := g.(*gogis.Polygon); ok {
+			*t = *v
+			return nil
+		}
+	case *gogis.GeographyPolygon:
+		if v, ok := g.(*gogis.Polygon); ok {
+			*t = gogis.GeographyPolygon(*v)
+			return nil
+		}
+	case *gogis.GeometryCollection:
+		if v, ok := g.(*gogis.GeometryCollection); ok {
+			*t = *v
+			return nil
+		}
+	case *gogis.GeographyCollection:
+		if v, ok := g.(*gogis.GeometryCollection); ok {
+			*t = gogis.GeographyCollection(*v)
+			return nil
+		}
+	}
+	return fmt.Errorf("pgxgis: cannot scan %T into %T", g, target)
+}
+
+// DecodeDatabaseSQLValue returns the value as hex encoded EWKB, the form the
+// sql.Scanner implementations of the gogis types read.
+func (Codec) DecodeDatabaseSQLValue(m *pgtype.Map, oid uint32, format int16, src []byte) (driver.Value, error) {
+	if src == nil {
+		return nil, nil
+	}
+	if format == pgtype.TextFormatCode {
+		return string(src), nil
+	}
+	return hex.EncodeToString(src), nil
+}
+
+// DecodeValue returns the value as a gogis.Geometry.
+func (Codec) DecodeValue(m *pgtype.Map, oid uint32, format int16, src []byte) (any, error) {
+	if src == nil {
+		return nil, nil
+	}
+	return decode(format, src)
+}
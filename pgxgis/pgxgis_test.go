@@ -0,0 +1,227 @@
+package pgxgis_test
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/pgxgis"
+)
+
+const (
+	geometryOID  = 16400
+	geographyOID = 16401
+)
+
+func newMap() *pgtype.Map {
+	m := pgtype.NewMap()
+	pgxgis.Register(m, geometryOID, geographyOID)
+	return m
+}
+
+func TestEncodeBinary(t *testing.T) {
+	m := newMap()
+	p := gogis.Point{Lng: 1, Lat: 2}
+	want := "0101000020e6100000000000000000f03f0000000000000040"
+
+	for _, value := range []any{p, &p, gogis.GeographyPoint(p), (*gogis.GeographyPoint)(&p)} {
+		for _, oid := range []uint32{geometryOID, geographyOID} {
+			buf, err := m.Encode(oid, pgtype.BinaryFormatCode, value, nil)
+			if err != nil {
+				t.Fatalf("Encode(%T) unexpected error = %v", value, err)
+			}
+			if got := hex.EncodeToString(buf); got != want {
+				t.Errorf("Encode(%T) = %s, want %s", value, got, want)
+			}
+		}
+	}
+}
+
+func TestEncodeText(t *testing.T) {
+	m := newMap()
+	buf, err := m.Encode(geometryOID, pgtype.TextFormatCode, &gogis.Point{Lng: 1, Lat: 2}, nil)
+	if err != nil {
+		t.Fatalf("Encode() unexpected error = %v", err)
+	}
+	if got, want := string(buf), "0101000020e6100000000000000000f03f0000000000000040"; got != want {
+		t.Errorf("Encode() = %s, want %s", got, want)
+	}
+}
+
+func TestEncodeNil(t *testing.T) {
+	m := newMap()
+	buf, err := m.Encode(geometryOID, pgtype.BinaryFormatCode, (*gogis.Point)(nil), nil)
+	if err != nil {
+		t.Fatalf("Encode() unexpected error = %v", err)
+	}
+	if buf != nil {
+		t.Errorf("Encode() = %x, want nil for NULL", buf)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	ring := []gogis.Point{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 0}, {Lng: 1, Lat: 1}, {Lng: 0, Lat: 0}}
+	tests := []struct {
+		name   string
+		oid    uint32
+		value  any
+		target any
+	}{
+		{name: "point", oid: geometryOID, value: &gogis.Point{Lng: -74.0445, Lat: 40.6892}, target: &gogis.Point{}},
+		{name: "linestring", oid: geometryOID, value: &gogis.LineString{Points: ring}, target: &gogis.LineString{}},
+		{name: "polygon", oid: geometryOID, value: &gogis.Polygon{Rings: [][]gogis.Point{ring}}, target: &gogis.Polygon{}},
+		{
+			name: "collection",
+			oid:  geometryOID,
+			value: &gogis.GeometryCollection{Geometries: []gogis.Geometry{
+				&gogis.Point{Lng: 1, Lat: 2},
+				&gogis.Polygon{Rings: [][]gogis.Point{ring}},
+			}},
+			target: &gogis.GeometryCollection{},
+		},
+		{name: "geography point", oid: geographyOID, value: &gogis.GeographyPoint{Lng: 2.2945, Lat: 48.8584}, target: &gogis.GeographyPoint{}},
+		{name: "geography linestring", oid: geographyOID, value: &gogis.GeographyLineString{Points: ring}, target: &gogis.GeographyLineString{}},
+		{name: "geography polygon", oid: geographyOID, value: &gogis.GeographyPolygon{Rings: [][]gogis.Point{ring}}, target: &gogis.GeographyPolygon{}},
+		{
+			name:   "geography collection",
+			oid:    geographyOID,
+			value:  &gogis.GeographyCollection{Geometries: []gogis.Geometry{&gogis.Point{Lng: 1, Lat: 2}}},
+			target: &gogis.GeographyCollection{},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, format := range []int16{pgtype.BinaryFormatCode, pgtype.TextFormatCode} {
+			t.Run(tt.name, func(t *testing.T) {
+				m := newMap()
+				buf, err := m.Encode(tt.oid, format, tt.value, nil)
+				if err != nil {
+					t.Fatalf("Encode() unexpected error = %v", err)
+				}
+				target := reflect.New(reflect.TypeOf(tt.target).Elem()).Interface()
+				if err := m.Scan(tt.oid, format, buf, target); err != nil {
+					t.Fatalf("Scan() unexpected error = %v", err)
+				}
+				if !reflect.DeepEqual(target, tt.value) {
+					t.Errorf("format %d: Scan() = %v, want %v", format, target, tt.value)
+				}
+			})
+		}
+	}
+}
+
+func TestScanGeometryInterface(t *testing.T) {
+	m := newMap()
+	// SELECT 'SRID=4326;MULTIPOINT(1 2,3 4)'::geometry
+	src, _ := hex.DecodeString("0104000020E610000002000000" +
+		"0101000000000000000000F03F0000000000000040" +
+		"010100000000000000000008400000000000001040")
+
+	var g gogis.Geometry
+	if err := m.Scan(geometryOID, pgtype.BinaryFormatCode, src, &g); err != nil {
+		t.Fatalf("Scan() unexpected error = %v", err)
+	}
+	want := &gogis.GeometryCollection{Geometries: []gogis.Geometry{
+		&gogis.Point{Lng: 1, Lat: 2},
+		&gogis.Point{Lng: 3, Lat: 4},
+	}}
+	if !reflect.DeepEqual(g, want) {
+		t.Errorf("Scan() = %v, want %v", g, want)
+	}
+
+	if err := m.Scan(geometryOID, pgtype.BinaryFormatCode, nil, &g); err != nil {
+		t.Fatalf("Scan(NULL) unexpected error = %v", err)
+	}
+	if g != nil {
+		t.Errorf("Scan(NULL) = %v, want nil", g)
+	}
+}
+
+func TestScanNull(t *testing.T) {
+	m := newMap()
+
+	p := &gogis.Point{Lng: 1, Lat: 2}
+	if err := m.Scan(geometryOID, pgtype.BinaryFormatCode, nil, &p); err != nil {
+		t.Fatalf("Scan(NULL) into **Point unexpected error = %v", err)
+	}
+	if p != nil {
+		t.Errorf("Scan(NULL) = %v, want nil", p)
+	}
+
+	var v gogis.Point
+	if err := m.Scan(geometryOID, pgtype.BinaryFormatCode, nil, &v); err == nil {
+		t.Error("Scan(NULL) into *Point expected error, got nil")
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	m := newMap()
+	line, _ := gogis.EncodeEWKB(&gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}}}, gogis.SRIDWGS84)
+
+	var p gogis.Point
+	if err := m.Scan(geometryOID, pgtype.BinaryFormatCode, line, &p); err == nil {
+		t.Error("Scan() of a line string into *Point expected error, got nil")
+	}
+	if err := m.Scan(geometryOID, pgtype.BinaryFormatCode, []byte{1, 2}, &p); err == nil {
+		t.Error("Scan() of invalid EWKB expected error, got nil")
+	}
+	if err := m.Scan(geometryOID, pgtype.TextFormatCode, []byte("zz"), &p); err == nil {
+		t.Error("Scan() of invalid hex expected error, got nil")
+	}
+}
+
+func TestDecodeValue(t *testing.T) {
+	m := newMap()
+	typ, ok := m.TypeForOID(geographyOID)
+	if !ok {
+		t.Fatal("geography type not registered")
+	}
+	src, _ := hex.DecodeString("0101000020E6100000000000000000F03F0000000000000040")
+
+	v, err := typ.Codec.DecodeValue(m, geographyOID, pgtype.BinaryFormatCode, src)
+	if err != nil {
+		t.Fatalf("DecodeValue() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(v, &gogis.Point{Lng: 1, Lat: 2}) {
+		t.Errorf("DecodeValue() = %v", v)
+	}
+
+	// database/sql receives hex EWKB, which the Scan methods of the gogis
+	// types read.
+	sqlValue, err := typ.Codec.DecodeDatabaseSQLValue(m, geographyOID, pgtype.BinaryFormatCode, src)
+	if err != nil {
+		t.Fatalf("DecodeDatabaseSQLValue() unexpected error = %v", err)
+	}
+	var p gogis.GeographyPoint
+	if err := p.Scan(sqlValue); err != nil {
+		t.Fatalf("Scan() unexpected error = %v", err)
+	}
+	if p != (gogis.GeographyPoint{Lng: 1, Lat: 2}) {
+		t.Errorf("Scan() = %v", p)
+	}
+}
+
+func TestDefaultPgType(t *testing.T) {
+	m := newMap()
+	tests := []struct {
+		value any
+		name  string
+	}{
+		{gogis.Point{}, "geometry"},
+		{&gogis.Polygon{}, "geometry"},
+		{&gogis.GeographyPoint{}, "geography"},
+		{gogis.GeographyCollection{}, "geography"},
+	}
+	for _, tt := range tests {
+		typ, ok := m.TypeForValue(tt.value)
+		if !ok {
+			t.Errorf("TypeForValue(%T) not found", tt.value)
+			continue
+		}
+		if typ.Name != tt.name {
+			t.Errorf("TypeForValue(%T) = %s, want %s", tt.value, typ.Name, tt.name)
+		}
+	}
+}
@@ -84,6 +84,17 @@ func (p *Point) Scan(val any) error {
 	if val == nil {
 		return nil
 	}
+	if g, ok, err := scanBinary(val, false); ok {
+		if err != nil {
+			return err
+		}
+		v, ok := g.(*Point)
+		if !ok {
+			return fmt.Errorf("cannot scan %T into Point", g)
+		}
+		*p = *v
+		return nil
+	}
 	var decode string
 	uint8Val, ok := val.([]uint8)
 	if ok {
@@ -40,6 +40,17 @@ func (p *Polygon) Scan(val any) error {
 	if val == nil {
 		return nil
 	}
+	if g, ok, err := scanBinary(val, false); ok {
+		if err != nil {
+			return err
+		}
+		v, ok := g.(*Polygon)
+		if !ok {
+			return fmt.Errorf("cannot scan %T into Polygon", g)
+		}
+		*p = *v
+		return nil
+	}
 
 	var decode string
 	switch v := val.(type) {
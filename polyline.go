@@ -0,0 +1,110 @@
+package gogis
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// maxPolylinePrecision bounds the precision of encoded polylines, keeping the
+// scaled coordinates well within int64.
+const maxPolylinePrecision = 10
+
+// EncodePolyline encodes the LineString with the Encoded Polyline Algorithm
+// used by Google Maps, OSRM and Valhalla, keeping precision decimal digits:
+// 5 for Google Maps and 6 for OSRM and Valhalla "polyline6". The encoding
+// stores each point as latitude followed by longitude.
+//
+// Example:
+//
+//	ls := gogis.LineString{Points: []gogis.Point{{Lng: -120.2, Lat: 38.5}, {Lng: -120.95, Lat: 40.7}}}
+//	s, err := ls.EncodePolyline(5) // "_p~iF~ps|U_ulLnnqC"
+func (ls *LineString) EncodePolyline(precision int) (string, error) {
+	if precision < 0 || precision > maxPolylinePrecision {
+		return "", fmt.Errorf("gogis: encoding polyline: precision %d out of range", precision)
+	}
+	factor := math.Pow10(precision)
+	var b strings.Builder
+	var prevLat, prevLng int64
+	for i, p := range ls.Points {
+		if math.IsNaN(p.Lng) || math.IsNaN(p.Lat) || math.Abs(p.Lng) > 360 || math.Abs(p.Lat) > 360 {
+			return "", fmt.Errorf("gogis: encoding polyline: point %d has invalid coordinates", i)
+		}
+		lat := int64(math.Round(p.Lat * factor))
+		lng := int64(math.Round(p.Lng * factor))
+		writePolylineValue(&b, lat-prevLat)
+		writePolylineValue(&b, lng-prevLng)
+		prevLat, prevLng = lat, lng
+	}
+	return b.String(), nil
+}
+
+// writePolylineValue writes a signed value as chunks of 5 bits, lowest
+// first, each offset by 63 into printable ASCII.
+func writePolylineValue(b *strings.Builder, v int64) {
+	u := uint64(v) << 1
+	if v < 0 {
+		u = ^u
+	}
+	for u >= 0x20 {
+		b.WriteByte(byte(0x20|u&0x1F) + 63)
+		u >>= 5
+	}
+	b.WriteByte(byte(u) + 63)
+}
+
+// DecodePolyline decodes a string produced by the Encoded Polyline Algorithm
+// with the given precision, 5 for Google Maps and 6 for OSRM and Valhalla
+// "polyline6", into a LineString.
+func DecodePolyline(s string, precision int) (*LineString, error) {
+	if precision < 0 || precision > maxPolylinePrecision {
+		return nil, fmt.Errorf("gogis: decoding polyline: precision %d out of range", precision)
+	}
+	factor := math.Pow10(precision)
+	ls := &LineString{Points: []Point{}}
+	var lat, lng int64
+	for pos := 0; pos < len(s); {
+		dlat, n, err := readPolylineValue(s[pos:])
+		if err != nil {
+			return nil, fmt.Errorf("gogis: decoding polyline: offset %d: %w", pos, err)
+		}
+		pos += n
+		if pos == len(s) {
+			return nil, fmt.Errorf("gogis: decoding polyline: missing longitude at offset %d", pos)
+		}
+		dlng, n, err := readPolylineValue(s[pos:])
+		if err != nil {
+			return nil, fmt.Errorf("gogis: decoding polyline: offset %d: %w", pos, err)
+		}
+		pos += n
+		lat += dlat
+		lng += dlng
+		ls.Points = append(ls.Points, Point{Lng: float64(lng) / factor, Lat: float64(lat) / factor})
+	}
+	return ls, nil
+}
+
+// readPolylineValue reads one value and returns it with the number of bytes
+// read.
+func readPolylineValue(s string) (int64, int, error) {
+	var u uint64
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 63 || c > 127 {
+			return 0, 0, fmt.Errorf("invalid character %q", c)
+		}
+		if i >= 12 {
+			return 0, 0, fmt.Errorf("value too long")
+		}
+		chunk := uint64(c - 63)
+		u |= (chunk & 0x1F) << (5 * uint(i))
+		if chunk < 0x20 {
+			v := int64(u >> 1)
+			if u&1 != 0 {
+				v = ^v
+			}
+			return v, i + 1, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("truncated value")
+}
@@ -0,0 +1,111 @@
+package gogis_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/restayway/gogis"
+)
+
+func TestEncodePolyline(t *testing.T) {
+	google := []gogis.Point{{Lng: -120.2, Lat: 38.5}, {Lng: -120.95, Lat: 40.7}, {Lng: -126.453, Lat: 43.252}}
+	tests := []struct {
+		name      string
+		points    []gogis.Point
+		precision int
+		want      string
+	}{
+		{
+			// Example of the Encoded Polyline Algorithm Format documentation
+			name:      "google example",
+			points:    google,
+			precision: 5,
+			want:      "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+		},
+		{
+			name:      "polyline6",
+			points:    google,
+			precision: 6,
+			want:      "_izlhA~rlgdF_{geC~ywl@_kwzCn`{nI",
+		},
+		{
+			name:      "rounding",
+			points:    []gogis.Point{{Lng: 0.000004, Lat: -0.000006}},
+			precision: 5,
+			want:      "@?",
+		},
+		{
+			name:      "empty",
+			points:    nil,
+			precision: 5,
+			want:      "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ls := &gogis.LineString{Points: tt.points}
+			got, err := ls.EncodePolyline(tt.precision)
+			if err != nil {
+				t.Fatalf("EncodePolyline() unexpected error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("EncodePolyline() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	for _, tt := range []struct {
+		name      string
+		points    []gogis.Point
+		precision int
+	}{
+		{name: "precision", points: google, precision: 11},
+		{name: "nan", points: []gogis.Point{{Lng: math.NaN()}}, precision: 5},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ls := &gogis.LineString{Points: tt.points}
+			if _, err := ls.EncodePolyline(tt.precision); err == nil {
+				t.Error("EncodePolyline() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestDecodePolyline(t *testing.T) {
+	want := &gogis.LineString{Points: []gogis.Point{{Lng: -120.2, Lat: 38.5}, {Lng: -120.95, Lat: 40.7}, {Lng: -126.453, Lat: 43.252}}}
+	got, err := gogis.DecodePolyline("_p~iF~ps|U_ulLnnqC_mqNvxq`@", 5)
+	if err != nil {
+		t.Fatalf("DecodePolyline() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodePolyline() = %v, want %v", got, want)
+	}
+
+	got, err = gogis.DecodePolyline("_izlhA~rlgdF_{geC~ywl@_kwzCn`{nI", 6)
+	if err != nil {
+		t.Fatalf("DecodePolyline() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodePolyline() with precision 6 = %v, want %v", got, want)
+	}
+
+	for _, tt := range []struct {
+		name      string
+		s         string
+		precision int
+	}{
+		{name: "truncated value", s: "_p~iF~ps|", precision: 5},
+		{name: "missing longitude", s: "_p~iF", precision: 5},
+		{name: "invalid character", s: "_p~iF ps|U", precision: 5},
+		{name: "value too long", s: "~~~~~~~~~~~~~?", precision: 5},
+		{name: "precision", s: "??", precision: -1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := gogis.DecodePolyline(tt.s, tt.precision); err == nil {
+				t.Error("DecodePolyline() expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package proj
+
+import (
+	"math"
+
+	"github.com/restayway/gogis"
+)
+
+// Datum describes a geodetic datum and how it relates to WGS 84.
+type Datum struct {
+	// Name is a human readable name of the datum.
+	Name string
+
+	// Ellipsoid is the reference ellipsoid of the datum.
+	Ellipsoid Ellipsoid
+
+	// ToWGS84 holds the seven Helmert parameters that convert geocentric
+	// coordinates on this datum to WGS 84, in the position vector convention
+	// used by PROJ's +towgs84: translations dx, dy, dz in meters, rotations
+	// rx, ry, rz in arc-seconds and the scale difference in parts per million.
+	ToWGS84 [7]float64
+}
+
+// Datums known by name to the PROJ string and WKT parsers.
+var (
+	DatumWGS84  = &Datum{Name: "WGS84", Ellipsoid: WGS84}
+	DatumNAD83  = &Datum{Name: "NAD83", Ellipsoid: GRS80}
+	DatumETRS89 = &Datum{Name: "ETRS89", Ellipsoid: GRS80}
+	DatumOSGB36 = &Datum{
+		Name:      "OSGB36",
+		Ellipsoid: Airy1830,
+		ToWGS84:   [7]float64{446.448, -125.157, 542.06, 0.15, 0.247, 0.842, -20.489},
+	}
+	DatumPotsdam = &Datum{
+		Name:      "Potsdam",
+		Ellipsoid: Bessel1841,
+		ToWGS84:   [7]float64{598.1, 73.7, 418.2, 0.202, 0.045, -2.455, 6.7},
+	}
+	DatumIRE65 = &Datum{
+		Name:      "Ireland 1965",
+		Ellipsoid: Ellipsoid{A: 6377340.189, F: 1 / 299.3249646},
+		ToWGS84:   [7]float64{482.530, -130.596, 564.557, -1.042, -0.214, -0.631, 8.15},
+	}
+	DatumGGRS87 = &Datum{
+		Name:      "GGRS87",
+		Ellipsoid: GRS80,
+		ToWGS84:   [7]float64{-199.87, 74.79, 246.62},
+	}
+)
+
+// sameDatum reports whether converting between the two datums is a no-op. A
+// nil datum stands for WGS 84.
+func sameDatum(a, b *Datum) bool {
+	if a == nil {
+		a = DatumWGS84
+	}
+	if b == nil {
+		b = DatumWGS84
+	}
+	return a == b || (a.Ellipsoid == b.Ellipsoid && a.ToWGS84 == b.ToWGS84)
+}
+
+// toWGS84 converts geographic coordinates on the datum to WGS 84.
+func (d *Datum) toWGS84(p gogis.Point) gogis.Point {
+	x := geodeticToGeocentric(p, d.Ellipsoid)
+	return geocentricToGeodetic(d.helmert(x), WGS84)
+}
+
+// fromWGS84 converts WGS 84 geographic coordinates to the datum.
+//
+// Heights are dropped between steps, so the plain inverse Helmert
+// transformation does not exactly undo toWGS84. The result is refined until
+// toWGS84 maps it back onto p, which makes round trips exact.
+func (d *Datum) fromWGS84(p gogis.Point) gogis.Point {
+	x := geodeticToGeocentric(p, WGS84)
+	q := geocentricToGeodetic(d.inverseHelmert(x), d.Ellipsoid)
+	for i := 0; i < 5; i++ {
+		r := d.toWGS84(q)
+		dLng, dLat := p.Lng-r.Lng, p.Lat-r.Lat
+		q.Lng += dLng
+		q.Lat += dLat
+		if math.Abs(dLng) < 1e-12 && math.Abs(dLat) < 1e-12 {
+			break
+		}
+	}
+	return q
+}
+
+// helmertMatrix returns the scaled rotation matrix and translation of the
+// datum's Helmert transformation.
+func (d *Datum) helmertMatrix() (m [3][3]float64, t [3]float64) {
+	const arcSecond = math.Pi / (180 * 3600)
+	p := d.ToWGS84
+	rx, ry, rz := p[3]*arcSecond, p[4]*arcSecond, p[5]*arcSecond
+	s := 1 + p[6]*1e-6
+	m = [3][3]float64{
+		{s, -s * rz, s * ry},
+		{s * rz, s, -s * rx},
+		{-s * ry, s * rx, s},
+	}
+	return m, [3]float64{p[0], p[1], p[2]}
+}
+
+func (d *Datum) helmert(v [3]float64) [3]float64 {
+	m, t := d.helmertMatrix()
+	var out [3]float64
+	for i := range out {
+		out[i] = t[i] + m[i][0]*v[0] + m[i][1]*v[1] + m[i][2]*v[2]
+	}
+	return out
+}
+
+// inverseHelmert reverses helmert exactly by solving the linear system with
+// Cramer's rule, rather than negating the parameters.
+func (d *Datum) inverseHelmert(v [3]float64) [3]float64 {
+	m, t := d.helmertMatrix()
+	b := [3]float64{v[0] - t[0], v[1] - t[1], v[2] - t[2]}
+
+	det := determinant(m)
+	var out [3]float64
+	for col := range out {
+		mc := m
+		for row := range mc {
+			mc[row][col] = b[row]
+		}
+		out[col] = determinant(mc) / det
+	}
+	return out
+}
+
+func determinant(m [3][3]float64) float64 {
+	return m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+}
+
+// geodeticToGeocentric converts longitude and latitude at zero ellipsoidal
+// height to earth-centred, earth-fixed coordinates in meters.
+func geodeticToGeocentric(p gogis.Point, el Ellipsoid) [3]float64 {
+	e2 := el.F * (2 - el.F)
+	lat, lng := toRadians(p.Lat), toRadians(p.Lng)
+	sinLat := math.Sin(lat)
+	n := el.A / math.Sqrt(1-e2*sinLat*sinLat)
+	return [3]float64{
+		n * math.Cos(lat) * math.Cos(lng),
+		n * math.Cos(lat) * math.Sin(lng),
+		n * (1 - e2) * sinLat,
+	}
+}
+
+// geocentricToGeodetic converts earth-centred, earth-fixed coordinates to
+// longitude and latitude, discarding the ellipsoidal height.
+func geocentricToGeodetic(v [3]float64, el Ellipsoid) gogis.Point {
+	e2 := el.F * (2 - el.F)
+	p := math.Hypot(v[0], v[1])
+	lat := math.Atan2(v[2], p*(1-e2))
+	for i := 0; i < 10; i++ {
+		sinLat := math.Sin(lat)
+		n := el.A / math.Sqrt(1-e2*sinLat*sinLat)
+		next := math.Atan2(v[2]+e2*n*sinLat, p)
+		if math.Abs(next-lat) < 1e-15 {
+			lat = next
+			break
+		}
+		lat = next
+	}
+	return gogis.Point{
+		Lng: toDegrees(math.Atan2(v[1], v[0])),
+		Lat: toDegrees(lat),
+	}
+}
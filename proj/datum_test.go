@@ -0,0 +1,71 @@
+package proj_test
+
+import (
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/proj"
+)
+
+func TestDatumShift(t *testing.T) {
+	// Big Ben. The OSGB36 seven parameter transformation is accurate to a few
+	// meters, so the expected grid reference is checked loosely.
+	bigBen := gogis.Point{Lng: -0.124625, Lat: 51.500729}
+
+	got, err := proj.TransformPoint(bigBen, gogis.SRIDWGS84, 27700)
+	if err != nil {
+		t.Fatalf("TransformPoint() unexpected error = %v", err)
+	}
+	assertNear(t, "TransformPoint()", got, gogis.Point{Lng: 530268, Lat: 179644}, 5)
+
+	// Without the datum shift the result is off by about a hundred meters.
+	noShift := &proj.CRS{Projection: proj.TransverseMercator{
+		Ellipsoid:        proj.Airy1830,
+		CentralMeridian:  -2,
+		LatitudeOfOrigin: 49,
+		ScaleFactor:      0.9996012717,
+		FalseEasting:     400000,
+		FalseNorthing:    -100000,
+	}}
+	g, err := proj.TransformCRS(&bigBen, &proj.CRS{}, noShift)
+	if err != nil {
+		t.Fatalf("TransformCRS() unexpected error = %v", err)
+	}
+	if p := g.(*gogis.Point); p.Lng-got.Lng < 50 && got.Lng-p.Lng < 50 {
+		t.Errorf("TransformCRS() without datum = %v, want it far from %v", p, got)
+	}
+}
+
+func TestDatumShiftRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		datum *proj.Datum
+		point gogis.Point
+	}{
+		{name: "OSGB36", datum: proj.DatumOSGB36, point: gogis.Point{Lng: -3.19, Lat: 55.95}},
+		{name: "Potsdam", datum: proj.DatumPotsdam, point: gogis.Point{Lng: 13.4, Lat: 52.5}},
+		{name: "translation only", datum: proj.DatumGGRS87, point: gogis.Point{Lng: 23.7, Lat: 37.98}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := &proj.CRS{Datum: tt.datum}
+			wgs84 := &proj.CRS{}
+
+			g, err := proj.TransformCRS(&tt.point, local, wgs84)
+			if err != nil {
+				t.Fatalf("TransformCRS() unexpected error = %v", err)
+			}
+			shifted := *g.(*gogis.Point)
+			if shifted == tt.point {
+				t.Errorf("TransformCRS() = %v, want a shifted point", shifted)
+			}
+
+			g, err = proj.TransformCRS(&shifted, wgs84, local)
+			if err != nil {
+				t.Fatalf("TransformCRS() back unexpected error = %v", err)
+			}
+			assertNear(t, "round trip", *g.(*gogis.Point), tt.point, 1e-9)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package proj
+
+import (
+	_ "embed"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/restayway/gogis"
+)
+
+//go:embed epsg.txt
+var epsgFile string
+
+// epsgEntry is a definition of the embedded EPSG registry.
+type epsgEntry struct {
+	name string
+	def  string
+}
+
+var (
+	epsgOnce    sync.Once
+	epsgEntries map[gogis.SRID]epsgEntry
+	epsgCache   sync.Map // gogis.SRID -> *CRS
+)
+
+// loadEPSG parses the embedded registry file.
+func loadEPSG() {
+	epsgEntries = make(map[gogis.SRID]epsgEntry)
+	var name string
+	for _, line := range strings.Split(epsgFile, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "#"))
+		case strings.HasPrefix(line, "<"):
+			end := strings.IndexByte(line, '>')
+			if end < 0 {
+				panic(fmt.Sprintf("proj: malformed epsg.txt line %q", line))
+			}
+			code, err := strconv.Atoi(line[1:end])
+			if err != nil {
+				panic(fmt.Sprintf("proj: malformed epsg.txt line %q", line))
+			}
+			def := strings.TrimSpace(strings.TrimSuffix(line[end+1:], "<>"))
+			epsgEntries[gogis.SRID(code)] = epsgEntry{name: name, def: def}
+		}
+	}
+}
+
+// EPSG returns the PROJ string of an EPSG code in the embedded registry.
+func EPSG(srid gogis.SRID) (string, bool) {
+	epsgOnce.Do(loadEPSG)
+	e, ok := epsgEntries[srid]
+	return e.def, ok
+}
+
+// lookupEPSG resolves an SRID from the embedded registry. It returns a nil
+// system and no error when the code is not part of the registry.
+func lookupEPSG(srid gogis.SRID) (*CRS, error) {
+	if crs, ok := epsgCache.Load(srid); ok {
+		return crs.(*CRS), nil
+	}
+	epsgOnce.Do(loadEPSG)
+	e, ok := epsgEntries[srid]
+	if !ok {
+		return nil, nil
+	}
+	crs, err := ParseProj(e.def)
+	if err != nil {
+		return nil, err
+	}
+	crs.Name, crs.SRID = e.name, srid
+	actual, _ := epsgCache.LoadOrStore(srid, crs)
+	return actual.(*CRS), nil
+}
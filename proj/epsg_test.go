@@ -0,0 +1,96 @@
+package proj_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/proj"
+)
+
+func TestLookupEmbeddedEPSG(t *testing.T) {
+	tests := []struct {
+		srid gogis.SRID
+		name string
+		geo  gogis.Point
+		grid gogis.Point
+		tol  float64
+	}{
+		{
+			// The projection origin of Lambert-93.
+			srid: 2154,
+			name: "RGF93 / Lambert-93",
+			geo:  gogis.Point{Lng: 3, Lat: 46.5},
+			grid: gogis.Point{Lng: 700000, Lat: 6600000},
+			tol:  0.001,
+		},
+		{
+			srid: 27700,
+			name: "OSGB 1936 / British National Grid",
+			geo:  gogis.Point{Lng: -0.124625, Lat: 51.500729},
+			grid: gogis.Point{Lng: 530268, Lat: 179644},
+			tol:  5,
+		},
+		{
+			srid: 25832,
+			name: "ETRS89 / UTM zone 32N",
+			geo:  gogis.Point{Lng: 9, Lat: 0},
+			grid: gogis.Point{Lng: 500000, Lat: 0},
+			tol:  0.001,
+		},
+		{
+			// The origin of New York Long Island is 300000 m east, in US
+			// survey feet.
+			srid: 2263,
+			name: "NAD83 / New York Long Island (ftUS)",
+			geo:  gogis.Point{Lng: -74, Lat: 40.1666666666667},
+			grid: gogis.Point{Lng: 984250, Lat: 0},
+			tol:  0.01,
+		},
+		{
+			srid: 4258,
+			name: "ETRS89",
+			geo:  gogis.Point{Lng: 10, Lat: 50},
+			grid: gogis.Point{Lng: 10, Lat: 50},
+			tol:  1e-9,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crs, err := proj.Lookup(tt.srid)
+			if err != nil {
+				t.Fatalf("Lookup() unexpected error = %v", err)
+			}
+			if crs.Name != tt.name || crs.SRID != tt.srid {
+				t.Errorf("Lookup() = %q (%d), want %q (%d)", crs.Name, crs.SRID, tt.name, tt.srid)
+			}
+
+			got, err := proj.TransformPoint(tt.geo, gogis.SRIDWGS84, tt.srid)
+			if err != nil {
+				t.Fatalf("TransformPoint() unexpected error = %v", err)
+			}
+			assertNear(t, "TransformPoint()", got, tt.grid, tt.tol)
+		})
+	}
+}
+
+func TestEPSG(t *testing.T) {
+	def, ok := proj.EPSG(27700)
+	if !ok || !strings.HasPrefix(def, "+proj=tmerc ") || strings.HasSuffix(def, "<>") {
+		t.Errorf("EPSG(27700) = %q, %v, want the British National Grid definition", def, ok)
+	}
+	if _, ok := proj.EPSG(99999); ok {
+		t.Errorf("EPSG(99999) found an unknown code")
+	}
+
+	// Every embedded definition must parse.
+	for srid := gogis.SRID(2000); srid < 33000; srid++ {
+		if _, ok := proj.EPSG(srid); !ok {
+			continue
+		}
+		if _, err := proj.Lookup(srid); err != nil {
+			t.Errorf("Lookup(%d) unexpected error = %v", srid, err)
+		}
+	}
+}
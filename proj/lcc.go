@@ -0,0 +1,142 @@
+package proj
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/restayway/gogis"
+)
+
+// LambertConformalConic is the ellipsoidal Lambert Conformal Conic projection,
+// used by many national and state grids for regions that extend mostly east
+// to west.
+//
+// With two distinct standard parallels it is the "2SP" variant. Setting both
+// standard parallels to the latitude of origin and providing a ScaleFactor
+// gives the "1SP" variant.
+//
+// Example (RGF93 / Lambert-93, EPSG:2154):
+//
+//	lambert93 := proj.LambertConformalConic{
+//	    Ellipsoid:          proj.GRS80,
+//	    CentralMeridian:    3,
+//	    LatitudeOfOrigin:   46.5,
+//	    StandardParallel1:  49,
+//	    StandardParallel2:  44,
+//	    FalseEasting:       700000,
+//	    FalseNorthing:      6600000,
+//	}
+type LambertConformalConic struct {
+	Ellipsoid         Ellipsoid
+	CentralMeridian   float64 // Longitude of the origin in degrees
+	LatitudeOfOrigin  float64 // Latitude of the origin in degrees
+	StandardParallel1 float64 // First standard parallel in degrees
+	StandardParallel2 float64 // Second standard parallel in degrees
+	ScaleFactor       float64 // Scale factor at the origin, 0 means 1
+	FalseEasting      float64 // Easting of the origin in meters
+	FalseNorthing     float64 // Northing of the origin in meters
+}
+
+// lccParams holds the constants derived from the projection parameters.
+type lccParams struct {
+	e    float64
+	n    float64 // Cone constant
+	af   float64 // Semi-major axis times F times the scale factor
+	rho0 float64 // Radius of the parallel of origin
+}
+
+func (l LambertConformalConic) params() (lccParams, error) {
+	e := l.Ellipsoid.eccentricity()
+	phi1 := toRadians(l.StandardParallel1)
+	phi2 := toRadians(l.StandardParallel2)
+	phi0 := toRadians(l.LatitudeOfOrigin)
+
+	m1, m2 := lccM(phi1, e), lccM(phi2, e)
+	t0, t1, t2 := lccT(phi0, e), lccT(phi1, e), lccT(phi2, e)
+
+	var n float64
+	if math.Abs(phi1-phi2) < 1e-12 {
+		n = math.Sin(phi1)
+	} else {
+		n = (math.Log(m1) - math.Log(m2)) / (math.Log(t1) - math.Log(t2))
+	}
+	if n == 0 || math.IsNaN(n) {
+		return lccParams{}, fmt.Errorf("invalid standard parallels %v and %v", l.StandardParallel1, l.StandardParallel2)
+	}
+
+	k0 := l.ScaleFactor
+	if k0 == 0 {
+		k0 = 1
+	}
+	af := l.Ellipsoid.A * m1 / (n * math.Pow(t1, n)) * k0
+	return lccParams{e: e, n: n, af: af, rho0: af * math.Pow(t0, n)}, nil
+}
+
+func lccM(phi, e float64) float64 {
+	s := e * math.Sin(phi)
+	return math.Cos(phi) / math.Sqrt(1-s*s)
+}
+
+func lccT(phi, e float64) float64 {
+	s := e * math.Sin(phi)
+	return math.Tan(math.Pi/4-phi/2) / math.Pow((1-s)/(1+s), e/2)
+}
+
+// Forward projects longitude and latitude to easting and northing.
+func (l LambertConformalConic) Forward(p gogis.Point) (gogis.Point, error) {
+	c, err := l.params()
+	if err != nil {
+		return gogis.Point{}, err
+	}
+	phi := toRadians(p.Lat)
+
+	var rho float64
+	if math.Abs(math.Abs(p.Lat)-90) < 1e-12 {
+		if p.Lat*c.n <= 0 {
+			return gogis.Point{}, fmt.Errorf("latitude %v cannot be projected", p.Lat)
+		}
+	} else {
+		rho = c.af * math.Pow(lccT(phi, c.e), c.n)
+	}
+
+	theta := c.n * toRadians(normalizeLng(p.Lng-l.CentralMeridian))
+	return gogis.Point{
+		Lng: l.FalseEasting + rho*math.Sin(theta),
+		Lat: l.FalseNorthing + c.rho0 - rho*math.Cos(theta),
+	}, nil
+}
+
+// Inverse converts easting and northing to longitude and latitude.
+func (l LambertConformalConic) Inverse(p gogis.Point) (gogis.Point, error) {
+	c, err := l.params()
+	if err != nil {
+		return gogis.Point{}, err
+	}
+
+	x := p.Lng - l.FalseEasting
+	y := c.rho0 - (p.Lat - l.FalseNorthing)
+	sign := math.Copysign(1, c.n)
+	rho := sign * math.Hypot(x, y)
+	theta := math.Atan2(sign*x, sign*y)
+
+	if rho == 0 {
+		return gogis.Point{Lng: l.CentralMeridian, Lat: sign * 90}, nil
+	}
+
+	t := math.Pow(rho/c.af, 1/c.n)
+	phi := math.Pi/2 - 2*math.Atan(t)
+	for i := 0; i < 15; i++ {
+		s := c.e * math.Sin(phi)
+		next := math.Pi/2 - 2*math.Atan(t*math.Pow((1-s)/(1+s), c.e/2))
+		if math.Abs(next-phi) < 1e-14 {
+			phi = next
+			break
+		}
+		phi = next
+	}
+
+	return gogis.Point{
+		Lng: normalizeLng(toDegrees(theta/c.n) + l.CentralMeridian),
+		Lat: toDegrees(phi),
+	}, nil
+}
@@ -0,0 +1,77 @@
+package proj_test
+
+import (
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/proj"
+)
+
+// usFoot is the length of the US survey foot in meters.
+const usFoot = 1200.0 / 3937
+
+func TestLambertConformalConicEPSGExample(t *testing.T) {
+	// EPSG Guidance Note 7-2, Lambert Conic Conformal (2SP) example (NAD27 /
+	// Texas South Central): 28°30'N 96°W -> 2963503.91 E, 254759.80 N (US ft).
+	texas := proj.LambertConformalConic{
+		Ellipsoid:         proj.Clarke1866,
+		CentralMeridian:   -99,
+		LatitudeOfOrigin:  27 + 50.0/60,
+		StandardParallel1: 28 + 23.0/60,
+		StandardParallel2: 30 + 17.0/60,
+		FalseEasting:      2000000 * usFoot,
+		FalseNorthing:     0,
+	}
+	geo := gogis.Point{Lng: -96, Lat: 28.5}
+	grid := gogis.Point{Lng: 2963503.91 * usFoot, Lat: 254759.80 * usFoot}
+
+	got, err := texas.Forward(geo)
+	if err != nil {
+		t.Fatalf("Forward() unexpected error = %v", err)
+	}
+	assertNear(t, "Forward()", got, grid, 0.01)
+
+	back, err := texas.Inverse(grid)
+	if err != nil {
+		t.Fatalf("Inverse() unexpected error = %v", err)
+	}
+	assertNear(t, "Inverse()", back, geo, 1e-7)
+}
+
+func TestLambertConformalConic1SP(t *testing.T) {
+	// A single standard parallel at the origin with a scale factor.
+	lcc := proj.LambertConformalConic{
+		Ellipsoid:         proj.WGS84,
+		CentralMeridian:   10,
+		LatitudeOfOrigin:  52,
+		StandardParallel1: 52,
+		StandardParallel2: 52,
+		ScaleFactor:       0.9999,
+		FalseEasting:      1000000,
+		FalseNorthing:     500000,
+	}
+
+	origin, err := lcc.Forward(gogis.Point{Lng: 10, Lat: 52})
+	if err != nil {
+		t.Fatalf("Forward() unexpected error = %v", err)
+	}
+	assertNear(t, "Forward(origin)", origin, gogis.Point{Lng: 1000000, Lat: 500000}, 1e-6)
+
+	geo := gogis.Point{Lng: 14.4, Lat: 50.1}
+	grid, err := lcc.Forward(geo)
+	if err != nil {
+		t.Fatalf("Forward() unexpected error = %v", err)
+	}
+	back, err := lcc.Inverse(grid)
+	if err != nil {
+		t.Fatalf("Inverse() unexpected error = %v", err)
+	}
+	assertNear(t, "round trip", back, geo, 1e-9)
+}
+
+func TestLambertConformalConicInvalid(t *testing.T) {
+	lcc := proj.LambertConformalConic{Ellipsoid: proj.WGS84}
+	if _, err := lcc.Forward(gogis.Point{Lng: 1, Lat: 1}); err == nil {
+		t.Errorf("Forward() expected error for equatorial standard parallels, got nil")
+	}
+}
@@ -0,0 +1,91 @@
+package proj
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/restayway/gogis"
+)
+
+// webMercatorRadius is the radius of the sphere used by Web Mercator.
+const webMercatorRadius = 6378137
+
+// WebMercator is the spherical Mercator projection used by web maps
+// (EPSG:3857). It treats WGS 84 coordinates as if they were on a sphere with
+// the WGS 84 semi-major axis as radius.
+type WebMercator struct{}
+
+// Forward projects longitude and latitude to Web Mercator meters. Latitudes
+// of ±90 degrees cannot be projected.
+func (WebMercator) Forward(p gogis.Point) (gogis.Point, error) {
+	if math.Abs(p.Lat) >= 90 {
+		return gogis.Point{}, fmt.Errorf("latitude %v out of range for Mercator", p.Lat)
+	}
+	lat := toRadians(p.Lat)
+	return gogis.Point{
+		Lng: webMercatorRadius * toRadians(p.Lng),
+		Lat: webMercatorRadius * math.Log(math.Tan(math.Pi/4+lat/2)),
+	}, nil
+}
+
+// Inverse converts Web Mercator meters to longitude and latitude.
+func (WebMercator) Inverse(p gogis.Point) (gogis.Point, error) {
+	return gogis.Point{
+		Lng: toDegrees(p.Lng / webMercatorRadius),
+		Lat: toDegrees(math.Pi/2 - 2*math.Atan(math.Exp(-p.Lat/webMercatorRadius))),
+	}, nil
+}
+
+// Mercator is the ellipsoidal Mercator projection, such as World Mercator
+// (EPSG:3395). On a sphere (zero flattening) with default parameters it
+// matches WebMercator.
+type Mercator struct {
+	Ellipsoid       Ellipsoid
+	CentralMeridian float64 // Longitude of the natural origin in degrees
+	ScaleFactor     float64 // Scale factor on the equator, 0 means 1
+	FalseEasting    float64 // Easting of the origin in meters
+	FalseNorthing   float64 // Northing of the origin in meters
+}
+
+// MercatorScale returns the equatorial scale factor of a Mercator projection
+// whose scale is true on the given latitude, which converts the "2SP"
+// (latitude of true scale) variant to the ScaleFactor field.
+func MercatorScale(el Ellipsoid, latTrueScale float64) float64 {
+	phi := toRadians(latTrueScale)
+	e2 := el.F * (2 - el.F)
+	sinPhi := math.Sin(phi)
+	return math.Cos(phi) / math.Sqrt(1-e2*sinPhi*sinPhi)
+}
+
+func (m Mercator) scale() float64 {
+	if m.ScaleFactor == 0 {
+		return 1
+	}
+	return m.ScaleFactor
+}
+
+// Forward projects longitude and latitude to easting and northing. Latitudes
+// of ±90 degrees cannot be projected.
+func (m Mercator) Forward(p gogis.Point) (gogis.Point, error) {
+	if math.Abs(p.Lat) >= 90 {
+		return gogis.Point{}, fmt.Errorf("latitude %v out of range for Mercator", p.Lat)
+	}
+	ak := m.Ellipsoid.A * m.scale()
+	e := m.Ellipsoid.eccentricity()
+	tau := conformalTan(math.Tan(toRadians(p.Lat)), e)
+	return gogis.Point{
+		Lng: m.FalseEasting + ak*toRadians(normalizeLng(p.Lng-m.CentralMeridian)),
+		Lat: m.FalseNorthing + ak*math.Asinh(tau),
+	}, nil
+}
+
+// Inverse converts easting and northing to longitude and latitude.
+func (m Mercator) Inverse(p gogis.Point) (gogis.Point, error) {
+	ak := m.Ellipsoid.A * m.scale()
+	e := m.Ellipsoid.eccentricity()
+	taup := math.Sinh((p.Lat - m.FalseNorthing) / ak)
+	return gogis.Point{
+		Lng: normalizeLng(m.CentralMeridian + toDegrees((p.Lng-m.FalseEasting)/ak)),
+		Lat: toDegrees(math.Atan(geodeticTan(taup, e))),
+	}, nil
+}
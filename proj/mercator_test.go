@@ -0,0 +1,90 @@
+package proj_test
+
+import (
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/proj"
+)
+
+func TestWebMercator(t *testing.T) {
+	tests := []struct {
+		name string
+		geo  gogis.Point
+		grid gogis.Point
+	}{
+		{
+			name: "origin",
+			geo:  gogis.Point{Lng: 0, Lat: 0},
+			grid: gogis.Point{Lng: 0, Lat: 0},
+		},
+		{
+			name: "web map corner",
+			geo:  gogis.Point{Lng: 180, Lat: 85.05112877980659},
+			grid: gogis.Point{Lng: 20037508.342789244, Lat: 20037508.342789244},
+		},
+		{
+			name: "southern hemisphere",
+			geo:  gogis.Point{Lng: -45, Lat: -45},
+			grid: gogis.Point{Lng: -5009377.085697311, Lat: -5621521.486192066},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := proj.WebMercator{}.Forward(tt.geo)
+			if err != nil {
+				t.Fatalf("Forward() unexpected error = %v", err)
+			}
+			assertNear(t, "Forward()", got, tt.grid, 1e-6)
+
+			back, err := proj.WebMercator{}.Inverse(tt.grid)
+			if err != nil {
+				t.Fatalf("Inverse() unexpected error = %v", err)
+			}
+			assertNear(t, "Inverse()", back, tt.geo, 1e-9)
+		})
+	}
+
+	if _, err := (proj.WebMercator{}).Forward(gogis.Point{Lat: 90}); err == nil {
+		t.Errorf("Forward() expected error at the pole, got nil")
+	}
+}
+
+func TestMercatorEPSGExample(t *testing.T) {
+	// EPSG Guidance Note 7-2, Mercator (variant A) example (Makassar /
+	// NEIEZ): 3°S 120°E -> 5009726.58 E, 569150.82 N.
+	neiez := proj.Mercator{
+		Ellipsoid:       proj.Bessel1841,
+		CentralMeridian: 110,
+		ScaleFactor:     0.997,
+		FalseEasting:    3900000,
+		FalseNorthing:   900000,
+	}
+	geo := gogis.Point{Lng: 120, Lat: -3}
+	grid := gogis.Point{Lng: 5009726.58, Lat: 569150.82}
+
+	got, err := neiez.Forward(geo)
+	if err != nil {
+		t.Fatalf("Forward() unexpected error = %v", err)
+	}
+	assertNear(t, "Forward()", got, grid, 0.01)
+
+	back, err := neiez.Inverse(grid)
+	if err != nil {
+		t.Fatalf("Inverse() unexpected error = %v", err)
+	}
+	assertNear(t, "Inverse()", back, geo, 1e-7)
+}
+
+func TestMercatorSphereMatchesWebMercator(t *testing.T) {
+	sphere := proj.Mercator{Ellipsoid: proj.Ellipsoid{A: 6378137}}
+	p := gogis.Point{Lng: -45, Lat: -45}
+
+	got, err := sphere.Forward(p)
+	if err != nil {
+		t.Fatalf("Forward() unexpected error = %v", err)
+	}
+	want, _ := proj.WebMercator{}.Forward(p)
+	assertNear(t, "Forward()", got, want, 1e-6)
+}
@@ -0,0 +1,285 @@
+package proj
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/restayway/gogis"
+)
+
+// Parse builds a coordinate reference system from a PROJ string, such as
+// "+proj=utm +zone=32 +datum=WGS84", or from an OGC WKT definition as found
+// in PostGIS' spatial_ref_sys table and in shapefile .prj files.
+func Parse(def string) (*CRS, error) {
+	def = strings.TrimSpace(def)
+	if strings.HasPrefix(def, "+") || strings.HasPrefix(def, "proj=") {
+		return ParseProj(def)
+	}
+	return ParseWKT(def)
+}
+
+// Ellipsoids known by their PROJ +ellps name.
+var projEllipsoids = map[string]Ellipsoid{
+	"WGS84":    WGS84,
+	"GRS80":    GRS80,
+	"airy":     Airy1830,
+	"mod_airy": {A: 6377340.189, F: 1 / 299.3249646},
+	"clrk66":   Clarke1866,
+	"clrk80":   {A: 6378249.145, F: 1 / 293.4663},
+	"intl":     International,
+	"bessel":   Bessel1841,
+	"krass":    {A: 6378245, F: 1 / 298.3},
+	"WGS72":    {A: 6378135, F: 1 / 298.26},
+	"aust_SA":  {A: 6378160, F: 1 / 298.25},
+	"GRS67":    {A: 6378160, F: 1 / 298.247167427},
+	"sphere":   {A: 6370997},
+}
+
+// Datums known by their PROJ +datum name.
+var projDatums = map[string]*Datum{
+	"WGS84":         DatumWGS84,
+	"NAD83":         DatumNAD83,
+	"OSGB36":        DatumOSGB36,
+	"potsdam":       DatumPotsdam,
+	"ire65":         DatumIRE65,
+	"GGRS87":        DatumGGRS87,
+	"hermannskogel": {Name: "Hermannskogel", Ellipsoid: Bessel1841, ToWGS84: [7]float64{577.326, 90.129, 463.919, 5.137, 1.474, 5.297, 2.4232}},
+	"carthage":      {Name: "Carthage", Ellipsoid: projEllipsoids["clrk80"], ToWGS84: [7]float64{-263, 6, 431}},
+	"nzgd49":        {Name: "NZGD49", Ellipsoid: International, ToWGS84: [7]float64{59.47, -5.04, 187.44, 0.47, -0.1, 1.024, -4.5993}},
+}
+
+// Linear units known by their PROJ +units name, in meters.
+var projUnits = map[string]float64{
+	"m":     1,
+	"km":    1000,
+	"dm":    0.1,
+	"cm":    0.01,
+	"ft":    0.3048,
+	"us-ft": 1200.0 / 3937,
+	"yd":    0.9144,
+	"mi":    1609.344,
+	"link":  0.201168,
+}
+
+// ParseProj builds a coordinate reference system from a PROJ string.
+//
+// The longlat, merc, tmerc, utm and lcc projections are supported, together
+// with the ellipsoid (+ellps, +a, +b, +rf, +f, +R), datum (+datum, +towgs84)
+// and unit (+units, +to_meter) parameters. Grid based datum shifts and prime
+// meridians other than Greenwich are not supported. As in PROJ 4, a system
+// without +datum or +towgs84 is assumed to need no datum shift.
+//
+// Example:
+//
+//	bng, err := proj.ParseProj("+proj=tmerc +lat_0=49 +lon_0=-2 +k=0.9996012717 " +
+//	    "+x_0=400000 +y_0=-100000 +ellps=airy " +
+//	    "+towgs84=446.448,-125.157,542.06,0.15,0.247,0.842,-20.489 +units=m")
+func ParseProj(s string) (*CRS, error) {
+	params := make(map[string]string)
+	for _, field := range strings.Fields(s) {
+		field = strings.TrimPrefix(field, "+")
+		key, value, _ := strings.Cut(field, "=")
+		params[key] = value
+	}
+	c := &projParser{params: params}
+	crs, err := c.crs()
+	if err != nil {
+		return nil, fmt.Errorf("proj: %q: %w", s, err)
+	}
+	return crs, nil
+}
+
+// projParser interprets the parameters of a PROJ string. The first error is
+// kept in err so that parameters can be read without checking each one.
+type projParser struct {
+	params map[string]string
+	err    error
+}
+
+func (c *projParser) has(key string) bool {
+	_, ok := c.params[key]
+	return ok
+}
+
+func (c *projParser) float(key string, def float64) float64 {
+	v, ok := c.params[key]
+	if !ok {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil && c.err == nil {
+		c.err = fmt.Errorf("invalid +%s value %q", key, v)
+	}
+	return f
+}
+
+func (c *projParser) crs() (*CRS, error) {
+	// Named prime meridians other than Greenwich, such as "paris", are
+	// reported here rather than as invalid numbers.
+	if v, ok := c.params["pm"]; ok && !strings.EqualFold(v, "greenwich") {
+		if f, err := strconv.ParseFloat(v, 64); err != nil || f != 0 {
+			return nil, fmt.Errorf("prime meridian %q is not supported", v)
+		}
+	}
+	if v, ok := c.params["nadgrids"]; ok && v != "@null" {
+		return nil, fmt.Errorf("grid shift %q is not supported", v)
+	}
+
+	el, datum, err := c.datum()
+	if err != nil {
+		return nil, err
+	}
+
+	toMeter := 1.0
+	if v, ok := c.params["units"]; ok {
+		if toMeter, ok = projUnits[v]; !ok {
+			return nil, fmt.Errorf("unknown unit %q", v)
+		}
+	}
+	toMeter = c.float("to_meter", toMeter)
+
+	crs := &CRS{Datum: datum}
+	name := c.params["proj"]
+	switch name {
+	case "longlat", "latlong", "lonlat", "latlon":
+	case "merc":
+		m := Mercator{
+			Ellipsoid:       el,
+			CentralMeridian: c.float("lon_0", 0),
+			ScaleFactor:     c.float("k_0", c.float("k", 1)),
+			FalseEasting:    c.float("x_0", 0),
+			FalseNorthing:   c.float("y_0", 0),
+		}
+		if c.has("lat_ts") {
+			m.ScaleFactor = MercatorScale(el, c.float("lat_ts", 0))
+		}
+		if m == (Mercator{Ellipsoid: Ellipsoid{A: webMercatorRadius}, ScaleFactor: 1}) {
+			crs.Projection = WebMercator{}
+		} else {
+			crs.Projection = m
+		}
+	case "tmerc", "etmerc":
+		crs.Projection = TransverseMercator{
+			Ellipsoid:        el,
+			CentralMeridian:  c.float("lon_0", 0),
+			LatitudeOfOrigin: c.float("lat_0", 0),
+			ScaleFactor:      c.float("k_0", c.float("k", 1)),
+			FalseEasting:     c.float("x_0", 0),
+			FalseNorthing:    c.float("y_0", 0),
+		}
+	case "utm":
+		zone := int(c.float("zone", 0))
+		if zone < 1 || zone > 60 {
+			return nil, fmt.Errorf("invalid UTM zone %q", c.params["zone"])
+		}
+		tm := UTM(zone, c.has("south"))
+		tm.Ellipsoid = el
+		crs.Projection = tm
+	case "lcc":
+		lat1 := c.float("lat_1", 0)
+		crs.Projection = LambertConformalConic{
+			Ellipsoid:         el,
+			CentralMeridian:   c.float("lon_0", 0),
+			LatitudeOfOrigin:  c.float("lat_0", lat1),
+			StandardParallel1: lat1,
+			StandardParallel2: c.float("lat_2", lat1),
+			ScaleFactor:       c.float("k_0", c.float("k", 1)),
+			FalseEasting:      c.float("x_0", 0),
+			FalseNorthing:     c.float("y_0", 0),
+		}
+	case "":
+		return nil, fmt.Errorf("missing +proj")
+	default:
+		return nil, fmt.Errorf("projection %q is not supported", name)
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	if crs.Projection != nil && toMeter != 1 {
+		crs.Projection = scaledProjection{Projection: crs.Projection, toMeter: toMeter}
+	}
+	return crs, nil
+}
+
+// datum resolves the ellipsoid and datum of the PROJ string.
+func (c *projParser) datum() (Ellipsoid, *Datum, error) {
+	el := WGS84
+	var datum *Datum
+	if v, ok := c.params["datum"]; ok {
+		if datum, ok = projDatums[v]; !ok {
+			return Ellipsoid{}, nil, fmt.Errorf("unknown datum %q", v)
+		}
+		el = datum.Ellipsoid
+	}
+	if v, ok := c.params["ellps"]; ok {
+		if el, ok = projEllipsoids[v]; !ok {
+			return Ellipsoid{}, nil, fmt.Errorf("unknown ellipsoid %q", v)
+		}
+	}
+
+	switch {
+	case c.has("R"):
+		el = Ellipsoid{A: c.float("R", 0)}
+	case c.has("a"):
+		el.A = c.float("a", 0)
+		switch {
+		case c.has("rf"):
+			el.F = 1 / c.float("rf", 0)
+		case c.has("f"):
+			el.F = c.float("f", 0)
+		case c.has("b"):
+			el.F = (el.A - c.float("b", 0)) / el.A
+		}
+	}
+	if el.A <= 0 || el.F < 0 || el.F >= 1 || c.err != nil {
+		return Ellipsoid{}, nil, fmt.Errorf("invalid ellipsoid")
+	}
+
+	if v, ok := c.params["towgs84"]; ok {
+		parts := strings.Split(v, ",")
+		if len(parts) != 3 && len(parts) != 7 {
+			return Ellipsoid{}, nil, fmt.Errorf("+towgs84 needs 3 or 7 values, got %d", len(parts))
+		}
+		datum = &Datum{Ellipsoid: el}
+		for i, part := range parts {
+			f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return Ellipsoid{}, nil, fmt.Errorf("invalid +towgs84 value %q", part)
+			}
+			datum.ToWGS84[i] = f
+		}
+	} else if datum != nil && datum.Ellipsoid != el {
+		d := *datum
+		d.Ellipsoid = el
+		datum = &d
+	}
+	return el, datum, nil
+}
+
+// scaledProjection wraps a projection whose coordinates are in a unit other
+// than meters.
+type scaledProjection struct {
+	Projection
+	toMeter float64
+}
+
+func (s scaledProjection) Forward(p gogis.Point) (gogis.Point, error) {
+	q, err := s.Projection.Forward(p)
+	if err != nil {
+		return gogis.Point{}, err
+	}
+	return gogis.Point{Lng: q.Lng / s.toMeter, Lat: q.Lat / s.toMeter}, nil
+}
+
+func (s scaledProjection) Inverse(p gogis.Point) (gogis.Point, error) {
+	return s.Projection.Inverse(gogis.Point{Lng: p.Lng * s.toMeter, Lat: p.Lat * s.toMeter})
+}
+
+// isZero reports whether f is zero within floating point noise, as produced by
+// unit conversions in parsed definitions.
+func isZero(f float64) bool {
+	return math.Abs(f) < 1e-12
+}
@@ -0,0 +1,135 @@
+package proj_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/proj"
+)
+
+func TestParseProj(t *testing.T) {
+	tests := []struct {
+		name       string
+		def        string
+		geographic bool
+		geo        gogis.Point
+		grid       gogis.Point
+		tol        float64
+	}{
+		{
+			name: "utm",
+			def:  "+proj=utm +zone=32 +datum=WGS84 +units=m +no_defs",
+			geo:  gogis.Point{Lng: 9, Lat: 0},
+			grid: gogis.Point{Lng: 500000, Lat: 0},
+			tol:  0.001,
+		},
+		{
+			name: "utm south",
+			def:  "+proj=utm +zone=33 +south +ellps=WGS84",
+			geo:  gogis.Point{Lng: 15, Lat: 0},
+			grid: gogis.Point{Lng: 500000, Lat: 10000000},
+			tol:  0.001,
+		},
+		{
+			name: "tmerc with datum shift",
+			def: "+proj=tmerc +lat_0=49 +lon_0=-2 +k=0.9996012717 +x_0=400000 +y_0=-100000 +ellps=airy " +
+				"+towgs84=446.448,-125.157,542.06,0.15,0.247,0.842,-20.489 +units=m +no_defs",
+			geo:  gogis.Point{Lng: -0.124625, Lat: 51.500729},
+			grid: gogis.Point{Lng: 530268, Lat: 179644},
+			tol:  5,
+		},
+		{
+			name: "lcc in feet",
+			def:  "+proj=lcc +lat_1=30 +lat_2=40 +lat_0=35 +lon_0=-100 +x_0=304.8 +y_0=0 +ellps=GRS80 +units=ft",
+			geo:  gogis.Point{Lng: -100, Lat: 35},
+			grid: gogis.Point{Lng: 1000, Lat: 0},
+			tol:  1e-6,
+		},
+		{
+			name: "spherical mercator",
+			def:  "+proj=merc +a=6378137 +b=6378137 +lat_ts=0 +lon_0=0 +x_0=0 +y_0=0 +k=1 +units=m +nadgrids=@null +wktext +no_defs",
+			geo:  gogis.Point{Lng: -45, Lat: -45},
+			grid: gogis.Point{Lng: -5009377.085697311, Lat: -5621521.486192066},
+			tol:  1e-6,
+		},
+		{
+			name:       "longlat",
+			def:        "+proj=longlat +datum=NAD83 +no_defs",
+			geographic: true,
+			geo:        gogis.Point{Lng: -100, Lat: 40},
+			grid:       gogis.Point{Lng: -100, Lat: 40},
+			tol:        1e-8,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crs, err := proj.ParseProj(tt.def)
+			if err != nil {
+				t.Fatalf("ParseProj() unexpected error = %v", err)
+			}
+			if crs.Geographic() != tt.geographic {
+				t.Errorf("Geographic() = %v, want %v", crs.Geographic(), tt.geographic)
+			}
+
+			g, err := proj.TransformCRS(&tt.geo, &proj.CRS{}, crs)
+			if err != nil {
+				t.Fatalf("TransformCRS() unexpected error = %v", err)
+			}
+			assertNear(t, "TransformCRS()", *g.(*gogis.Point), tt.grid, tt.tol)
+
+			back, err := proj.TransformCRS(g, crs, &proj.CRS{})
+			if err != nil {
+				t.Fatalf("TransformCRS() back unexpected error = %v", err)
+			}
+			assertNear(t, "round trip", *back.(*gogis.Point), tt.geo, 1e-9)
+		})
+	}
+}
+
+func TestParseProjErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		def  string
+	}{
+		{name: "missing proj", def: "+ellps=WGS84"},
+		{name: "unsupported projection", def: "+proj=laea +lat_0=52 +lon_0=10"},
+		{name: "unknown ellipsoid", def: "+proj=longlat +ellps=nope"},
+		{name: "unknown datum", def: "+proj=longlat +datum=nope"},
+		{name: "invalid number", def: "+proj=tmerc +lon_0=abc"},
+		{name: "invalid utm zone", def: "+proj=utm +zone=61"},
+		{name: "bad towgs84", def: "+proj=longlat +towgs84=1,2"},
+		{name: "grid shift", def: "+proj=longlat +nadgrids=conus"},
+		{name: "prime meridian", def: "+proj=longlat +pm=2.337229166667"},
+		{name: "named prime meridian", def: "+proj=lcc +lat_1=46.8 +lat_0=46.8 +lon_0=0 +ellps=clrk80ign +pm=paris"},
+		{name: "unknown unit", def: "+proj=utm +zone=1 +units=furlong"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := proj.ParseProj(tt.def); err == nil {
+				t.Errorf("ParseProj(%q) expected error, got nil", tt.def)
+			}
+		})
+	}
+
+	_, err := proj.ParseProj("+proj=longlat +ellps=WGS84 +pm=paris")
+	if err == nil || !strings.Contains(err.Error(), `prime meridian "paris"`) {
+		t.Errorf("ParseProj() with a named prime meridian error = %v, want unsupported prime meridian", err)
+	}
+	if _, err := proj.ParseProj("+proj=longlat +ellps=WGS84 +pm=greenwich"); err != nil {
+		t.Errorf("ParseProj() with the Greenwich meridian unexpected error = %v", err)
+	}
+}
+
+func TestParseDetectsFormat(t *testing.T) {
+	crs, err := proj.Parse("  +proj=utm +zone=31")
+	if err != nil || crs.Geographic() {
+		t.Errorf("Parse(PROJ string) = %v, %v, want a projected system", crs, err)
+	}
+	crs, err = proj.Parse(`GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563]],AUTHORITY["EPSG","4326"]]`)
+	if err != nil || !crs.Geographic() || crs.SRID != gogis.SRIDWGS84 {
+		t.Errorf("Parse(WKT) = %v, %v, want geographic WGS 84", crs, err)
+	}
+}
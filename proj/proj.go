@@ -0,0 +1,289 @@
+// Package proj transforms gogis geometries between coordinate reference
+// systems.
+//
+// All gogis geometries are stored as WGS 84 longitude and latitude (SRID
+// 4326). Distances and areas computed directly on those coordinates are in
+// degrees, which makes planar math awkward. This package projects geometries
+// into systems measured in meters, such as Web Mercator (EPSG:3857) or the UTM
+// zones, and back again.
+//
+// Example:
+//
+//	// Project a route into UTM zone 18N to measure it in meters
+//	g, err := proj.Transform(&route.Path, gogis.SRIDWGS84, 32618)
+//	if err != nil {
+//	    return err
+//	}
+//	utm := g.(*gogis.LineString)
+//
+// The Lng and Lat fields of projected points hold the easting (X) and
+// northing (Y) in the units of the target system. Note that String() and
+// Value() still emit SRID=4326, so projected geometries are meant for
+// computations and should be transformed back before being stored.
+//
+// Custom systems can be built from the projection types in this package,
+// parsed from PROJ strings or WKT with Parse, and registered with Register.
+// Lookup also resolves a set of common EPSG codes from an embedded registry.
+package proj
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/restayway/gogis"
+)
+
+// Projection converts between geographic and projected coordinates.
+//
+// Geographic coordinates are longitude (Lng) and latitude (Lat) in decimal
+// degrees on the projection's ellipsoid. Projected coordinates are easting
+// (Lng) and northing (Lat), usually in meters.
+type Projection interface {
+	Forward(p gogis.Point) (gogis.Point, error)
+	Inverse(p gogis.Point) (gogis.Point, error)
+}
+
+// CRS describes a coordinate reference system.
+type CRS struct {
+	// Name is a human readable description of the system.
+	Name string
+
+	// Projection maps geographic coordinates to the system's coordinates.
+	// A nil Projection denotes a geographic system using longitude and
+	// latitude in decimal degrees.
+	Projection Projection
+
+	// Datum is the geodetic datum of the system. A nil Datum means WGS 84,
+	// or a datum treated as equivalent to it such as ETRS89 or NAD83.
+	Datum *Datum
+
+	// SRID is the EPSG code of the system, or 0 when it is not known.
+	SRID gogis.SRID
+}
+
+// Geographic reports whether the system uses longitude and latitude.
+func (c *CRS) Geographic() bool {
+	return c.Projection == nil
+}
+
+// Ellipsoid describes the reference ellipsoid of a datum.
+type Ellipsoid struct {
+	A float64 // Semi-major axis in meters
+	F float64 // Flattening, (a - b) / a
+}
+
+// Commonly used reference ellipsoids.
+var (
+	WGS84         = Ellipsoid{A: 6378137, F: 1 / 298.257223563}
+	GRS80         = Ellipsoid{A: 6378137, F: 1 / 298.257222101}
+	Airy1830      = Ellipsoid{A: 6377563.396, F: 1 / 299.3249646}
+	Clarke1866    = Ellipsoid{A: 6378206.4, F: 1 / 294.9786982}
+	International = Ellipsoid{A: 6378388, F: 1 / 297}
+	Bessel1841    = Ellipsoid{A: 6377397.155, F: 1 / 299.1528128}
+)
+
+// eccentricity returns the first eccentricity of the ellipsoid.
+func (e Ellipsoid) eccentricity() float64 {
+	return math.Sqrt(e.F * (2 - e.F))
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[gogis.SRID]*CRS{
+		gogis.SRIDWGS84:       {Name: "WGS 84", SRID: gogis.SRIDWGS84},
+		gogis.SRIDWebMercator: {Name: "WGS 84 / Pseudo-Mercator", Projection: WebMercator{}, SRID: gogis.SRIDWebMercator},
+		900913:                {Name: "Google Maps Global Mercator", Projection: WebMercator{}, SRID: 900913},
+	}
+)
+
+// Register makes a coordinate reference system available under the given
+// SRID, replacing any previous definition.
+func Register(srid gogis.SRID, crs *CRS) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[srid] = crs
+}
+
+// Lookup returns the coordinate reference system for an SRID.
+//
+// Besides registered systems, the WGS 84 UTM zones (EPSG:32601 to 32660 for
+// the northern and EPSG:32701 to 32760 for the southern hemisphere) are always
+// available, as are the EPSG codes of the embedded registry, such as
+// Lambert-93 (2154), the British National Grid (27700) and the ETRS89 and
+// NAD83 UTM zones.
+func Lookup(srid gogis.SRID) (*CRS, error) {
+	registryMu.RLock()
+	crs, ok := registry[srid]
+	registryMu.RUnlock()
+	if ok {
+		return crs, nil
+	}
+
+	switch {
+	case srid > 32600 && srid <= 32660:
+		zone := int(srid - 32600)
+		return &CRS{Name: fmt.Sprintf("WGS 84 / UTM zone %dN", zone), Projection: UTM(zone, false), SRID: srid}, nil
+	case srid > 32700 && srid <= 32760:
+		zone := int(srid - 32700)
+		return &CRS{Name: fmt.Sprintf("WGS 84 / UTM zone %dS", zone), Projection: UTM(zone, true), SRID: srid}, nil
+	}
+	if crs, err := lookupEPSG(srid); crs != nil || err != nil {
+		return crs, err
+	}
+	return nil, fmt.Errorf("proj: unknown SRID %d", srid)
+}
+
+// Transform returns a copy of g with every vertex transformed from one
+// coordinate reference system to another.
+//
+// Point, LineString, Polygon and GeometryCollection geometries and their
+// geography variants are supported; the result has the same concrete type as
+// g.
+func Transform(g gogis.Geometry, from, to gogis.SRID) (gogis.Geometry, error) {
+	src, err := Lookup(from)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := Lookup(to)
+	if err != nil {
+		return nil, err
+	}
+	return TransformCRS(g, src, dst)
+}
+
+// TransformCRS is like Transform but takes the coordinate reference systems
+// directly, which allows using systems that are not registered.
+func TransformCRS(g gogis.Geometry, from, to *CRS) (gogis.Geometry, error) {
+	out, err := mapGeometry(g, func(p gogis.Point) (gogis.Point, error) {
+		return transformPoint(p, from, to)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("proj: %w", err)
+	}
+	return out, nil
+}
+
+// TransformPoint transforms a single point between two SRIDs.
+func TransformPoint(p gogis.Point, from, to gogis.SRID) (gogis.Point, error) {
+	src, err := Lookup(from)
+	if err != nil {
+		return gogis.Point{}, err
+	}
+	dst, err := Lookup(to)
+	if err != nil {
+		return gogis.Point{}, err
+	}
+	q, err := transformPoint(p, src, dst)
+	if err != nil {
+		return gogis.Point{}, fmt.Errorf("proj: %w", err)
+	}
+	return q, nil
+}
+
+func transformPoint(p gogis.Point, from, to *CRS) (gogis.Point, error) {
+	var err error
+	if from.Projection != nil {
+		if p, err = from.Projection.Inverse(p); err != nil {
+			return gogis.Point{}, err
+		}
+	}
+	if !sameDatum(from.Datum, to.Datum) {
+		if from.Datum != nil {
+			p = from.Datum.toWGS84(p)
+		}
+		if to.Datum != nil {
+			p = to.Datum.fromWGS84(p)
+		}
+	}
+	if to.Projection != nil {
+		if p, err = to.Projection.Forward(p); err != nil {
+			return gogis.Point{}, err
+		}
+	}
+	return p, nil
+}
+
+// mapGeometry returns a copy of g with fn applied to every vertex.
+func mapGeometry(g gogis.Geometry, fn func(gogis.Point) (gogis.Point, error)) (gogis.Geometry, error) {
+	mapPoints := func(points []gogis.Point) ([]gogis.Point, error) {
+		out := make([]gogis.Point, len(points))
+		for i, p := range points {
+			q, err := fn(p)
+			if err != nil {
+				return nil, fmt.Errorf("vertex %d: %w", i, err)
+			}
+			out[i] = q
+		}
+		return out, nil
+	}
+
+	switch v := g.(type) {
+	case *gogis.Point:
+		p, err := fn(*v)
+		if err != nil {
+			return nil, err
+		}
+		return &p, nil
+	case *gogis.LineString:
+		points, err := mapPoints(v.Points)
+		if err != nil {
+			return nil, err
+		}
+		return &gogis.LineString{Points: points}, nil
+	case *gogis.Polygon:
+		out := &gogis.Polygon{Rings: make([][]gogis.Point, len(v.Rings))}
+		for i, ring := range v.Rings {
+			points, err := mapPoints(ring)
+			if err != nil {
+				return nil, fmt.Errorf("ring %d: %w", i, err)
+			}
+			out.Rings[i] = points
+		}
+		return out, nil
+	case *gogis.GeometryCollection:
+		out := &gogis.GeometryCollection{Geometries: make([]gogis.Geometry, len(v.Geometries))}
+		for i, child := range v.Geometries {
+			mapped, err := mapGeometry(child, fn)
+			if err != nil {
+				return nil, fmt.Errorf("geometry %d: %w", i, err)
+			}
+			out.Geometries[i] = mapped
+		}
+		return out, nil
+	case *gogis.GeographyPoint:
+		out, err := mapGeometry((*gogis.Point)(v), fn)
+		if err != nil {
+			return nil, err
+		}
+		return (*gogis.GeographyPoint)(out.(*gogis.Point)), nil
+	case *gogis.GeographyLineString:
+		out, err := mapGeometry((*gogis.LineString)(v), fn)
+		if err != nil {
+			return nil, err
+		}
+		return (*gogis.GeographyLineString)(out.(*gogis.LineString)), nil
+	case *gogis.GeographyPolygon:
+		out, err := mapGeometry((*gogis.Polygon)(v), fn)
+		if err != nil {
+			return nil, err
+		}
+		return (*gogis.GeographyPolygon)(out.(*gogis.Polygon)), nil
+	case *gogis.GeographyCollection:
+		out, err := mapGeometry((*gogis.GeometryCollection)(v), fn)
+		if err != nil {
+			return nil, err
+		}
+		return (*gogis.GeographyCollection)(out.(*gogis.GeometryCollection)), nil
+	default:
+		return nil, fmt.Errorf("unsupported geometry type %T", g)
+	}
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func toDegrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
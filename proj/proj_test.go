@@ -0,0 +1,165 @@
+package proj_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/proj"
+)
+
+func TestTransformPoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		point    gogis.Point
+		from, to gogis.SRID
+		expected gogis.Point
+		tol      float64
+	}{
+		{
+			name:     "wgs84 to web mercator",
+			point:    gogis.Point{Lng: -74.0445, Lat: 40.6892},
+			from:     gogis.SRIDWGS84,
+			to:       gogis.SRIDWebMercator,
+			expected: gogis.Point{Lng: -8242596.04, Lat: 4966606.26},
+			tol:      0.01,
+		},
+		{
+			name:     "web mercator to wgs84",
+			point:    gogis.Point{Lng: 20037508.342789244, Lat: 0},
+			from:     gogis.SRIDWebMercator,
+			to:       gogis.SRIDWGS84,
+			expected: gogis.Point{Lng: 180, Lat: 0},
+			tol:      1e-9,
+		},
+		{
+			name:     "web mercator to utm",
+			point:    gogis.Point{Lng: 333958.47, Lat: 0},
+			from:     gogis.SRIDWebMercator,
+			to:       32631,
+			expected: gogis.Point{Lng: 500000, Lat: 0},
+			tol:      0.01,
+		},
+		{
+			name:     "same system",
+			point:    gogis.Point{Lng: 12.5, Lat: 41.9},
+			from:     gogis.SRIDWGS84,
+			to:       gogis.SRIDWGS84,
+			expected: gogis.Point{Lng: 12.5, Lat: 41.9},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := proj.TransformPoint(tt.point, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("TransformPoint() unexpected error = %v", err)
+			}
+			assertNear(t, "TransformPoint()", got, tt.expected, tt.tol)
+		})
+	}
+}
+
+func TestTransformGeometries(t *testing.T) {
+	polygon := &gogis.Polygon{
+		Rings: [][]gogis.Point{
+			{{Lng: 9, Lat: 45}, {Lng: 9.01, Lat: 45}, {Lng: 9.01, Lat: 45.01}, {Lng: 9, Lat: 45}},
+		},
+	}
+	gc := &gogis.GeometryCollection{
+		Geometries: []gogis.Geometry{
+			&gogis.Point{Lng: 9, Lat: 45},
+			&gogis.LineString{Points: []gogis.Point{{Lng: 9, Lat: 45}, {Lng: 9, Lat: 45.01}}},
+			polygon,
+		},
+	}
+
+	g, err := proj.Transform(gc, gogis.SRIDWGS84, 32632)
+	if err != nil {
+		t.Fatalf("Transform() unexpected error = %v", err)
+	}
+	out, ok := g.(*gogis.GeometryCollection)
+	if !ok || len(out.Geometries) != 3 {
+		t.Fatalf("Transform() = %v, want a collection of 3 geometries", g)
+	}
+
+	// Zone 32 has its central meridian at 9°E.
+	p := out.Geometries[0].(*gogis.Point)
+	if p.Lng != 500000 {
+		t.Errorf("transformed point easting = %v, want 500000", p.Lng)
+	}
+
+	line := out.Geometries[1].(*gogis.LineString)
+	if length := line.Points[1].Lat - line.Points[0].Lat; length < 1110 || length > 1112 {
+		t.Errorf("0.01 degree of latitude = %v m, want about 1111 m", length)
+	}
+
+	ring := out.Geometries[2].(*gogis.Polygon).Rings[0]
+	if ring[0] != ring[3] {
+		t.Errorf("transformed ring is no longer closed")
+	}
+	if polygon.Rings[0][0].Lng != 9 {
+		t.Errorf("Transform() modified its input")
+	}
+
+	back, err := proj.Transform(out, 32632, gogis.SRIDWGS84)
+	if err != nil {
+		t.Fatalf("Transform() back unexpected error = %v", err)
+	}
+	assertNear(t, "round trip", *back.(*gogis.GeometryCollection).Geometries[0].(*gogis.Point), gogis.Point{Lng: 9, Lat: 45}, 1e-9)
+
+	// Geography values keep their type.
+	g, err = proj.Transform((*gogis.GeographyCollection)(gc), gogis.SRIDWGS84, 32632)
+	if err != nil {
+		t.Fatalf("Transform() of GeographyCollection unexpected error = %v", err)
+	}
+	if geo, ok := g.(*gogis.GeographyCollection); !ok || !reflect.DeepEqual((*gogis.GeometryCollection)(geo), out) {
+		t.Errorf("Transform() of GeographyCollection = %#v, want %v", g, out)
+	}
+	g, err = proj.Transform((*gogis.GeographyPoint)(&gogis.Point{Lng: 9, Lat: 45}), gogis.SRIDWGS84, 32632)
+	if p, ok := g.(*gogis.GeographyPoint); err != nil || !ok || p.Lng != 500000 {
+		t.Errorf("Transform() of GeographyPoint = %#v, %v", g, err)
+	}
+}
+
+func TestTransformErrors(t *testing.T) {
+	p := &gogis.Point{Lng: 0, Lat: 90}
+
+	if _, err := proj.Transform(p, gogis.SRIDWGS84, 99999); err == nil {
+		t.Errorf("Transform() expected error for unknown SRID, got nil")
+	}
+	if _, err := proj.Transform(p, gogis.SRIDWGS84, gogis.SRIDWebMercator); err == nil {
+		t.Errorf("Transform() expected error for the pole in Mercator, got nil")
+	}
+	line := &gogis.LineString{Points: []gogis.Point{{Lng: 0, Lat: 0}, {Lng: 0, Lat: -90}}}
+	if _, err := proj.Transform(line, gogis.SRIDWGS84, gogis.SRIDWebMercator); err == nil {
+		t.Errorf("Transform() expected error for a vertex at the pole, got nil")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	const srid gogis.SRID = 990001
+	proj.Register(srid, &proj.CRS{
+		Name:       "Custom TM",
+		Projection: proj.TransverseMercator{Ellipsoid: proj.GRS80, CentralMeridian: 10, ScaleFactor: 1},
+	})
+
+	crs, err := proj.Lookup(srid)
+	if err != nil {
+		t.Fatalf("Lookup() unexpected error = %v", err)
+	}
+	if crs.Name != "Custom TM" || crs.Geographic() {
+		t.Errorf("Lookup() = %+v, want the registered projected system", crs)
+	}
+
+	got, err := proj.TransformPoint(gogis.Point{Lng: 10, Lat: 0}, gogis.SRIDWGS84, srid)
+	if err != nil {
+		t.Fatalf("TransformPoint() unexpected error = %v", err)
+	}
+	assertNear(t, "TransformPoint()", got, gogis.Point{}, 1e-9)
+
+	utm, err := proj.Lookup(32733)
+	if err != nil || utm.Name != "WGS 84 / UTM zone 33S" {
+		t.Errorf("Lookup(32733) = %v, %v, want UTM zone 33S", utm, err)
+	}
+}
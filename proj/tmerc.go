@@ -0,0 +1,210 @@
+package proj
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/restayway/gogis"
+)
+
+// TransverseMercator is the ellipsoidal Transverse Mercator projection, the
+// basis of UTM and of many national grids.
+//
+// The implementation uses the sixth order Krüger series, which is accurate to
+// well below a millimetre within 4000 km of the central meridian.
+//
+// Example (British National Grid, on the Airy 1830 ellipsoid):
+//
+//	bng := proj.TransverseMercator{
+//	    Ellipsoid:        proj.Airy1830,
+//	    CentralMeridian:  -2,
+//	    LatitudeOfOrigin: 49,
+//	    ScaleFactor:      0.9996012717,
+//	    FalseEasting:     400000,
+//	    FalseNorthing:    -100000,
+//	}
+type TransverseMercator struct {
+	Ellipsoid        Ellipsoid
+	CentralMeridian  float64 // Longitude of the central meridian in degrees
+	LatitudeOfOrigin float64 // Latitude of the origin in degrees
+	ScaleFactor      float64 // Scale factor on the central meridian, 0 means 1
+	FalseEasting     float64 // Easting of the origin in meters
+	FalseNorthing    float64 // Northing of the origin in meters
+}
+
+// UTM returns the Transverse Mercator projection of a WGS 84 UTM zone (1 to
+// 60). Southern zones use a false northing of 10,000 km.
+func UTM(zone int, south bool) TransverseMercator {
+	tm := TransverseMercator{
+		Ellipsoid:       WGS84,
+		CentralMeridian: float64(zone*6 - 183),
+		ScaleFactor:     0.9996,
+		FalseEasting:    500000,
+	}
+	if south {
+		tm.FalseNorthing = 10000000
+	}
+	return tm
+}
+
+// UTMZone returns the UTM zone number of a longitude in degrees.
+func UTMZone(lng float64) int {
+	zone := int(math.Floor((lng+180)/6)) + 1
+	if zone < 1 {
+		return 1
+	}
+	if zone > 60 {
+		return 60
+	}
+	return zone
+}
+
+// tmSeries holds the Krüger series coefficients for an ellipsoid.
+type tmSeries struct {
+	e     float64    // First eccentricity
+	a     float64    // Rectifying radius
+	alpha [6]float64 // Forward series
+	beta  [6]float64 // Inverse series
+}
+
+func newTMSeries(el Ellipsoid) tmSeries {
+	n := el.F / (2 - el.F)
+	n2 := n * n
+	n3 := n2 * n
+	n4 := n3 * n
+	n5 := n4 * n
+	n6 := n5 * n
+
+	return tmSeries{
+		e: el.eccentricity(),
+		a: el.A / (1 + n) * (1 + n2/4 + n4/64 + n6/256),
+		alpha: [6]float64{
+			n/2 - 2*n2/3 + 5*n3/16 + 41*n4/180 - 127*n5/288 + 7891*n6/37800,
+			13*n2/48 - 3*n3/5 + 557*n4/1440 + 281*n5/630 - 1983433*n6/1935360,
+			61*n3/240 - 103*n4/140 + 15061*n5/26880 + 167603*n6/181440,
+			49561*n4/161280 - 179*n5/168 + 6601661*n6/7257600,
+			34729*n5/80640 - 3418889*n6/1995840,
+			212378941 * n6 / 319334400,
+		},
+		beta: [6]float64{
+			n/2 - 2*n2/3 + 37*n3/96 - n4/360 - 81*n5/512 + 96199*n6/604800,
+			n2/48 + n3/15 - 437*n4/1440 + 46*n5/105 - 1118711*n6/3870720,
+			17*n3/480 - 37*n4/840 - 209*n5/4480 + 5569*n6/90720,
+			4397*n4/161280 - 11*n5/504 - 830251*n6/7257600,
+			4583*n5/161280 - 108847*n6/3991680,
+			20648693 * n6 / 638668800,
+		},
+	}
+}
+
+// conformalTan returns the tangent of the conformal latitude for the tangent
+// tau of a geodetic latitude.
+func conformalTan(tau, e float64) float64 {
+	sigma := math.Sinh(e * math.Atanh(e*tau/math.Hypot(1, tau)))
+	return tau*math.Hypot(1, sigma) - sigma*math.Hypot(1, tau)
+}
+
+// geodeticTan inverts conformalTan with Newton's method.
+func geodeticTan(taup, e float64) float64 {
+	e2m := 1 - e*e
+	tau := taup / e2m
+	for i := 0; i < 10; i++ {
+		tp := conformalTan(tau, e)
+		dtau := (taup - tp) / math.Hypot(1, tp) * (1 + e2m*tau*tau) / (e2m * math.Hypot(1, tau))
+		tau += dtau
+		if math.Abs(dtau) < 1e-14*math.Max(1, math.Abs(tau)) {
+			break
+		}
+	}
+	return tau
+}
+
+// xiOnMeridian returns the northing, in units of the rectifying radius, of the
+// point on the central meridian at the given latitude in radians.
+func (s tmSeries) xiOnMeridian(lat float64) float64 {
+	xi := math.Atan(conformalTan(math.Tan(lat), s.e))
+	sum := xi
+	for j, a := range s.alpha {
+		sum += a * math.Sin(2*float64(j+1)*xi)
+	}
+	return sum
+}
+
+func (tm TransverseMercator) scale() float64 {
+	if tm.ScaleFactor == 0 {
+		return 1
+	}
+	return tm.ScaleFactor
+}
+
+// Forward projects longitude and latitude to easting and northing.
+func (tm TransverseMercator) Forward(p gogis.Point) (gogis.Point, error) {
+	if math.Abs(p.Lat) > 90 {
+		return gogis.Point{}, fmt.Errorf("latitude %v out of range", p.Lat)
+	}
+	s := newTMSeries(tm.Ellipsoid)
+	k0 := tm.scale()
+
+	lat := toRadians(p.Lat)
+	dlng := toRadians(normalizeLng(p.Lng - tm.CentralMeridian))
+	if math.Abs(dlng) >= math.Pi/2 {
+		return gogis.Point{}, fmt.Errorf("longitude %v too far from central meridian %v", p.Lng, tm.CentralMeridian)
+	}
+
+	var xip, etap float64
+	if math.Abs(p.Lat) == 90 {
+		xip, etap = math.Copysign(math.Pi/2, lat), 0
+	} else {
+		taup := conformalTan(math.Tan(lat), s.e)
+		xip = math.Atan2(taup, math.Cos(dlng))
+		etap = math.Asinh(math.Sin(dlng) / math.Hypot(taup, math.Cos(dlng)))
+	}
+
+	xi, eta := xip, etap
+	for j, a := range s.alpha {
+		k := 2 * float64(j+1)
+		xi += a * math.Sin(k*xip) * math.Cosh(k*etap)
+		eta += a * math.Cos(k*xip) * math.Sinh(k*etap)
+	}
+
+	xi0 := s.xiOnMeridian(toRadians(tm.LatitudeOfOrigin))
+	return gogis.Point{
+		Lng: tm.FalseEasting + k0*s.a*eta,
+		Lat: tm.FalseNorthing + k0*s.a*(xi-xi0),
+	}, nil
+}
+
+// Inverse converts easting and northing to longitude and latitude.
+func (tm TransverseMercator) Inverse(p gogis.Point) (gogis.Point, error) {
+	s := newTMSeries(tm.Ellipsoid)
+	k0 := tm.scale()
+
+	xi := (p.Lat-tm.FalseNorthing)/(k0*s.a) + s.xiOnMeridian(toRadians(tm.LatitudeOfOrigin))
+	eta := (p.Lng - tm.FalseEasting) / (k0 * s.a)
+
+	xip, etap := xi, eta
+	for j, b := range s.beta {
+		k := 2 * float64(j+1)
+		xip -= b * math.Sin(k*xi) * math.Cosh(k*eta)
+		etap -= b * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+
+	sinhEta := math.Sinh(etap)
+	cosXi := math.Cos(xip)
+	taup := math.Sin(xip) / math.Hypot(sinhEta, cosXi)
+	tau := geodeticTan(taup, s.e)
+
+	return gogis.Point{
+		Lng: normalizeLng(tm.CentralMeridian + toDegrees(math.Atan2(sinhEta, cosXi))),
+		Lat: toDegrees(math.Atan(tau)),
+	}, nil
+}
+
+// normalizeLng wraps a longitude in degrees into [-180, 180).
+func normalizeLng(lng float64) float64 {
+	lng = math.Mod(lng+180, 360)
+	if lng < 0 {
+		lng += 360
+	}
+	return lng - 180
+}
@@ -0,0 +1,119 @@
+package proj_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/proj"
+)
+
+func assertNear(t *testing.T, what string, got, want gogis.Point, tol float64) {
+	t.Helper()
+	if math.Abs(got.Lng-want.Lng) > tol || math.Abs(got.Lat-want.Lat) > tol {
+		t.Errorf("%s = (%.6f %.6f), want (%.6f %.6f) within %v", what, got.Lng, got.Lat, want.Lng, want.Lat, tol)
+	}
+}
+
+func TestTransverseMercatorEPSGExample(t *testing.T) {
+	// EPSG Guidance Note 7-2, Transverse Mercator example (OSGB 1936 /
+	// British National Grid): 50°30'N 0°30'E -> 577274.99 E, 69740.50 N.
+	bng := proj.TransverseMercator{
+		Ellipsoid:        proj.Airy1830,
+		CentralMeridian:  -2,
+		LatitudeOfOrigin: 49,
+		ScaleFactor:      0.9996012717,
+		FalseEasting:     400000,
+		FalseNorthing:    -100000,
+	}
+	geo := gogis.Point{Lng: 0.5, Lat: 50.5}
+	grid := gogis.Point{Lng: 577274.99, Lat: 69740.50}
+
+	got, err := bng.Forward(geo)
+	if err != nil {
+		t.Fatalf("Forward() unexpected error = %v", err)
+	}
+	assertNear(t, "Forward()", got, grid, 0.01)
+
+	back, err := bng.Inverse(grid)
+	if err != nil {
+		t.Fatalf("Inverse() unexpected error = %v", err)
+	}
+	assertNear(t, "Inverse()", back, geo, 1e-7)
+}
+
+func TestUTM(t *testing.T) {
+	tests := []struct {
+		name  string
+		zone  int
+		south bool
+		geo   gogis.Point
+		grid  gogis.Point
+	}{
+		{
+			name: "central meridian on the equator",
+			zone: 31,
+			geo:  gogis.Point{Lng: 3, Lat: 0},
+			grid: gogis.Point{Lng: 500000, Lat: 0},
+		},
+		{
+			name:  "southern hemisphere false northing",
+			zone:  31,
+			south: true,
+			geo:   gogis.Point{Lng: 3, Lat: 0},
+			grid:  gogis.Point{Lng: 500000, Lat: 10000000},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := proj.UTM(tt.zone, tt.south).Forward(tt.geo)
+			if err != nil {
+				t.Fatalf("Forward() unexpected error = %v", err)
+			}
+			assertNear(t, "Forward()", got, tt.grid, 1e-6)
+		})
+	}
+}
+
+func TestTransverseMercatorRoundTrip(t *testing.T) {
+	utm := proj.UTM(18, false)
+	for _, geo := range []gogis.Point{
+		{Lng: -74.0445, Lat: 40.6892},
+		{Lng: -78.5, Lat: 10},
+		{Lng: -70, Lat: 84},
+		{Lng: -75, Lat: 89.9},
+	} {
+		grid, err := utm.Forward(geo)
+		if err != nil {
+			t.Fatalf("Forward(%v) unexpected error = %v", geo, err)
+		}
+		back, err := utm.Inverse(grid)
+		if err != nil {
+			t.Fatalf("Inverse(%v) unexpected error = %v", grid, err)
+		}
+		assertNear(t, "round trip", back, geo, 1e-9)
+	}
+
+	if _, err := utm.Forward(gogis.Point{Lng: 105, Lat: 0}); err == nil {
+		t.Errorf("Forward() expected error for a point 180 degrees away, got nil")
+	}
+}
+
+func TestUTMZone(t *testing.T) {
+	tests := []struct {
+		lng  float64
+		zone int
+	}{
+		{-180, 1},
+		{-74.0445, 18},
+		{0, 31},
+		{179.99, 60},
+		{180, 60},
+	}
+	for _, tt := range tests {
+		if got := proj.UTMZone(tt.lng); got != tt.zone {
+			t.Errorf("UTMZone(%v) = %d, want %d", tt.lng, got, tt.zone)
+		}
+	}
+}
@@ -0,0 +1,608 @@
+package proj
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/restayway/gogis"
+)
+
+// ParseWKT builds a coordinate reference system from an OGC WKT definition.
+//
+// Both WKT 1 (PROJCS, GEOGCS, including the ESRI dialect used by shapefile
+// .prj files) and WKT 2 (PROJCRS, GEOGCRS, BOUNDCRS) are understood, for the
+// same projection methods as ParseProj. Datum shifts come from TOWGS84 or a
+// BOUNDCRS transformation, or from the datum name for a few well-known
+// datums. The EPSG code, when present, is stored in the SRID field.
+//
+// As everywhere in gogis, coordinates are ordered longitude (or easting)
+// first, whatever axis order the definition declares.
+func ParseWKT(s string) (*CRS, error) {
+	p := &wktParser{s: s}
+	root, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("proj: WKT: %w", err)
+	}
+	crs, err := wktCRS(root)
+	if err != nil {
+		return nil, fmt.Errorf("proj: WKT: %w", err)
+	}
+	return crs, nil
+}
+
+// IdentifyWKT returns the name and the EPSG code, or 0, of the coordinate
+// system of a WKT definition. Unlike ParseWKT it does not build the system,
+// so it also identifies definitions using projection methods or datums the
+// package does not support.
+func IdentifyWKT(s string) (string, gogis.SRID, error) {
+	p := &wktParser{s: s}
+	root, err := p.parse()
+	if err != nil {
+		return "", 0, fmt.Errorf("proj: WKT: %w", err)
+	}
+	if root.keyword == "BOUNDCRS" {
+		if source := root.child("SOURCECRS"); source != nil && len(source.args) > 0 {
+			if n, ok := source.args[0].(*wktNode); ok {
+				root = n
+			}
+		}
+	}
+	return root.name(), wktSRID(root), nil
+}
+
+// wktNode is a WKT keyword with its bracketed arguments. Each argument is a
+// string, a float64 or a *wktNode; bare enumeration values such as EAST are
+// stored as strings.
+type wktNode struct {
+	keyword string
+	args    []any
+}
+
+// name returns the first argument of the node when it is a string.
+func (n *wktNode) name() string {
+	if n == nil || len(n.args) == 0 {
+		return ""
+	}
+	s, _ := n.args[0].(string)
+	return s
+}
+
+// number returns the i-th numeric argument of the node.
+func (n *wktNode) number(i int) (float64, bool) {
+	for _, arg := range n.args {
+		if f, ok := arg.(float64); ok {
+			if i == 0 {
+				return f, true
+			}
+			i--
+		}
+	}
+	return 0, false
+}
+
+// child returns the first direct child with one of the keywords.
+func (n *wktNode) child(keywords ...string) *wktNode {
+	if n == nil {
+		return nil
+	}
+	for _, arg := range n.args {
+		if c, ok := arg.(*wktNode); ok {
+			for _, k := range keywords {
+				if c.keyword == k {
+					return c
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// children returns all direct children with the keyword.
+func (n *wktNode) children(keyword string) []*wktNode {
+	var out []*wktNode
+	for _, arg := range n.args {
+		if c, ok := arg.(*wktNode); ok && c.keyword == keyword {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+type wktParser struct {
+	s   string
+	pos int
+}
+
+func (p *wktParser) parse() (*wktNode, error) {
+	n, err := p.node()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos != len(p.s) {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.s[p.pos], p.pos)
+	}
+	return n, nil
+}
+
+func (p *wktParser) skipSpace() {
+	for p.pos < len(p.s) && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *wktParser) keyword() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			break
+		}
+		p.pos++
+	}
+	return strings.ToUpper(p.s[start:p.pos])
+}
+
+func (p *wktParser) node() (*wktNode, error) {
+	kw := p.keyword()
+	if kw == "" {
+		return nil, fmt.Errorf("expected keyword at offset %d", p.pos)
+	}
+	n := &wktNode{keyword: kw}
+	p.skipSpace()
+	if p.pos >= len(p.s) || (p.s[p.pos] != '[' && p.s[p.pos] != '(') {
+		return nil, fmt.Errorf("expected '[' after %s", kw)
+	}
+	closing := byte(']')
+	if p.s[p.pos] == '(' {
+		closing = ')'
+	}
+	p.pos++
+
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.s) {
+			return nil, fmt.Errorf("unterminated %s", kw)
+		}
+		switch c := p.s[p.pos]; {
+		case c == '"':
+			s, err := p.quoted()
+			if err != nil {
+				return nil, err
+			}
+			n.args = append(n.args, s)
+		case c == '-' || c == '+' || c == '.' || c >= '0' && c <= '9':
+			start := p.pos
+			for p.pos < len(p.s) && strings.IndexByte("+-.eE0123456789", p.s[p.pos]) >= 0 {
+				p.pos++
+			}
+			f, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q in %s", p.s[start:p.pos], kw)
+			}
+			n.args = append(n.args, f)
+		default:
+			start := p.pos
+			word := p.keyword()
+			if word == "" {
+				return nil, fmt.Errorf("unexpected %q at offset %d", c, p.pos)
+			}
+			p.skipSpace()
+			if p.pos < len(p.s) && (p.s[p.pos] == '[' || p.s[p.pos] == '(') {
+				p.pos = start
+				child, err := p.node()
+				if err != nil {
+					return nil, err
+				}
+				n.args = append(n.args, child)
+			} else {
+				n.args = append(n.args, word)
+			}
+		}
+
+		p.skipSpace()
+		if p.pos >= len(p.s) {
+			return nil, fmt.Errorf("unterminated %s", kw)
+		}
+		switch p.s[p.pos] {
+		case ',':
+			p.pos++
+		case closing:
+			p.pos++
+			return n, nil
+		default:
+			return nil, fmt.Errorf("unexpected %q in %s", p.s[p.pos], kw)
+		}
+	}
+}
+
+// quoted reads a double quoted string, in which "" stands for a quote.
+func (p *wktParser) quoted() (string, error) {
+	var b strings.Builder
+	p.pos++
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		p.pos++
+		if c != '"' {
+			b.WriteByte(c)
+			continue
+		}
+		if p.pos < len(p.s) && p.s[p.pos] == '"' {
+			b.WriteByte('"')
+			p.pos++
+			continue
+		}
+		return b.String(), nil
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+// wktCRS interprets a parsed WKT definition.
+func wktCRS(root *wktNode) (*CRS, error) {
+	if root.keyword == "BOUNDCRS" {
+		return wktBoundCRS(root)
+	}
+
+	crs := &CRS{Name: root.name(), SRID: wktSRID(root)}
+	switch root.keyword {
+	case "GEOGCS", "GEOGCRS", "GEODCRS", "GEOGRAPHICCRS", "GEODETICCRS":
+		datum, err := wktDatum(root)
+		if err != nil {
+			return nil, err
+		}
+		crs.Datum = datum
+		return crs, nil
+	case "PROJCS", "PROJCRS", "PROJECTEDCRS":
+		base := root.child("GEOGCS", "BASEGEOGCRS", "BASEGEODCRS")
+		if base == nil {
+			return nil, fmt.Errorf("%s has no base geographic system", root.keyword)
+		}
+		datum, err := wktDatum(base)
+		if err != nil {
+			return nil, err
+		}
+		crs.Datum = datum
+
+		el, err := wktEllipsoid(base)
+		if err != nil {
+			return nil, err
+		}
+		projection, webMercator, err := wktProjection(root, base, el)
+		if err != nil {
+			return nil, err
+		}
+		if webMercator {
+			crs.Datum = nil
+		}
+		crs.Projection = projection
+		return crs, nil
+	default:
+		return nil, fmt.Errorf("unsupported coordinate system %s", root.keyword)
+	}
+}
+
+// wktBoundCRS reads a WKT 2 BOUNDCRS, whose transformation to WGS 84 provides
+// the datum shift of its source system.
+func wktBoundCRS(root *wktNode) (*CRS, error) {
+	source := root.child("SOURCECRS")
+	if source == nil || len(source.args) == 0 {
+		return nil, fmt.Errorf("BOUNDCRS has no SOURCECRS")
+	}
+	inner, ok := source.args[0].(*wktNode)
+	if !ok {
+		return nil, fmt.Errorf("BOUNDCRS has no SOURCECRS")
+	}
+	crs, err := wktCRS(inner)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := crs.Projection.(WebMercator); ok {
+		return crs, nil
+	}
+
+	tr := root.child("ABRIDGEDTRANSFORMATION")
+	if tr == nil {
+		return crs, nil
+	}
+	base := inner
+	if b := inner.child("BASEGEOGCRS", "BASEGEODCRS"); b != nil {
+		base = b
+	}
+	el, err := wktEllipsoid(base)
+	if err != nil {
+		return nil, err
+	}
+
+	method := normalizeWKTName(tr.child("METHOD").name())
+	sign := 1.0
+	if strings.Contains(method, "coordinateframe") {
+		sign = -1
+	} else if !strings.Contains(method, "positionvector") && !strings.Contains(method, "geocentrictranslation") {
+		return nil, fmt.Errorf("unsupported transformation method %q", tr.child("METHOD").name())
+	}
+
+	d := &Datum{Name: base.child("DATUM", "ENSEMBLE").name(), Ellipsoid: el}
+	for _, param := range tr.children("PARAMETER") {
+		v, _ := param.number(0)
+		switch name := normalizeWKTName(param.name()); name {
+		case "xaxistranslation":
+			d.ToWGS84[0] = v
+		case "yaxistranslation":
+			d.ToWGS84[1] = v
+		case "zaxistranslation":
+			d.ToWGS84[2] = v
+		case "xaxisrotation":
+			d.ToWGS84[3] = sign * v
+		case "yaxisrotation":
+			d.ToWGS84[4] = sign * v
+		case "zaxisrotation":
+			d.ToWGS84[5] = sign * v
+		case "scaledifference":
+			d.ToWGS84[6] = v
+		}
+	}
+	crs.Datum = d
+	return crs, nil
+}
+
+// wktSRID returns the EPSG code from an AUTHORITY or ID node.
+func wktSRID(n *wktNode) gogis.SRID {
+	id := n.child("AUTHORITY", "ID")
+	if id == nil || !strings.EqualFold(id.name(), "EPSG") || len(id.args) < 2 {
+		return 0
+	}
+	switch v := id.args[1].(type) {
+	case float64:
+		return gogis.SRID(v)
+	case string:
+		code, err := strconv.Atoi(v)
+		if err != nil {
+			return 0
+		}
+		return gogis.SRID(code)
+	}
+	return 0
+}
+
+// wktEllipsoid reads the ellipsoid of a geographic system node.
+func wktEllipsoid(geog *wktNode) (Ellipsoid, error) {
+	datum := geog.child("DATUM", "ENSEMBLE")
+	sph := datum.child("SPHEROID", "ELLIPSOID")
+	if sph == nil {
+		return Ellipsoid{}, fmt.Errorf("missing ellipsoid")
+	}
+	a, ok := sph.number(0)
+	rf, ok2 := sph.number(1)
+	if !ok || !ok2 || a <= 0 {
+		return Ellipsoid{}, fmt.Errorf("invalid ellipsoid %q", sph.name())
+	}
+	if unit := sph.child("LENGTHUNIT"); unit != nil {
+		if f, ok := unit.number(0); ok {
+			a *= f
+		}
+	}
+	el := Ellipsoid{A: a}
+	if rf != 0 {
+		el.F = 1 / rf
+	}
+	return el, nil
+}
+
+// wktKnownDatums maps normalized datum names to datums, for definitions that
+// name their datum without giving TOWGS84 parameters.
+var wktKnownDatums = map[string]*Datum{
+	"wgs1984":                          DatumWGS84,
+	"wgs84":                            DatumWGS84,
+	"worldgeodeticsystem1984":          DatumWGS84,
+	"osgb1936":                         DatumOSGB36,
+	"osgb36":                           DatumOSGB36,
+	"deutscheshauptdreiecksnetz":       DatumPotsdam,
+	"dhdn":                             DatumPotsdam,
+	"potsdam":                          DatumPotsdam,
+	"tm65":                             DatumIRE65,
+	"ireland1965":                      DatumIRE65,
+	"greekgeodeticreferencesystem1987": DatumGGRS87,
+	"ggrs87":                           DatumGGRS87,
+}
+
+// wktDatum reads the datum of a geographic system node. It returns nil when
+// no datum shift is known.
+func wktDatum(geog *wktNode) (*Datum, error) {
+	if pm := geog.child("PRIMEM"); pm != nil {
+		if v, _ := pm.number(0); !isZero(v) {
+			return nil, fmt.Errorf("prime meridian %q is not supported", pm.name())
+		}
+	}
+	el, err := wktEllipsoid(geog)
+	if err != nil {
+		return nil, err
+	}
+
+	node := geog.child("DATUM", "ENSEMBLE")
+	if tw := node.child("TOWGS84"); tw != nil {
+		d := &Datum{Name: node.name(), Ellipsoid: el}
+		for i := range d.ToWGS84 {
+			d.ToWGS84[i], _ = tw.number(i)
+		}
+		return d, nil
+	}
+
+	name := normalizeWKTName(node.name())
+	name = strings.TrimPrefix(name, "d")
+	known, ok := wktKnownDatums[name]
+	if !ok {
+		known, ok = wktKnownDatums["d"+name]
+	}
+	if !ok || known == DatumWGS84 {
+		return nil, nil
+	}
+	d := *known
+	d.Ellipsoid = el
+	return &d, nil
+}
+
+// normalizeWKTName lowercases a name and removes everything but letters and
+// digits, so that "Latitude of natural origin" and "latitude_of_origin" style
+// spellings can be compared.
+func normalizeWKTName(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// wktParams holds the projection parameters of a WKT definition, converted to
+// degrees and meters.
+type wktParams struct {
+	lat0, lon0, k, fe, fn, sp1, sp2 float64
+	hasSP2                          bool
+}
+
+// wktProjection reads the projection method and parameters of a projected
+// system. It reports whether the projection is Web Mercator, which ignores
+// the datum.
+func wktProjection(root, base *wktNode, el Ellipsoid) (Projection, bool, error) {
+	toMeter := wktLinearUnit(root)
+	angleUnit := 1.0
+	if u := base.child("UNIT", "ANGLEUNIT"); u != nil {
+		if f, ok := u.number(0); ok {
+			angleUnit = toDegrees(f)
+		}
+	}
+
+	var method string
+	var params []*wktNode
+	if conv := root.child("CONVERSION"); conv != nil {
+		method = conv.child("METHOD").name()
+		params = conv.children("PARAMETER")
+	} else {
+		method = root.child("PROJECTION").name()
+		params = root.children("PARAMETER")
+	}
+
+	p := wktParams{k: 1}
+	for _, param := range params {
+		v, ok := param.number(0)
+		if !ok {
+			continue
+		}
+		angle := v * angleUnit
+		length := v * toMeter
+		if u := param.child("ANGLEUNIT"); u != nil {
+			f, _ := u.number(0)
+			angle = toDegrees(v * f)
+		}
+		if u := param.child("LENGTHUNIT"); u != nil {
+			f, _ := u.number(0)
+			length = v * f
+		}
+
+		switch normalizeWKTName(param.name()) {
+		case "latitudeoforigin", "latitudeofnaturalorigin", "latitudeoffalseorigin", "latitudeofcenter":
+			p.lat0 = angle
+		case "centralmeridian", "longitudeofnaturalorigin", "longitudeoffalseorigin", "longitudeofcenter", "longitudeoforigin":
+			p.lon0 = angle
+		case "scalefactor", "scalefactoratnaturalorigin":
+			p.k = v
+		case "falseeasting", "eastingatfalseorigin":
+			p.fe = length
+		case "falsenorthing", "northingatfalseorigin":
+			p.fn = length
+		case "standardparallel1", "latitudeof1ststandardparallel":
+			p.sp1 = angle
+		case "standardparallel2", "latitudeof2ndstandardparallel":
+			p.sp2, p.hasSP2 = angle, true
+		}
+	}
+
+	var proj Projection
+	switch m := normalizeWKTName(method); m {
+	case "transversemercator", "gausskruger":
+		proj = TransverseMercator{
+			Ellipsoid:        el,
+			CentralMeridian:  p.lon0,
+			LatitudeOfOrigin: p.lat0,
+			ScaleFactor:      p.k,
+			FalseEasting:     p.fe,
+			FalseNorthing:    p.fn,
+		}
+	case "lambertconformalconic1sp", "lambertconicconformal1sp",
+		"lambertconformalconic2sp", "lambertconicconformal2sp", "lambertconformalconic":
+		lcc := LambertConformalConic{
+			Ellipsoid:         el,
+			CentralMeridian:   p.lon0,
+			LatitudeOfOrigin:  p.lat0,
+			StandardParallel1: p.lat0,
+			StandardParallel2: p.lat0,
+			ScaleFactor:       p.k,
+			FalseEasting:      p.fe,
+			FalseNorthing:     p.fn,
+		}
+		if !strings.HasSuffix(m, "1sp") && (p.hasSP2 || !isZero(p.sp1)) {
+			lcc.StandardParallel1, lcc.StandardParallel2 = p.sp1, p.sp1
+			if p.hasSP2 {
+				lcc.StandardParallel2 = p.sp2
+			}
+		}
+		proj = lcc
+	case "mercator1sp", "mercatorvarianta", "mercator2sp", "mercatorvariantb", "mercator":
+		merc := Mercator{
+			Ellipsoid:       el,
+			CentralMeridian: p.lon0,
+			ScaleFactor:     p.k,
+			FalseEasting:    p.fe,
+			FalseNorthing:   p.fn,
+		}
+		if !isZero(p.sp1) {
+			merc.ScaleFactor = MercatorScale(el, p.sp1)
+		}
+		proj = merc
+	case "popularvisualisationpseudomercator", "mercatorauxiliarysphere":
+		if !isZero(p.lon0) || !isZero(p.fe) || !isZero(p.fn) {
+			return nil, false, fmt.Errorf("unsupported Web Mercator parameters")
+		}
+		proj = WebMercator{}
+		if toMeter != 1 {
+			proj = scaledProjection{Projection: proj, toMeter: toMeter}
+		}
+		return proj, true, nil
+	case "":
+		return nil, false, fmt.Errorf("missing projection method")
+	default:
+		return nil, false, fmt.Errorf("projection method %q is not supported", method)
+	}
+
+	if toMeter != 1 {
+		proj = scaledProjection{Projection: proj, toMeter: toMeter}
+	}
+	return proj, false, nil
+}
+
+// wktLinearUnit returns the size in meters of the linear unit of a projected
+// system, declared directly on the system in WKT 1 and on its axes or
+// coordinate system in WKT 2.
+func wktLinearUnit(root *wktNode) float64 {
+	unit := root.child("UNIT", "LENGTHUNIT")
+	if unit == nil {
+		for _, axis := range root.children("AXIS") {
+			if unit = axis.child("LENGTHUNIT"); unit != nil {
+				break
+			}
+		}
+	}
+	if unit == nil {
+		return 1
+	}
+	f, ok := unit.number(0)
+	if !ok || f <= 0 || math.IsInf(f, 0) {
+		return 1
+	}
+	return f
+}
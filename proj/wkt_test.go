@@ -0,0 +1,192 @@
+package proj_test
+
+import (
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/proj"
+)
+
+const (
+	wktBNG = `PROJCS["OSGB 1936 / British National Grid",
+		GEOGCS["OSGB 1936",
+			DATUM["OSGB_1936",
+				SPHEROID["Airy 1830",6377563.396,299.3249646,AUTHORITY["EPSG","7001"]],
+				TOWGS84[446.448,-125.157,542.06,0.15,0.247,0.842,-20.489],
+				AUTHORITY["EPSG","6277"]],
+			PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],
+			UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],
+			AUTHORITY["EPSG","4277"]],
+		PROJECTION["Transverse_Mercator"],
+		PARAMETER["latitude_of_origin",49],
+		PARAMETER["central_meridian",-2],
+		PARAMETER["scale_factor",0.9996012717],
+		PARAMETER["false_easting",400000],
+		PARAMETER["false_northing",-100000],
+		UNIT["metre",1,AUTHORITY["EPSG","9001"]],
+		AXIS["Easting",EAST],
+		AXIS["Northing",NORTH],
+		AUTHORITY["EPSG","27700"]]`
+
+	esriBNG = `PROJCS["British_National_Grid",GEOGCS["GCS_OSGB_1936",DATUM["D_OSGB_1936",` +
+		`SPHEROID["Airy_1830",6377563.396,299.3249646]],PRIMEM["Greenwich",0.0],` +
+		`UNIT["Degree",0.0174532925199433]],PROJECTION["Transverse_Mercator"],` +
+		`PARAMETER["False_Easting",400000.0],PARAMETER["False_Northing",-100000.0],` +
+		`PARAMETER["Central_Meridian",-2.0],PARAMETER["Scale_Factor",0.9996012717],` +
+		`PARAMETER["Latitude_Of_Origin",49.0],UNIT["Meter",1.0]]`
+
+	wkt2Lambert93 = `PROJCRS["RGF93 v1 / Lambert-93",
+		BASEGEOGCRS["RGF93 v1",
+			DATUM["Reseau Geodesique Francais 1993 v1",
+				ELLIPSOID["GRS 1980",6378137,298.257222101,LENGTHUNIT["metre",1]]],
+			PRIMEM["Greenwich",0,ANGLEUNIT["degree",0.0174532925199433]],
+			ID["EPSG",4171]],
+		CONVERSION["Lambert-93",
+			METHOD["Lambert Conic Conformal (2SP)",ID["EPSG",9802]],
+			PARAMETER["Latitude of false origin",46.5,ANGLEUNIT["degree",0.0174532925199433]],
+			PARAMETER["Longitude of false origin",3,ANGLEUNIT["degree",0.0174532925199433]],
+			PARAMETER["Latitude of 1st standard parallel",49,ANGLEUNIT["degree",0.0174532925199433]],
+			PARAMETER["Latitude of 2nd standard parallel",44,ANGLEUNIT["degree",0.0174532925199433]],
+			PARAMETER["Easting at false origin",700000,LENGTHUNIT["metre",1]],
+			PARAMETER["Northing at false origin",6600000,LENGTHUNIT["metre",1]]],
+		CS[Cartesian,2],
+			AXIS["easting (X)",east,ORDER[1],LENGTHUNIT["metre",1]],
+			AXIS["northing (Y)",north,ORDER[2],LENGTHUNIT["metre",1]],
+		ID["EPSG",2154]]`
+
+	wkt2BoundBNG = `BOUNDCRS[
+		SOURCECRS[
+			PROJCRS["unknown",
+				BASEGEOGCRS["unknown",
+					DATUM["Unknown based on Airy 1830 ellipsoid",
+						ELLIPSOID["Airy 1830",6377563.396,299.3249646,LENGTHUNIT["metre",1]]],
+					PRIMEM["Greenwich",0,ANGLEUNIT["degree",0.0174532925199433]]],
+				CONVERSION["unknown",
+					METHOD["Transverse Mercator",ID["EPSG",9807]],
+					PARAMETER["Latitude of natural origin",49,ANGLEUNIT["degree",0.0174532925199433]],
+					PARAMETER["Longitude of natural origin",-2,ANGLEUNIT["degree",0.0174532925199433]],
+					PARAMETER["Scale factor at natural origin",0.9996012717,SCALEUNIT["unity",1]],
+					PARAMETER["False easting",400000,LENGTHUNIT["metre",1]],
+					PARAMETER["False northing",-100000,LENGTHUNIT["metre",1]]],
+				CS[Cartesian,2],
+					AXIS["(E)",east,ORDER[1],LENGTHUNIT["metre",1]],
+					AXIS["(N)",north,ORDER[2],LENGTHUNIT["metre",1]]]],
+		TARGETCRS[
+			GEOGCRS["WGS 84",
+				DATUM["World Geodetic System 1984",
+					ELLIPSOID["WGS 84",6378137,298.257223563,LENGTHUNIT["metre",1]]],
+				PRIMEM["Greenwich",0,ANGLEUNIT["degree",0.0174532925199433]],
+				CS[ellipsoidal,2],
+					AXIS["latitude",north,ORDER[1],ANGLEUNIT["degree",0.0174532925199433]],
+					AXIS["longitude",east,ORDER[2],ANGLEUNIT["degree",0.0174532925199433]],
+				ID["EPSG",4326]]],
+		ABRIDGEDTRANSFORMATION["Transformation from unknown to WGS84",
+			METHOD["Position Vector transformation (geog2D domain)",ID["EPSG",9606]],
+			PARAMETER["X-axis translation",446.448,ID["EPSG",8605]],
+			PARAMETER["Y-axis translation",-125.157,ID["EPSG",8606]],
+			PARAMETER["Z-axis translation",542.06,ID["EPSG",8607]],
+			PARAMETER["X-axis rotation",0.15,ID["EPSG",8608]],
+			PARAMETER["Y-axis rotation",0.247,ID["EPSG",8609]],
+			PARAMETER["Z-axis rotation",0.842,ID["EPSG",8610]],
+			PARAMETER["Scale difference",-20.489,ID["EPSG",8611]]]]`
+)
+
+func TestParseWKT(t *testing.T) {
+	bigBen := gogis.Point{Lng: -0.124625, Lat: 51.500729}
+	bigBenGrid := gogis.Point{Lng: 530268, Lat: 179644}
+
+	tests := []struct {
+		name string
+		wkt  string
+		srid gogis.SRID
+		geo  gogis.Point
+		grid gogis.Point
+		tol  float64
+	}{
+		{name: "wkt1 with towgs84", wkt: wktBNG, srid: 27700, geo: bigBen, grid: bigBenGrid, tol: 5},
+		{name: "esri prj", wkt: esriBNG, geo: bigBen, grid: bigBenGrid, tol: 5},
+		{
+			name: "wkt2",
+			wkt:  wkt2Lambert93,
+			srid: 2154,
+			geo:  gogis.Point{Lng: 3, Lat: 46.5},
+			grid: gogis.Point{Lng: 700000, Lat: 6600000},
+			tol:  0.001,
+		},
+		{name: "wkt2 bound crs", wkt: wkt2BoundBNG, geo: bigBen, grid: bigBenGrid, tol: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crs, err := proj.ParseWKT(tt.wkt)
+			if err != nil {
+				t.Fatalf("ParseWKT() unexpected error = %v", err)
+			}
+			if crs.SRID != tt.srid {
+				t.Errorf("SRID = %d, want %d", crs.SRID, tt.srid)
+			}
+
+			g, err := proj.TransformCRS(&tt.geo, &proj.CRS{}, crs)
+			if err != nil {
+				t.Fatalf("TransformCRS() unexpected error = %v", err)
+			}
+			assertNear(t, "TransformCRS()", *g.(*gogis.Point), tt.grid, tt.tol)
+		})
+	}
+}
+
+func TestParseWKTMatchesRegistry(t *testing.T) {
+	parsed, err := proj.ParseWKT(wktBNG)
+	if err != nil {
+		t.Fatalf("ParseWKT() unexpected error = %v", err)
+	}
+	p := gogis.Point{Lng: -3.19, Lat: 55.95}
+
+	want, err := proj.TransformPoint(p, gogis.SRIDWGS84, 27700)
+	if err != nil {
+		t.Fatalf("TransformPoint() unexpected error = %v", err)
+	}
+	got, err := proj.TransformCRS(&p, &proj.CRS{}, parsed)
+	if err != nil {
+		t.Fatalf("TransformCRS() unexpected error = %v", err)
+	}
+	assertNear(t, "TransformCRS()", *got.(*gogis.Point), want, 1e-6)
+}
+
+func TestParseWKTErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		wkt  string
+	}{
+		{name: "empty", wkt: ""},
+		{name: "unterminated", wkt: `GEOGCS["WGS 84",DATUM["WGS_1984"`},
+		{name: "unterminated string", wkt: `GEOGCS["WGS 84`},
+		{name: "trailing data", wkt: `GEOGCS["x",DATUM["d",SPHEROID["s",6378137,298.257223563]]] x`},
+		{name: "missing ellipsoid", wkt: `GEOGCS["x",DATUM["d"]]`},
+		{name: "unsupported system", wkt: `VERTCS["NAVD88"]`},
+		{name: "unsupported method", wkt: `PROJCS["x",GEOGCS["x",DATUM["d",SPHEROID["s",6378137,298.257223563]]],PROJECTION["Albers"]]`},
+		{name: "prime meridian", wkt: `GEOGCS["x",DATUM["d",SPHEROID["s",6378137,298.257223563]],PRIMEM["Paris",2.33722917]]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := proj.ParseWKT(tt.wkt); err == nil {
+				t.Errorf("ParseWKT() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestIdentifyWKT(t *testing.T) {
+	name, srid, err := proj.IdentifyWKT(wktBNG)
+	if err != nil || name != "OSGB 1936 / British National Grid" || srid != 27700 {
+		t.Errorf("IdentifyWKT() = %q, %d, %v", name, srid, err)
+	}
+	name, srid, err = proj.IdentifyWKT(`PROJCS["x",GEOGCS["x",DATUM["d",SPHEROID["s",6378137,298.257223563]]],PROJECTION["Albers"],AUTHORITY["EPSG","5070"]]`)
+	if err != nil || name != "x" || srid != 5070 {
+		t.Errorf("IdentifyWKT() of an unsupported method = %q, %d, %v", name, srid, err)
+	}
+	if _, _, err := proj.IdentifyWKT(`GEOGCS["WGS 84"`); err == nil {
+		t.Error("IdentifyWKT() of invalid WKT expected error, got nil")
+	}
+}
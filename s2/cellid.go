@@ -0,0 +1,412 @@
+// Package s2 provides S2-style hierarchical cells on the unit sphere for gogis
+// geometries.
+//
+// The sphere is projected onto the six faces of a cube and every face is
+// recursively subdivided into four children along a Hilbert curve, giving 31
+// levels of cells (0 to 30). Each cell is identified by a 64-bit CellID whose
+// numeric order follows the curve, so nearby cells have nearby ids. This makes
+// cell ids a good sharding or bucketing key for spatial data.
+//
+// The encoding is compatible with the reference S2 library: a Point converted
+// with CellIDFromPoint yields the same id and token as S2's
+// CellIDFromLatLng for the same location.
+//
+// Example:
+//
+//	id := s2.CellIDFromPoint(gogis.Point{Lng: -73.9857, Lat: 40.7484}, 15)
+//	fmt.Println(id.ToToken()) // "89c259a9c"
+//
+//	rc := s2.RegionCoverer{MinLevel: 8, MaxLevel: 15, MaxCells: 16}
+//	cells := rc.Covering(&polygon)
+package s2
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/restayway/gogis"
+)
+
+const (
+	// MaxLevel is the level of the smallest (leaf) cells.
+	MaxLevel = 30
+
+	// NumFaces is the number of cube faces, and therefore of level 0 cells.
+	NumFaces = 6
+
+	posBits    = 2*MaxLevel + 1
+	maxSize    = 1 << MaxLevel
+	swapMask   = 0x01
+	invertMask = 0x02
+)
+
+// Hilbert curve traversal tables, indexed by the current orientation.
+var (
+	ijToPos          = [4][4]int{{0, 1, 3, 2}, {0, 3, 1, 2}, {2, 3, 1, 0}, {2, 1, 3, 0}}
+	posToIJ          = [4][4]int{{0, 1, 3, 2}, {0, 2, 3, 1}, {3, 2, 0, 1}, {3, 1, 0, 2}}
+	posToOrientation = [4]int{swapMask, 0, 0, invertMask | swapMask}
+)
+
+// CellID uniquely identifies a cell in the S2 cell decomposition.
+//
+// The top three bits hold the cube face, followed by two bits per level
+// describing the position of the cell along the Hilbert curve, followed by a
+// single trailing 1 bit that marks the level of the cell.
+type CellID uint64
+
+// CellIDFromPoint returns the cell at the given level containing the point.
+//
+// The point is interpreted as WGS 84 longitude and latitude in decimal degrees.
+// Levels outside [0, MaxLevel] are clamped.
+func CellIDFromPoint(p gogis.Point, level int) CellID {
+	face, u, v := xyzToFaceUV(lngLatToXYZ(p))
+	i := stToIJ(uvToST(u))
+	j := stToIJ(uvToST(v))
+	return cellIDFromFaceIJ(face, i, j).Parent(clampLevel(level))
+}
+
+// CellIDFromFace returns the level 0 cell covering the given cube face.
+func CellIDFromFace(face int) CellID {
+	return CellID(uint64(face)<<posBits + lsbForLevel(0))
+}
+
+// CellIDFromToken parses a token produced by ToToken.
+func CellIDFromToken(token string) (CellID, error) {
+	if token == "" || len(token) > 16 {
+		return 0, fmt.Errorf("invalid cell token %q", token)
+	}
+	if token == "X" {
+		return 0, nil
+	}
+	v, err := strconv.ParseUint(token+strings.Repeat("0", 16-len(token)), 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cell token %q", token)
+	}
+	id := CellID(v)
+	if !id.IsValid() {
+		return 0, fmt.Errorf("invalid cell token %q", token)
+	}
+	return id, nil
+}
+
+// IsValid reports whether id represents a valid cell.
+func (id CellID) IsValid() bool {
+	return id.Face() < NumFaces && id.lsb()&0x1555555555555555 != 0
+}
+
+// Face returns the cube face (0 to 5) the cell lies on.
+func (id CellID) Face() int {
+	return int(uint64(id) >> posBits)
+}
+
+// Level returns the subdivision level of the cell, from 0 (a whole face) to
+// MaxLevel (a leaf cell of roughly one square centimetre).
+func (id CellID) Level() int {
+	return MaxLevel - bits.TrailingZeros64(uint64(id))>>1
+}
+
+// IsLeaf reports whether the cell is at MaxLevel.
+func (id CellID) IsLeaf() bool {
+	return uint64(id)&1 != 0
+}
+
+// IsFace reports whether the cell is a level 0 face cell.
+func (id CellID) IsFace() bool {
+	return uint64(id)&(lsbForLevel(0)-1) == 0
+}
+
+// Parent returns the ancestor of the cell at the given level. Levels deeper
+// than the cell's own level return the cell itself.
+func (id CellID) Parent(level int) CellID {
+	if level >= id.Level() {
+		return id
+	}
+	lsb := lsbForLevel(clampLevel(level))
+	return CellID((uint64(id) & -lsb) | lsb)
+}
+
+// Children returns the four children of the cell in Hilbert curve order.
+// Leaf cells have no children and return nil.
+func (id CellID) Children() []CellID {
+	if id.IsLeaf() {
+		return nil
+	}
+	lsb := id.lsb()
+	step := lsb >> 1
+	first := uint64(id) - lsb + lsb>>2
+	return []CellID{
+		CellID(first),
+		CellID(first + step),
+		CellID(first + 2*step),
+		CellID(first + 3*step),
+	}
+}
+
+// RangeMin returns the smallest leaf cell id contained in the cell.
+func (id CellID) RangeMin() CellID {
+	return CellID(uint64(id) - (id.lsb() - 1))
+}
+
+// RangeMax returns the largest leaf cell id contained in the cell.
+func (id CellID) RangeMax() CellID {
+	return CellID(uint64(id) + (id.lsb() - 1))
+}
+
+// Contains reports whether other is the cell itself or one of its descendants.
+func (id CellID) Contains(other CellID) bool {
+	return other >= id.RangeMin() && other <= id.RangeMax()
+}
+
+// Intersects reports whether the two cells share any leaf cell, which for the
+// S2 hierarchy means one of them contains the other.
+func (id CellID) Intersects(other CellID) bool {
+	return other.RangeMin() <= id.RangeMax() && other.RangeMax() >= id.RangeMin()
+}
+
+// ToToken returns a compact hexadecimal representation of the cell id with
+// trailing zeros removed, as used by the reference S2 implementations.
+func (id CellID) ToToken() string {
+	if id == 0 {
+		return "X"
+	}
+	s := fmt.Sprintf("%016x", uint64(id))
+	return strings.TrimRight(s, "0")
+}
+
+// String returns the cell as "face/positions", where positions holds one
+// Hilbert curve digit (0 to 3) per level, for example "4/0123".
+func (id CellID) String() string {
+	if !id.IsValid() {
+		return fmt.Sprintf("Invalid: %016x", uint64(id))
+	}
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(id.Face()))
+	b.WriteByte('/')
+	for level := 1; level <= id.Level(); level++ {
+		b.WriteByte('0' + byte(id.childPosition(level)))
+	}
+	return b.String()
+}
+
+// Center returns the center of the cell as a Point.
+func (id CellID) Center() gogis.Point {
+	face, u0, u1, v0, v1 := id.faceUVBounds()
+	return xyzToLngLat(faceUVToXYZ(face, 0.5*(u0+u1), 0.5*(v0+v1)))
+}
+
+// Vertices returns the four corners of the cell in counter-clockwise order.
+func (id CellID) Vertices() [4]gogis.Point {
+	face, u0, u1, v0, v1 := id.faceUVBounds()
+	return [4]gogis.Point{
+		xyzToLngLat(faceUVToXYZ(face, u0, v0)),
+		xyzToLngLat(faceUVToXYZ(face, u1, v0)),
+		xyzToLngLat(faceUVToXYZ(face, u1, v1)),
+		xyzToLngLat(faceUVToXYZ(face, u0, v1)),
+	}
+}
+
+// Boundary returns the outline of the cell as a closed Polygon.
+//
+// Cell edges are geodesics on the sphere, while the Polygon connects the four
+// corners with straight lines in longitude/latitude space. For small cells the
+// difference is negligible; callers that need accuracy for low level cells
+// should densify the boundary before using it for planar computations.
+func (id CellID) Boundary() gogis.Polygon {
+	v := id.Vertices()
+	return gogis.Polygon{
+		Rings: [][]gogis.Point{{v[0], v[1], v[2], v[3], v[0]}},
+	}
+}
+
+// lsb returns the lowest set bit of the id, which encodes its level.
+func (id CellID) lsb() uint64 {
+	return uint64(id) & -uint64(id)
+}
+
+func lsbForLevel(level int) uint64 {
+	return 1 << uint(2*(MaxLevel-level))
+}
+
+// childPosition returns the Hilbert curve position (0 to 3) of the ancestor of
+// the cell at the given level within its parent.
+func (id CellID) childPosition(level int) int {
+	return int(uint64(id)>>uint(posBits-2*level)) & 3
+}
+
+// faceIJ decodes the face and the (i, j) coordinates of the lower-left leaf
+// cell of id.
+func (id CellID) faceIJ() (face, i, j int) {
+	face = id.Face()
+	orientation := face & swapMask
+	level := id.Level()
+	for k := 1; k <= level; k++ {
+		pos := id.childPosition(k)
+		ij := posToIJ[orientation][pos]
+		i = i<<1 | ij>>1
+		j = j<<1 | ij&1
+		orientation ^= posToOrientation[pos]
+	}
+	shift := uint(MaxLevel - level)
+	return face, i << shift, j << shift
+}
+
+// faceUVBounds returns the face of the cell and its extent in (u, v) space.
+func (id CellID) faceUVBounds() (face int, u0, u1, v0, v1 float64) {
+	face, i, j := id.faceIJ()
+	size := 1 << uint(MaxLevel-id.Level())
+	u0 = stToUV(float64(i) / maxSize)
+	u1 = stToUV(float64(i+size) / maxSize)
+	v0 = stToUV(float64(j) / maxSize)
+	v1 = stToUV(float64(j+size) / maxSize)
+	return face, u0, u1, v0, v1
+}
+
+// cellIDFromFaceIJ returns the leaf cell with the given face and (i, j)
+// coordinates.
+func cellIDFromFaceIJ(face, i, j int) CellID {
+	var n uint64
+	orientation := face & swapMask
+	for k := MaxLevel - 1; k >= 0; k-- {
+		ij := ((i>>uint(k))&1)<<1 | (j>>uint(k))&1
+		pos := ijToPos[orientation][ij]
+		n = n<<2 | uint64(pos)
+		orientation ^= posToOrientation[pos]
+	}
+	return CellID(uint64(face)<<posBits | n<<1 | 1)
+}
+
+func clampLevel(level int) int {
+	if level < 0 {
+		return 0
+	}
+	if level > MaxLevel {
+		return MaxLevel
+	}
+	return level
+}
+
+// vector is a point in three-dimensional space.
+type vector struct {
+	X, Y, Z float64
+}
+
+func (v vector) add(o vector) vector  { return vector{v.X + o.X, v.Y + o.Y, v.Z + o.Z} }
+func (v vector) sub(o vector) vector  { return vector{v.X - o.X, v.Y - o.Y, v.Z - o.Z} }
+func (v vector) mul(f float64) vector { return vector{v.X * f, v.Y * f, v.Z * f} }
+func (v vector) component(axis int) float64 {
+	switch axis {
+	case 0:
+		return v.X
+	case 1:
+		return v.Y
+	default:
+		return v.Z
+	}
+}
+
+func lngLatToXYZ(p gogis.Point) vector {
+	lng := p.Lng * math.Pi / 180
+	lat := p.Lat * math.Pi / 180
+	cosLat := math.Cos(lat)
+	return vector{cosLat * math.Cos(lng), cosLat * math.Sin(lng), math.Sin(lat)}
+}
+
+func xyzToLngLat(v vector) gogis.Point {
+	return gogis.Point{
+		Lng: math.Atan2(v.Y, v.X) * 180 / math.Pi,
+		Lat: math.Atan2(v.Z, math.Hypot(v.X, v.Y)) * 180 / math.Pi,
+	}
+}
+
+// faceAxis returns the signed component of v along the normal of the face.
+// Points with a positive value lie in the hemisphere centred on the face.
+func faceAxis(face int, v vector) float64 {
+	if face < 3 {
+		return v.component(face)
+	}
+	return -v.component(face - 3)
+}
+
+// xyzToFaceUV returns the face v projects onto and its (u, v) coordinates.
+func xyzToFaceUV(v vector) (face int, u, w float64) {
+	ax, ay, az := math.Abs(v.X), math.Abs(v.Y), math.Abs(v.Z)
+	switch {
+	case ax >= ay && ax >= az:
+		face = 0
+	case ay >= az:
+		face = 1
+	default:
+		face = 2
+	}
+	if v.component(face) < 0 {
+		face += 3
+	}
+	u, w = faceXYZToUV(face, v)
+	return face, u, w
+}
+
+// faceXYZToUV projects v onto the plane of the given face. The result is only
+// meaningful when faceAxis(face, v) is positive.
+func faceXYZToUV(face int, v vector) (u, w float64) {
+	switch face {
+	case 0:
+		return v.Y / v.X, v.Z / v.X
+	case 1:
+		return -v.X / v.Y, v.Z / v.Y
+	case 2:
+		return -v.X / v.Z, -v.Y / v.Z
+	case 3:
+		return v.Z / v.X, v.Y / v.X
+	case 4:
+		return v.Z / v.Y, -v.X / v.Y
+	default:
+		return -v.Y / v.Z, -v.X / v.Z
+	}
+}
+
+func faceUVToXYZ(face int, u, v float64) vector {
+	switch face {
+	case 0:
+		return vector{1, u, v}
+	case 1:
+		return vector{-u, 1, v}
+	case 2:
+		return vector{-u, -v, 1}
+	case 3:
+		return vector{-1, -v, -u}
+	case 4:
+		return vector{v, -1, -u}
+	default:
+		return vector{v, u, -1}
+	}
+}
+
+// stToUV applies the quadratic transform S2 uses to make cells of the same
+// level roughly equal in area.
+func stToUV(s float64) float64 {
+	if s >= 0.5 {
+		return (1 / 3.0) * (4*s*s - 1)
+	}
+	return (1 / 3.0) * (1 - 4*(1-s)*(1-s))
+}
+
+func uvToST(u float64) float64 {
+	if u >= 0 {
+		return 0.5 * math.Sqrt(1+3*u)
+	}
+	return 1 - 0.5*math.Sqrt(1-3*u)
+}
+
+func stToIJ(s float64) int {
+	i := int(math.Floor(maxSize * s))
+	if i < 0 {
+		return 0
+	}
+	if i > maxSize-1 {
+		return maxSize - 1
+	}
+	return i
+}
@@ -0,0 +1,154 @@
+package s2_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/s2"
+)
+
+func TestCellIDFromPoint(t *testing.T) {
+	tests := []struct {
+		name  string
+		point gogis.Point
+		level int
+		token string
+	}{
+		{
+			name:  "origin leaf",
+			point: gogis.Point{Lng: 0, Lat: 0},
+			level: 30,
+			token: "1000000000000001",
+		},
+		{
+			name:  "reference S2 leaf",
+			point: gogis.Point{Lng: 11.770681595, Lat: 49.703498679},
+			level: 30,
+			token: "47a1cbd595522b39",
+		},
+		{
+			name:  "empire state building level 15",
+			point: gogis.Point{Lng: -73.9857, Lat: 40.7484},
+			level: 15,
+			token: "89c259a9c",
+		},
+		{
+			name:  "north pole face",
+			point: gogis.Point{Lng: 0, Lat: 90},
+			level: 0,
+			token: "5",
+		},
+		{
+			name:  "level is clamped",
+			point: gogis.Point{Lng: 0, Lat: 0},
+			level: -3,
+			token: "1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := s2.CellIDFromPoint(tt.point, tt.level)
+			if got := id.ToToken(); got != tt.token {
+				t.Errorf("CellIDFromPoint().ToToken() = %v, want %v", got, tt.token)
+			}
+			if !id.IsValid() {
+				t.Errorf("CellIDFromPoint() = %v, want a valid cell", id)
+			}
+		})
+	}
+}
+
+func TestCellIDHierarchy(t *testing.T) {
+	leaf := s2.CellIDFromPoint(gogis.Point{Lng: 2.2945, Lat: 48.8584}, 30)
+
+	for level := 0; level <= s2.MaxLevel; level++ {
+		parent := leaf.Parent(level)
+		if parent.Level() != level {
+			t.Fatalf("Parent(%d).Level() = %d", level, parent.Level())
+		}
+		if !parent.Contains(leaf) {
+			t.Fatalf("Parent(%d) does not contain leaf", level)
+		}
+		if level < s2.MaxLevel {
+			children := parent.Children()
+			if len(children) != 4 {
+				t.Fatalf("Children() returned %d cells, want 4", len(children))
+			}
+			found := 0
+			for _, child := range children {
+				if child.Parent(level) != parent {
+					t.Errorf("child %v has parent %v, want %v", child, child.Parent(level), parent)
+				}
+				if child.Contains(leaf) {
+					found++
+				}
+			}
+			if found != 1 {
+				t.Errorf("leaf is contained in %d children of level %d cell, want 1", found, level)
+			}
+		}
+	}
+
+	if leaf.Children() != nil {
+		t.Errorf("leaf.Children() = %v, want nil", leaf.Children())
+	}
+	if !s2.CellIDFromFace(3).IsFace() {
+		t.Errorf("CellIDFromFace(3).IsFace() = false, want true")
+	}
+}
+
+func TestCellIDToken(t *testing.T) {
+	id := s2.CellIDFromPoint(gogis.Point{Lng: 139.6917, Lat: 35.6895}, 12)
+	parsed, err := s2.CellIDFromToken(id.ToToken())
+	if err != nil {
+		t.Fatalf("CellIDFromToken() unexpected error = %v", err)
+	}
+	if parsed != id {
+		t.Errorf("CellIDFromToken() = %v, want %v", parsed, id)
+	}
+
+	for _, token := range []string{"", "zz", "d", "00000000000000000"} {
+		if _, err := s2.CellIDFromToken(token); err == nil {
+			t.Errorf("CellIDFromToken(%q) expected error, got nil", token)
+		}
+	}
+}
+
+func TestCellIDString(t *testing.T) {
+	id := s2.CellIDFromFace(4).Children()[0].Children()[1].Children()[2]
+	if got := id.String(); got != "4/012" {
+		t.Errorf("CellID.String() = %v, want 4/012", got)
+	}
+}
+
+func TestCellIDBoundary(t *testing.T) {
+	p := gogis.Point{Lng: -0.1276, Lat: 51.5072}
+	for _, level := range []int{4, 10, 20} {
+		id := s2.CellIDFromPoint(p, level)
+		boundary := id.Boundary()
+		if len(boundary.Rings) != 1 || len(boundary.Rings[0]) != 5 {
+			t.Fatalf("Boundary() = %v, want one closed ring of 5 points", boundary)
+		}
+		ring := boundary.Rings[0]
+		if ring[0] != ring[4] {
+			t.Errorf("Boundary() ring is not closed")
+		}
+
+		minLng, maxLng := math.Inf(1), math.Inf(-1)
+		minLat, maxLat := math.Inf(1), math.Inf(-1)
+		for _, v := range ring {
+			minLng, maxLng = math.Min(minLng, v.Lng), math.Max(maxLng, v.Lng)
+			minLat, maxLat = math.Min(minLat, v.Lat), math.Max(maxLat, v.Lat)
+		}
+		if p.Lng < minLng || p.Lng > maxLng || p.Lat < minLat || p.Lat > maxLat {
+			t.Errorf("level %d boundary %v does not surround %v", level, boundary.String(), p)
+		}
+
+		center := id.Center()
+		if s2.CellIDFromPoint(center, level) != id {
+			t.Errorf("level %d center %v maps to a different cell", level, center)
+		}
+	}
+}
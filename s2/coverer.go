@@ -0,0 +1,158 @@
+package s2
+
+import (
+	"sort"
+
+	"github.com/restayway/gogis"
+)
+
+// RegionCoverer computes a set of cells that together cover a geometry.
+//
+// The covering is built top-down: starting from the six face cells, cells that
+// intersect the geometry are subdivided until MaxLevel is reached, a cell is
+// found to lie entirely inside a polygon, or subdividing further would exceed
+// MaxCells. MaxCells is a target rather than a hard limit; it can be exceeded
+// when MinLevel forces a finer covering or when a geometry spans several faces.
+//
+// Edges of LineStrings and Polygon rings are interpreted as geodesics
+// (great-circle arcs) on the sphere, consistently with S2.
+//
+// Example:
+//
+//	rc := s2.RegionCoverer{MinLevel: 10, MaxLevel: 16, MaxCells: 20}
+//	for _, id := range rc.Covering(&area) {
+//	    db.Create(&Shard{Token: id.ToToken()})
+//	}
+type RegionCoverer struct {
+	MinLevel int // Coarsest level used in the covering
+	MaxLevel int // Finest level used in the covering, 0 means s2.MaxLevel
+	MaxCells int // Desired maximum number of cells, 0 means 8
+}
+
+// Covering returns a sorted, normalized set of cells covering g.
+//
+// Point, LineString, Polygon and GeometryCollection geometries and their
+// geography variants are supported. Other Geometry implementations are ignored, and a geometry without any
+// supported vertices yields an empty covering.
+func (rc RegionCoverer) Covering(g gogis.Geometry) []CellID {
+	minLevel, maxLevel, maxCells := rc.params()
+
+	r := newRegion(g)
+	if r.empty() {
+		return nil
+	}
+
+	c := covering{region: r, minLevel: minLevel, maxLevel: maxLevel}
+	for face := 0; face < NumFaces; face++ {
+		id := CellIDFromFace(face)
+		c.add(id, r.relate(id))
+	}
+
+	// Candidates are appended one level at a time, so processing the queue in
+	// order always expands the largest remaining cells first.
+	for len(c.queue) > 0 {
+		id := c.queue[0]
+		c.queue = c.queue[1:]
+
+		children := c.intersectingChildren(id)
+		if id.Level() < minLevel || len(children) == 1 ||
+			len(c.result)+len(c.queue)+len(children) <= maxCells {
+			for _, child := range children {
+				c.add(child.id, child.rel)
+			}
+		} else {
+			c.result = append(c.result, id)
+		}
+	}
+
+	return normalize(c.result, minLevel)
+}
+
+func (rc RegionCoverer) params() (minLevel, maxLevel, maxCells int) {
+	minLevel = clampLevel(rc.MinLevel)
+	maxLevel = MaxLevel
+	if rc.MaxLevel > 0 {
+		maxLevel = clampLevel(rc.MaxLevel)
+	}
+	if maxLevel < minLevel {
+		maxLevel = minLevel
+	}
+	maxCells = rc.MaxCells
+	if maxCells <= 0 {
+		maxCells = 8
+	}
+	return minLevel, maxLevel, maxCells
+}
+
+// covering holds the state of a single Covering call.
+type covering struct {
+	region   *region
+	minLevel int
+	maxLevel int
+	queue    []CellID
+	result   []CellID
+}
+
+type relatedCell struct {
+	id  CellID
+	rel relation
+}
+
+// add records a cell either as part of the result or as a candidate for
+// further subdivision.
+func (c *covering) add(id CellID, rel relation) {
+	if rel == disjoint {
+		return
+	}
+	level := id.Level()
+	if level >= c.minLevel && (rel == contained || level >= c.maxLevel) {
+		c.result = append(c.result, id)
+		return
+	}
+	c.queue = append(c.queue, id)
+}
+
+func (c *covering) intersectingChildren(id CellID) []relatedCell {
+	var children []relatedCell
+	for _, child := range id.Children() {
+		if rel := c.region.relate(child); rel != disjoint {
+			children = append(children, relatedCell{id: child, rel: rel})
+		}
+	}
+	return children
+}
+
+// normalize sorts the cells, drops cells contained in others and replaces
+// groups of four siblings with their parent as long as the parent is not
+// coarser than minLevel.
+func normalize(ids []CellID, minLevel int) []CellID {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	out := make([]CellID, 0, len(ids))
+	for _, id := range ids {
+		if n := len(out); n > 0 && out[n-1].Contains(id) {
+			continue
+		}
+		for len(out) > 0 && id.Contains(out[len(out)-1]) {
+			out = out[:len(out)-1]
+		}
+		for len(out) >= 3 && id.Level() > minLevel && completesSiblings(out[len(out)-3:], id) {
+			out = out[:len(out)-3]
+			id = id.Parent(id.Level() - 1)
+		}
+		out = append(out, id)
+	}
+	return out
+}
+
+// completesSiblings reports whether the three sorted cells in prev together
+// with id are the four children of a single parent.
+func completesSiblings(prev []CellID, id CellID) bool {
+	level := id.Level()
+	for _, p := range prev {
+		if p.Level() != level {
+			return false
+		}
+	}
+	return prev[0].Parent(level-1) == id.Parent(level-1)
+}
@@ -0,0 +1,168 @@
+package s2_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/s2"
+)
+
+func coveringContains(cells []s2.CellID, p gogis.Point) bool {
+	leaf := s2.CellIDFromPoint(p, s2.MaxLevel)
+	for _, c := range cells {
+		if c.Contains(leaf) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRegionCovererPoint(t *testing.T) {
+	p := gogis.Point{Lng: 12.4924, Lat: 41.8902}
+	rc := s2.RegionCoverer{MaxLevel: 14}
+
+	cells := rc.Covering(&p)
+	if len(cells) != 1 {
+		t.Fatalf("Covering() returned %d cells, want 1", len(cells))
+	}
+	if want := s2.CellIDFromPoint(p, 14); cells[0] != want {
+		t.Errorf("Covering() = %v, want %v", cells[0], want)
+	}
+}
+
+func TestRegionCovererPolygon(t *testing.T) {
+	polygon := gogis.Polygon{
+		Rings: [][]gogis.Point{
+			{
+				{Lng: -73.9812, Lat: 40.7644},
+				{Lng: -73.9734, Lat: 40.7644},
+				{Lng: -73.9734, Lat: 40.7947},
+				{Lng: -73.9812, Lat: 40.7947},
+				{Lng: -73.9812, Lat: 40.7644},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		coverer s2.RegionCoverer
+	}{
+		{name: "defaults", coverer: s2.RegionCoverer{}},
+		{name: "bounded levels", coverer: s2.RegionCoverer{MinLevel: 10, MaxLevel: 16, MaxCells: 20}},
+		{name: "single cell", coverer: s2.RegionCoverer{MaxLevel: 18, MaxCells: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cells := tt.coverer.Covering(&polygon)
+			if len(cells) == 0 {
+				t.Fatal("Covering() returned no cells")
+			}
+
+			maxCells := tt.coverer.MaxCells
+			if maxCells == 0 {
+				maxCells = 8
+			}
+			if len(cells) > maxCells {
+				t.Errorf("Covering() returned %d cells, want at most %d", len(cells), maxCells)
+			}
+
+			for i, c := range cells {
+				if c.Level() < tt.coverer.MinLevel {
+					t.Errorf("cell %v level %d below MinLevel %d", c, c.Level(), tt.coverer.MinLevel)
+				}
+				if tt.coverer.MaxLevel > 0 && c.Level() > tt.coverer.MaxLevel {
+					t.Errorf("cell %v level %d above MaxLevel %d", c, c.Level(), tt.coverer.MaxLevel)
+				}
+				if i > 0 && cells[i-1] >= c {
+					t.Errorf("Covering() is not sorted at index %d", i)
+				}
+			}
+
+			for _, p := range []gogis.Point{
+				{Lng: -73.9812, Lat: 40.7644},
+				{Lng: -73.9734, Lat: 40.7947},
+				{Lng: -73.9770, Lat: 40.7800},
+			} {
+				if !coveringContains(cells, p) {
+					t.Errorf("Covering() does not contain %v", p)
+				}
+			}
+		})
+	}
+}
+
+func TestRegionCovererPolygonInterior(t *testing.T) {
+	// A large square with a hole: the hole's center should not be covered at a
+	// fine level, while the solid part should be covered by interior cells.
+	polygon := &gogis.Polygon{
+		Rings: [][]gogis.Point{
+			{{Lng: 0, Lat: 0}, {Lng: 10, Lat: 0}, {Lng: 10, Lat: 10}, {Lng: 0, Lat: 10}, {Lng: 0, Lat: 0}},
+			{{Lng: 4, Lat: 4}, {Lng: 6, Lat: 4}, {Lng: 6, Lat: 6}, {Lng: 4, Lat: 6}, {Lng: 4, Lat: 4}},
+		},
+	}
+	cells := s2.RegionCoverer{MaxLevel: 12, MaxCells: 500}.Covering(polygon)
+
+	if coveringContains(cells, gogis.Point{Lng: 5, Lat: 5}) {
+		t.Errorf("Covering() contains the center of the hole")
+	}
+	if !coveringContains(cells, gogis.Point{Lng: 2, Lat: 2}) {
+		t.Errorf("Covering() does not contain the polygon interior")
+	}
+	if coveringContains(cells, gogis.Point{Lng: 20, Lat: 20}) {
+		t.Errorf("Covering() contains a point far outside the polygon")
+	}
+}
+
+func TestRegionCovererLineString(t *testing.T) {
+	// Crosses the boundary between face 0 and face 1.
+	line := gogis.LineString{
+		Points: []gogis.Point{
+			{Lng: 40, Lat: 10},
+			{Lng: 50, Lat: 10},
+		},
+	}
+	cells := s2.RegionCoverer{MaxLevel: 10, MaxCells: 32}.Covering(&line)
+
+	faces := map[int]bool{}
+	for _, c := range cells {
+		faces[c.Face()] = true
+	}
+	if !faces[0] || !faces[1] {
+		t.Errorf("Covering() faces = %v, want faces 0 and 1", faces)
+	}
+	for _, p := range line.Points {
+		if !coveringContains(cells, p) {
+			t.Errorf("Covering() does not contain vertex %v", p)
+		}
+	}
+}
+
+func TestRegionCovererGeometryCollection(t *testing.T) {
+	gc := &gogis.GeometryCollection{
+		Geometries: []gogis.Geometry{
+			&gogis.Point{Lng: -122.4194, Lat: 37.7749},
+			&gogis.Point{Lng: 151.2093, Lat: -33.8688},
+		},
+	}
+	cells := s2.RegionCoverer{MaxLevel: 20}.Covering(gc)
+	if len(cells) != 2 {
+		t.Fatalf("Covering() returned %d cells, want 2", len(cells))
+	}
+	for _, g := range gc.Geometries {
+		if !coveringContains(cells, *g.(*gogis.Point)) {
+			t.Errorf("Covering() does not contain %v", g)
+		}
+	}
+
+	// Geography values are covered like their geometry counterparts.
+	geo := s2.RegionCoverer{MaxLevel: 20}.Covering((*gogis.GeographyCollection)(gc))
+	if !reflect.DeepEqual(geo, cells) {
+		t.Errorf("Covering() of GeographyCollection = %v, want %v", geo, cells)
+	}
+
+	if cells := (s2.RegionCoverer{}).Covering(&gogis.GeometryCollection{}); cells != nil {
+		t.Errorf("Covering() of empty collection = %v, want nil", cells)
+	}
+}
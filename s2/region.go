@@ -0,0 +1,287 @@
+package s2
+
+import (
+	"math"
+
+	"github.com/restayway/gogis"
+)
+
+// relation describes how a cell relates to a region.
+type relation int
+
+const (
+	disjoint   relation = iota // The cell and the region do not touch
+	intersects                 // The cell overlaps the region
+	contained                  // The cell lies entirely inside the region
+)
+
+// clipEpsilon bounds how close to the edge of a face hemisphere geometry is
+// kept when projecting it onto that face. Anything closer than this lies far
+// outside the face itself, so dropping it does not change any relation.
+const clipEpsilon = 1e-9
+
+// uvPadding grows cell rectangles slightly so that geometry lying exactly on
+// a shared cell edge is reported as intersecting both cells.
+const uvPadding = 1e-12
+
+// region is a geometry converted to unit vectors, with lazily computed
+// gnomonic projections onto each cube face.
+//
+// The gnomonic projection maps great circles to straight lines, so once the
+// geometry is projected onto a face, relating it to a cell reduces to planar
+// tests against the cell's (u, v) rectangle.
+type region struct {
+	points   []vector
+	lines    [][]vector
+	polygons [][][]vector
+	faces    [NumFaces]*faceShape
+}
+
+type uv struct {
+	U, V float64
+}
+
+// faceShape is a region projected onto one cube face.
+type faceShape struct {
+	points   []uv
+	segments [][2]uv
+	polygons [][][]uv
+}
+
+func newRegion(g gogis.Geometry) *region {
+	r := &region{}
+	r.addGeometry(g)
+	return r
+}
+
+func (r *region) addGeometry(g gogis.Geometry) {
+	switch v := g.(type) {
+	case *gogis.Point:
+		r.points = append(r.points, lngLatToXYZ(*v))
+	case *gogis.LineString:
+		r.addLineString(v.Points)
+	case *gogis.Polygon:
+		r.addPolygon(v.Rings)
+	case *gogis.GeometryCollection:
+		for _, child := range v.Geometries {
+			r.addGeometry(child)
+		}
+	case *gogis.GeographyPoint:
+		r.addGeometry((*gogis.Point)(v))
+	case *gogis.GeographyLineString:
+		r.addGeometry((*gogis.LineString)(v))
+	case *gogis.GeographyPolygon:
+		r.addGeometry((*gogis.Polygon)(v))
+	case *gogis.GeographyCollection:
+		r.addGeometry((*gogis.GeometryCollection)(v))
+	}
+}
+
+func (r *region) addLineString(points []gogis.Point) {
+	switch len(points) {
+	case 0:
+	case 1:
+		r.points = append(r.points, lngLatToXYZ(points[0]))
+	default:
+		r.lines = append(r.lines, toVectors(points))
+	}
+}
+
+func (r *region) addPolygon(rings [][]gogis.Point) {
+	var polygon [][]vector
+	for _, ring := range rings {
+		// Rings are stored closed; the closing vertex is implied below.
+		if n := len(ring); n > 1 && ring[0] == ring[n-1] {
+			ring = ring[:n-1]
+		}
+		if len(ring) == 0 {
+			continue
+		}
+		polygon = append(polygon, toVectors(ring))
+	}
+	if len(polygon) > 0 {
+		r.polygons = append(r.polygons, polygon)
+	}
+}
+
+func (r *region) empty() bool {
+	return len(r.points) == 0 && len(r.lines) == 0 && len(r.polygons) == 0
+}
+
+// relate returns how the cell relates to the region.
+func (r *region) relate(id CellID) relation {
+	face, u0, u1, v0, v1 := id.faceUVBounds()
+	rect := uvRect{u0 - uvPadding, u1 + uvPadding, v0 - uvPadding, v1 + uvPadding}
+	fs := r.face(face)
+
+	for _, s := range fs.segments {
+		if rect.intersectsSegment(s[0], s[1]) {
+			return intersects
+		}
+	}
+
+	center := uv{0.5 * (u0 + u1), 0.5 * (v0 + v1)}
+	for _, polygon := range fs.polygons {
+		if containsPoint(polygon, center) {
+			return contained
+		}
+	}
+
+	for _, p := range fs.points {
+		if rect.contains(p) {
+			return intersects
+		}
+	}
+	return disjoint
+}
+
+// face returns the projection of the region onto the given face, computing
+// it on first use.
+func (r *region) face(face int) *faceShape {
+	if fs := r.faces[face]; fs != nil {
+		return fs
+	}
+
+	fs := &faceShape{}
+	for _, p := range r.points {
+		if faceAxis(face, p) > 0 {
+			fs.points = append(fs.points, project(face, p))
+		}
+	}
+	for _, line := range r.lines {
+		for i := 1; i < len(line); i++ {
+			if a, b, ok := clipSegment(face, line[i-1], line[i]); ok {
+				fs.segments = append(fs.segments, [2]uv{project(face, a), project(face, b)})
+			}
+		}
+	}
+	for _, polygon := range r.polygons {
+		var rings [][]uv
+		for _, ring := range polygon {
+			clipped := clipRing(face, ring)
+			if len(clipped) < 3 {
+				continue
+			}
+			projected := make([]uv, len(clipped))
+			for i, v := range clipped {
+				projected[i] = project(face, v)
+			}
+			for i := range projected {
+				fs.segments = append(fs.segments, [2]uv{projected[i], projected[(i+1)%len(projected)]})
+			}
+			rings = append(rings, projected)
+		}
+		if len(rings) > 0 {
+			fs.polygons = append(fs.polygons, rings)
+		}
+	}
+
+	r.faces[face] = fs
+	return fs
+}
+
+func toVectors(points []gogis.Point) []vector {
+	vs := make([]vector, len(points))
+	for i, p := range points {
+		vs[i] = lngLatToXYZ(p)
+	}
+	return vs
+}
+
+func project(face int, v vector) uv {
+	u, w := faceXYZToUV(face, v)
+	return uv{u, w}
+}
+
+// clipSegment clips the chord from a to b to the half-space in which
+// faceAxis is at least clipEpsilon. The chord and the geodesic between a and b
+// project onto the same line, so clipping the chord is sufficient.
+func clipSegment(face int, a, b vector) (vector, vector, bool) {
+	da, db := faceAxis(face, a), faceAxis(face, b)
+	if da < clipEpsilon && db < clipEpsilon {
+		return a, b, false
+	}
+	if da < clipEpsilon {
+		a = intersectPlane(a, b, da, db)
+	} else if db < clipEpsilon {
+		b = intersectPlane(a, b, da, db)
+	}
+	return a, b, true
+}
+
+// clipRing clips a closed ring to the same half-space as clipSegment using
+// the Sutherland-Hodgman algorithm.
+func clipRing(face int, ring []vector) []vector {
+	var out []vector
+	for i := range ring {
+		a, b := ring[i], ring[(i+1)%len(ring)]
+		da, db := faceAxis(face, a), faceAxis(face, b)
+		inA, inB := da >= clipEpsilon, db >= clipEpsilon
+		if inA {
+			out = append(out, a)
+		}
+		if inA != inB {
+			out = append(out, intersectPlane(a, b, da, db))
+		}
+	}
+	return out
+}
+
+// intersectPlane returns the point on the chord from a to b where faceAxis
+// equals clipEpsilon, given the faceAxis values da and db of its endpoints.
+func intersectPlane(a, b vector, da, db float64) vector {
+	t := (clipEpsilon - da) / (db - da)
+	return a.add(b.sub(a).mul(t))
+}
+
+// uvRect is an axis-aligned rectangle in (u, v) space.
+type uvRect struct {
+	U0, U1, V0, V1 float64
+}
+
+func (r uvRect) contains(p uv) bool {
+	return p.U >= r.U0 && p.U <= r.U1 && p.V >= r.V0 && p.V <= r.V1
+}
+
+// intersectsSegment reports whether the segment from a to b touches the
+// rectangle, using Liang-Barsky clipping.
+func (r uvRect) intersectsSegment(a, b uv) bool {
+	t0, t1 := 0.0, 1.0
+	du, dv := b.U-a.U, b.V-a.V
+	clip := func(p, q float64) bool {
+		if p == 0 {
+			return q >= 0
+		}
+		t := q / p
+		if p < 0 {
+			if t > t1 {
+				return false
+			}
+			t0 = math.Max(t0, t)
+		} else {
+			if t < t0 {
+				return false
+			}
+			t1 = math.Min(t1, t)
+		}
+		return true
+	}
+	return clip(-du, a.U-r.U0) && clip(du, r.U1-a.U) &&
+		clip(-dv, a.V-r.V0) && clip(dv, r.V1-a.V) && t0 <= t1
+}
+
+// containsPoint reports whether p lies inside the polygon formed by rings
+// using the even-odd rule, so holes are excluded.
+func containsPoint(rings [][]uv, p uv) bool {
+	inside := false
+	for _, ring := range rings {
+		for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+			a, b := ring[i], ring[j]
+			if (a.V > p.V) != (b.V > p.V) &&
+				p.U < (b.U-a.U)*(p.V-a.V)/(b.V-a.V)+a.U {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
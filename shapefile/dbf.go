@@ -0,0 +1,285 @@
+package shapefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// FieldType is the dBase type of an attribute field.
+type FieldType byte
+
+const (
+	FieldCharacter FieldType = 'C' // string
+	FieldNumeric   FieldType = 'N' // int64 without decimals, float64 otherwise
+	FieldFloat     FieldType = 'F' // float64
+	FieldLogical   FieldType = 'L' // bool
+	FieldDate      FieldType = 'D' // time.Time, in UTC
+)
+
+// Field describes an attribute column of the .dbf file.
+type Field struct {
+	Name     string    // Column name, at most 10 bytes
+	Type     FieldType // Column type
+	Length   int       // Width in bytes, at most 254 (255 for character fields)
+	Decimals int       // Digits after the decimal point of numeric fields
+}
+
+// dbfHeader holds the layout of a dBase table.
+type dbfHeader struct {
+	records    int
+	headerSize int
+	recordSize int
+	fields     []Field
+}
+
+const (
+	dbfVersion    = 0x03
+	dbfTerminator = 0x0D
+	dbfEOF        = 0x1A
+)
+
+// readDBFHeader reads the header and field descriptors of a dBase table.
+func readDBFHeader(r io.Reader) (*dbfHeader, error) {
+	var head [32]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, fmt.Errorf("shapefile: reading dbf header: %w", err)
+	}
+	h := &dbfHeader{
+		records:    int(binary.LittleEndian.Uint32(head[4:])),
+		headerSize: int(binary.LittleEndian.Uint16(head[8:])),
+		recordSize: int(binary.LittleEndian.Uint16(head[10:])),
+	}
+	if h.headerSize < 33 {
+		return nil, fmt.Errorf("shapefile: invalid dbf header size %d", h.headerSize)
+	}
+	rest := make([]byte, h.headerSize-32)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, fmt.Errorf("shapefile: reading dbf fields: %w", err)
+	}
+
+	size := 1
+	for i := 0; i+32 <= len(rest) && rest[i] != dbfTerminator; i += 32 {
+		d := rest[i : i+32]
+		name := d[:11]
+		if n := bytes.IndexByte(name, 0); n >= 0 {
+			name = name[:n]
+		}
+		f := Field{Name: string(name), Type: FieldType(d[11]), Length: int(d[16]), Decimals: int(d[17])}
+		h.fields = append(h.fields, f)
+		size += f.Length
+	}
+	if size > h.recordSize {
+		return nil, fmt.Errorf("shapefile: dbf fields of %d bytes exceed the record size %d", size, h.recordSize)
+	}
+	return h, nil
+}
+
+// readDBFRecord reads the next record of the table.
+func readDBFRecord(r io.Reader, h *dbfHeader) (map[string]any, error) {
+	rec := make([]byte, h.recordSize)
+	if _, err := io.ReadFull(r, rec); err != nil {
+		return nil, fmt.Errorf("shapefile: reading dbf record: %w", err)
+	}
+	attrs := make(map[string]any, len(h.fields))
+	pos := 1 // skip the deletion flag
+	for _, f := range h.fields {
+		v, err := parseValue(f, rec[pos:pos+f.Length])
+		if err != nil {
+			return nil, fmt.Errorf("shapefile: field %s: %w", f.Name, err)
+		}
+		attrs[f.Name] = v
+		pos += f.Length
+	}
+	return attrs, nil
+}
+
+// parseValue converts a field of a record. Blank values of fields other than
+// character fields are nil.
+func parseValue(f Field, raw []byte) (any, error) {
+	if f.Type == FieldCharacter {
+		return strings.TrimRight(string(raw), " \x00"), nil
+	}
+	s := strings.Trim(string(raw), " \x00")
+	if s == "" {
+		return nil, nil
+	}
+
+	switch f.Type {
+	case FieldNumeric, FieldFloat:
+		if strings.Trim(s, "*") == "" {
+			return nil, nil // overflow marker
+		}
+		if f.Type == FieldNumeric && f.Decimals == 0 {
+			if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return v, nil
+			}
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	case FieldLogical:
+		switch s {
+		case "T", "t", "Y", "y":
+			return true, nil
+		case "F", "f", "N", "n":
+			return false, nil
+		}
+		return nil, nil
+	case FieldDate:
+		if strings.Trim(s, "0") == "" {
+			return nil, nil
+		}
+		return time.Parse("20060102", s)
+	default:
+		return s, nil
+	}
+}
+
+// checkFields validates field definitions for writing and returns the
+// record size.
+func checkFields(fields []Field) (int, error) {
+	size := 1
+	seen := map[string]bool{}
+	for _, f := range fields {
+		if f.Name == "" || len(f.Name) > 10 {
+			return 0, fmt.Errorf("shapefile: field name %q must have 1 to 10 bytes", f.Name)
+		}
+		if seen[f.Name] {
+			return 0, fmt.Errorf("shapefile: duplicate field name %q", f.Name)
+		}
+		seen[f.Name] = true
+
+		maxLength := 254
+		switch f.Type {
+		case FieldCharacter:
+			maxLength = 255
+		case FieldNumeric, FieldFloat:
+		case FieldLogical:
+			maxLength = 1
+		case FieldDate:
+			maxLength = 8
+		default:
+			return 0, fmt.Errorf("shapefile: field %s: unsupported type %q", f.Name, byte(f.Type))
+		}
+		if f.Length < 1 || f.Length > maxLength {
+			return 0, fmt.Errorf("shapefile: field %s: invalid length %d", f.Name, f.Length)
+		}
+		if f.Decimals < 0 || (f.Decimals > 0 && f.Decimals > f.Length-2) {
+			return 0, fmt.Errorf("shapefile: field %s: invalid decimal count %d", f.Name, f.Decimals)
+		}
+		size += f.Length
+	}
+	if size > math.MaxUint16 {
+		return 0, fmt.Errorf("shapefile: dbf record size %d is too large", size)
+	}
+	return size, nil
+}
+
+// writeDBFHeader writes the header and field descriptors of a table with
+// the given number of records.
+func writeDBFHeader(w io.Writer, fields []Field, records, recordSize int, updated time.Time) error {
+	buf := make([]byte, 32+32*len(fields)+1)
+	buf[0] = dbfVersion
+	buf[1] = byte(updated.Year() - 1900)
+	buf[2] = byte(updated.Month())
+	buf[3] = byte(updated.Day())
+	binary.LittleEndian.PutUint32(buf[4:], uint32(records))
+	binary.LittleEndian.PutUint16(buf[8:], uint16(len(buf)))
+	binary.LittleEndian.PutUint16(buf[10:], uint16(recordSize))
+	for i, f := range fields {
+		d := buf[32+32*i:]
+		copy(d[:11], f.Name)
+		d[11] = byte(f.Type)
+		d[16] = byte(f.Length)
+		d[17] = byte(f.Decimals)
+	}
+	buf[len(buf)-1] = dbfTerminator
+	_, err := w.Write(buf)
+	return err
+}
+
+// formatRecord encodes the attributes of a record. Missing attributes are
+// written blank; attributes without a field are ignored.
+func formatRecord(fields []Field, attrs map[string]any, recordSize int) ([]byte, error) {
+	rec := bytes.Repeat([]byte{' '}, recordSize)
+	pos := 1
+	for _, f := range fields {
+		if v := attrs[f.Name]; v != nil {
+			s, err := formatValue(f, v)
+			if err != nil {
+				return nil, fmt.Errorf("shapefile: field %s: %w", f.Name, err)
+			}
+			if f.Type == FieldCharacter {
+				copy(rec[pos:], s)
+			} else {
+				copy(rec[pos+f.Length-len(s):], s)
+			}
+		}
+		pos += f.Length
+	}
+	return rec, nil
+}
+
+// formatValue formats a value for a field. Character values longer than the
+// field are cut at a rune boundary; numbers that do not fit are an error.
+func formatValue(f Field, v any) (string, error) {
+	switch f.Type {
+	case FieldCharacter:
+		s, ok := v.(string)
+		if !ok {
+			s = fmt.Sprint(v)
+		}
+		for len(s) > f.Length {
+			_, size := utf8.DecodeLastRuneInString(s)
+			s = s[:len(s)-size]
+		}
+		return s, nil
+	case FieldNumeric, FieldFloat:
+		var s string
+		switch n := v.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			s = fmt.Sprint(n)
+			if f.Decimals > 0 {
+				s += "." + strings.Repeat("0", f.Decimals)
+			}
+		case float32:
+			s = strconv.FormatFloat(float64(n), 'f', f.Decimals, 32)
+		case float64:
+			if math.IsNaN(n) || math.IsInf(n, 0) {
+				return "", fmt.Errorf("invalid number %v", n)
+			}
+			s = strconv.FormatFloat(n, 'f', f.Decimals, 64)
+		default:
+			return "", fmt.Errorf("cannot store %T in a numeric field", v)
+		}
+		if len(s) > f.Length {
+			return "", fmt.Errorf("%s does not fit in %d bytes", s, f.Length)
+		}
+		return s, nil
+	case FieldLogical:
+		b, ok := v.(bool)
+		if !ok {
+			return "", fmt.Errorf("cannot store %T in a logical field", v)
+		}
+		if b {
+			return "T", nil
+		}
+		return "F", nil
+	case FieldDate:
+		t, ok := v.(time.Time)
+		if !ok {
+			return "", fmt.Errorf("cannot store %T in a date field", v)
+		}
+		return t.Format("20060102"), nil
+	}
+	return "", fmt.Errorf("unsupported type %q", byte(f.Type))
+}
@@ -0,0 +1,110 @@
+package shapefile
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/proj"
+)
+
+// ESRI WKT of the geographic systems Prj writes.
+const (
+	gcsWGS84 = `GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],` +
+		`PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`
+	gcsNAD83 = `GEOGCS["GCS_North_American_1983",DATUM["D_North_American_1983",SPHEROID["GRS_1980",6378137.0,298.257222101]],` +
+		`PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`
+	gcsETRS89 = `GEOGCS["GCS_ETRS_1989",DATUM["D_ETRS_1989",SPHEROID["GRS_1980",6378137.0,298.257222101]],` +
+		`PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`
+)
+
+// esriNames maps the names ESRI gives to coordinate systems, which .prj files
+// usually carry without an EPSG code, to SRIDs.
+var esriNames = map[string]gogis.SRID{
+	"GCS_WGS_1984":                           4326,
+	"GCS_North_American_1983":                4269,
+	"GCS_North_American_1927":                4267,
+	"GCS_ETRS_1989":                          4258,
+	"GCS_GDA_1994":                           4283,
+	"GCS_OSGB_1936":                          4277,
+	"WGS_1984_Web_Mercator_Auxiliary_Sphere": 3857,
+	"WGS_1984_Web_Mercator":                  3857,
+	"British_National_Grid":                  27700,
+	"ETRS_1989_LAEA":                         3035,
+}
+
+// SRIDFromPrj returns the SRID of the coordinate system in the WKT of a .prj
+// file. It uses the EPSG code of the definition if present, and otherwise
+// recognizes the ESRI names of common systems, including the UTM zones on
+// WGS 84, NAD83 and ETRS89. Definitions are identified by name and code
+// only, so systems whose projection proj does not implement are recognized
+// too. It reports false for definitions it cannot identify.
+func SRIDFromPrj(wkt string) (gogis.SRID, bool) {
+	name, srid, err := proj.IdentifyWKT(wkt)
+	if err != nil {
+		return 0, false
+	}
+	if srid != 0 {
+		return srid, true
+	}
+	if srid, ok := esriNames[name]; ok {
+		return srid, true
+	}
+
+	var zone int
+	var hemisphere string
+	for _, utm := range []struct {
+		format string
+		north  gogis.SRID
+		south  gogis.SRID
+		zones  int
+	}{
+		{format: "WGS_1984_UTM_Zone_%d%s", north: 32600, south: 32700, zones: 60},
+		{format: "NAD_1983_UTM_Zone_%d%s", north: 26900, zones: 23},
+		{format: "ETRS_1989_UTM_Zone_%d%s", north: 25800, zones: 38},
+	} {
+		n, _ := fmt.Sscanf(name, utm.format, &zone, &hemisphere)
+		if n != 2 || zone < 1 || zone > utm.zones {
+			continue
+		}
+		switch {
+		case hemisphere == "N":
+			return utm.north + gogis.SRID(zone), true
+		case hemisphere == "S" && utm.south != 0:
+			return utm.south + gogis.SRID(zone), true
+		}
+	}
+	return 0, false
+}
+
+// Prj returns the ESRI WKT written to the .prj file of a shapefile in the
+// given system. WGS 84 (4326), NAD83 (4269), ETRS89 (4258), Web Mercator
+// (3857) and the WGS 84 UTM zones (32601 to 32660 and 32701 to 32760) are
+// supported.
+func Prj(srid gogis.SRID) (string, bool) {
+	switch {
+	case srid == 4326:
+		return gcsWGS84, true
+	case srid == 4269:
+		return gcsNAD83, true
+	case srid == 4258:
+		return gcsETRS89, true
+	case srid == gogis.SRIDWebMercator:
+		return `PROJCS["WGS_1984_Web_Mercator_Auxiliary_Sphere",` + gcsWGS84 +
+			`,PROJECTION["Mercator_Auxiliary_Sphere"],PARAMETER["False_Easting",0.0],PARAMETER["False_Northing",0.0],` +
+			`PARAMETER["Central_Meridian",0.0],PARAMETER["Standard_Parallel_1",0.0],PARAMETER["Auxiliary_Sphere_Type",0.0],` +
+			`UNIT["Meter",1.0]]`, true
+	case srid > 32600 && srid <= 32660, srid > 32700 && srid <= 32760:
+		zone, hemisphere, northing := int(srid%100), "N", "0.0"
+		if srid > 32700 {
+			hemisphere, northing = "S", "10000000.0"
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, `PROJCS["WGS_1984_UTM_Zone_%d%s",%s,PROJECTION["Transverse_Mercator"],`, zone, hemisphere, gcsWGS84)
+		fmt.Fprintf(&b, `PARAMETER["False_Easting",500000.0],PARAMETER["False_Northing",%s],`, northing)
+		fmt.Fprintf(&b, `PARAMETER["Central_Meridian",%.1f],PARAMETER["Scale_Factor",0.9996],`, float64(zone*6-183))
+		b.WriteString(`PARAMETER["Latitude_Of_Origin",0.0],UNIT["Meter",1.0]]`)
+		return b.String(), true
+	}
+	return "", false
+}
@@ -0,0 +1,96 @@
+package shapefile_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/shapefile"
+)
+
+func bytesReader(b []byte) io.Reader {
+	return bytes.NewReader(b)
+}
+
+func TestSRIDFromPrj(t *testing.T) {
+	tests := []struct {
+		name string
+		wkt  string
+		want gogis.SRID
+	}{
+		{
+			name: "esri wgs84",
+			wkt:  `GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`,
+			want: 4326,
+		},
+		{
+			name: "authority",
+			wkt: `PROJCS["RGF93 / Lambert-93",GEOGCS["RGF93",DATUM["Reseau_Geodesique_Francais_1993",SPHEROID["GRS 1980",6378137,298.257222101]],` +
+				`PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]],PROJECTION["Lambert_Conformal_Conic_2SP"],` +
+				`PARAMETER["standard_parallel_1",49],PARAMETER["standard_parallel_2",44],PARAMETER["latitude_of_origin",46.5],` +
+				`PARAMETER["central_meridian",3],PARAMETER["false_easting",700000],PARAMETER["false_northing",6600000],` +
+				`UNIT["metre",1],AUTHORITY["EPSG","2154"]]`,
+			want: 2154,
+		},
+		{
+			name: "nad83 utm",
+			wkt: `PROJCS["NAD_1983_UTM_Zone_18N",GEOGCS["GCS_North_American_1983",DATUM["D_North_American_1983",SPHEROID["GRS_1980",6378137.0,298.257222101]],` +
+				`PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Transverse_Mercator"],PARAMETER["False_Easting",500000.0],` +
+				`PARAMETER["False_Northing",0.0],PARAMETER["Central_Meridian",-75.0],PARAMETER["Scale_Factor",0.9996],` +
+				`PARAMETER["Latitude_Of_Origin",0.0],UNIT["Meter",1.0]]`,
+			want: 26918,
+		},
+		{
+			// Lambert Azimuthal Equal Area is not implemented by proj.
+			name: "esri laea",
+			wkt: `PROJCS["ETRS_1989_LAEA",GEOGCS["GCS_ETRS_1989",DATUM["D_ETRS_1989",SPHEROID["GRS_1980",6378137.0,298.257222101]],` +
+				`PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Lambert_Azimuthal_Equal_Area"],` +
+				`PARAMETER["False_Easting",4321000.0],PARAMETER["False_Northing",3210000.0],PARAMETER["Central_Meridian",10.0],` +
+				`PARAMETER["Latitude_Of_Origin",52.0],UNIT["Meter",1.0]]`,
+			want: 3035,
+		},
+		{
+			name: "authority of unsupported projection",
+			wkt: `PROJCS["S-JTSK / Krovak East North",GEOGCS["S-JTSK",DATUM["System_Jednotne_Trigonometricke_Site_Katastralni",` +
+				`SPHEROID["Bessel 1841",6377397.155,299.1528128]],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]],` +
+				`PROJECTION["Krovak"],PARAMETER["latitude_of_center",49.5],PARAMETER["longitude_of_center",24.83333333333333],` +
+				`UNIT["metre",1],AUTHORITY["EPSG","5514"]]`,
+			want: 5514,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := shapefile.SRIDFromPrj(tt.wkt)
+			if !ok || got != tt.want {
+				t.Errorf("SRIDFromPrj() = %d, %v, want %d", got, ok, tt.want)
+			}
+		})
+	}
+
+	if _, ok := shapefile.SRIDFromPrj(`GEOGCS["Custom",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]]]`); ok {
+		t.Error("SRIDFromPrj() of an unknown system reported true")
+	}
+	if _, ok := shapefile.SRIDFromPrj("not wkt"); ok {
+		t.Error("SRIDFromPrj() of invalid WKT reported true")
+	}
+}
+
+func TestPrj(t *testing.T) {
+	for _, srid := range []gogis.SRID{4326, 4269, 4258, 3857, 32601, 32633, 32660, 32733} {
+		wkt, ok := shapefile.Prj(srid)
+		if !ok {
+			t.Errorf("Prj(%d) reported false", srid)
+			continue
+		}
+		if got, ok := shapefile.SRIDFromPrj(wkt); !ok || got != srid {
+			t.Errorf("SRIDFromPrj(Prj(%d)) = %d, %v", srid, got, ok)
+		}
+	}
+	for _, srid := range []gogis.SRID{0, 2154, 32600, 32661} {
+		if _, ok := shapefile.Prj(srid); ok {
+			t.Errorf("Prj(%d) reported true", srid)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package shapefile
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/restayway/gogis"
+)
+
+const (
+	fileCode      = 9994
+	fileVersion   = 1000
+	headerSize    = 100
+	recordHeader  = 8
+	maxRecordSize = 1 << 30
+)
+
+// Reader reads the features of a shapefile in order.
+type Reader struct {
+	shapeType ShapeType
+	bounds    Bounds
+	srid      gogis.SRID
+	fields    []Field
+
+	shp     *bufio.Reader
+	dbf     *bufio.Reader
+	dbfHead *dbfHeader
+	closers []io.Closer
+	remain  int64 // bytes of the .shp file left to read
+	number  int
+	err     error
+}
+
+// Open opens the shapefile with the given .shp path, or base name without
+// extension. The .dbf file is read if present, and the SRID is taken from
+// the .prj file if present and recognized by SRIDFromPrj.
+func Open(name string) (*Reader, error) {
+	base := name
+	if ext := filepath.Ext(name); strings.EqualFold(ext, ".shp") {
+		base = strings.TrimSuffix(name, ext)
+	}
+
+	shp, err := openSibling(base, ".shp")
+	if err != nil {
+		return nil, fmt.Errorf("shapefile: %w", err)
+	}
+	dbf, err := openSibling(base, ".dbf")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		shp.Close()
+		return nil, fmt.Errorf("shapefile: %w", err)
+	}
+
+	var dbfReader io.Reader
+	closers := []io.Closer{shp}
+	if dbf != nil {
+		dbfReader = dbf
+		closers = append(closers, dbf)
+	}
+	r, err := NewReader(shp, dbfReader)
+	if err != nil {
+		for _, c := range closers {
+			c.Close()
+		}
+		return nil, err
+	}
+	r.closers = closers
+
+	if prj, err := readSibling(base, ".prj"); err == nil {
+		r.srid, _ = SRIDFromPrj(string(prj))
+	}
+	return r, nil
+}
+
+// siblingNames returns the candidate paths of a shapefile component, with a
+// lower and an upper case extension.
+func siblingNames(base, ext string) []string {
+	return []string{base + ext, base + strings.ToUpper(ext)}
+}
+
+func openSibling(base, ext string) (*os.File, error) {
+	var err error
+	for _, name := range siblingNames(base, ext) {
+		var f *os.File
+		if f, err = os.Open(name); err == nil {
+			return f, nil
+		}
+	}
+	return nil, err
+}
+
+func readSibling(base, ext string) ([]byte, error) {
+	var err error
+	for _, name := range siblingNames(base, ext) {
+		var b []byte
+		if b, err = os.ReadFile(name); err == nil {
+			return b, nil
+		}
+	}
+	return nil, err
+}
+
+// NewReader returns a reader of the shapes in shp, with attributes from the
+// dBase table in dbf. A nil dbf reads shapes without attributes. The index
+// file is not needed for reading in order.
+func NewReader(shp, dbf io.Reader) (*Reader, error) {
+	r := &Reader{shp: bufio.NewReader(shp)}
+	var head [headerSize]byte
+	if _, err := io.ReadFull(r.shp, head[:]); err != nil {
+		return nil, fmt.Errorf("shapefile: reading header: %w", err)
+	}
+	if code := binary.BigEndian.Uint32(head[0:]); code != fileCode {
+		return nil, fmt.Errorf("shapefile: invalid file code %d", code)
+	}
+	if v := binary.LittleEndian.Uint32(head[28:]); v != fileVersion {
+		return nil, fmt.Errorf("shapefile: unsupported version %d", v)
+	}
+	r.remain = int64(binary.BigEndian.Uint32(head[24:]))*2 - headerSize
+	r.shapeType = ShapeType(binary.LittleEndian.Uint32(head[32:]))
+	r.bounds = Bounds{
+		MinX: float64At(head[:], 36),
+		MinY: float64At(head[:], 44),
+		MaxX: float64At(head[:], 52),
+		MaxY: float64At(head[:], 60),
+	}
+
+	if dbf != nil {
+		r.dbf = bufio.NewReader(dbf)
+		h, err := readDBFHeader(r.dbf)
+		if err != nil {
+			return nil, err
+		}
+		r.dbfHead, r.fields = h, h.fields
+	}
+	return r, nil
+}
+
+// ShapeType returns the type of the shapes in the file.
+func (r *Reader) ShapeType() ShapeType {
+	return r.shapeType
+}
+
+// Bounds returns the bounding box of the file as stored in its header.
+func (r *Reader) Bounds() Bounds {
+	return r.bounds
+}
+
+// Fields returns the attribute fields of the .dbf file.
+func (r *Reader) Fields() []Field {
+	return r.fields
+}
+
+// SRID returns the SRID of the .prj file, or 0 when it is missing or not
+// recognized. Readers created with NewReader always return 0.
+func (r *Reader) SRID() gogis.SRID {
+	return r.srid
+}
+
+// Close closes the files opened by Open.
+func (r *Reader) Close() error {
+	var err error
+	for _, c := range r.closers {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	r.closers = nil
+	return err
+}
+
+// Next returns the next feature, or io.EOF after the last one. Once Next
+// fails, all later calls return the same error.
+func (r *Reader) Next() (*Feature, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	f, err := r.next()
+	if err != nil {
+		r.err = err
+		return nil, err
+	}
+	return f, nil
+}
+
+func (r *Reader) next() (*Feature, error) {
+	if r.remain <= 0 {
+		return nil, io.EOF
+	}
+	var head [recordHeader]byte
+	if _, err := io.ReadFull(r.shp, head[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("shapefile: reading record header: %w", err)
+	}
+	size := int64(binary.BigEndian.Uint32(head[4:])) * 2
+	if size < 4 || size > maxRecordSize || size > r.remain-recordHeader {
+		return nil, fmt.Errorf("shapefile: invalid record length %d", size)
+	}
+	content := make([]byte, size)
+	if _, err := io.ReadFull(r.shp, content); err != nil {
+		return nil, fmt.Errorf("shapefile: reading record: %w", err)
+	}
+	r.remain -= recordHeader + size
+	r.number++
+
+	g, err := decodeShape(content, r.shapeType)
+	if err != nil {
+		return nil, fmt.Errorf("shapefile: record %d: %w", r.number, err)
+	}
+	f := &Feature{Number: r.number, Geometry: g}
+	if r.dbf != nil {
+		if r.number > r.dbfHead.records {
+			return nil, fmt.Errorf("shapefile: record %d has no attributes in the dbf file", r.number)
+		}
+		if f.Attributes, err = readDBFRecord(r.dbf, r.dbfHead); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func float64At(b []byte, pos int) float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(b[pos:]))
+}
+
+// decodeShape decodes the content of a record of a file of the given type.
+func decodeShape(b []byte, fileType ShapeType) (gogis.Geometry, error) {
+	typ := ShapeType(binary.LittleEndian.Uint32(b))
+	if typ == ShapeTypeNull {
+		return nil, nil
+	}
+	if typ != fileType {
+		return nil, fmt.Errorf("%v shape in a %v file", typ, fileType)
+	}
+
+	switch typ.base() {
+	case ShapeTypePoint:
+		if len(b) < 20 {
+			return nil, fmt.Errorf("truncated point")
+		}
+		return &gogis.Point{Lng: float64At(b, 4), Lat: float64At(b, 12)}, nil
+	case ShapeTypeMultiPoint:
+		if len(b) < 40 {
+			return nil, fmt.Errorf("truncated multipoint")
+		}
+		n := int(binary.LittleEndian.Uint32(b[36:]))
+		if n > (len(b)-40)/16 {
+			return nil, fmt.Errorf("truncated multipoint")
+		}
+		gc := &gogis.GeometryCollection{Geometries: make([]gogis.Geometry, n)}
+		for i := range gc.Geometries {
+			pos := 40 + 16*i
+			gc.Geometries[i] = &gogis.Point{Lng: float64At(b, pos), Lat: float64At(b, pos+8)}
+		}
+		return gc, nil
+	case ShapeTypePolyLine, ShapeTypePolygon:
+		parts, err := decodeParts(b)
+		if err != nil {
+			return nil, err
+		}
+		if typ.base() == ShapeTypePolygon {
+			polygons := assemblePolygons(parts)
+			if len(polygons) == 1 {
+				return polygons[0], nil
+			}
+			gc := &gogis.GeometryCollection{Geometries: make([]gogis.Geometry, len(polygons))}
+			for i, p := range polygons {
+				gc.Geometries[i] = p
+			}
+			return gc, nil
+		}
+		if len(parts) == 1 {
+			return &gogis.LineString{Points: parts[0]}, nil
+		}
+		gc := &gogis.GeometryCollection{Geometries: make([]gogis.Geometry, len(parts))}
+		for i, part := range parts {
+			gc.Geometries[i] = &gogis.LineString{Points: part}
+		}
+		return gc, nil
+	default:
+		return nil, fmt.Errorf("unsupported shape type %v", typ)
+	}
+}
+
+// decodeParts reads the parts of a polyline or polygon record.
+func decodeParts(b []byte) ([][]gogis.Point, error) {
+	if len(b) < 44 {
+		return nil, fmt.Errorf("truncated shape")
+	}
+	numParts := int(binary.LittleEndian.Uint32(b[36:]))
+	numPoints := int(binary.LittleEndian.Uint32(b[40:]))
+	if numParts > (len(b)-44)/4 || numPoints > (len(b)-44-4*numParts)/16 {
+		return nil, fmt.Errorf("truncated shape")
+	}
+	pointsAt := 44 + 4*numParts
+
+	parts := make([][]gogis.Point, numParts)
+	for i := range parts {
+		start := int(binary.LittleEndian.Uint32(b[44+4*i:]))
+		end := numPoints
+		if i+1 < numParts {
+			end = int(binary.LittleEndian.Uint32(b[48+4*i:]))
+		}
+		if start > end || end > numPoints {
+			return nil, fmt.Errorf("invalid part index %d", start)
+		}
+		points := make([]gogis.Point, end-start)
+		for k := range points {
+			pos := pointsAt + 16*(start+k)
+			points[k] = gogis.Point{Lng: float64At(b, pos), Lat: float64At(b, pos+8)}
+		}
+		parts[i] = points
+	}
+	return parts, nil
+}
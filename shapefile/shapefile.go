@@ -0,0 +1,219 @@
+// Package shapefile reads and writes ESRI shapefiles with gogis geometries.
+//
+// A shapefile is a bundle of files sharing a base name: the .shp file holds
+// the shapes, the .shx file an index of their offsets, the .dbf file a dBase
+// table with one attribute record per shape and the optional .prj file the
+// coordinate reference system as WKT.
+//
+// Shapes map to gogis types as follows. Multipoints and polylines or polygons
+// with several parts become a *gogis.GeometryCollection, and the rings of a
+// polygon are grouped into shells and holes by their orientation: shells are
+// clockwise, holes counter-clockwise and belong to the smallest shell
+// containing them. Z and M values are dropped and null shapes have a nil
+// geometry.
+//
+//	Point              *gogis.Point
+//	MultiPoint         *gogis.GeometryCollection of *gogis.Point
+//	PolyLine           *gogis.LineString, or a collection of them
+//	Polygon            *gogis.Polygon, or a collection of them
+//
+// Example:
+//
+//	r, err := shapefile.Open("parcels.shp")
+//	if err != nil {
+//	    return err
+//	}
+//	defer r.Close()
+//	for {
+//	    f, err := r.Next()
+//	    if err == io.EOF {
+//	        break
+//	    }
+//	    if err != nil {
+//	        return err
+//	    }
+//	    fmt.Println(f.Geometry, f.Attributes["NAME"])
+//	}
+//
+// Coordinates are stored as they are. Shapefiles in a projected system, as
+// reported by the SRID of the Reader, can be converted with the proj
+// subpackage.
+package shapefile
+
+import (
+	"fmt"
+
+	"github.com/restayway/gogis"
+)
+
+// ShapeType is the type of the shapes in a shapefile.
+type ShapeType int32
+
+const (
+	ShapeTypeNull        ShapeType = 0
+	ShapeTypePoint       ShapeType = 1
+	ShapeTypePolyLine    ShapeType = 3
+	ShapeTypePolygon     ShapeType = 5
+	ShapeTypeMultiPoint  ShapeType = 8
+	ShapeTypePointZ      ShapeType = 11
+	ShapeTypePolyLineZ   ShapeType = 13
+	ShapeTypePolygonZ    ShapeType = 15
+	ShapeTypeMultiPointZ ShapeType = 18
+	ShapeTypePointM      ShapeType = 21
+	ShapeTypePolyLineM   ShapeType = 23
+	ShapeTypePolygonM    ShapeType = 25
+	ShapeTypeMultiPointM ShapeType = 28
+	ShapeTypeMultiPatch  ShapeType = 31
+)
+
+// base returns the two-dimensional type of t, ShapeTypePolygon for
+// ShapeTypePolygonZ for example.
+func (t ShapeType) base() ShapeType {
+	switch t {
+	case ShapeTypePointZ, ShapeTypePointM:
+		return ShapeTypePoint
+	case ShapeTypePolyLineZ, ShapeTypePolyLineM:
+		return ShapeTypePolyLine
+	case ShapeTypePolygonZ, ShapeTypePolygonM:
+		return ShapeTypePolygon
+	case ShapeTypeMultiPointZ, ShapeTypeMultiPointM:
+		return ShapeTypeMultiPoint
+	}
+	return t
+}
+
+// String returns the name of the shape type, such as "PolygonZ".
+func (t ShapeType) String() string {
+	switch t {
+	case ShapeTypeNull:
+		return "Null"
+	case ShapeTypePoint:
+		return "Point"
+	case ShapeTypePolyLine:
+		return "PolyLine"
+	case ShapeTypePolygon:
+		return "Polygon"
+	case ShapeTypeMultiPoint:
+		return "MultiPoint"
+	case ShapeTypePointZ:
+		return "PointZ"
+	case ShapeTypePolyLineZ:
+		return "PolyLineZ"
+	case ShapeTypePolygonZ:
+		return "PolygonZ"
+	case ShapeTypeMultiPointZ:
+		return "MultiPointZ"
+	case ShapeTypePointM:
+		return "PointM"
+	case ShapeTypePolyLineM:
+		return "PolyLineM"
+	case ShapeTypePolygonM:
+		return "PolygonM"
+	case ShapeTypeMultiPointM:
+		return "MultiPointM"
+	case ShapeTypeMultiPatch:
+		return "MultiPatch"
+	}
+	return fmt.Sprintf("ShapeType(%d)", int32(t))
+}
+
+// Feature is a shape with its attributes.
+type Feature struct {
+	Number     int            // Record number, starting at 1
+	Geometry   gogis.Geometry // Shape geometry, nil for null shapes
+	Attributes map[string]any // DBF attributes by field name
+}
+
+// Bounds is the bounding box of the shapes in a shapefile.
+type Bounds struct {
+	MinX, MinY, MaxX, MaxY float64
+}
+
+// extend grows b to include the points.
+func (b *Bounds) extend(points []gogis.Point, first bool) {
+	for i, p := range points {
+		if first && i == 0 {
+			*b = Bounds{MinX: p.Lng, MinY: p.Lat, MaxX: p.Lng, MaxY: p.Lat}
+			continue
+		}
+		if p.Lng < b.MinX {
+			b.MinX = p.Lng
+		}
+		if p.Lat < b.MinY {
+			b.MinY = p.Lat
+		}
+		if p.Lng > b.MaxX {
+			b.MaxX = p.Lng
+		}
+		if p.Lat > b.MaxY {
+			b.MaxY = p.Lat
+		}
+	}
+}
+
+// signedArea returns twice the signed area of a ring, positive for
+// counter-clockwise rings.
+func signedArea(ring []gogis.Point) float64 {
+	var a float64
+	for i := 0; i+1 < len(ring); i++ {
+		a += ring[i].Lng*ring[i+1].Lat - ring[i+1].Lng*ring[i].Lat
+	}
+	return a
+}
+
+// contains reports whether p lies inside ring, by the even-odd rule.
+func contains(ring []gogis.Point, p gogis.Point) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) &&
+			p.Lng < (b.Lng-a.Lng)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lng {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// reversed returns a reversed copy of ring.
+func reversed(ring []gogis.Point) []gogis.Point {
+	r := make([]gogis.Point, len(ring))
+	for i, p := range ring {
+		r[len(ring)-1-i] = p
+	}
+	return r
+}
+
+// assemblePolygons groups the rings of a polygon shape into polygons. Shells
+// are clockwise; each hole goes to the smallest shell containing its first
+// point, and holes outside every shell become shells themselves.
+func assemblePolygons(rings [][]gogis.Point) []*gogis.Polygon {
+	var polygons []*gogis.Polygon
+	var shellAreas []float64
+	var holes [][]gogis.Point
+	for _, ring := range rings {
+		if a := signedArea(ring); a <= 0 {
+			polygons = append(polygons, &gogis.Polygon{Rings: [][]gogis.Point{ring}})
+			shellAreas = append(shellAreas, -a)
+		} else {
+			holes = append(holes, ring)
+		}
+	}
+
+	for _, hole := range holes {
+		best := -1
+		if len(hole) > 0 {
+			for i, p := range polygons {
+				if shellAreas[i] > 0 && contains(p.Rings[0], hole[0]) && (best < 0 || shellAreas[i] < shellAreas[best]) {
+					best = i
+				}
+			}
+		}
+		if best < 0 {
+			polygons = append(polygons, &gogis.Polygon{Rings: [][]gogis.Point{hole}})
+			shellAreas = append(shellAreas, 0)
+			continue
+		}
+		polygons[best].Rings = append(polygons[best].Rings, hole)
+	}
+	return polygons
+}
@@ -0,0 +1,347 @@
+package shapefile_test
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/shapefile"
+)
+
+func readAll(t *testing.T, r *shapefile.Reader) []*shapefile.Feature {
+	t.Helper()
+	var features []*shapefile.Feature
+	for {
+		f, err := r.Next()
+		if err == io.EOF {
+			return features
+		}
+		if err != nil {
+			t.Fatalf("Next() unexpected error = %v", err)
+		}
+		features = append(features, f)
+	}
+}
+
+func square(x, y, size float64, clockwise bool) []gogis.Point {
+	ring := []gogis.Point{{Lng: x, Lat: y}, {Lng: x + size, Lat: y}, {Lng: x + size, Lat: y + size}, {Lng: x, Lat: y + size}, {Lng: x, Lat: y}}
+	if clockwise {
+		for i, j := 0, len(ring)-1; i < j; i, j = i+1, j-1 {
+			ring[i], ring[j] = ring[j], ring[i]
+		}
+	}
+	return ring
+}
+
+func TestRoundTrip(t *testing.T) {
+	line := []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}, {Lng: 5, Lat: 0}}
+	tests := []struct {
+		name  string
+		typ   shapefile.ShapeType
+		geoms []gogis.Geometry
+	}{
+		{
+			name:  "point",
+			typ:   shapefile.ShapeTypePoint,
+			geoms: []gogis.Geometry{&gogis.Point{Lng: -73.9857, Lat: 40.7484}, nil, &gogis.Point{Lng: 2.3522, Lat: 48.8566}},
+		},
+		{
+			name:  "multipoint",
+			typ:   shapefile.ShapeTypeMultiPoint,
+			geoms: []gogis.Geometry{&gogis.GeometryCollection{Geometries: []gogis.Geometry{&line[0], &line[1]}}},
+		},
+		{
+			name: "polyline",
+			typ:  shapefile.ShapeTypePolyLine,
+			geoms: []gogis.Geometry{
+				&gogis.LineString{Points: line},
+				&gogis.GeometryCollection{Geometries: []gogis.Geometry{&gogis.LineString{Points: line[:2]}, &gogis.LineString{Points: line[1:]}}},
+			},
+		},
+		{
+			name: "polygon",
+			typ:  shapefile.ShapeTypePolygon,
+			geoms: []gogis.Geometry{
+				&gogis.Polygon{Rings: [][]gogis.Point{square(0, 0, 10, true), square(2, 2, 2, false)}},
+				&gogis.GeometryCollection{Geometries: []gogis.Geometry{
+					&gogis.Polygon{Rings: [][]gogis.Point{square(0, 0, 1, true)}},
+					&gogis.Polygon{Rings: [][]gogis.Point{square(5, 5, 1, true)}},
+				}},
+			},
+		},
+	}
+
+	fields := []shapefile.Field{
+		{Name: "NAME", Type: shapefile.FieldCharacter, Length: 20},
+		{Name: "POP", Type: shapefile.FieldNumeric, Length: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "test.shp")
+			w, err := shapefile.Create(name, tt.typ, fields, gogis.SRIDWGS84)
+			if err != nil {
+				t.Fatalf("Create() unexpected error = %v", err)
+			}
+			for i, g := range tt.geoms {
+				if err := w.Write(g, map[string]any{"NAME": tt.name, "POP": i}); err != nil {
+					t.Fatalf("Write() unexpected error = %v", err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() unexpected error = %v", err)
+			}
+
+			r, err := shapefile.Open(name)
+			if err != nil {
+				t.Fatalf("Open() unexpected error = %v", err)
+			}
+			defer r.Close()
+			if r.ShapeType() != tt.typ || r.SRID() != gogis.SRIDWGS84 || !reflect.DeepEqual(r.Fields(), fields) {
+				t.Errorf("Open() type = %v, SRID = %d, fields = %v", r.ShapeType(), r.SRID(), r.Fields())
+			}
+			features := readAll(t, r)
+			if len(features) != len(tt.geoms) {
+				t.Fatalf("read %d features, want %d", len(features), len(tt.geoms))
+			}
+			for i, f := range features {
+				if f.Number != i+1 || !reflect.DeepEqual(f.Geometry, tt.geoms[i]) {
+					t.Errorf("feature %d = %d %v, want %v", i, f.Number, f.Geometry, tt.geoms[i])
+				}
+				want := map[string]any{"NAME": tt.name, "POP": int64(i)}
+				if !reflect.DeepEqual(f.Attributes, want) {
+					t.Errorf("feature %d attributes = %v, want %v", i, f.Attributes, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteFileLayout(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "points")
+	w, err := shapefile.Create(base, shapefile.ShapeTypePoint, nil, 0)
+	if err != nil {
+		t.Fatalf("Create() unexpected error = %v", err)
+	}
+	for _, p := range []gogis.Point{{Lng: 1, Lat: 5}, {Lng: -2, Lat: 3}} {
+		p := p
+		if err := w.Write(&p, nil); err != nil {
+			t.Fatalf("Write() unexpected error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() unexpected error = %v", err)
+	}
+
+	shp, _ := os.ReadFile(base + ".shp")
+	shx, _ := os.ReadFile(base + ".shx")
+	if len(shp) != 100+2*28 || len(shx) != 100+2*8 {
+		t.Fatalf("file sizes = %d, %d", len(shp), len(shx))
+	}
+	if got := binary.BigEndian.Uint32(shp[24:]); got != uint32(len(shp)/2) {
+		t.Errorf("shp file length = %d words, want %d", got, len(shp)/2)
+	}
+	if got := binary.BigEndian.Uint32(shx[108:]); got != 64 {
+		t.Errorf("second record offset = %d words, want 64", got)
+	}
+	if _, err := os.Stat(base + ".prj"); !os.IsNotExist(err) {
+		t.Errorf("Create() without SRID wrote a .prj file")
+	}
+
+	r, err := shapefile.Open(base)
+	if err != nil {
+		t.Fatalf("Open() unexpected error = %v", err)
+	}
+	defer r.Close()
+	if b := r.Bounds(); b != (shapefile.Bounds{MinX: -2, MinY: 3, MaxX: 1, MaxY: 5}) {
+		t.Errorf("Bounds() = %+v", b)
+	}
+	if len(readAll(t, r)) != 2 {
+		t.Error("expected 2 features")
+	}
+}
+
+func TestPolygonOrientation(t *testing.T) {
+	// Rings in the wrong orientation are fixed on write.
+	want := &gogis.Polygon{Rings: [][]gogis.Point{square(0, 0, 10, true), square(2, 2, 2, false)}}
+	input := &gogis.Polygon{Rings: [][]gogis.Point{square(0, 0, 10, false), square(2, 2, 2, true)}}
+
+	name := filepath.Join(t.TempDir(), "poly.shp")
+	w, err := shapefile.Create(name, shapefile.ShapeTypePolygon, nil, 0)
+	if err != nil {
+		t.Fatalf("Create() unexpected error = %v", err)
+	}
+	if err := w.Write(input, nil); err != nil {
+		t.Fatalf("Write() unexpected error = %v", err)
+	}
+	_ = w.Close()
+
+	r, err := shapefile.Open(name)
+	if err != nil {
+		t.Fatalf("Open() unexpected error = %v", err)
+	}
+	defer r.Close()
+	f, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(f.Geometry, want) {
+		t.Errorf("Next() = %v, want %v", f.Geometry, want)
+	}
+}
+
+func TestPolygonAssembly(t *testing.T) {
+	// Two shells written as one multipart polygon, with the hole of the
+	// second shell stored before it.
+	shellA := square(0, 0, 4, true)
+	shellB := square(10, 10, 10, true)
+	hole := square(12, 12, 2, false)
+	input := &gogis.GeometryCollection{Geometries: []gogis.Geometry{
+		&gogis.Polygon{Rings: [][]gogis.Point{shellA}},
+		&gogis.Polygon{Rings: [][]gogis.Point{shellB, hole}},
+	}}
+
+	name := filepath.Join(t.TempDir(), "multi.shp")
+	w, err := shapefile.Create(name, shapefile.ShapeTypePolygon, nil, 0)
+	if err != nil {
+		t.Fatalf("Create() unexpected error = %v", err)
+	}
+	if err := w.Write(input, nil); err != nil {
+		t.Fatalf("Write() unexpected error = %v", err)
+	}
+	_ = w.Close()
+
+	r, _ := shapefile.Open(name)
+	defer r.Close()
+	f, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(f.Geometry, input) {
+		t.Errorf("Next() = %v, want %v", f.Geometry, input)
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := shapefile.Create(filepath.Join(dir, "z"), shapefile.ShapeTypePointZ, nil, 0); err == nil {
+		t.Error("Create() of a PointZ file expected error, got nil")
+	}
+	if _, err := shapefile.Create(filepath.Join(dir, "prj"), shapefile.ShapeTypePoint, nil, 2154); err == nil {
+		t.Error("Create() with an unknown SRID expected error, got nil")
+	}
+	if _, err := shapefile.Create(filepath.Join(dir, "f"), shapefile.ShapeTypePoint, []shapefile.Field{{Name: "TOO_LONG_NAME", Type: shapefile.FieldCharacter, Length: 5}}, 0); err == nil {
+		t.Error("Create() with a long field name expected error, got nil")
+	}
+
+	w, err := shapefile.Create(filepath.Join(dir, "lines"), shapefile.ShapeTypePolyLine, []shapefile.Field{{Name: "N", Type: shapefile.FieldNumeric, Length: 3}}, 0)
+	if err != nil {
+		t.Fatalf("Create() unexpected error = %v", err)
+	}
+	if err := w.Write(&gogis.Point{}, nil); err == nil {
+		t.Error("Write() of a point to a PolyLine file expected error, got nil")
+	}
+	if err := w.Write(nil, map[string]any{"N": 12345}); err == nil {
+		t.Error("Write() of an overflowing number expected error, got nil")
+	}
+	if err := w.Write(nil, map[string]any{"N": 123}); err != nil {
+		t.Errorf("Write() after rejected features unexpected error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() unexpected error = %v", err)
+	}
+	if err := w.Write(nil, nil); err == nil {
+		t.Error("Write() after Close() expected error, got nil")
+	}
+}
+
+func TestDBFValues(t *testing.T) {
+	fields := []shapefile.Field{
+		{Name: "TEXT", Type: shapefile.FieldCharacter, Length: 5},
+		{Name: "AREA", Type: shapefile.FieldNumeric, Length: 12, Decimals: 3},
+		{Name: "RATIO", Type: shapefile.FieldFloat, Length: 10, Decimals: 2},
+		{Name: "OPEN", Type: shapefile.FieldLogical, Length: 1},
+		{Name: "SINCE", Type: shapefile.FieldDate, Length: 8},
+	}
+	records := []map[string]any{
+		{"TEXT": "Zürich-Nord", "AREA": 12.5, "RATIO": 3, "OPEN": true, "SINCE": time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"TEXT": "", "OPEN": false},
+	}
+	want := []map[string]any{
+		{"TEXT": "Züri", "AREA": 12.5, "RATIO": 3.0, "OPEN": true, "SINCE": time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"TEXT": "", "AREA": nil, "RATIO": nil, "OPEN": false, "SINCE": nil},
+	}
+
+	name := filepath.Join(t.TempDir(), "attrs")
+	w, err := shapefile.Create(name, shapefile.ShapeTypePoint, fields, 0)
+	if err != nil {
+		t.Fatalf("Create() unexpected error = %v", err)
+	}
+	for _, rec := range records {
+		if err := w.Write(nil, rec); err != nil {
+			t.Fatalf("Write() unexpected error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() unexpected error = %v", err)
+	}
+
+	shp, _ := os.Open(name + ".shp")
+	defer shp.Close()
+	dbf, _ := os.Open(name + ".dbf")
+	defer dbf.Close()
+	r, err := shapefile.NewReader(shp, dbf)
+	if err != nil {
+		t.Fatalf("NewReader() unexpected error = %v", err)
+	}
+	for i, f := range readAll(t, r) {
+		if f.Geometry != nil {
+			t.Errorf("feature %d geometry = %v, want nil", i, f.Geometry)
+		}
+		if !reflect.DeepEqual(f.Attributes, want[i]) {
+			t.Errorf("feature %d attributes = %v, want %v", i, f.Attributes, want[i])
+		}
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "bad")
+	w, _ := shapefile.Create(name, shapefile.ShapeTypePoint, nil, 0)
+	_ = w.Write(&gogis.Point{Lng: 1, Lat: 2}, nil)
+	_ = w.Close()
+	shp, _ := os.ReadFile(name + ".shp")
+
+	t.Run("file code", func(t *testing.T) {
+		bad := append([]byte(nil), shp...)
+		bad[3] = 0
+		if _, err := shapefile.NewReader(bytesReader(bad), nil); err == nil {
+			t.Error("NewReader() expected error, got nil")
+		}
+	})
+	t.Run("truncated record", func(t *testing.T) {
+		r, err := shapefile.NewReader(bytesReader(shp[:len(shp)-4]), nil)
+		if err != nil {
+			t.Fatalf("NewReader() unexpected error = %v", err)
+		}
+		if _, err := r.Next(); err == nil || err == io.EOF {
+			t.Errorf("Next() = %v, want error", err)
+		}
+	})
+	t.Run("shape type", func(t *testing.T) {
+		bad := append([]byte(nil), shp...)
+		bad[108] = byte(shapefile.ShapeTypePolygon)
+		r, _ := shapefile.NewReader(bytesReader(bad), nil)
+		if _, err := r.Next(); err == nil {
+			t.Error("Next() expected error, got nil")
+		}
+	})
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := shapefile.Open(filepath.Join(t.TempDir(), "none.shp")); err == nil {
+			t.Error("Open() expected error, got nil")
+		}
+	})
+}
@@ -0,0 +1,388 @@
+package shapefile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/restayway/gogis"
+)
+
+// Writer writes features to a shapefile. The headers are completed by Close,
+// so the files must be seekable.
+type Writer struct {
+	shapeType  ShapeType
+	fields     []Field
+	recordSize int
+
+	shp, shx, dbf          io.WriteSeeker
+	shpBuf, shxBuf, dbfBuf *bufio.Writer
+	closers                []io.Closer
+
+	bounds  Bounds
+	hasData bool
+	offset  int64 // size of the .shp file in bytes
+	records int
+	err     error
+}
+
+// Create creates the .shp, .shx and .dbf files of a shapefile with the given
+// .shp path or base name, holding shapes of a two-dimensional type. When
+// srid is not 0, a .prj file with the WKT returned by Prj is written as well.
+func Create(name string, typ ShapeType, fields []Field, srid gogis.SRID) (*Writer, error) {
+	base := name
+	if ext := filepath.Ext(name); strings.EqualFold(ext, ".shp") {
+		base = strings.TrimSuffix(name, ext)
+	}
+	var prj string
+	if srid != 0 {
+		var ok bool
+		if prj, ok = Prj(srid); !ok {
+			return nil, fmt.Errorf("shapefile: no .prj definition for SRID %d", srid)
+		}
+	}
+
+	var files []*os.File
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	for _, ext := range []string{".shp", ".shx", ".dbf"} {
+		f, err := os.Create(base + ext)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("shapefile: %w", err)
+		}
+		files = append(files, f)
+	}
+	w, err := NewWriter(files[0], files[1], files[2], typ, fields)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	for _, f := range files {
+		w.closers = append(w.closers, f)
+	}
+
+	if prj != "" {
+		if err := os.WriteFile(base+".prj", []byte(prj), 0o644); err != nil {
+			closeAll()
+			return nil, fmt.Errorf("shapefile: %w", err)
+		}
+	}
+	return w, nil
+}
+
+// NewWriter returns a writer of shapes of a two-dimensional type to shp, its
+// index to shx and attributes with the given fields to dbf.
+func NewWriter(shp, shx, dbf io.WriteSeeker, typ ShapeType, fields []Field) (*Writer, error) {
+	switch typ {
+	case ShapeTypePoint, ShapeTypeMultiPoint, ShapeTypePolyLine, ShapeTypePolygon:
+	default:
+		return nil, fmt.Errorf("shapefile: cannot write %v shapes", typ)
+	}
+	recordSize, err := checkFields(fields)
+	if err != nil {
+		return nil, err
+	}
+	w := &Writer{
+		shapeType:  typ,
+		fields:     append([]Field(nil), fields...),
+		recordSize: recordSize,
+		shp:        shp,
+		shx:        shx,
+		dbf:        dbf,
+		shpBuf:     bufio.NewWriter(shp),
+		shxBuf:     bufio.NewWriter(shx),
+		dbfBuf:     bufio.NewWriter(dbf),
+		offset:     headerSize,
+	}
+
+	// Reserve the headers, written again by Close.
+	var empty [headerSize]byte
+	w.shpBuf.Write(empty[:])
+	w.shxBuf.Write(empty[:])
+	if err := writeDBFHeader(w.dbfBuf, w.fields, 0, recordSize, time.Now()); err != nil {
+		return nil, fmt.Errorf("shapefile: %w", err)
+	}
+	return w, nil
+}
+
+// Write appends a feature. A nil geometry is written as a null shape. The
+// geometry must match the shape type of the file: a *gogis.Point for Point
+// files, and for the other types the single geometry of the type or a
+// collection of them, such as a *gogis.LineString or a
+// *gogis.GeometryCollection of line strings for PolyLine files. Polygon
+// rings are reoriented as the format requires.
+//
+// Attributes are matched to the fields by name. A feature that cannot be
+// encoded is not written and leaves the writer usable; write errors are
+// returned by all later calls.
+func (w *Writer) Write(g gogis.Geometry, attrs map[string]any) error {
+	if w.err != nil {
+		return w.err
+	}
+	content, points, err := w.encodeShape(g)
+	if err != nil {
+		return fmt.Errorf("shapefile: record %d: %w", w.records+1, err)
+	}
+	rec, err := formatRecord(w.fields, attrs, w.recordSize)
+	if err != nil {
+		return err
+	}
+	if w.offset+recordHeader+int64(len(content)) > math.MaxInt32*2 {
+		return fmt.Errorf("shapefile: file size limit of 4 GB reached")
+	}
+
+	w.records++
+	var head [recordHeader]byte
+	binary.BigEndian.PutUint32(head[0:], uint32(w.records))
+	binary.BigEndian.PutUint32(head[4:], uint32(len(content)/2))
+	var index [recordHeader]byte
+	binary.BigEndian.PutUint32(index[0:], uint32(w.offset/2))
+	binary.BigEndian.PutUint32(index[4:], uint32(len(content)/2))
+	w.offset += recordHeader + int64(len(content))
+	if len(points) > 0 {
+		w.bounds.extend(points, !w.hasData)
+		w.hasData = true
+	}
+
+	for _, out := range []struct {
+		w *bufio.Writer
+		b []byte
+	}{
+		{w.shpBuf, head[:]},
+		{w.shpBuf, content},
+		{w.shxBuf, index[:]},
+		{w.dbfBuf, rec},
+	} {
+		if _, err := out.w.Write(out.b); err != nil {
+			w.err = fmt.Errorf("shapefile: %w", err)
+			return w.err
+		}
+	}
+	return nil
+}
+
+// Close completes the file headers and closes the files opened by Create.
+// Features cannot be written after Close.
+func (w *Writer) Close() error {
+	err := w.err
+	if err == nil {
+		err = w.finish()
+		w.err = fmt.Errorf("shapefile: writer is closed")
+	}
+	for _, c := range w.closers {
+		if cerr := c.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("shapefile: %w", cerr)
+		}
+	}
+	w.closers = nil
+	return err
+}
+
+// finish flushes the buffers and rewrites the headers.
+func (w *Writer) finish() error {
+	if err := w.dbfBuf.WriteByte(dbfEOF); err != nil {
+		return fmt.Errorf("shapefile: %w", err)
+	}
+	for _, b := range []*bufio.Writer{w.shpBuf, w.shxBuf, w.dbfBuf} {
+		if err := b.Flush(); err != nil {
+			return fmt.Errorf("shapefile: %w", err)
+		}
+	}
+
+	var dbfHead bytes.Buffer
+	if err := writeDBFHeader(&dbfHead, w.fields, w.records, w.recordSize, time.Now()); err != nil {
+		return fmt.Errorf("shapefile: %w", err)
+	}
+	shxSize := int64(headerSize + recordHeader*w.records)
+	for _, h := range []struct {
+		w    io.WriteSeeker
+		head []byte
+	}{
+		{w.shp, mainHeader(w.shapeType, w.offset, w.bounds)},
+		{w.shx, mainHeader(w.shapeType, shxSize, w.bounds)},
+		{w.dbf, dbfHead.Bytes()},
+	} {
+		if _, err := h.w.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("shapefile: %w", err)
+		}
+		if _, err := h.w.Write(h.head); err != nil {
+			return fmt.Errorf("shapefile: %w", err)
+		}
+	}
+	return nil
+}
+
+// mainHeader builds the 100 byte header of the .shp and .shx files.
+func mainHeader(typ ShapeType, size int64, b Bounds) []byte {
+	head := make([]byte, headerSize)
+	binary.BigEndian.PutUint32(head[0:], fileCode)
+	binary.BigEndian.PutUint32(head[24:], uint32(size/2))
+	binary.LittleEndian.PutUint32(head[28:], fileVersion)
+	binary.LittleEndian.PutUint32(head[32:], uint32(typ))
+	for i, v := range []float64{b.MinX, b.MinY, b.MaxX, b.MaxY} {
+		binary.LittleEndian.PutUint64(head[36+8*i:], math.Float64bits(v))
+	}
+	return head
+}
+
+// encodeShape encodes the record content of g and returns its points for
+// the file bounds.
+func (w *Writer) encodeShape(g gogis.Geometry) ([]byte, []gogis.Point, error) {
+	if g == nil {
+		return nullShape(), nil, nil
+	}
+
+	var parts [][]gogis.Point
+	switch w.shapeType {
+	case ShapeTypePoint:
+		var p *gogis.Point
+		switch v := g.(type) {
+		case *gogis.Point:
+			p = v
+		case *gogis.GeographyPoint:
+			p = (*gogis.Point)(v)
+		default:
+			return nil, nil, fmt.Errorf("cannot write %T to a Point file", g)
+		}
+		var buf bytes.Buffer
+		writeInt32(&buf, int32(ShapeTypePoint))
+		writeFloat64(&buf, p.Lng)
+		writeFloat64(&buf, p.Lat)
+		return buf.Bytes(), []gogis.Point{*p}, nil
+	case ShapeTypeMultiPoint:
+		var points []gogis.Point
+		if err := collect(g, func(g gogis.Geometry) bool {
+			p, ok := g.(*gogis.Point)
+			if ok {
+				points = append(points, *p)
+			}
+			return ok
+		}); err != nil {
+			return nil, nil, err
+		}
+		if len(points) == 0 {
+			return nullShape(), nil, nil
+		}
+		return encodeParts(ShapeTypeMultiPoint, nil, points), points, nil
+	case ShapeTypePolyLine:
+		if err := collect(g, func(g gogis.Geometry) bool {
+			ls, ok := g.(*gogis.LineString)
+			if ok && len(ls.Points) > 0 {
+				parts = append(parts, ls.Points)
+			}
+			return ok
+		}); err != nil {
+			return nil, nil, err
+		}
+	case ShapeTypePolygon:
+		if err := collect(g, func(g gogis.Geometry) bool {
+			p, ok := g.(*gogis.Polygon)
+			if !ok {
+				return false
+			}
+			for i, ring := range p.Rings {
+				if len(ring) == 0 {
+					continue
+				}
+				// Shells are clockwise, holes counter-clockwise.
+				if a := signedArea(ring); (i == 0 && a > 0) || (i > 0 && a < 0) {
+					ring = reversed(ring)
+				}
+				parts = append(parts, ring)
+			}
+			return true
+		}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(parts) == 0 {
+		return nullShape(), nil, nil
+	}
+	var points []gogis.Point
+	starts := make([]int32, len(parts))
+	for i, part := range parts {
+		starts[i] = int32(len(points))
+		points = append(points, part...)
+	}
+	return encodeParts(w.shapeType, starts, points), points, nil
+}
+
+// collect calls add for g, or for each member of a collection, with the
+// geography variants converted to the geometry types. It fails when add
+// rejects a geometry.
+func collect(g gogis.Geometry, add func(gogis.Geometry) bool) error {
+	switch v := g.(type) {
+	case *gogis.GeometryCollection:
+		for _, child := range v.Geometries {
+			if err := collect(child, add); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *gogis.GeographyCollection:
+		return collect((*gogis.GeometryCollection)(v), add)
+	case *gogis.GeographyPoint:
+		g = (*gogis.Point)(v)
+	case *gogis.GeographyLineString:
+		g = (*gogis.LineString)(v)
+	case *gogis.GeographyPolygon:
+		g = (*gogis.Polygon)(v)
+	}
+	if !add(g) {
+		return fmt.Errorf("unexpected %T", g)
+	}
+	return nil
+}
+
+func nullShape() []byte {
+	return make([]byte, 4)
+}
+
+// encodeParts encodes a multipoint, or a polyline or polygon with parts
+// starting at the given point indexes.
+func encodeParts(typ ShapeType, starts []int32, points []gogis.Point) []byte {
+	var b Bounds
+	b.extend(points, true)
+
+	var buf bytes.Buffer
+	writeInt32(&buf, int32(typ))
+	for _, v := range []float64{b.MinX, b.MinY, b.MaxX, b.MaxY} {
+		writeFloat64(&buf, v)
+	}
+	if typ != ShapeTypeMultiPoint {
+		writeInt32(&buf, int32(len(starts)))
+	}
+	writeInt32(&buf, int32(len(points)))
+	for _, s := range starts {
+		writeInt32(&buf, s)
+	}
+	for _, p := range points {
+		writeFloat64(&buf, p.Lng)
+		writeFloat64(&buf, p.Lat)
+	}
+	return buf.Bytes()
+}
+
+func writeInt32(buf *bytes.Buffer, v int32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(v))
+	buf.Write(b[:])
+}
+
+func writeFloat64(buf *bytes.Buffer, v float64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+	buf.Write(b[:])
+}
@@ -0,0 +1,231 @@
+package gogis
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"gorm.io/gorm/clause"
+)
+
+// SpatiaLite BLOB markers.
+const (
+	spatialiteStart  = 0x00
+	spatialiteMBREnd = 0x7C
+	spatialiteEntity = 0x69
+	spatialiteEnd    = 0xFE
+)
+
+// SpatiaLiteDialect writes geometries as SpatiaLite BLOBs with SRID 4326,
+// for SQLite databases used through a GORM sqlite driver. It is the default
+// dialect of the "sqlite" dialector; register GeoPackageDialect instead for
+// GeoPackage files.
+type SpatiaLiteDialect struct{}
+
+// GeometryExpr returns a parameter holding the SpatiaLite BLOB of g.
+func (SpatiaLiteDialect) GeometryExpr(g Geometry) (clause.Expr, error) {
+	b, err := EncodeSpatiaLite(g, SRIDWGS84)
+	if err != nil {
+		return clause.Expr{}, err
+	}
+	return clause.Expr{SQL: "?", Vars: []interface{}{wkbValue(b)}}, nil
+}
+
+// EncodeSpatiaLite encodes g in the SpatiaLite BLOB geometry format, as
+// stored in the geometry columns of a SpatiaLite database: a header with the
+// SRID and the minimum bounding rectangle, followed by the geometry in a
+// WKB-like layout.
+//
+// SpatiaLite collections can only hold points, line strings and polygons, so
+// nested collections are rejected. Collections whose members all have the
+// same type are written with the matching MULTIPOINT, MULTILINESTRING or
+// MULTIPOLYGON class, as SpatiaLite itself does, so that they can be stored
+// in columns of those types.
+func EncodeSpatiaLite(g Geometry, srid SRID) ([]byte, error) {
+	if v, ok := g.(*GeographyCollection); ok {
+		g = (*GeometryCollection)(v)
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(spatialiteStart)
+	buf.WriteByte(1) // little-endian
+	writeUint32(&buf, uint32(srid))
+	e, ok := envelopeOf(g)
+	if !ok {
+		e = envelope{} // SpatiaLite uses a zero MBR for empty geometries
+	}
+	writeFloat64(&buf, e.minX)
+	writeFloat64(&buf, e.minY)
+	writeFloat64(&buf, e.maxX)
+	writeFloat64(&buf, e.maxY)
+	buf.WriteByte(spatialiteMBREnd)
+
+	switch v := g.(type) {
+	case *GeometryCollection:
+		writeUint32(&buf, spatialiteCollectionClass(v))
+		writeUint32(&buf, uint32(len(v.Geometries)))
+		for i, child := range v.Geometries {
+			buf.WriteByte(spatialiteEntity)
+			if err := writeSpatiaLite(&buf, child); err != nil {
+				return nil, fmt.Errorf("geometry %d: %w", i, err)
+			}
+		}
+	default:
+		if err := writeSpatiaLite(&buf, g); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte(spatialiteEnd)
+	return buf.Bytes(), nil
+}
+
+// spatialiteCollectionClass returns the MULTI class of a collection whose
+// members all have the same type, and the GEOMETRYCOLLECTION class otherwise.
+func spatialiteCollectionClass(gc *GeometryCollection) uint32 {
+	class := uint32(GeometryTypeGeometryCollection)
+	for i, child := range gc.Geometries {
+		var c uint32
+		switch child.(type) {
+		case *Point, *GeographyPoint:
+			c = wkbMultiPoint
+		case *LineString, *GeographyLineString:
+			c = wkbMultiLineString
+		case *Polygon, *GeographyPolygon:
+			c = wkbMultiPolygon
+		default:
+			return uint32(GeometryTypeGeometryCollection)
+		}
+		if i > 0 && c != class {
+			return uint32(GeometryTypeGeometryCollection)
+		}
+		class = c
+	}
+	return class
+}
+
+// writeSpatiaLite writes the class type and body of a point, line string or
+// polygon, which share the layout of WKB after the byte order.
+func writeSpatiaLite(buf *bytes.Buffer, g Geometry) error {
+	switch g.(type) {
+	case *Point, *LineString, *Polygon, *GeographyPoint, *GeographyLineString, *GeographyPolygon:
+	case *GeometryCollection, *GeographyCollection:
+		return fmt.Errorf("nested geometry collections are not supported by SpatiaLite")
+	default:
+		return fmt.Errorf("unsupported geometry type %T", g)
+	}
+	var wkb bytes.Buffer
+	if err := writeEWKB(&wkb, g, 0); err != nil {
+		return err
+	}
+	buf.Write(wkb.Bytes()[1:])
+	return nil
+}
+
+// DecodeSpatiaLite decodes a SpatiaLite BLOB geometry and returns the
+// geometry and its SRID. Multi geometries decode to a *GeometryCollection, Z
+// and M ordinates are dropped. Compressed geometries are not supported.
+func DecodeSpatiaLite(b []byte) (Geometry, SRID, error) {
+	g, srid, err := decodeSpatiaLite(b)
+	if err != nil {
+		return nil, 0, fmt.Errorf("gogis: decoding SpatiaLite geometry: %w", err)
+	}
+	return g, srid, nil
+}
+
+// spatialiteHeaderSize is the size of the header up to the class type.
+const spatialiteHeaderSize = 39
+
+func decodeSpatiaLite(b []byte) (Geometry, SRID, error) {
+	if len(b) < spatialiteHeaderSize+5 || b[0] != spatialiteStart || b[38] != spatialiteMBREnd {
+		return nil, 0, fmt.Errorf("invalid header")
+	}
+	if b[len(b)-1] != spatialiteEnd {
+		return nil, 0, fmt.Errorf("missing end marker")
+	}
+	r := &wkbReader{data: b[:len(b)-1], pos: 2}
+	switch b[1] {
+	case 0:
+		r.order = binary.BigEndian
+	case 1:
+		r.order = binary.LittleEndian
+	default:
+		return nil, 0, fmt.Errorf("invalid byte order %d", b[1])
+	}
+	srid, _ := r.uint32()
+	r.pos = spatialiteHeaderSize
+
+	g, err := r.spatialite(false)
+	if err != nil {
+		return nil, 0, err
+	}
+	if r.pos != len(r.data) {
+		return nil, 0, fmt.Errorf("%d trailing bytes", len(r.data)-r.pos)
+	}
+	return g, SRID(srid), nil
+}
+
+// spatialite reads a class type and the geometry following it. Entities of
+// collections are preceded by a marker.
+func (r *wkbReader) spatialite(entity bool) (Geometry, error) {
+	class, err := r.uint32()
+	if err != nil {
+		return nil, err
+	}
+	if class >= 1000000 {
+		return nil, fmt.Errorf("compressed geometries are not supported")
+	}
+	dims := 2
+	switch class / 1000 {
+	case 1, 2:
+		dims = 3
+	case 3:
+		dims = 4
+	}
+
+	switch class % 1000 {
+	case uint32(GeometryTypePoint):
+		p, err := r.point(dims)
+		if err != nil {
+			return nil, err
+		}
+		return &p, nil
+	case uint32(GeometryTypeLineString):
+		points, err := r.points(dims)
+		if err != nil {
+			return nil, err
+		}
+		return &LineString{Points: points}, nil
+	case uint32(GeometryTypePolygon):
+		n, err := r.count(4)
+		if err != nil {
+			return nil, err
+		}
+		p := &Polygon{Rings: make([][]Point, n)}
+		for i := range p.Rings {
+			if p.Rings[i], err = r.points(dims); err != nil {
+				return nil, err
+			}
+		}
+		return p, nil
+	case wkbMultiPoint, wkbMultiLineString, wkbMultiPolygon, uint32(GeometryTypeGeometryCollection):
+		if entity {
+			return nil, fmt.Errorf("nested geometry collection")
+		}
+		n, err := r.count(5)
+		if err != nil {
+			return nil, err
+		}
+		gc := &GeometryCollection{Geometries: make([]Geometry, n)}
+		for i := range gc.Geometries {
+			if r.pos >= len(r.data) || r.data[r.pos] != spatialiteEntity {
+				return nil, fmt.Errorf("missing entity marker")
+			}
+			r.pos++
+			if gc.Geometries[i], err = r.spatialite(true); err != nil {
+				return nil, err
+			}
+		}
+		return gc, nil
+	default:
+		return nil, fmt.Errorf("unsupported class type %d", class)
+	}
+}
@@ -0,0 +1,145 @@
+package gogis_test
+
+import (
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/restayway/gogis"
+)
+
+func TestEncodeSpatiaLite(t *testing.T) {
+	b, err := gogis.EncodeSpatiaLite(&gogis.Point{Lng: 1, Lat: 2}, gogis.SRIDWGS84)
+	if err != nil {
+		t.Fatalf("EncodeSpatiaLite() unexpected error = %v", err)
+	}
+	// SELECT hex(MakePoint(1, 2, 4326))
+	want := "0001e6100000000000000000f03f0000000000000040000000000000f03f00000000000000407c01000000000000000000f03f0000000000000040fe"
+	if got := hex.EncodeToString(b); got != want {
+		t.Errorf("EncodeSpatiaLite() = %s, want %s", got, want)
+	}
+
+	// SELECT hex(GeomFromText('MULTIPOINT(1 2, 3 4)', 4326))
+	b, err = gogis.EncodeSpatiaLite(&gogis.GeometryCollection{Geometries: []gogis.Geometry{
+		&gogis.Point{Lng: 1, Lat: 2}, &gogis.GeographyPoint{Lng: 3, Lat: 4},
+	}}, gogis.SRIDWGS84)
+	if err != nil {
+		t.Fatalf("EncodeSpatiaLite() unexpected error = %v", err)
+	}
+	want = "0001e6100000000000000000f03f0000000000000040000000000000084000000000000010407c04000000020000006901000000000000000000f03f0000000000000040" +
+		"690100000000000000000008400000000000001040fe"
+	if got := hex.EncodeToString(b); got != want {
+		t.Errorf("EncodeSpatiaLite() of points = %s, want %s", got, want)
+	}
+
+	// Mixed and empty collections keep the GEOMETRYCOLLECTION class.
+	for _, gc := range []*gogis.GeometryCollection{
+		{Geometries: []gogis.Geometry{&gogis.Point{}, &gogis.LineString{Points: []gogis.Point{{}, {}}}}},
+		{},
+	} {
+		b, err := gogis.EncodeSpatiaLite(gc, gogis.SRIDWGS84)
+		if err != nil {
+			t.Fatalf("EncodeSpatiaLite() unexpected error = %v", err)
+		}
+		if class := b[39]; class != 7 {
+			t.Errorf("EncodeSpatiaLite(%v) class = %d, want 7", gc, class)
+		}
+	}
+
+	nested := &gogis.GeometryCollection{Geometries: []gogis.Geometry{&gogis.GeometryCollection{}}}
+	if _, err := gogis.EncodeSpatiaLite(nested, gogis.SRIDWGS84); err == nil {
+		t.Error("EncodeSpatiaLite() expected error for nested collection, got nil")
+	}
+}
+
+func TestDecodeSpatiaLite(t *testing.T) {
+	ring := []gogis.Point{{Lng: 0, Lat: 0}, {Lng: 4, Lat: 0}, {Lng: 4, Lat: 3}, {Lng: 0, Lat: 0}}
+	tests := []struct {
+		name string
+		hex  string
+		want gogis.Geometry
+	}{
+		{
+			name: "point",
+			hex:  "0001E6100000000000000000F03F0000000000000040000000000000F03F00000000000000407C01000000000000000000F03F0000000000000040FE",
+			want: &gogis.Point{Lng: 1, Lat: 2},
+		},
+		{
+			name: "big-endian point",
+			hex:  "0000000010E63FF000000000000040000000000000003FF000000000000040000000000000007C000000013FF00000000000004000000000000000FE",
+			want: &gogis.Point{Lng: 1, Lat: 2},
+		},
+		{
+			// SELECT hex(GeomFromText('POINT Z(1 2 3)', 4326))
+			name: "point z",
+			hex:  "0001E6100000000000000000F03F0000000000000040000000000000F03F00000000000000407CE9030000000000000000F03F00000000000000400000000000000840FE",
+			want: &gogis.Point{Lng: 1, Lat: 2},
+		},
+		{
+			// SELECT hex(GeomFromText('MULTIPOINT(1 2, 3 4)', 4326))
+			name: "multipoint",
+			hex: "0001E6100000000000000000F03F0000000000000040000000000000084000000000000010407C04000000020000006901000000000000000000F03F0000000000000040" +
+				"690100000000000000000008400000000000001040FE",
+			want: &gogis.GeometryCollection{Geometries: []gogis.Geometry{&gogis.Point{Lng: 1, Lat: 2}, &gogis.Point{Lng: 3, Lat: 4}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := hex.DecodeString(tt.hex)
+			g, srid, err := gogis.DecodeSpatiaLite(b)
+			if err != nil {
+				t.Fatalf("DecodeSpatiaLite() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(g, tt.want) || srid != gogis.SRIDWGS84 {
+				t.Errorf("DecodeSpatiaLite() = %v, %d, want %v", g, srid, tt.want)
+			}
+		})
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		for _, want := range []gogis.Geometry{
+			&gogis.LineString{Points: ring},
+			&gogis.Polygon{Rings: [][]gogis.Point{ring}},
+			&gogis.GeometryCollection{Geometries: []gogis.Geometry{&ring[1], &gogis.Polygon{Rings: [][]gogis.Point{ring}}}},
+		} {
+			b, err := gogis.EncodeSpatiaLite(want, 3857)
+			if err != nil {
+				t.Fatalf("EncodeSpatiaLite() unexpected error = %v", err)
+			}
+			g, srid, err := gogis.DecodeSpatiaLite(b)
+			if err != nil {
+				t.Fatalf("DecodeSpatiaLite() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(g, want) || srid != 3857 {
+				t.Errorf("DecodeSpatiaLite() = %v, %d, want %v", g, srid, want)
+			}
+		}
+	})
+}
+
+func TestDecodeSpatiaLiteErrors(t *testing.T) {
+	point := "0001E6100000000000000000F03F0000000000000040000000000000F03F00000000000000407C01000000000000000000F03F0000000000000040FE"
+	tests := []struct {
+		name string
+		hex  string
+	}{
+		{name: "too short", hex: "0001E6100000"},
+		{name: "missing mbr end", hex: strings.Replace(point, "407C01", "407B01", 1)},
+		{name: "missing end marker", hex: point[:len(point)-2] + "FF"},
+		{name: "trailing bytes", hex: point[:len(point)-2] + "00FE"},
+		{name: "truncated", hex: point[:len(point)-6] + "FE"},
+		{name: "compressed", hex: strings.Replace(point, "7C01000000", "7C4B420F00", 1)},
+		{name: "unknown class", hex: strings.Replace(point, "7C01000000", "7C09000000", 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := hex.DecodeString(tt.hex)
+			if _, _, err := gogis.DecodeSpatiaLite(b); err == nil {
+				t.Error("DecodeSpatiaLite() expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,453 @@
+package gogis
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"gorm.io/gorm/clause"
+)
+
+// Serialization properties of the SQL Server CLR format.
+const (
+	sqlServerZ                 = 0x01
+	sqlServerM                 = 0x02
+	sqlServerValid             = 0x04
+	sqlServerSinglePoint       = 0x08
+	sqlServerSingleLineSegment = 0x10
+)
+
+// Figure attributes of version 1 of the SQL Server CLR format.
+const (
+	sqlServerInteriorRing = 0
+	sqlServerStroke       = 1
+	sqlServerExteriorRing = 2
+)
+
+// SQLServerDialect writes geometries for SQL Server geometry and geography
+// columns, as the SqlGeometry or SqlGeography serialization format cast to
+// the column type. The geography variants produce geography values and the
+// other types geometry values, both with SRID 4326.
+//
+// Selecting such columns returns the same serialization format, which the
+// Scan methods of the geometry types decode. The format does not record
+// whether a value is a geometry or a geography, so geography columns must be
+// scanned into the geography variants, which read latitude-longitude order.
+// Scanning them into Point, LineString, Polygon or GeometryCollection
+// returns the axes swapped, without an error.
+type SQLServerDialect struct{}
+
+// GeometryExpr returns g as CAST(? AS geometry), or CAST(? AS geography)
+// for the geography variants.
+func (SQLServerDialect) GeometryExpr(g Geometry) (clause.Expr, error) {
+	b, err := EncodeSQLServer(g, SRIDWGS84)
+	if err != nil {
+		return clause.Expr{}, err
+	}
+	if isGeography(g) {
+		return clause.Expr{SQL: "CAST(? AS geography)", Vars: []interface{}{wkbValue(b)}}, nil
+	}
+	return clause.Expr{SQL: "CAST(? AS geometry)", Vars: []interface{}{wkbValue(b)}}, nil
+}
+
+// isGeography reports whether g is one of the geography variants.
+func isGeography(g Geometry) bool {
+	switch g.(type) {
+	case *GeographyPoint, *GeographyLineString, *GeographyPolygon, *GeographyCollection:
+		return true
+	}
+	return false
+}
+
+// EncodeSQLServer encodes g in the serialization format of the SQL Server
+// CLR types, as stored in geometry and geography columns. The geography
+// variants are encoded as SqlGeography, with points in latitude-longitude
+// order, and the other types as SqlGeometry.
+//
+// SQL Server geography takes the area to the left of a ring as its
+// interior, so the rings of geography polygons are written with exterior
+// rings counter-clockwise and holes clockwise, reversing them as needed.
+// The orientation is that of the ring in the longitude-latitude plane. The
+// geometry is flagged as valid without further checks, so SQL Server relies
+// on it having closed rings.
+func EncodeSQLServer(g Geometry, srid SRID) ([]byte, error) {
+	w := sqlServerWriter{geography: isGeography(g)}
+	if err := w.shape(g, -1); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeUint32(&buf, uint32(srid))
+	buf.WriteByte(1) // version
+	switch {
+	case len(w.shapes) == 1 && w.shapes[0].typ == GeometryTypePoint && len(w.points) == 1:
+		buf.WriteByte(sqlServerValid | sqlServerSinglePoint)
+		w.writePoints(&buf)
+		return buf.Bytes(), nil
+	case len(w.shapes) == 1 && w.shapes[0].typ == GeometryTypeLineString && len(w.points) == 2:
+		buf.WriteByte(sqlServerValid | sqlServerSingleLineSegment)
+		w.writePoints(&buf)
+		return buf.Bytes(), nil
+	}
+	buf.WriteByte(sqlServerValid)
+	writeUint32(&buf, uint32(len(w.points)))
+	w.writePoints(&buf)
+	writeUint32(&buf, uint32(len(w.figures)))
+	for _, f := range w.figures {
+		buf.WriteByte(f.attribute)
+		writeUint32(&buf, uint32(f.offset))
+	}
+	writeUint32(&buf, uint32(len(w.shapes)))
+	for _, s := range w.shapes {
+		writeUint32(&buf, uint32(s.parent))
+		writeUint32(&buf, uint32(s.figure))
+		buf.WriteByte(byte(s.typ))
+	}
+	return buf.Bytes(), nil
+}
+
+type sqlServerFigure struct {
+	attribute byte
+	offset    int32
+}
+
+type sqlServerShape struct {
+	parent, figure int32
+	typ            GeometryType
+}
+
+// sqlServerWriter flattens a geometry into the point, figure and shape
+// lists of the CLR format.
+type sqlServerWriter struct {
+	geography bool
+	points    []Point
+	figures   []sqlServerFigure
+	shapes    []sqlServerShape
+}
+
+func (w *sqlServerWriter) figure(attribute byte, points []Point) {
+	w.figures = append(w.figures, sqlServerFigure{attribute: attribute, offset: int32(len(w.points))})
+	w.points = append(w.points, points...)
+}
+
+// shape appends g and its children in preorder.
+func (w *sqlServerWriter) shape(g Geometry, parent int32) error {
+	index := len(w.shapes)
+	firstFigure := len(w.figures)
+	var typ GeometryType
+
+	switch v := g.(type) {
+	case *Point:
+		typ = GeometryTypePoint
+		w.shapes = append(w.shapes, sqlServerShape{})
+		w.figure(sqlServerStroke, []Point{*v})
+	case *LineString:
+		typ = GeometryTypeLineString
+		w.shapes = append(w.shapes, sqlServerShape{})
+		if len(v.Points) > 0 {
+			w.figure(sqlServerStroke, v.Points)
+		}
+	case *Polygon:
+		typ = GeometryTypePolygon
+		w.shapes = append(w.shapes, sqlServerShape{})
+		for i, ring := range v.Rings {
+			if w.geography {
+				ring = orientRing(ring, i == 0)
+			}
+			if i == 0 {
+				w.figure(sqlServerExteriorRing, ring)
+			} else {
+				w.figure(sqlServerInteriorRing, ring)
+			}
+		}
+	case *GeometryCollection:
+		typ = GeometryTypeGeometryCollection
+		w.shapes = append(w.shapes, sqlServerShape{})
+		for i, child := range v.Geometries {
+			if err := w.shape(child, int32(index)); err != nil {
+				return fmt.Errorf("geometry %d: %w", i, err)
+			}
+		}
+	case *GeographyPoint:
+		return w.shape((*Point)(v), parent)
+	case *GeographyLineString:
+		return w.shape((*LineString)(v), parent)
+	case *GeographyPolygon:
+		return w.shape((*Polygon)(v), parent)
+	case *GeographyCollection:
+		return w.shape((*GeometryCollection)(v), parent)
+	default:
+		return fmt.Errorf("unsupported geometry type %T", g)
+	}
+
+	figure := int32(firstFigure)
+	if len(w.figures) == firstFigure {
+		figure = -1
+	}
+	w.shapes[index] = sqlServerShape{parent: parent, figure: figure, typ: typ}
+	return nil
+}
+
+// orientRing returns ring counter-clockwise if ccw is set and clockwise
+// otherwise, reversing a copy of it when needed.
+func orientRing(ring []Point, ccw bool) []Point {
+	var area float64
+	for i := 0; i+1 < len(ring); i++ {
+		area += ring[i].Lng*ring[i+1].Lat - ring[i+1].Lng*ring[i].Lat
+	}
+	if area == 0 || (area > 0) == ccw {
+		return ring
+	}
+	reversed := make([]Point, len(ring))
+	for i, p := range ring {
+		reversed[len(ring)-1-i] = p
+	}
+	return reversed
+}
+
+func (w *sqlServerWriter) writePoints(buf *bytes.Buffer) {
+	for _, p := range w.points {
+		if w.geography {
+			writeFloat64(buf, p.Lat)
+			writeFloat64(buf, p.Lng)
+		} else {
+			writeFloat64(buf, p.Lng)
+			writeFloat64(buf, p.Lat)
+		}
+	}
+}
+
+// DecodeSQLServer decodes the serialization format of the SQL Server CLR
+// types and returns the geometry and its SRID. The format does not record
+// whether it holds a SqlGeography, whose points are in latitude-longitude
+// order, or a SqlGeometry, so the caller tells.
+//
+// Shapes decode as by DecodeEWKB: multi geometries become a
+// *GeometryCollection and Z and M values are dropped. Empty points and the
+// circular arcs of version 2 are not supported.
+func DecodeSQLServer(b []byte, geography bool) (Geometry, SRID, error) {
+	g, srid, err := decodeSQLServer(b, geography)
+	if err != nil {
+		return nil, 0, fmt.Errorf("gogis: decoding SQL Server geometry: %w", err)
+	}
+	return g, srid, nil
+}
+
+func decodeSQLServer(b []byte, geography bool) (Geometry, SRID, error) {
+	if len(b) < 6 {
+		return nil, 0, fmt.Errorf("%d bytes is too short", len(b))
+	}
+	r := &sqlServerReader{wkbReader: wkbReader{data: b, pos: 6, order: binary.LittleEndian}, geography: geography}
+	srid := SRID(binary.LittleEndian.Uint32(b))
+	version, props := b[4], b[5]
+	if version != 1 && version != 2 {
+		return nil, 0, fmt.Errorf("unsupported version %d", version)
+	}
+
+	var g Geometry
+	var err error
+	switch {
+	case props&sqlServerSinglePoint != 0:
+		var points []Point
+		if points, err = r.pointList(1, props); err == nil {
+			g = &points[0]
+		}
+	case props&sqlServerSingleLineSegment != 0:
+		var points []Point
+		if points, err = r.pointList(2, props); err == nil {
+			g = &LineString{Points: points}
+		}
+	default:
+		g, err = r.shapes(version, props)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if r.pos != len(b) {
+		return nil, 0, fmt.Errorf("%d trailing bytes", len(b)-r.pos)
+	}
+	return g, srid, nil
+}
+
+type sqlServerReader struct {
+	wkbReader
+	geography bool
+	points    []Point
+	figures   []sqlServerFigure
+	shapeList []sqlServerShape
+	children  [][]int
+}
+
+// pointList reads n points followed by their Z and M values.
+func (r *sqlServerReader) pointList(n int, props byte) ([]Point, error) {
+	points := make([]Point, n)
+	for i := range points {
+		p, err := r.point(2)
+		if err != nil {
+			return nil, err
+		}
+		if r.geography {
+			p.Lng, p.Lat = p.Lat, p.Lng
+		}
+		points[i] = p
+	}
+	for _, flag := range []byte{sqlServerZ, sqlServerM} {
+		if props&flag == 0 {
+			continue
+		}
+		if len(r.data)-r.pos < 8*n {
+			return nil, fmt.Errorf("unexpected end of data")
+		}
+		r.pos += 8 * n
+	}
+	return points, nil
+}
+
+// shapes reads the point, figure and shape lists and builds the geometry of
+// the root shape.
+func (r *sqlServerReader) shapes(version, props byte) (Geometry, error) {
+	size := 16
+	if props&sqlServerZ != 0 {
+		size += 8
+	}
+	if props&sqlServerM != 0 {
+		size += 8
+	}
+	n, err := r.count(size)
+	if err != nil {
+		return nil, err
+	}
+	if r.points, err = r.pointList(n, props); err != nil {
+		return nil, err
+	}
+
+	if n, err = r.count(5); err != nil {
+		return nil, err
+	}
+	r.figures = make([]sqlServerFigure, n)
+	for i := range r.figures {
+		attribute := r.data[r.pos]
+		r.pos++
+		offset, _ := r.uint32()
+		if version == 2 && attribute > sqlServerStroke {
+			return nil, fmt.Errorf("circular arcs are not supported")
+		}
+		if int(offset) > len(r.points) || (i > 0 && int32(offset) < r.figures[i-1].offset) {
+			return nil, fmt.Errorf("invalid point offset %d", offset)
+		}
+		r.figures[i] = sqlServerFigure{attribute: attribute, offset: int32(offset)}
+	}
+
+	if n, err = r.count(9); err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("no shapes")
+	}
+	r.shapeList = make([]sqlServerShape, n)
+	r.children = make([][]int, n)
+	for i := range r.shapeList {
+		parent, _ := r.uint32()
+		figure, _ := r.uint32()
+		typ := r.data[r.pos]
+		r.pos++
+		s := sqlServerShape{parent: int32(parent), figure: int32(figure), typ: GeometryType(typ)}
+		if (i == 0) != (s.parent == -1) || s.parent >= int32(i) {
+			return nil, fmt.Errorf("invalid parent offset %d", s.parent)
+		}
+		if s.figure < -1 || s.figure > int32(len(r.figures)) {
+			return nil, fmt.Errorf("invalid figure offset %d", s.figure)
+		}
+		if i > 0 {
+			r.children[s.parent] = append(r.children[s.parent], i)
+		}
+		r.shapeList[i] = s
+	}
+
+	if version == 2 && r.pos < len(r.data) {
+		segments, err := r.uint32()
+		if err != nil {
+			return nil, err
+		}
+		if segments != 0 {
+			return nil, fmt.Errorf("circular arcs are not supported")
+		}
+	}
+	return r.shape(0, 0)
+}
+
+// figureRange returns the figures of shape i, which extend to the first
+// figure of the next shape that has any.
+func (r *sqlServerReader) figureRange(i int) (start, end int) {
+	if r.shapeList[i].figure < 0 {
+		return 0, 0
+	}
+	start, end = int(r.shapeList[i].figure), len(r.figures)
+	for _, s := range r.shapeList[i+1:] {
+		if s.figure >= 0 {
+			end = int(s.figure)
+			break
+		}
+	}
+	if end < start {
+		return 0, 0
+	}
+	return start, end
+}
+
+// figurePoints returns the points of figure k, which extend to the first
+// point of the next figure.
+func (r *sqlServerReader) figurePoints(k int) []Point {
+	end := int32(len(r.points))
+	if k+1 < len(r.figures) {
+		end = r.figures[k+1].offset
+	}
+	return r.points[r.figures[k].offset:end:end]
+}
+
+func (r *sqlServerReader) shape(i, depth int) (Geometry, error) {
+	if depth > maxWKBDepth {
+		return nil, fmt.Errorf("geometry collections nested too deeply")
+	}
+	s := r.shapeList[i]
+	start, end := r.figureRange(i)
+
+	switch s.typ {
+	case GeometryTypePoint:
+		if end-start != 1 {
+			return nil, fmt.Errorf("empty points are not supported")
+		}
+		points := r.figurePoints(start)
+		if len(points) != 1 {
+			return nil, fmt.Errorf("point with %d coordinates", len(points))
+		}
+		return &points[0], nil
+	case GeometryTypeLineString:
+		if end-start > 1 {
+			return nil, fmt.Errorf("line string with %d figures", end-start)
+		}
+		ls := &LineString{}
+		if end-start == 1 {
+			ls.Points = r.figurePoints(start)
+		}
+		return ls, nil
+	case GeometryTypePolygon:
+		p := &Polygon{}
+		for k := start; k < end; k++ {
+			p.Rings = append(p.Rings, r.figurePoints(k))
+		}
+		return p, nil
+	case wkbMultiPoint, wkbMultiLineString, wkbMultiPolygon, GeometryTypeGeometryCollection:
+		gc := &GeometryCollection{Geometries: make([]Geometry, len(r.children[i]))}
+		for k, child := range r.children[i] {
+			g, err := r.shape(child, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			gc.Geometries[k] = g
+		}
+		return gc, nil
+	default:
+		return nil, fmt.Errorf("unsupported shape type %d", s.typ)
+	}
+}
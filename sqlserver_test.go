@@ -0,0 +1,256 @@
+package gogis_test
+
+import (
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/restayway/gogis"
+)
+
+// sqlServerPolygon is geometry::STGeomFromText('POLYGON((0 0, 1 0, 1 1, 0 0))', 4326).
+const sqlServerPolygon = "E6100000010404000000" +
+	"00000000000000000000000000000000" +
+	"000000000000F03F0000000000000000" +
+	"000000000000F03F000000000000F03F" +
+	"00000000000000000000000000000000" +
+	"010000000200000000" +
+	"01000000FFFFFFFF0000000003"
+
+func TestEncodeSQLServer(t *testing.T) {
+	tests := []struct {
+		name string
+		geom gogis.Geometry
+		want string
+	}{
+		{
+			// geometry::Point(1, 2, 4326)
+			name: "point",
+			geom: &gogis.Point{Lng: 1, Lat: 2},
+			want: "E6100000010C000000000000F03F0000000000000040",
+		},
+		{
+			// geography::Point(2, 1, 4326)
+			name: "geography point",
+			geom: &gogis.GeographyPoint{Lng: 1, Lat: 2},
+			want: "E6100000010C0000000000000040000000000000F03F",
+		},
+		{
+			name: "line segment",
+			geom: &gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}}},
+			want: "E61000000114000000000000F03F000000000000004000000000000008400000000000001040",
+		},
+		{
+			name: "polygon",
+			geom: &gogis.Polygon{Rings: [][]gogis.Point{{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 0}, {Lng: 1, Lat: 1}, {Lng: 0, Lat: 0}}}},
+			want: sqlServerPolygon,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := gogis.EncodeSQLServer(tt.geom, gogis.SRIDWGS84)
+			if err != nil {
+				t.Fatalf("EncodeSQLServer() unexpected error = %v", err)
+			}
+			if got := strings.ToUpper(hex.EncodeToString(b)); got != tt.want {
+				t.Errorf("EncodeSQLServer() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeSQLServer(t *testing.T) {
+	tests := []struct {
+		name      string
+		hex       string
+		geography bool
+		want      gogis.Geometry
+	}{
+		{
+			name: "point",
+			hex:  "E6100000010C000000000000F03F0000000000000040",
+			want: &gogis.Point{Lng: 1, Lat: 2},
+		},
+		{
+			name:      "geography point",
+			hex:       "E6100000010C0000000000000040000000000000F03F",
+			geography: true,
+			want:      &gogis.Point{Lng: 1, Lat: 2},
+		},
+		{
+			// geometry::STGeomFromText('POINT(1 2 3)', 0)
+			name: "point z",
+			hex:  "00000000010D000000000000F03F00000000000000400000000000000840",
+			want: &gogis.Point{Lng: 1, Lat: 2},
+		},
+		{
+			name: "polygon",
+			hex:  sqlServerPolygon,
+			want: &gogis.Polygon{Rings: [][]gogis.Point{{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 0}, {Lng: 1, Lat: 1}, {Lng: 0, Lat: 0}}}},
+		},
+		{
+			// geometry::STGeomFromText('MULTIPOINT((1 2), (3 4))', 4326)
+			name: "multipoint",
+			hex: "E6100000010402000000" +
+				"000000000000F03F000000000000004000000000000008400000000000001040" +
+				"02000000" + "0100000000" + "0101000000" +
+				"03000000" + "FFFFFFFF0000000004" + "000000000000000001" + "000000000100000001",
+			want: &gogis.GeometryCollection{Geometries: []gogis.Geometry{&gogis.Point{Lng: 1, Lat: 2}, &gogis.Point{Lng: 3, Lat: 4}}},
+		},
+		{
+			name: "version 2",
+			hex:  "E6100000020C000000000000F03F0000000000000040",
+			want: &gogis.Point{Lng: 1, Lat: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := hex.DecodeString(tt.hex)
+			g, _, err := gogis.DecodeSQLServer(b, tt.geography)
+			if err != nil {
+				t.Fatalf("DecodeSQLServer() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(g, tt.want) {
+				t.Errorf("DecodeSQLServer() = %v, want %v", g, tt.want)
+			}
+		})
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		outer := []gogis.Point{{Lng: 0, Lat: 0}, {Lng: 10, Lat: 0}, {Lng: 10, Lat: 10}, {Lng: 0, Lat: 0}}
+		hole := []gogis.Point{{Lng: 2, Lat: 2}, {Lng: 3, Lat: 3}, {Lng: 3, Lat: 2}, {Lng: 2, Lat: 2}}
+		want := &gogis.GeometryCollection{Geometries: []gogis.Geometry{
+			&gogis.LineString{},
+			&gogis.Polygon{Rings: [][]gogis.Point{outer, hole}},
+			&gogis.GeometryCollection{Geometries: []gogis.Geometry{&gogis.Point{Lng: 5, Lat: 6}}},
+			&gogis.LineString{Points: outer},
+		}}
+		for _, geography := range []bool{false, true} {
+			var g gogis.Geometry = want
+			if geography {
+				g = (*gogis.GeographyCollection)(want)
+			}
+			b, err := gogis.EncodeSQLServer(g, 3857)
+			if err != nil {
+				t.Fatalf("EncodeSQLServer() unexpected error = %v", err)
+			}
+			got, srid, err := gogis.DecodeSQLServer(b, geography)
+			if err != nil {
+				t.Fatalf("DecodeSQLServer() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) || srid != 3857 {
+				t.Errorf("DecodeSQLServer() = %v, %d, want %v", got, srid, want)
+			}
+		}
+	})
+}
+
+func TestDecodeSQLServerErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+	}{
+		{name: "too short", hex: "E610"},
+		{name: "version", hex: "E6100000030C000000000000F03F0000000000000040"},
+		{name: "truncated point", hex: "E6100000010C000000000000F03F"},
+		{name: "trailing bytes", hex: "E6100000010C000000000000F03F000000000000004000"},
+		{name: "point count", hex: "E610000001040A000000"},
+		{name: "no shapes", hex: "E61000000104000000000000000000000000"},
+		{name: "parent offset", hex: strings.Replace(sqlServerPolygon, "FFFFFFFF00", "0000000000", 1)},
+		{name: "figure offset", hex: strings.Replace(sqlServerPolygon, "FFFFFFFF00000000", "FFFFFFFF05000000", 1)},
+		{name: "shape type", hex: sqlServerPolygon[:len(sqlServerPolygon)-2] + "08"},
+		{name: "arc", hex: "E61000000204" + sqlServerPolygon[12:len(sqlServerPolygon)-36] + "03" + sqlServerPolygon[len(sqlServerPolygon)-34:]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := hex.DecodeString(tt.hex)
+			if _, _, err := gogis.DecodeSQLServer(b, false); err == nil {
+				t.Error("DecodeSQLServer() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestEncodeSQLServerGeographyOrientation(t *testing.T) {
+	// A clockwise exterior ring and a counter-clockwise hole.
+	cw := []gogis.Point{{Lng: 0, Lat: 0}, {Lng: 0, Lat: 4}, {Lng: 4, Lat: 4}, {Lng: 4, Lat: 0}, {Lng: 0, Lat: 0}}
+	ccwHole := []gogis.Point{{Lng: 1, Lat: 1}, {Lng: 2, Lat: 1}, {Lng: 2, Lat: 2}, {Lng: 1, Lat: 1}}
+	poly := &gogis.GeographyPolygon{Rings: [][]gogis.Point{cw, ccwHole}}
+	b, err := gogis.EncodeSQLServer(poly, gogis.SRIDWGS84)
+	if err != nil {
+		t.Fatalf("EncodeSQLServer() unexpected error = %v", err)
+	}
+	g, _, err := gogis.DecodeSQLServer(b, true)
+	if err != nil {
+		t.Fatalf("DecodeSQLServer() unexpected error = %v", err)
+	}
+	want := &gogis.Polygon{Rings: [][]gogis.Point{
+		{{Lng: 0, Lat: 0}, {Lng: 4, Lat: 0}, {Lng: 4, Lat: 4}, {Lng: 0, Lat: 4}, {Lng: 0, Lat: 0}},
+		{{Lng: 1, Lat: 1}, {Lng: 2, Lat: 2}, {Lng: 2, Lat: 1}, {Lng: 1, Lat: 1}},
+	}}
+	if !reflect.DeepEqual(g, want) {
+		t.Errorf("geography rings = %v, want %v", g, want)
+	}
+	if !reflect.DeepEqual(poly.Rings[0], cw) {
+		t.Errorf("EncodeSQLServer() modified its argument: %v", poly.Rings[0])
+	}
+
+	// Geometry rings are written as given.
+	b, _ = gogis.EncodeSQLServer(&gogis.Polygon{Rings: [][]gogis.Point{cw}}, gogis.SRIDWGS84)
+	if g, _, _ := gogis.DecodeSQLServer(b, false); !reflect.DeepEqual(g, &gogis.Polygon{Rings: [][]gogis.Point{cw}}) {
+		t.Errorf("geometry rings = %v, want %v", g, cw)
+	}
+}
+
+func TestScanSQLServer(t *testing.T) {
+	b, _ := hex.DecodeString("E6100000010C0000000000000040000000000000F03F")
+
+	var gp gogis.GeographyPoint
+	if err := gp.Scan(b); err != nil {
+		t.Fatalf("GeographyPoint.Scan() unexpected error = %v", err)
+	}
+	if gp != (gogis.GeographyPoint{Lng: 1, Lat: 2}) {
+		t.Errorf("GeographyPoint.Scan() = %v, want POINT(1 2)", &gp)
+	}
+
+	var p gogis.Point
+	if err := p.Scan(b); err != nil {
+		t.Fatalf("Point.Scan() unexpected error = %v", err)
+	}
+	if p != (gogis.Point{Lng: 2, Lat: 1}) {
+		t.Errorf("Point.Scan() = %v, want POINT(2 1)", &p)
+	}
+
+	// The format does not tell geography from geometry: a geography line
+	// string scans correctly into GeographyLineString, and with its axes
+	// swapped into LineString.
+	line := gogis.GeographyLineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}}}
+	b, err := gogis.EncodeSQLServer(&line, gogis.SRIDWGS84)
+	if err != nil {
+		t.Fatalf("EncodeSQLServer() unexpected error = %v", err)
+	}
+	var gl gogis.GeographyLineString
+	if err := gl.Scan(b); err != nil || !reflect.DeepEqual(gl, line) {
+		t.Errorf("GeographyLineString.Scan() = %v, %v, want %v", &gl, err, &line)
+	}
+	var l gogis.LineString
+	if err := l.Scan(b); err != nil {
+		t.Fatalf("LineString.Scan() unexpected error = %v", err)
+	}
+	if want := []gogis.Point{{Lng: 2, Lat: 1}, {Lng: 4, Lat: 3}}; !reflect.DeepEqual(l.Points, want) {
+		t.Errorf("LineString.Scan() = %v, want swapped axes %v", &l, want)
+	}
+
+	var poly gogis.Polygon
+	b, _ = hex.DecodeString(sqlServerPolygon)
+	if err := poly.Scan(b); err != nil {
+		t.Fatalf("Polygon.Scan() unexpected error = %v", err)
+	}
+	if len(poly.Rings) != 1 || len(poly.Rings[0]) != 4 {
+		t.Errorf("Polygon.Scan() = %v", &poly)
+	}
+}
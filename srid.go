@@ -0,0 +1,19 @@
+package gogis
+
+// SRID is a Spatial Reference System Identifier, usually the EPSG code of a
+// coordinate reference system.
+//
+// The geometry types in this package always describe their coordinates as
+// SRID 4326. Other systems are used when transforming geometries with the
+// proj subpackage, for example to do planar math in meters.
+type SRID int
+
+const (
+	// SRIDWGS84 is EPSG:4326, longitude and latitude in decimal degrees on
+	// the WGS 84 datum. This is the SRID of all gogis geometry values.
+	SRIDWGS84 SRID = 4326
+
+	// SRIDWebMercator is EPSG:3857, the spherical Mercator projection used by
+	// web maps, in meters.
+	SRIDWebMercator SRID = 3857
+)
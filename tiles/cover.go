@@ -0,0 +1,167 @@
+package tiles
+
+import (
+	"math"
+	"sort"
+
+	"github.com/restayway/gogis"
+)
+
+// Cover returns every tile at zoom z that a geometry touches, sorted by row
+// and then column.
+//
+// Points map to the tile containing them, LineStrings to every tile their
+// segments pass through and Polygons to every tile overlapping their area,
+// holes excluded. GeometryCollections return the union of their members, and
+// the geography variants cover like their geometry counterparts.
+// Edges are treated as straight lines in Web Mercator, matching how they are
+// drawn on a web map.
+//
+// The number of tiles grows with the square of the zoom level for polygons,
+// so callers covering large areas should pick z accordingly.
+func Cover(g gogis.Geometry, z uint32) []Tile {
+	c := &cover{z: clampZoom(z), seen: map[Tile]bool{}}
+	c.geometry(g)
+
+	sort.Slice(c.tiles, func(i, j int) bool {
+		if c.tiles[i].Y != c.tiles[j].Y {
+			return c.tiles[i].Y < c.tiles[j].Y
+		}
+		return c.tiles[i].X < c.tiles[j].X
+	})
+	return c.tiles
+}
+
+// cover collects the tiles of a single Cover call without duplicates.
+type cover struct {
+	z     uint32
+	seen  map[Tile]bool
+	tiles []Tile
+}
+
+func (c *cover) add(x, y float64) {
+	t := Tile{X: clampIndex(x, c.z), Y: clampIndex(y, c.z), Z: c.z}
+	if !c.seen[t] {
+		c.seen[t] = true
+		c.tiles = append(c.tiles, t)
+	}
+}
+
+func (c *cover) geometry(g gogis.Geometry) {
+	switch v := g.(type) {
+	case *gogis.Point:
+		c.add(fraction(*v, c.z))
+	case *gogis.LineString:
+		c.line(c.project(v.Points))
+	case *gogis.Polygon:
+		c.polygon(v.Rings)
+	case *gogis.GeometryCollection:
+		for _, child := range v.Geometries {
+			c.geometry(child)
+		}
+	case *gogis.GeographyPoint:
+		c.geometry((*gogis.Point)(v))
+	case *gogis.GeographyLineString:
+		c.geometry((*gogis.LineString)(v))
+	case *gogis.GeographyPolygon:
+		c.geometry((*gogis.Polygon)(v))
+	case *gogis.GeographyCollection:
+		c.geometry((*gogis.GeometryCollection)(v))
+	}
+}
+
+type vertex struct {
+	x, y float64
+}
+
+func (c *cover) project(points []gogis.Point) []vertex {
+	vs := make([]vertex, len(points))
+	for i, p := range points {
+		vs[i].x, vs[i].y = fraction(p, c.z)
+	}
+	return vs
+}
+
+// line adds every tile crossed by the polyline using a grid traversal.
+func (c *cover) line(vs []vertex) {
+	if len(vs) == 1 {
+		c.add(vs[0].x, vs[0].y)
+	}
+	for i := 1; i < len(vs); i++ {
+		c.segment(vs[i-1], vs[i])
+	}
+}
+
+// segment walks the tiles between a and b, stepping into the neighbouring
+// column or row whose boundary the segment crosses first.
+func (c *cover) segment(a, b vertex) {
+	dx, dy := b.x-a.x, b.y-a.y
+	x, y := math.Floor(a.x), math.Floor(a.y)
+	stepX, tMaxX, tDeltaX := gridStep(a.x, x, dx)
+	stepY, tMaxY, tDeltaY := gridStep(a.y, y, dy)
+
+	c.add(x, y)
+	for tMaxX <= 1 || tMaxY <= 1 {
+		if tMaxX < tMaxY {
+			tMaxX += tDeltaX
+			x += stepX
+		} else {
+			tMaxY += tDeltaY
+			y += stepY
+		}
+		c.add(x, y)
+	}
+}
+
+// gridStep returns the direction of travel along one axis, the parameter at
+// which the first cell boundary is crossed and the parameter distance between
+// consecutive boundaries.
+func gridStep(start, cell, delta float64) (step, tMax, tDelta float64) {
+	switch {
+	case delta > 0:
+		return 1, (cell + 1 - start) / delta, 1 / delta
+	case delta < 0:
+		return -1, (start - cell) / -delta, 1 / -delta
+	default:
+		return 0, math.Inf(1), math.Inf(1)
+	}
+}
+
+// polygon adds the tiles along every ring and fills the interior row by row
+// using the even-odd rule, so holes are left empty.
+func (c *cover) polygon(rings [][]gogis.Point) {
+	crossings := map[int][]float64{}
+	for _, ring := range rings {
+		vs := c.project(ring)
+		if len(vs) == 0 {
+			continue
+		}
+		if vs[0] != vs[len(vs)-1] {
+			vs = append(vs, vs[0])
+		}
+		c.line(vs)
+
+		// Record where each edge crosses the horizontal center line of a row.
+		for i := 1; i < len(vs); i++ {
+			a, b := vs[i-1], vs[i]
+			if a.y == b.y {
+				continue
+			}
+			lo, hi := math.Min(a.y, b.y), math.Max(a.y, b.y)
+			for row := math.Ceil(lo - 0.5); row+0.5 < hi; row++ {
+				cy := row + 0.5
+				x := a.x + (cy-a.y)*(b.x-a.x)/(b.y-a.y)
+				crossings[int(row)] = append(crossings[int(row)], x)
+			}
+		}
+	}
+
+	for row, xs := range crossings {
+		sort.Float64s(xs)
+		for i := 0; i+1 < len(xs); i += 2 {
+			for x := math.Floor(xs[i]) + 1; x < math.Floor(xs[i+1]); x++ {
+				c.add(x, float64(row))
+			}
+		}
+	}
+}
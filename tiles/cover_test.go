@@ -0,0 +1,108 @@
+package tiles_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/tiles"
+)
+
+func TestCoverPoint(t *testing.T) {
+	p := &gogis.Point{Lng: -73.9857, Lat: 40.7484}
+	got := tiles.Cover(p, 12)
+	want := []tiles.Tile{{X: 1206, Y: 1539, Z: 12}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Cover() = %v, want %v", got, want)
+	}
+}
+
+func TestCoverLineString(t *testing.T) {
+	// A line from the north-west to the south-east tile crosses the
+	// prime meridian north of the equator.
+	line := &gogis.LineString{
+		Points: []gogis.Point{
+			{Lng: -90, Lat: 45},
+			{Lng: 90, Lat: -10},
+		},
+	}
+	got := tiles.Cover(line, 1)
+	want := []tiles.Tile{{X: 0, Y: 0, Z: 1}, {X: 1, Y: 0, Z: 1}, {X: 1, Y: 1, Z: 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Cover() = %v, want %v", got, want)
+	}
+
+	// A horizontal line crosses every column of a row.
+	row := &gogis.LineString{
+		Points: []gogis.Point{
+			{Lng: -170, Lat: 10},
+			{Lng: 170, Lat: 10},
+		},
+	}
+	if got := tiles.Cover(row, 3); len(got) != 8 {
+		t.Errorf("Cover() returned %d tiles, want 8", len(got))
+	}
+}
+
+func TestCoverPolygon(t *testing.T) {
+	tile := tiles.Tile{X: 5, Y: 5, Z: 4}
+	bounds := tile.Bounds()
+
+	// The bounds of a single tile cover its four descendants one level down
+	// (edges shared with neighbours also touch the tiles next to them).
+	got := tiles.Cover(&bounds, 5)
+	for _, child := range tile.Children() {
+		found := false
+		for _, g := range got {
+			if g == child {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Cover() = %v, missing child %v", got, child)
+		}
+	}
+
+	// A polygon with a hole leaves the tiles inside the hole uncovered.
+	polygon := &gogis.Polygon{
+		Rings: [][]gogis.Point{
+			{{Lng: -100, Lat: -60}, {Lng: 100, Lat: -60}, {Lng: 100, Lat: 60}, {Lng: -100, Lat: 60}, {Lng: -100, Lat: -60}},
+			{{Lng: -50, Lat: -30}, {Lng: 50, Lat: -30}, {Lng: 50, Lat: 30}, {Lng: -50, Lat: 30}, {Lng: -50, Lat: -30}},
+		},
+	}
+	covered := map[tiles.Tile]bool{}
+	for _, tile := range tiles.Cover(polygon, 5) {
+		covered[tile] = true
+	}
+	if covered[tiles.At(gogis.Point{Lng: 0, Lat: 0}, 5)] {
+		t.Errorf("Cover() includes the tile in the middle of the hole")
+	}
+	if !covered[tiles.At(gogis.Point{Lng: -75, Lat: 0}, 5)] {
+		t.Errorf("Cover() is missing an interior tile")
+	}
+	if covered[tiles.At(gogis.Point{Lng: 150, Lat: 0}, 5)] {
+		t.Errorf("Cover() includes a tile outside the polygon")
+	}
+}
+
+func TestCoverGeometryCollection(t *testing.T) {
+	gc := &gogis.GeometryCollection{
+		Geometries: []gogis.Geometry{
+			&gogis.Point{Lng: 10, Lat: 10},
+			&gogis.Point{Lng: 10.0001, Lat: 10.0001},
+			&gogis.Point{Lng: -10, Lat: -10},
+		},
+	}
+	got := tiles.Cover(gc, 2)
+	want := []tiles.Tile{{X: 2, Y: 1, Z: 2}, {X: 1, Y: 2, Z: 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Cover() = %v, want %v", got, want)
+	}
+	if got := tiles.Cover((*gogis.GeographyCollection)(gc), 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("Cover() of GeographyCollection = %v, want %v", got, want)
+	}
+
+	if got := tiles.Cover(&gogis.GeometryCollection{}, 4); len(got) != 0 {
+		t.Errorf("Cover() of empty collection = %v, want none", got)
+	}
+}
@@ -0,0 +1,233 @@
+// Package tiles provides web map tile math for gogis geometries.
+//
+// Tiles follow the XYZ scheme used by OpenStreetMap, Google Maps and most web
+// map libraries: the world in Web Mercator (EPSG:3857) is split into 2^z by
+// 2^z tiles at zoom z, with X growing eastwards and Y growing southwards from
+// the top-left corner. Helpers are provided for the TMS scheme (Y growing
+// northwards) and for Bing Maps quadkeys.
+//
+// Example:
+//
+//	t := tiles.At(gogis.Point{Lng: -73.9857, Lat: 40.7484}, 12)
+//	fmt.Println(t)           // "12/1206/1539"
+//	fmt.Println(t.Quadkey()) // "032010110132"
+//	bounds := t.Bounds()     // gogis.Polygon in EPSG:4326
+package tiles
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/restayway/gogis"
+)
+
+const (
+	// MaxZoom is the deepest zoom level supported by the package.
+	MaxZoom = 31
+
+	// MaxLatitude is the latitude at which Web Mercator is cut off so that the
+	// world projects onto a square.
+	MaxLatitude = 85.05112877980659
+
+	// originShift is half the circumference of the Web Mercator sphere in
+	// meters, i.e. the largest absolute EPSG:3857 coordinate.
+	originShift = math.Pi * 6378137
+)
+
+// Tile identifies a single XYZ map tile.
+type Tile struct {
+	X uint32 `json:"x"` // Column, from 0 at the antimeridian eastwards
+	Y uint32 `json:"y"` // Row, from 0 at the top (north) southwards
+	Z uint32 `json:"z"` // Zoom level
+}
+
+// At returns the tile at zoom z containing the point.
+//
+// Latitudes beyond ±MaxLatitude are clamped to the first or last row, and
+// zoom levels beyond MaxZoom are clamped to MaxZoom.
+func At(p gogis.Point, z uint32) Tile {
+	z = clampZoom(z)
+	x, y := fraction(p, z)
+	return Tile{X: clampIndex(x, z), Y: clampIndex(y, z), Z: z}
+}
+
+// FromQuadkey parses a Bing Maps quadkey. The zoom level is the length of the
+// quadkey.
+func FromQuadkey(quadkey string) (Tile, error) {
+	if len(quadkey) > MaxZoom {
+		return Tile{}, fmt.Errorf("quadkey too long: %d digits", len(quadkey))
+	}
+	t := Tile{Z: uint32(len(quadkey))}
+	for i, c := range quadkey {
+		mask := uint32(1) << uint(len(quadkey)-i-1)
+		switch c {
+		case '0':
+		case '1':
+			t.X |= mask
+		case '2':
+			t.Y |= mask
+		case '3':
+			t.X |= mask
+			t.Y |= mask
+		default:
+			return Tile{}, fmt.Errorf("invalid quadkey digit %q", c)
+		}
+	}
+	return t, nil
+}
+
+// String returns the tile as "z/x/y".
+func (t Tile) String() string {
+	return fmt.Sprintf("%d/%d/%d", t.Z, t.X, t.Y)
+}
+
+// Valid reports whether the tile lies within the tile grid of its zoom level.
+func (t Tile) Valid() bool {
+	if t.Z > MaxZoom {
+		return false
+	}
+	n := uint64(1) << t.Z
+	return uint64(t.X) < n && uint64(t.Y) < n
+}
+
+// Quadkey returns the Bing Maps quadkey of the tile.
+func (t Tile) Quadkey() string {
+	b := make([]byte, t.Z)
+	for i := t.Z; i > 0; i-- {
+		digit := byte('0')
+		mask := uint32(1) << (i - 1)
+		if t.X&mask != 0 {
+			digit++
+		}
+		if t.Y&mask != 0 {
+			digit += 2
+		}
+		b[t.Z-i] = digit
+	}
+	return string(b)
+}
+
+// TMS converts between the XYZ and TMS tile schemes by flipping the Y axis.
+// Applying it twice returns the original tile.
+func (t Tile) TMS() Tile {
+	return Tile{X: t.X, Y: uint32((uint64(1) << t.Z) - 1 - uint64(t.Y)), Z: t.Z}
+}
+
+// Parent returns the tile one zoom level up containing this tile. The parent
+// of a zoom 0 tile is the tile itself.
+func (t Tile) Parent() Tile {
+	if t.Z == 0 {
+		return t
+	}
+	return Tile{X: t.X >> 1, Y: t.Y >> 1, Z: t.Z - 1}
+}
+
+// Children returns the four tiles one zoom level down, in quadkey order
+// (top-left, top-right, bottom-left, bottom-right). Tiles at MaxZoom have no
+// children and return nil.
+func (t Tile) Children() []Tile {
+	if t.Z >= MaxZoom {
+		return nil
+	}
+	x, y, z := t.X<<1, t.Y<<1, t.Z+1
+	return []Tile{
+		{X: x, Y: y, Z: z},
+		{X: x + 1, Y: y, Z: z},
+		{X: x, Y: y + 1, Z: z},
+		{X: x + 1, Y: y + 1, Z: z},
+	}
+}
+
+// Contains reports whether other is this tile or one of its descendants.
+func (t Tile) Contains(other Tile) bool {
+	if other.Z < t.Z {
+		return false
+	}
+	shift := other.Z - t.Z
+	return other.X>>shift == t.X && other.Y>>shift == t.Y
+}
+
+// Center returns the center of the tile in EPSG:4326.
+func (t Tile) Center() gogis.Point {
+	n := float64(uint64(1) << t.Z)
+	return gogis.Point{
+		Lng: tileToLng(float64(t.X)+0.5, n),
+		Lat: tileToLat(float64(t.Y)+0.5, n),
+	}
+}
+
+// Bounds returns the extent of the tile as a Polygon in EPSG:4326 (longitude
+// and latitude in decimal degrees).
+func (t Tile) Bounds() gogis.Polygon {
+	n := float64(uint64(1) << t.Z)
+	return rectangle(
+		tileToLng(float64(t.X), n),
+		tileToLat(float64(t.Y)+1, n),
+		tileToLng(float64(t.X)+1, n),
+		tileToLat(float64(t.Y), n),
+	)
+}
+
+// MercatorBounds returns the extent of the tile as a Polygon in EPSG:3857.
+//
+// The Lng and Lat fields of the returned points hold Web Mercator X and Y
+// coordinates in meters rather than degrees.
+func (t Tile) MercatorBounds() gogis.Polygon {
+	size := 2 * originShift / float64(uint64(1)<<t.Z)
+	minX := -originShift + float64(t.X)*size
+	maxY := originShift - float64(t.Y)*size
+	return rectangle(minX, maxY-size, minX+size, maxY)
+}
+
+// rectangle returns a closed, counter-clockwise Polygon for the given extent.
+func rectangle(minX, minY, maxX, maxY float64) gogis.Polygon {
+	return gogis.Polygon{
+		Rings: [][]gogis.Point{
+			{
+				{Lng: minX, Lat: minY},
+				{Lng: maxX, Lat: minY},
+				{Lng: maxX, Lat: maxY},
+				{Lng: minX, Lat: maxY},
+				{Lng: minX, Lat: minY},
+			},
+		},
+	}
+}
+
+// fraction returns the fractional tile coordinates of p at zoom z.
+func fraction(p gogis.Point, z uint32) (x, y float64) {
+	n := float64(uint64(1) << z)
+	lat := math.Max(-MaxLatitude, math.Min(MaxLatitude, p.Lat)) * math.Pi / 180
+	x = (p.Lng + 180) / 360 * n
+	y = (1 - math.Log(math.Tan(lat)+1/math.Cos(lat))/math.Pi) / 2 * n
+	return x, y
+}
+
+func tileToLng(x, n float64) float64 {
+	return x/n*360 - 180
+}
+
+func tileToLat(y, n float64) float64 {
+	return math.Atan(math.Sinh(math.Pi*(1-2*y/n))) * 180 / math.Pi
+}
+
+// clampIndex converts a fractional tile coordinate to a tile index within the
+// grid of zoom z.
+func clampIndex(v float64, z uint32) uint32 {
+	max := float64(uint64(1)<<z) - 1
+	v = math.Floor(v)
+	if v < 0 || math.IsNaN(v) {
+		return 0
+	}
+	if v > max {
+		return uint32(max)
+	}
+	return uint32(v)
+}
+
+func clampZoom(z uint32) uint32 {
+	if z > MaxZoom {
+		return MaxZoom
+	}
+	return z
+}
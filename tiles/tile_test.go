@@ -0,0 +1,162 @@
+package tiles_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/restayway/gogis"
+	"github.com/restayway/gogis/tiles"
+)
+
+func TestAt(t *testing.T) {
+	tests := []struct {
+		name     string
+		point    gogis.Point
+		zoom     uint32
+		expected tiles.Tile
+	}{
+		{
+			name:     "world tile",
+			point:    gogis.Point{Lng: 12.5, Lat: 41.9},
+			zoom:     0,
+			expected: tiles.Tile{X: 0, Y: 0, Z: 0},
+		},
+		{
+			name:     "empire state building",
+			point:    gogis.Point{Lng: -73.9857, Lat: 40.7484},
+			zoom:     12,
+			expected: tiles.Tile{X: 1206, Y: 1539, Z: 12},
+		},
+		{
+			name:     "south east quadrant",
+			point:    gogis.Point{Lng: 151.2093, Lat: -33.8688},
+			zoom:     1,
+			expected: tiles.Tile{X: 1, Y: 1, Z: 1},
+		},
+		{
+			name:     "latitude beyond mercator limit",
+			point:    gogis.Point{Lng: 180, Lat: 89.9},
+			zoom:     3,
+			expected: tiles.Tile{X: 7, Y: 0, Z: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tiles.At(tt.point, tt.zoom); got != tt.expected {
+				t.Errorf("At() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestQuadkey(t *testing.T) {
+	tile := tiles.Tile{X: 1206, Y: 1539, Z: 12}
+	if got := tile.Quadkey(); got != "032010110132" {
+		t.Errorf("Quadkey() = %v, want 032010110132", got)
+	}
+
+	parsed, err := tiles.FromQuadkey("032010110132")
+	if err != nil {
+		t.Fatalf("FromQuadkey() unexpected error = %v", err)
+	}
+	if parsed != tile {
+		t.Errorf("FromQuadkey() = %v, want %v", parsed, tile)
+	}
+
+	if got := (tiles.Tile{}).Quadkey(); got != "" {
+		t.Errorf("zoom 0 Quadkey() = %q, want empty", got)
+	}
+	if _, err := tiles.FromQuadkey("0124"); err == nil {
+		t.Errorf("FromQuadkey() expected error for invalid digit, got nil")
+	}
+}
+
+func TestTMS(t *testing.T) {
+	tile := tiles.Tile{X: 1206, Y: 1539, Z: 12}
+	tms := tile.TMS()
+	if tms != (tiles.Tile{X: 1206, Y: 2556, Z: 12}) {
+		t.Errorf("TMS() = %v, want 12/1206/2556", tms)
+	}
+	if tms.TMS() != tile {
+		t.Errorf("TMS().TMS() = %v, want %v", tms.TMS(), tile)
+	}
+}
+
+func TestParentChildren(t *testing.T) {
+	tile := tiles.Tile{X: 1206, Y: 1539, Z: 12}
+	parent := tile.Parent()
+	if parent != (tiles.Tile{X: 603, Y: 769, Z: 11}) {
+		t.Errorf("Parent() = %v, want 11/603/769", parent)
+	}
+	if !parent.Contains(tile) {
+		t.Errorf("Parent().Contains() = false, want true")
+	}
+
+	children := parent.Children()
+	if len(children) != 4 {
+		t.Fatalf("Children() returned %d tiles, want 4", len(children))
+	}
+	found := false
+	for _, child := range children {
+		if child.Parent() != parent {
+			t.Errorf("child %v has parent %v, want %v", child, child.Parent(), parent)
+		}
+		if child == tile {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Children() = %v, want to include %v", children, tile)
+	}
+
+	root := tiles.Tile{}
+	if root.Parent() != root {
+		t.Errorf("zoom 0 Parent() = %v, want itself", root.Parent())
+	}
+	if (tiles.Tile{Z: tiles.MaxZoom}).Children() != nil {
+		t.Errorf("MaxZoom Children() should be nil")
+	}
+}
+
+func TestBounds(t *testing.T) {
+	tile := tiles.Tile{X: 1, Y: 0, Z: 1}
+
+	bounds := tile.Bounds()
+	ring := bounds.Rings[0]
+	if len(ring) != 5 || ring[0] != ring[4] {
+		t.Fatalf("Bounds() ring = %v, want closed ring of 5 points", ring)
+	}
+	if ring[0].Lng != 0 || absFloat(ring[0].Lat) > 1e-9 {
+		t.Errorf("Bounds() min corner = %v, want (0 0)", ring[0])
+	}
+	if ring[2].Lng != 180 || absFloat(ring[2].Lat-tiles.MaxLatitude) > 1e-9 {
+		t.Errorf("Bounds() max corner = %v, want (180 %v)", ring[2], tiles.MaxLatitude)
+	}
+
+	merc := tile.MercatorBounds().Rings[0]
+	if absFloat(merc[0].Lng) > 1e-6 || absFloat(merc[0].Lat) > 1e-6 {
+		t.Errorf("MercatorBounds() min corner = %v, want (0 0)", merc[0])
+	}
+	if absFloat(merc[2].Lng-20037508.342789244) > 1e-6 || absFloat(merc[2].Lat-20037508.342789244) > 1e-6 {
+		t.Errorf("MercatorBounds() max corner = %v, want (20037508.34 20037508.34)", merc[2])
+	}
+
+	center := tile.Center()
+	if tiles.At(center, tile.Z) != tile {
+		t.Errorf("Center() %v maps to %v, want %v", center, tiles.At(center, tile.Z), tile)
+	}
+}
+
+func TestValid(t *testing.T) {
+	if !(tiles.Tile{X: 3, Y: 3, Z: 2}).Valid() {
+		t.Errorf("2/3/3 Valid() = false, want true")
+	}
+	if (tiles.Tile{X: 4, Y: 0, Z: 2}).Valid() {
+		t.Errorf("2/4/0 Valid() = true, want false")
+	}
+}
+
+func absFloat(x float64) float64 {
+	return math.Abs(x)
+}
@@ -0,0 +1,547 @@
+package gogis
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// TWKB geometry types and metadata flags.
+const (
+	twkbPoint           = 1
+	twkbLineString      = 2
+	twkbPolygon         = 3
+	twkbMultiPoint      = 4
+	twkbMultiLineString = 5
+	twkbMultiPolygon    = 6
+	twkbCollection      = 7
+
+	twkbHasBBox  = 0x01
+	twkbHasSize  = 0x02
+	twkbHasIDs   = 0x04
+	twkbExtended = 0x08
+	twkbEmpty    = 0x10
+)
+
+// twkbMaxQuantity bounds quantized coordinates, keeping deltas within int64.
+const twkbMaxQuantity = 1 << 62
+
+// TWKBOptions controls the output of EncodeTWKB.
+type TWKBOptions struct {
+	// Precision is the number of decimal digits kept, from -8 to 7.
+	// Coordinates are rounded to multiples of 10^-Precision, so 5 keeps
+	// about a meter in degrees and -2 rounds projected meters to hundreds.
+	Precision int
+
+	// BBox adds the bounding box of every geometry.
+	BBox bool
+
+	// Size adds the size in bytes of every geometry, which lets readers
+	// skip geometries without decoding them.
+	Size bool
+
+	// IDs are the ids of the members of a collection, one per member.
+	IDs []int64
+}
+
+// EncodeTWKB encodes g as Tiny Well-Known Binary, the compressed format of
+// PostGIS ST_AsTWKB. Coordinates are quantized to the given precision and
+// stored as variable length deltas.
+//
+// Collections whose members are all points, all line strings or all polygons
+// are written as MultiPoint, MultiLineString and MultiPolygon, as PostGIS
+// does for multi geometries. Like PostGIS, repeated points that quantize to
+// the same position are dropped from line strings and rings, keeping the
+// minimum of 2 and 4 points. The output matches ST_AsTWKB for the same
+// geometry and options.
+func EncodeTWKB(g Geometry, opts TWKBOptions) ([]byte, error) {
+	if opts.Precision < -8 || opts.Precision > 7 {
+		return nil, fmt.Errorf("gogis: encoding TWKB: precision %d out of range", opts.Precision)
+	}
+	w := &twkbWriter{opts: opts, factor: math.Pow10(opts.Precision)}
+	b, _, err := w.geometry(g, opts.IDs)
+	if err != nil {
+		return nil, fmt.Errorf("gogis: encoding TWKB: %w", err)
+	}
+	return b, nil
+}
+
+type twkbWriter struct {
+	opts   TWKBOptions
+	factor float64
+}
+
+// twkbBox is the bounding box of quantized coordinates.
+type twkbBox struct {
+	min, max [2]int64
+	valid    bool
+}
+
+func (b *twkbBox) add(x, y int64) {
+	if !b.valid {
+		b.min, b.max, b.valid = [2]int64{x, y}, [2]int64{x, y}, true
+		return
+	}
+	for i, v := range [2]int64{x, y} {
+		if v < b.min[i] {
+			b.min[i] = v
+		}
+		if v > b.max[i] {
+			b.max[i] = v
+		}
+	}
+}
+
+func (b *twkbBox) merge(o twkbBox) {
+	if o.valid {
+		b.add(o.min[0], o.min[1])
+		b.add(o.max[0], o.max[1])
+	}
+}
+
+// twkbBody accumulates the body of a geometry, whose coordinates are deltas
+// from the previous point of the same geometry.
+type twkbBody struct {
+	buf  []byte
+	prev [2]int64
+	box  twkbBox
+}
+
+// geometry returns the full TWKB of g with its bounding box.
+func (w *twkbWriter) geometry(g Geometry, ids []int64) ([]byte, twkbBox, error) {
+	body := &twkbBody{}
+	var typ byte
+	empty := false
+
+	switch v := normalizeGeography(g).(type) {
+	case *Point:
+		typ = twkbPoint
+		if err := w.points(body, []Point{*v}, 1, false); err != nil {
+			return nil, twkbBox{}, err
+		}
+	case *LineString:
+		typ, empty = twkbLineString, len(v.Points) == 0
+		if err := w.points(body, v.Points, 2, true); err != nil {
+			return nil, twkbBox{}, err
+		}
+	case *Polygon:
+		typ, empty = twkbPolygon, len(v.Rings) == 0
+		if err := w.polygon(body, v); err != nil {
+			return nil, twkbBox{}, err
+		}
+	case *GeometryCollection:
+		if len(ids) > 0 && len(ids) != len(v.Geometries) {
+			return nil, twkbBox{}, fmt.Errorf("%d ids for %d geometries", len(ids), len(v.Geometries))
+		}
+		typ, empty = twkbMultiType(v), len(v.Geometries) == 0
+		body.buf = binary.AppendUvarint(body.buf, uint64(len(v.Geometries)))
+		for _, id := range ids {
+			body.buf = binary.AppendVarint(body.buf, id)
+		}
+		// The parts of multi geometries share one delta chain, while the
+		// members of collections are complete geometries.
+		for i, child := range v.Geometries {
+			var err error
+			if typ == twkbCollection {
+				var b []byte
+				var box twkbBox
+				if b, box, err = w.geometry(child, nil); err == nil {
+					body.buf = append(body.buf, b...)
+					body.box.merge(box)
+				}
+			} else {
+				switch c := normalizeGeography(child).(type) {
+				case *Point:
+					err = w.points(body, []Point{*c}, 1, false)
+				case *LineString:
+					err = w.points(body, c.Points, 2, true)
+				case *Polygon:
+					err = w.polygon(body, c)
+				}
+			}
+			if err != nil {
+				return nil, twkbBox{}, fmt.Errorf("geometry %d: %w", i, err)
+			}
+		}
+	default:
+		return nil, twkbBox{}, fmt.Errorf("unsupported geometry type %T", g)
+	}
+	if len(ids) > 0 && typ < twkbMultiPoint {
+		return nil, twkbBox{}, fmt.Errorf("ids given for a %T", g)
+	}
+
+	zigzag := byte((w.opts.Precision << 1) ^ (w.opts.Precision >> 31))
+	out := []byte{typ | zigzag<<4, 0}
+	if empty {
+		out[1] = twkbEmpty
+		if w.opts.Size {
+			out[1] |= twkbHasSize
+			out = append(out, 0)
+		}
+		return out, twkbBox{}, nil
+	}
+
+	var box []byte
+	if w.opts.BBox {
+		out[1] |= twkbHasBBox
+		for i := 0; i < 2; i++ {
+			box = binary.AppendVarint(box, body.box.min[i])
+			box = binary.AppendVarint(box, body.box.max[i]-body.box.min[i])
+		}
+	}
+	if len(ids) > 0 {
+		out[1] |= twkbHasIDs
+	}
+	if w.opts.Size {
+		out[1] |= twkbHasSize
+		out = binary.AppendUvarint(out, uint64(len(box)+len(body.buf)))
+	}
+	out = append(out, box...)
+	out = append(out, body.buf...)
+	return out, body.box, nil
+}
+
+// twkbMultiType returns the multi type of a collection whose members share a
+// type, or twkbCollection.
+func twkbMultiType(gc *GeometryCollection) byte {
+	var typ byte
+	for _, child := range gc.Geometries {
+		var t byte
+		switch normalizeGeography(child).(type) {
+		case *Point:
+			t = twkbMultiPoint
+		case *LineString:
+			t = twkbMultiLineString
+		case *Polygon:
+			t = twkbMultiPolygon
+		default:
+			return twkbCollection
+		}
+		if typ != 0 && t != typ {
+			return twkbCollection
+		}
+		typ = t
+	}
+	if typ == 0 {
+		return twkbCollection
+	}
+	return typ
+}
+
+// normalizeGeography returns the geometry type of the geography variants.
+func normalizeGeography(g Geometry) Geometry {
+	switch v := g.(type) {
+	case *GeographyPoint:
+		return (*Point)(v)
+	case *GeographyLineString:
+		return (*LineString)(v)
+	case *GeographyPolygon:
+		return (*Polygon)(v)
+	case *GeographyCollection:
+		return (*GeometryCollection)(v)
+	}
+	return g
+}
+
+func (w *twkbWriter) polygon(body *twkbBody, p *Polygon) error {
+	body.buf = binary.AppendUvarint(body.buf, uint64(len(p.Rings)))
+	for _, ring := range p.Rings {
+		if err := w.points(body, ring, 4, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// points appends the deltas of the points, preceded by their number when
+// count is set. Points repeating the previous position are skipped as long
+// as more than minPoints remain.
+func (w *twkbWriter) points(body *twkbBody, points []Point, minPoints int, count bool) error {
+	var deltas []byte
+	written, left := 0, len(points)
+	for i, p := range points {
+		x, err := w.quantize(p.Lng)
+		if err != nil {
+			return err
+		}
+		y, err := w.quantize(p.Lat)
+		if err != nil {
+			return err
+		}
+		dx, dy := x-body.prev[0], y-body.prev[1]
+		if i > 0 && dx == 0 && dy == 0 && left > minPoints {
+			left--
+			continue
+		}
+		deltas = binary.AppendVarint(deltas, dx)
+		deltas = binary.AppendVarint(deltas, dy)
+		body.prev = [2]int64{x, y}
+		body.box.add(x, y)
+		written++
+	}
+	if count {
+		body.buf = binary.AppendUvarint(body.buf, uint64(written))
+	}
+	body.buf = append(body.buf, deltas...)
+	return nil
+}
+
+func (w *twkbWriter) quantize(v float64) (int64, error) {
+	q := math.Round(v * w.factor)
+	if math.IsNaN(q) || math.Abs(q) >= twkbMaxQuantity {
+		return 0, fmt.Errorf("coordinate %v out of range", v)
+	}
+	return int64(q), nil
+}
+
+// DecodeTWKB decodes Tiny Well-Known Binary as produced by EncodeTWKB and
+// PostGIS ST_AsTWKB. It returns the geometry and the ids of the members of a
+// top-level collection, or nil when there are none.
+//
+// Multi geometries decode to a *GeometryCollection of their parts. Z and M
+// ordinates are dropped, and empty points are not supported.
+func DecodeTWKB(b []byte) (Geometry, []int64, error) {
+	r := &twkbReader{data: b}
+	g, ids, err := r.geometry(0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("gogis: decoding TWKB: %w", err)
+	}
+	if r.pos != len(b) {
+		return nil, nil, fmt.Errorf("gogis: decoding TWKB: %d trailing bytes", len(b)-r.pos)
+	}
+	return g, ids, nil
+}
+
+type twkbReader struct {
+	data []byte
+	pos  int
+
+	// State of the geometry being decoded.
+	dims    int
+	divisor float64
+	scale   float64
+	prev    [4]int64
+}
+
+func (r *twkbReader) byte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, fmt.Errorf("unexpected end of data")
+	}
+	r.pos++
+	return r.data[r.pos-1], nil
+}
+
+func (r *twkbReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		return 0, fmt.Errorf("invalid varint at offset %d", r.pos)
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *twkbReader) varint() (int64, error) {
+	v, n := binary.Varint(r.data[r.pos:])
+	if n <= 0 {
+		return 0, fmt.Errorf("invalid varint at offset %d", r.pos)
+	}
+	r.pos += n
+	return v, nil
+}
+
+// count reads an element count, checking that the remaining data can hold
+// that many elements of at least minSize bytes.
+func (r *twkbReader) count(minSize int) (int, error) {
+	n, err := r.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64((len(r.data)-r.pos)/minSize) {
+		return 0, fmt.Errorf("count %d exceeds the data", n)
+	}
+	return int(n), nil
+}
+
+func (r *twkbReader) point() (Point, error) {
+	var p Point
+	for i := 0; i < r.dims; i++ {
+		d, err := r.varint()
+		if err != nil {
+			return Point{}, err
+		}
+		r.prev[i] += d
+	}
+	if r.divisor != 0 {
+		p = Point{Lng: float64(r.prev[0]) / r.divisor, Lat: float64(r.prev[1]) / r.divisor}
+	} else {
+		p = Point{Lng: float64(r.prev[0]) * r.scale, Lat: float64(r.prev[1]) * r.scale}
+	}
+	return p, nil
+}
+
+func (r *twkbReader) points() ([]Point, error) {
+	n, err := r.count(r.dims)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]Point, n)
+	for i := range points {
+		if points[i], err = r.point(); err != nil {
+			return nil, err
+		}
+	}
+	return points, nil
+}
+
+func (r *twkbReader) polygon() (*Polygon, error) {
+	n, err := r.count(1)
+	if err != nil {
+		return nil, err
+	}
+	p := &Polygon{Rings: make([][]Point, n)}
+	for i := range p.Rings {
+		if p.Rings[i], err = r.points(); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (r *twkbReader) geometry(depth int) (Geometry, []int64, error) {
+	if depth > maxWKBDepth {
+		return nil, nil, fmt.Errorf("geometry collections nested too deeply")
+	}
+	head, err := r.byte()
+	if err != nil {
+		return nil, nil, err
+	}
+	flags, err := r.byte()
+	if err != nil {
+		return nil, nil, err
+	}
+	typ := head & 0x0F
+	zigzag := int(head >> 4)
+	precision := (zigzag >> 1) ^ -(zigzag & 1)
+
+	dims := 2
+	if flags&twkbExtended != 0 {
+		ext, err := r.byte()
+		if err != nil {
+			return nil, nil, err
+		}
+		if ext&0x01 != 0 {
+			dims++
+		}
+		if ext&0x02 != 0 {
+			dims++
+		}
+	}
+	size, start := -1, 0
+	if flags&twkbHasSize != 0 {
+		n, err := r.uvarint()
+		if err != nil {
+			return nil, nil, err
+		}
+		if n > uint64(len(r.data)-r.pos) {
+			return nil, nil, fmt.Errorf("size %d exceeds the data", n)
+		}
+		size, start = int(n), r.pos
+	}
+
+	var g Geometry
+	var ids []int64
+	if flags&twkbEmpty != 0 {
+		switch typ {
+		case twkbPoint:
+			return nil, nil, fmt.Errorf("empty points are not supported")
+		case twkbLineString:
+			g = &LineString{Points: []Point{}}
+		case twkbPolygon:
+			g = &Polygon{Rings: [][]Point{}}
+		case twkbMultiPoint, twkbMultiLineString, twkbMultiPolygon, twkbCollection:
+			g = &GeometryCollection{Geometries: []Geometry{}}
+		default:
+			return nil, nil, fmt.Errorf("unsupported geometry type %d", typ)
+		}
+	} else {
+		if flags&twkbHasBBox != 0 {
+			for i := 0; i < 2*dims; i++ {
+				if _, err := r.varint(); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+		if g, ids, err = r.body(typ, flags, precision, dims, depth); err != nil {
+			return nil, nil, err
+		}
+	}
+	if size >= 0 && r.pos-start != size {
+		return nil, nil, fmt.Errorf("geometry of %d bytes does not match its size %d", r.pos-start, size)
+	}
+	return g, ids, nil
+}
+
+// body decodes the body of a geometry of the given type.
+func (r *twkbReader) body(typ, flags byte, precision, dims, depth int) (Geometry, []int64, error) {
+	r.dims, r.prev = dims, [4]int64{}
+	r.divisor, r.scale = 0, 0
+	if precision >= 0 {
+		r.divisor = math.Pow10(precision)
+	} else {
+		r.scale = math.Pow10(-precision)
+	}
+
+	switch typ {
+	case twkbPoint:
+		p, err := r.point()
+		if err != nil {
+			return nil, nil, err
+		}
+		return &p, nil, nil
+	case twkbLineString:
+		points, err := r.points()
+		if err != nil {
+			return nil, nil, err
+		}
+		return &LineString{Points: points}, nil, nil
+	case twkbPolygon:
+		p, err := r.polygon()
+		return p, nil, err
+	case twkbMultiPoint, twkbMultiLineString, twkbMultiPolygon, twkbCollection:
+		n, err := r.count(1)
+		if err != nil {
+			return nil, nil, err
+		}
+		var ids []int64
+		if flags&twkbHasIDs != 0 {
+			ids = make([]int64, n)
+			for i := range ids {
+				if ids[i], err = r.varint(); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+		gc := &GeometryCollection{Geometries: make([]Geometry, n)}
+		for i := range gc.Geometries {
+			switch typ {
+			case twkbMultiPoint:
+				var p Point
+				p, err = r.point()
+				gc.Geometries[i] = &p
+			case twkbMultiLineString:
+				var points []Point
+				points, err = r.points()
+				gc.Geometries[i] = &LineString{Points: points}
+			case twkbMultiPolygon:
+				gc.Geometries[i], err = r.polygon()
+			default:
+				gc.Geometries[i], _, err = r.geometry(depth + 1)
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		return gc, ids, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported geometry type %d", typ)
+}
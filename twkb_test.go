@@ -0,0 +1,202 @@
+package gogis_test
+
+import (
+	"encoding/hex"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/restayway/gogis"
+)
+
+func TestEncodeTWKB(t *testing.T) {
+	line := &gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 1}, {Lng: 5, Lat: 5}}}
+	tests := []struct {
+		name string
+		geom gogis.Geometry
+		opts gogis.TWKBOptions
+		want string
+	}{
+		{
+			// SELECT ST_AsTWKB('LINESTRING(1 1,5 5)')
+			name: "linestring",
+			geom: line,
+			want: "02000202020808",
+		},
+		{
+			// SELECT ST_AsTWKB('LINESTRING(1 1,5 5)', 0, 0, 0, true, true)
+			name: "size and bbox",
+			geom: line,
+			opts: gogis.TWKBOptions{Size: true, BBox: true},
+			want: "020309020802080202020808",
+		},
+		{
+			// SELECT ST_AsTWKB('POINT(1.2345 -6.789)', 2)
+			name: "precision",
+			geom: &gogis.GeographyPoint{Lng: 1.2345, Lat: -6.789},
+			opts: gogis.TWKBOptions{Precision: 2},
+			want: "4100f601cd0a",
+		},
+		{
+			name: "negative precision",
+			geom: &gogis.Point{Lng: 1234, Lat: -5678},
+			opts: gogis.TWKBOptions{Precision: -2},
+			want: "31001871",
+		},
+		{
+			// SELECT ST_AsTWKB(ARRAY['POINT(0 0)'::geometry, 'POINT(1 1)'], ARRAY[1, 2])
+			name: "multipoint with ids",
+			geom: &gogis.GeometryCollection{Geometries: []gogis.Geometry{&gogis.Point{}, &gogis.Point{Lng: 1, Lat: 1}}},
+			opts: gogis.TWKBOptions{IDs: []int64{1, 2}},
+			want: "040402020400000202",
+		},
+		{
+			name: "repeated point dropped",
+			geom: &gogis.LineString{Points: []gogis.Point{{Lng: 0, Lat: 0}, {Lng: 0.1, Lat: 0.1}, {Lng: 1, Lat: 1}}},
+			want: "02000200000202",
+		},
+		{
+			name: "collection",
+			geom: &gogis.GeometryCollection{Geometries: []gogis.Geometry{&gogis.Point{Lng: 1, Lat: 2}, &gogis.LineString{Points: []gogis.Point{{}, {Lng: 1, Lat: 1}}}}},
+			want: "0700020100020402000200000202",
+		},
+		{
+			name: "empty",
+			geom: &gogis.LineString{},
+			opts: gogis.TWKBOptions{Size: true, BBox: true},
+			want: "021200",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := gogis.EncodeTWKB(tt.geom, tt.opts)
+			if err != nil {
+				t.Fatalf("EncodeTWKB() unexpected error = %v", err)
+			}
+			if hex.EncodeToString(got) != tt.want {
+				t.Errorf("EncodeTWKB() = %x, want %s", got, tt.want)
+			}
+		})
+	}
+
+	for _, tt := range []struct {
+		name string
+		geom gogis.Geometry
+		opts gogis.TWKBOptions
+	}{
+		{name: "precision", geom: &gogis.Point{}, opts: gogis.TWKBOptions{Precision: 8}},
+		{name: "nan", geom: &gogis.Point{Lng: math.NaN()}},
+		{name: "overflow", geom: &gogis.Point{Lng: 1e18}, opts: gogis.TWKBOptions{Precision: 7}},
+		{name: "ids on point", geom: &gogis.Point{}, opts: gogis.TWKBOptions{IDs: []int64{1}}},
+		{name: "id count", geom: &gogis.GeometryCollection{Geometries: []gogis.Geometry{&gogis.Point{}}}, opts: gogis.TWKBOptions{IDs: []int64{1, 2}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := gogis.EncodeTWKB(tt.geom, tt.opts); err == nil {
+				t.Error("EncodeTWKB() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestDecodeTWKB(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+		want gogis.Geometry
+		ids  []int64
+	}{
+		{
+			name: "point z",
+			hex:  "010801020406",
+			want: &gogis.Point{Lng: 1, Lat: 2},
+		},
+		{
+			name: "negative precision",
+			hex:  "31001871",
+			want: &gogis.Point{Lng: 1200, Lat: -5700},
+		},
+		{
+			name: "size and bbox",
+			hex:  "020309020802080202020808",
+			want: &gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 1}, {Lng: 5, Lat: 5}}},
+		},
+		{
+			name: "multipoint with ids",
+			hex:  "040402020400000202",
+			want: &gogis.GeometryCollection{Geometries: []gogis.Geometry{&gogis.Point{}, &gogis.Point{Lng: 1, Lat: 1}}},
+			ids:  []int64{1, 2},
+		},
+		{
+			name: "empty polygon",
+			hex:  "0310",
+			want: &gogis.Polygon{Rings: [][]gogis.Point{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := hex.DecodeString(tt.hex)
+			got, ids, err := gogis.DecodeTWKB(b)
+			if err != nil {
+				t.Fatalf("DecodeTWKB() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) || !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("DecodeTWKB() = %v, %v, want %v, %v", got, ids, tt.want, tt.ids)
+			}
+		})
+	}
+
+	for _, tt := range []struct {
+		name string
+		hex  string
+	}{
+		{name: "empty input", hex: ""},
+		{name: "truncated", hex: "020002020208"},
+		{name: "trailing bytes", hex: "02000202020808ff"},
+		{name: "size mismatch", hex: "0202040202020808"},
+		{name: "size too large", hex: "0202ff01"},
+		{name: "empty point", hex: "0110"},
+		{name: "unknown type", hex: "0800"},
+		{name: "huge count", hex: "0200ffffffff0f"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := hex.DecodeString(tt.hex)
+			if _, _, err := gogis.DecodeTWKB(b); err == nil {
+				t.Error("DecodeTWKB() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestTWKBRoundTrip(t *testing.T) {
+	ring := []gogis.Point{{Lng: 13.40501, Lat: 52.52}, {Lng: 13.41, Lat: 52.52}, {Lng: 13.41, Lat: 52.53}, {Lng: 13.40501, Lat: 52.52}}
+	geoms := []gogis.Geometry{
+		&gogis.Polygon{Rings: [][]gogis.Point{ring, ring}},
+		&gogis.GeometryCollection{Geometries: []gogis.Geometry{
+			&gogis.Polygon{Rings: [][]gogis.Point{ring}},
+			&gogis.Polygon{Rings: [][]gogis.Point{ring}},
+		}},
+		&gogis.GeometryCollection{Geometries: []gogis.Geometry{
+			&gogis.LineString{Points: ring},
+			&gogis.GeometryCollection{Geometries: []gogis.Geometry{&ring[1]}},
+		}},
+	}
+	for _, g := range geoms {
+		b, err := gogis.EncodeTWKB(g, gogis.TWKBOptions{Precision: 5, Size: true, BBox: true})
+		if err != nil {
+			t.Fatalf("EncodeTWKB() unexpected error = %v", err)
+		}
+		got, _, err := gogis.DecodeTWKB(b)
+		if err != nil {
+			t.Fatalf("DecodeTWKB() unexpected error = %v", err)
+		}
+		if !reflect.DeepEqual(got, g) {
+			t.Errorf("DecodeTWKB(EncodeTWKB()) = %v, want %v", got, g)
+		}
+		wkb, _ := gogis.EncodeEWKB(g, 0)
+		if len(b) >= len(wkb) {
+			t.Errorf("TWKB of %d bytes is not smaller than WKB of %d bytes", len(b), len(wkb))
+		}
+	}
+}
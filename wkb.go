@@ -0,0 +1,312 @@
+package gogis
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+)
+
+// EWKB geometry type flags used by PostGIS.
+const (
+	ewkbZ    = 0x80000000
+	ewkbM    = 0x40000000
+	ewkbSRID = 0x20000000
+)
+
+// Additional WKB geometry types, which decode to a GeometryCollection.
+const (
+	wkbMultiPoint      = 4
+	wkbMultiLineString = 5
+	wkbMultiPolygon    = 6
+)
+
+// EncodeEWKB encodes g as little-endian Extended Well-Known Binary, the
+// format PostGIS uses for geometry and geography values. The SRID is
+// embedded in the header unless it is 0, which produces plain WKB.
+//
+// Point, LineString, Polygon, GeometryCollection and their geography
+// variants are supported.
+func EncodeEWKB(g Geometry, srid SRID) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeEWKB(&buf, g, srid); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeEWKB(buf *bytes.Buffer, g Geometry, srid SRID) error {
+	header := func(typ GeometryType) {
+		buf.WriteByte(1)
+		t := uint32(typ)
+		if srid != 0 {
+			t |= ewkbSRID
+		}
+		writeUint32(buf, t)
+		if srid != 0 {
+			writeUint32(buf, uint32(srid))
+		}
+	}
+	points := func(points []Point) {
+		writeUint32(buf, uint32(len(points)))
+		for _, p := range points {
+			writeFloat64(buf, p.Lng)
+			writeFloat64(buf, p.Lat)
+		}
+	}
+
+	switch v := g.(type) {
+	case *Point:
+		header(GeometryTypePoint)
+		writeFloat64(buf, v.Lng)
+		writeFloat64(buf, v.Lat)
+	case *LineString:
+		header(GeometryTypeLineString)
+		points(v.Points)
+	case *Polygon:
+		header(GeometryTypePolygon)
+		writeUint32(buf, uint32(len(v.Rings)))
+		for _, ring := range v.Rings {
+			points(ring)
+		}
+	case *GeometryCollection:
+		header(GeometryTypeGeometryCollection)
+		writeUint32(buf, uint32(len(v.Geometries)))
+		for i, child := range v.Geometries {
+			if err := writeEWKB(buf, child, 0); err != nil {
+				return fmt.Errorf("geometry %d: %w", i, err)
+			}
+		}
+	case *GeographyPoint:
+		return writeEWKB(buf, (*Point)(v), srid)
+	case *GeographyLineString:
+		return writeEWKB(buf, (*LineString)(v), srid)
+	case *GeographyPolygon:
+		return writeEWKB(buf, (*Polygon)(v), srid)
+	case *GeographyCollection:
+		return writeEWKB(buf, (*GeometryCollection)(v), srid)
+	default:
+		return fmt.Errorf("unsupported geometry type %T", g)
+	}
+	return nil
+}
+
+func writeUint32(buf *bytes.Buffer, v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	buf.Write(b[:])
+}
+
+func writeFloat64(buf *bytes.Buffer, v float64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+	buf.Write(b[:])
+}
+
+// DecodeEWKB decodes Extended Well-Known Binary as produced by PostGIS for
+// geometry and geography values, as well as plain and ISO WKB. It returns the
+// geometry and the embedded SRID, or 0 when there is none.
+//
+// Points, line strings, polygons and collections decode to *Point,
+// *LineString, *Polygon and *GeometryCollection. Multi geometries decode to a
+// *GeometryCollection of their parts. Z and M ordinates are dropped.
+func DecodeEWKB(b []byte) (Geometry, SRID, error) {
+	r := &wkbReader{data: b}
+	g, srid, err := r.geometry(0)
+	if err != nil {
+		return nil, 0, fmt.Errorf("gogis: decoding WKB: %w", err)
+	}
+	if r.pos != len(b) {
+		return nil, 0, fmt.Errorf("gogis: decoding WKB: %d trailing bytes", len(b)-r.pos)
+	}
+	return g, srid, nil
+}
+
+// maxWKBDepth limits the nesting of geometry collections.
+const maxWKBDepth = 32
+
+type wkbReader struct {
+	data  []byte
+	pos   int
+	order binary.ByteOrder
+}
+
+func (r *wkbReader) uint32() (uint32, error) {
+	if r.pos+4 > len(r.data) {
+		return 0, fmt.Errorf("unexpected end of data")
+	}
+	v := r.order.Uint32(r.data[r.pos:])
+	r.pos += 4
+	return v, nil
+}
+
+func (r *wkbReader) float64() (float64, error) {
+	if r.pos+8 > len(r.data) {
+		return 0, fmt.Errorf("unexpected end of data")
+	}
+	v := math.Float64frombits(r.order.Uint64(r.data[r.pos:]))
+	r.pos += 8
+	return v, nil
+}
+
+// count reads an element count, rejecting counts that cannot fit in the
+// remaining data so that corrupt input does not cause huge allocations.
+func (r *wkbReader) count(minSize int) (int, error) {
+	n, err := r.uint32()
+	if err != nil {
+		return 0, err
+	}
+	if int(n) > (len(r.data)-r.pos)/minSize {
+		return 0, fmt.Errorf("count %d exceeds data length", n)
+	}
+	return int(n), nil
+}
+
+func (r *wkbReader) point(dims int) (Point, error) {
+	var p Point
+	var err error
+	if p.Lng, err = r.float64(); err != nil {
+		return Point{}, err
+	}
+	if p.Lat, err = r.float64(); err != nil {
+		return Point{}, err
+	}
+	for i := 2; i < dims; i++ {
+		if _, err := r.float64(); err != nil {
+			return Point{}, err
+		}
+	}
+	return p, nil
+}
+
+func (r *wkbReader) points(dims int) ([]Point, error) {
+	n, err := r.count(8 * dims)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]Point, n)
+	for i := range points {
+		if points[i], err = r.point(dims); err != nil {
+			return nil, err
+		}
+	}
+	return points, nil
+}
+
+func (r *wkbReader) geometry(depth int) (Geometry, SRID, error) {
+	if depth > maxWKBDepth {
+		return nil, 0, fmt.Errorf("geometry collections nested too deeply")
+	}
+	if r.pos >= len(r.data) {
+		return nil, 0, fmt.Errorf("unexpected end of data")
+	}
+	switch r.data[r.pos] {
+	case 0:
+		r.order = binary.BigEndian
+	case 1:
+		r.order = binary.LittleEndian
+	default:
+		return nil, 0, fmt.Errorf("invalid byte order %d", r.data[r.pos])
+	}
+	r.pos++
+
+	t, err := r.uint32()
+	if err != nil {
+		return nil, 0, err
+	}
+	var srid SRID
+	if t&ewkbSRID != 0 {
+		s, err := r.uint32()
+		if err != nil {
+			return nil, 0, err
+		}
+		srid = SRID(s)
+	}
+	dims := 2
+	if t&ewkbZ != 0 {
+		dims++
+	}
+	if t&ewkbM != 0 {
+		dims++
+	}
+	typ := t &^ (ewkbZ | ewkbM | ewkbSRID)
+	switch typ / 1000 {
+	case 1, 2:
+		dims++
+	case 3:
+		dims += 2
+	}
+	typ %= 1000
+
+	switch typ {
+	case uint32(GeometryTypePoint):
+		p, err := r.point(dims)
+		if err != nil {
+			return nil, 0, err
+		}
+		return &p, srid, nil
+	case uint32(GeometryTypeLineString):
+		points, err := r.points(dims)
+		if err != nil {
+			return nil, 0, err
+		}
+		return &LineString{Points: points}, srid, nil
+	case uint32(GeometryTypePolygon):
+		n, err := r.count(4)
+		if err != nil {
+			return nil, 0, err
+		}
+		p := &Polygon{Rings: make([][]Point, n)}
+		for i := range p.Rings {
+			if p.Rings[i], err = r.points(dims); err != nil {
+				return nil, 0, err
+			}
+		}
+		return p, srid, nil
+	case wkbMultiPoint, wkbMultiLineString, wkbMultiPolygon, uint32(GeometryTypeGeometryCollection):
+		n, err := r.count(5)
+		if err != nil {
+			return nil, 0, err
+		}
+		gc := &GeometryCollection{Geometries: make([]Geometry, n)}
+		for i := range gc.Geometries {
+			if gc.Geometries[i], _, err = r.geometry(depth + 1); err != nil {
+				return nil, 0, err
+			}
+		}
+		return gc, srid, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported geometry type %d", typ)
+	}
+}
+
+// scanBytes returns the binary value of a database value holding WKB, either
+// raw or hex encoded as PostGIS returns it in text format.
+func scanBytes(val any) ([]byte, error) {
+	switch v := val.(type) {
+	case []byte:
+		if isHex(v) {
+			return hex.DecodeString(string(v))
+		}
+		return v, nil
+	case string:
+		return hex.DecodeString(v)
+	default:
+		return nil, fmt.Errorf("cannot scan type %T as WKB", val)
+	}
+}
+
+// isHex reports whether b looks like hex encoded WKB. Raw WKB always starts
+// with a 0 or 1 byte order marker, which is not a hex digit.
+func isHex(b []byte) bool {
+	if len(b) == 0 || len(b)%2 != 0 {
+		return false
+	}
+	for _, c := range b {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,157 @@
+package gogis_test
+
+import (
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/restayway/gogis"
+)
+
+func TestEncodeEWKB(t *testing.T) {
+	tests := []struct {
+		name     string
+		geometry gogis.Geometry
+		srid     gogis.SRID
+		expected string
+	}{
+		{
+			name:     "point with SRID",
+			geometry: &gogis.Point{Lng: 1, Lat: 2},
+			srid:     gogis.SRIDWGS84,
+			expected: "0101000020e6100000000000000000f03f0000000000000040",
+		},
+		{
+			name:     "point without SRID",
+			geometry: &gogis.Point{Lng: 1, Lat: 2},
+			expected: "0101000000000000000000f03f0000000000000040",
+		},
+		{
+			name:     "geography point",
+			geometry: &gogis.GeographyPoint{Lng: 1, Lat: 2},
+			srid:     gogis.SRIDWGS84,
+			expected: "0101000020e6100000000000000000f03f0000000000000040",
+		},
+		{
+			name:     "linestring",
+			geometry: &gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}}},
+			expected: "010200000002000000000000000000f03f000000000000004000000000000008400000000000001040",
+		},
+		{
+			name:     "collection children have no SRID",
+			geometry: &gogis.GeometryCollection{Geometries: []gogis.Geometry{&gogis.Point{Lng: 1, Lat: 2}}},
+			srid:     gogis.SRIDWGS84,
+			expected: "0107000020e6100000010000000101000000000000000000f03f0000000000000040",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := gogis.EncodeEWKB(tt.geometry, tt.srid)
+			if err != nil {
+				t.Fatalf("EncodeEWKB() unexpected error = %v", err)
+			}
+			if got := hex.EncodeToString(b); got != tt.expected {
+				t.Errorf("EncodeEWKB() = %s, want %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDecodeEWKB(t *testing.T) {
+	tests := []struct {
+		name     string
+		hex      string
+		expected gogis.Geometry
+		srid     gogis.SRID
+	}{
+		{
+			name:     "EWKB point",
+			hex:      "0101000020E6100000000000000000F03F0000000000000040",
+			expected: &gogis.Point{Lng: 1, Lat: 2},
+			srid:     gogis.SRIDWGS84,
+		},
+		{
+			name:     "big endian WKB point",
+			hex:      "00000000013FF00000000000004000000000000000",
+			expected: &gogis.Point{Lng: 1, Lat: 2},
+		},
+		{
+			name:     "EWKB point Z drops the elevation",
+			hex:      "01010000A0E6100000000000000000F03F00000000000000400000000000000840",
+			expected: &gogis.Point{Lng: 1, Lat: 2},
+			srid:     gogis.SRIDWGS84,
+		},
+		{
+			name:     "ISO WKB point ZM",
+			hex:      "01B90B0000000000000000F03F000000000000004000000000000008400000000000001040",
+			expected: &gogis.Point{Lng: 1, Lat: 2},
+		},
+		{
+			name: "polygon",
+			hex: "0103000020E61000000100000004000000" +
+				"00000000000000000000000000000000" +
+				"000000000000F03F0000000000000000" +
+				"000000000000F03F000000000000F03F" +
+				"00000000000000000000000000000000",
+			expected: &gogis.Polygon{Rings: [][]gogis.Point{{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 0}, {Lng: 1, Lat: 1}, {Lng: 0, Lat: 0}}}},
+			srid:     gogis.SRIDWGS84,
+		},
+		{
+			name: "multipoint decodes to a collection",
+			hex: "0104000020E610000002000000" +
+				"0101000000000000000000F03F0000000000000040" +
+				"010100000000000000000008400000000000001040",
+			expected: &gogis.GeometryCollection{Geometries: []gogis.Geometry{
+				&gogis.Point{Lng: 1, Lat: 2},
+				&gogis.Point{Lng: 3, Lat: 4},
+			}},
+			srid: gogis.SRIDWGS84,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := hex.DecodeString(tt.hex)
+			if err != nil {
+				t.Fatalf("invalid test hex: %v", err)
+			}
+			g, srid, err := gogis.DecodeEWKB(b)
+			if err != nil {
+				t.Fatalf("DecodeEWKB() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(g, tt.expected) {
+				t.Errorf("DecodeEWKB() = %s, want %s", g, tt.expected)
+			}
+			if srid != tt.srid {
+				t.Errorf("DecodeEWKB() srid = %d, want %d", srid, tt.srid)
+			}
+		})
+	}
+}
+
+func TestDecodeEWKBErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		hex   string
+		error string
+	}{
+		{name: "empty", hex: "", error: "unexpected end of data"},
+		{name: "invalid byte order", hex: "02", error: "invalid byte order"},
+		{name: "truncated point", hex: "0101000000000000000000F03F", error: "unexpected end of data"},
+		{name: "unsupported type", hex: "0111000000", error: "unsupported geometry type 17"},
+		{name: "huge count", hex: "0102000000FFFFFFFF", error: "exceeds data length"},
+		{name: "trailing bytes", hex: "0101000000000000000000F03F000000000000004000", error: "trailing bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := hex.DecodeString(tt.hex)
+			_, _, err := gogis.DecodeEWKB(b)
+			if err == nil || !strings.Contains(err.Error(), tt.error) {
+				t.Errorf("DecodeEWKB() error = %v, want error containing %q", err, tt.error)
+			}
+		})
+	}
+}
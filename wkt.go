@@ -0,0 +1,285 @@
+package gogis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxWKTDepth limits the nesting of geometry collections.
+const maxWKTDepth = 32
+
+// ParseWKT parses Well-Known Text, such as "POINT(-74.0445 40.6892)", or the
+// Extended WKT that String returns, such as "SRID=4326;POINT(-74.0445
+// 40.6892)". It returns the geometry and the SRID of the prefix, or 0 when
+// there is none.
+//
+// Keywords are case insensitive. Multi geometries parse to a
+// *GeometryCollection of their parts, and Z and M ordinates are dropped.
+func ParseWKT(s string) (Geometry, SRID, error) {
+	p := &wktParser{s: s}
+	srid, err := p.srid()
+	if err != nil {
+		return nil, 0, fmt.Errorf("gogis: parsing WKT: %w", err)
+	}
+	g, err := p.geometry(0)
+	if err == nil {
+		p.space()
+		if p.pos < len(p.s) {
+			err = fmt.Errorf("unexpected %q at offset %d", p.s[p.pos:], p.pos)
+		}
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("gogis: parsing WKT: %w", err)
+	}
+	return g, srid, nil
+}
+
+type wktParser struct {
+	s   string
+	pos int
+}
+
+func (p *wktParser) space() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// peek returns the next non-space byte, or 0 at the end of the input.
+func (p *wktParser) peek() byte {
+	p.space()
+	if p.pos == len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *wktParser) expect(c byte) error {
+	if p.peek() != c {
+		return p.unexpected(fmt.Sprintf("%q", c))
+	}
+	p.pos++
+	return nil
+}
+
+func (p *wktParser) unexpected(want string) error {
+	if p.pos == len(p.s) {
+		return fmt.Errorf("unexpected end of text, expecting %s", want)
+	}
+	return fmt.Errorf("unexpected %q at offset %d, expecting %s", p.s[p.pos], p.pos, want)
+}
+
+// word reads a keyword and returns it in upper case.
+func (p *wktParser) word() string {
+	p.space()
+	start := p.pos
+	for p.pos < len(p.s) && (p.s[p.pos] >= 'A' && p.s[p.pos] <= 'Z' || p.s[p.pos] >= 'a' && p.s[p.pos] <= 'z') {
+		p.pos++
+	}
+	return strings.ToUpper(p.s[start:p.pos])
+}
+
+// srid reads the "SRID=n;" prefix of Extended WKT, if present.
+func (p *wktParser) srid() (SRID, error) {
+	p.space()
+	if len(p.s)-p.pos < 5 || !strings.EqualFold(p.s[p.pos:p.pos+5], "SRID=") {
+		return 0, nil
+	}
+	end := strings.IndexByte(p.s[p.pos:], ';')
+	if end < 0 {
+		return 0, fmt.Errorf("SRID prefix without ';'")
+	}
+	value := p.s[p.pos+5 : p.pos+end]
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid SRID %q", value)
+	}
+	p.pos += end + 1
+	return SRID(n), nil
+}
+
+// empty reads the EMPTY keyword, reporting whether it was present.
+func (p *wktParser) empty() bool {
+	save := p.pos
+	if p.word() == "EMPTY" {
+		return true
+	}
+	p.pos = save
+	return false
+}
+
+func (p *wktParser) geometry(depth int) (Geometry, error) {
+	if depth > maxWKTDepth {
+		return nil, fmt.Errorf("geometry collections nested too deeply")
+	}
+	typ := p.word()
+	if typ == "" {
+		return nil, p.unexpected("geometry type")
+	}
+	// Dimension suffixes, written apart as in "POINT Z" or attached as in
+	// "POINTZM". The ordinates are counted when parsing positions.
+	base := typ
+	for _, suffix := range []string{"ZM", "Z", "M"} {
+		if trimmed := strings.TrimSuffix(typ, suffix); trimmed != typ && isWKTType(trimmed) {
+			base = trimmed
+			break
+		}
+	}
+	if base == typ {
+		save := p.pos
+		if w := p.word(); w != "Z" && w != "M" && w != "ZM" {
+			p.pos = save
+		}
+	}
+
+	switch base {
+	case "POINT":
+		if p.empty() {
+			return nil, fmt.Errorf("empty points are not supported")
+		}
+		if err := p.expect('('); err != nil {
+			return nil, err
+		}
+		pt, err := p.position()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(')'); err != nil {
+			return nil, err
+		}
+		return &pt, nil
+	case "LINESTRING":
+		points, err := p.positions()
+		if err != nil {
+			return nil, err
+		}
+		return &LineString{Points: points}, nil
+	case "POLYGON":
+		rings, err := p.rings()
+		if err != nil {
+			return nil, err
+		}
+		return &Polygon{Rings: rings}, nil
+	case "MULTIPOINT":
+		gc := &GeometryCollection{Geometries: []Geometry{}}
+		err := p.list(func() error {
+			// Points may be written with or without parentheses.
+			parens := p.peek() == '('
+			if parens {
+				p.pos++
+			}
+			pt, err := p.position()
+			if err != nil {
+				return err
+			}
+			if parens {
+				if err := p.expect(')'); err != nil {
+					return err
+				}
+			}
+			gc.Geometries = append(gc.Geometries, &pt)
+			return nil
+		})
+		return gc, err
+	case "MULTILINESTRING":
+		gc := &GeometryCollection{Geometries: []Geometry{}}
+		err := p.list(func() error {
+			points, err := p.positions()
+			gc.Geometries = append(gc.Geometries, &LineString{Points: points})
+			return err
+		})
+		return gc, err
+	case "MULTIPOLYGON":
+		gc := &GeometryCollection{Geometries: []Geometry{}}
+		err := p.list(func() error {
+			rings, err := p.rings()
+			gc.Geometries = append(gc.Geometries, &Polygon{Rings: rings})
+			return err
+		})
+		return gc, err
+	case "GEOMETRYCOLLECTION":
+		gc := &GeometryCollection{Geometries: []Geometry{}}
+		err := p.list(func() error {
+			g, err := p.geometry(depth + 1)
+			gc.Geometries = append(gc.Geometries, g)
+			return err
+		})
+		return gc, err
+	}
+	return nil, fmt.Errorf("unsupported geometry type %q", typ)
+}
+
+func isWKTType(s string) bool {
+	switch s {
+	case "POINT", "LINESTRING", "POLYGON", "MULTIPOINT", "MULTILINESTRING", "MULTIPOLYGON", "GEOMETRYCOLLECTION":
+		return true
+	}
+	return false
+}
+
+// list reads EMPTY or a parenthesized, comma separated list, calling item
+// for each element.
+func (p *wktParser) list(item func() error) error {
+	if p.empty() {
+		return nil
+	}
+	if err := p.expect('('); err != nil {
+		return err
+	}
+	for {
+		if err := item(); err != nil {
+			return err
+		}
+		if p.peek() != ',' {
+			return p.expect(')')
+		}
+		p.pos++
+	}
+}
+
+func (p *wktParser) positions() ([]Point, error) {
+	points := []Point{}
+	err := p.list(func() error {
+		pt, err := p.position()
+		points = append(points, pt)
+		return err
+	})
+	return points, err
+}
+
+func (p *wktParser) rings() ([][]Point, error) {
+	rings := [][]Point{}
+	err := p.list(func() error {
+		ring, err := p.positions()
+		rings = append(rings, ring)
+		return err
+	})
+	return rings, err
+}
+
+// position reads two to four ordinates, keeping the first two.
+func (p *wktParser) position() (Point, error) {
+	var values [4]float64
+	n := 0
+	for n < len(values) {
+		p.space()
+		start := p.pos
+		for p.pos < len(p.s) && strings.IndexByte(" \t\r\n,()", p.s[p.pos]) < 0 {
+			p.pos++
+		}
+		if start == p.pos {
+			break
+		}
+		v, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+		if err != nil {
+			return Point{}, fmt.Errorf("invalid coordinate %q at offset %d", p.s[start:p.pos], start)
+		}
+		values[n] = v
+		n++
+	}
+	if n < 2 {
+		return Point{}, p.unexpected("coordinate")
+	}
+	return Point{Lng: values[0], Lat: values[1]}, nil
+}
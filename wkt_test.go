@@ -0,0 +1,101 @@
+package gogis_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/restayway/gogis"
+)
+
+func TestParseWKT(t *testing.T) {
+	square := []gogis.Point{{Lng: 0, Lat: 0}, {Lng: 4, Lat: 0}, {Lng: 4, Lat: 4}, {Lng: 0, Lat: 0}}
+	tests := []struct {
+		name string
+		wkt  string
+		want gogis.Geometry
+		srid gogis.SRID
+	}{
+		{"point", "POINT(-74.0445 40.6892)", &gogis.Point{Lng: -74.0445, Lat: 40.6892}, 0},
+		{"ewkt", "SRID=4326;POINT(1 2)", &gogis.Point{Lng: 1, Lat: 2}, 4326},
+		{"lower case", "point ( 1  2 )", &gogis.Point{Lng: 1, Lat: 2}, 0},
+		{"z", "POINT Z (1 2 3)", &gogis.Point{Lng: 1, Lat: 2}, 0},
+		{"attached zm", "POINTZM(1 2 3 4)", &gogis.Point{Lng: 1, Lat: 2}, 0},
+		{"exponent", "POINT(1e2 -2.5E-1)", &gogis.Point{Lng: 100, Lat: -0.25}, 0},
+		{
+			"linestring", "LINESTRING(1 2, 3 4)",
+			&gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}}}, 0,
+		},
+		{"empty linestring", "LINESTRING EMPTY", &gogis.LineString{Points: []gogis.Point{}}, 0},
+		{
+			"polygon", "SRID=3857;POLYGON((0 0,4 0,4 4,0 0))",
+			&gogis.Polygon{Rings: [][]gogis.Point{square}}, 3857,
+		},
+		{
+			"multipoint", "MULTIPOINT((1 2),3 4)",
+			&gogis.GeometryCollection{Geometries: []gogis.Geometry{&gogis.Point{Lng: 1, Lat: 2}, &gogis.Point{Lng: 3, Lat: 4}}}, 0,
+		},
+		{
+			"multipolygon", "MULTIPOLYGON(((0 0,4 0,4 4,0 0)))",
+			&gogis.GeometryCollection{Geometries: []gogis.Geometry{&gogis.Polygon{Rings: [][]gogis.Point{square}}}}, 0,
+		},
+		{
+			"collection", "GEOMETRYCOLLECTION(POINT(1 2),GEOMETRYCOLLECTION EMPTY)",
+			&gogis.GeometryCollection{Geometries: []gogis.Geometry{
+				&gogis.Point{Lng: 1, Lat: 2},
+				&gogis.GeometryCollection{Geometries: []gogis.Geometry{}},
+			}}, 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, srid, err := gogis.ParseWKT(tt.wkt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) || srid != tt.srid {
+				t.Errorf("ParseWKT(%q) = %v, %d, want %v, %d", tt.wkt, got, srid, tt.want, tt.srid)
+			}
+		})
+	}
+}
+
+func TestParseWKTString(t *testing.T) {
+	for _, g := range []gogis.Geometry{
+		&gogis.Point{Lng: -74.0445, Lat: 40.6892},
+		&gogis.LineString{Points: []gogis.Point{{Lng: 1.5, Lat: 2}, {Lng: 3, Lat: -4.25}}},
+		&gogis.Polygon{Rings: [][]gogis.Point{{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 0}, {Lng: 1, Lat: 1}, {Lng: 0, Lat: 0}}}},
+		&gogis.GeometryCollection{Geometries: []gogis.Geometry{
+			&gogis.Point{Lng: 1, Lat: 2},
+			&gogis.LineString{Points: []gogis.Point{{Lng: 1, Lat: 2}, {Lng: 3, Lat: 4}}},
+		}},
+	} {
+		got, srid, err := gogis.ParseWKT(g.String())
+		if err != nil {
+			t.Fatalf("ParseWKT(%q): %v", g.String(), err)
+		}
+		if !reflect.DeepEqual(got, g) || srid != 4326 {
+			t.Errorf("ParseWKT(%q) = %v, %d", g.String(), got, srid)
+		}
+	}
+}
+
+func TestParseWKTErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"POINT",
+		"POINT EMPTY",
+		"POINT(1)",
+		"POINT(1 x)",
+		"POINT(1 2",
+		"POINT(1 2) junk",
+		"CIRCLE(1 2)",
+		"LINESTRING(1 2,)",
+		"SRID=x;POINT(1 2)",
+		"SRID=4326 POINT(1 2)",
+		"GEOMETRYCOLLECTION(POINT(1 2),CIRCLE(1 2))",
+	} {
+		if g, _, err := gogis.ParseWKT(s); err == nil {
+			t.Errorf("ParseWKT(%q) = %v, want error", s, g)
+		}
+	}
+}